@@ -21,7 +21,11 @@ type Response struct {
 }
 
 func ResultJson(ctx *gin.Context, code int, msg string, data interface{}) {
-	ctx.JSON(http.StatusOK, Response{
+	ResultJsonWithStatus(ctx, http.StatusOK, code, msg, data)
+}
+
+func ResultJsonWithStatus(ctx *gin.Context, status int, code int, msg string, data interface{}) {
+	ctx.JSON(status, Response{
 		Code:    code,
 		Message: msg,
 		Data:    data,
@@ -0,0 +1,193 @@
+package common
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed five-field cron expression: minute, hour, day of
+// month, month and day of week. Fields accept *, numbers, ranges (1-5), steps
+// (*/15, 1-30/2), lists (1,15) and month and weekday names (JAN, MON).
+type CronSchedule struct {
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+	// When both day fields are restricted a day matches if either matches,
+	// as in classic cron.
+	anyDay     bool
+	anyWeekday bool
+}
+
+type cronField struct {
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	minuteField  = cronField{min: 0, max: 59}
+	hourField    = cronField{min: 0, max: 23}
+	dayField     = cronField{min: 1, max: 31}
+	monthField   = cronField{min: 1, max: 12, names: map[string]int{"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6, "JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12}}
+	weekdayField = cronField{min: 0, max: 7, names: map[string]int{"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6}}
+)
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronSearchLimit bounds the search for the next occurrence so expressions
+// that never match, e.g. 30 February, do not loop forever.
+const cronSearchLimit = 5
+
+func ParseCron(expression string) (*CronSchedule, error) {
+	expression = strings.TrimSpace(expression)
+	if macro, ok := cronMacros[strings.ToLower(expression)]; ok {
+		expression = macro
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, errors.New("cron expression needs five fields")
+	}
+
+	schedule := &CronSchedule{
+		anyDay:     fields[2] == "*" || fields[2] == "?",
+		anyWeekday: fields[4] == "*" || fields[4] == "?",
+	}
+
+	var err error
+	if schedule.minutes, err = minuteField.parse(fields[0]); err != nil {
+		return nil, err
+	}
+	if schedule.hours, err = hourField.parse(fields[1]); err != nil {
+		return nil, err
+	}
+	if schedule.days, err = dayField.parse(fields[2]); err != nil {
+		return nil, err
+	}
+	if schedule.months, err = monthField.parse(fields[3]); err != nil {
+		return nil, err
+	}
+	if schedule.weekdays, err = weekdayField.parse(fields[4]); err != nil {
+		return nil, err
+	}
+	// 7 is another name for Sunday.
+	if schedule.weekdays&(1<<7) != 0 {
+		schedule.weekdays |= 1
+	}
+
+	return schedule, nil
+}
+
+func (field cronField) parse(expression string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expression, ",") {
+		invalid := errors.New("invalid cron field: " + part)
+
+		rangePart, step := part, 1
+		if slash := strings.Index(part, "/"); slash != -1 {
+			rangePart = part[:slash]
+			parsedStep, err := strconv.Atoi(part[slash+1:])
+			if err != nil || parsedStep < 1 {
+				return 0, invalid
+			}
+			step = parsedStep
+		}
+
+		low, high := field.min, field.max
+		switch {
+		case rangePart == "*" || rangePart == "?":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = field.value(bounds[0]); err != nil {
+				return 0, invalid
+			}
+			if high, err = field.value(bounds[1]); err != nil {
+				return 0, invalid
+			}
+		default:
+			value, err := field.value(rangePart)
+			if err != nil {
+				return 0, invalid
+			}
+			low = value
+			if step == 1 {
+				high = value
+			}
+		}
+
+		if low > high {
+			return 0, invalid
+		}
+		for value := low; value <= high; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+
+	return bits, nil
+}
+
+func (field cronField) value(text string) (int, error) {
+	if value, ok := field.names[strings.ToUpper(text)]; ok {
+		return value, nil
+	}
+	value, err := strconv.Atoi(text)
+	if err != nil || value < field.min || value > field.max {
+		return 0, errors.New("value out of range")
+	}
+	return value, nil
+}
+
+func (schedule *CronSchedule) dayMatches(t time.Time) bool {
+	dayMatch := schedule.days&(1<<uint(t.Day())) != 0
+	weekdayMatch := schedule.weekdays&(1<<uint(t.Weekday())) != 0
+	if schedule.anyDay || schedule.anyWeekday {
+		return dayMatch && weekdayMatch
+	}
+	return dayMatch || weekdayMatch
+}
+
+// Next returns the first occurrence strictly after the given time, in the
+// location of that time. It returns the zero time if there is none within the
+// next five years.
+func (schedule *CronSchedule) Next(after time.Time) time.Time {
+	location := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + cronSearchLimit
+
+	for t.Year() <= limit {
+		if schedule.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, location)
+			continue
+		}
+		if !schedule.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, location)
+			continue
+		}
+		if schedule.hours&(1<<uint(t.Hour())) == 0 {
+			// Adding an hour rather than building the next hour keeps the search
+			// moving across daylight saving time changes.
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, location).Add(time.Hour)
+			continue
+		}
+		if schedule.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,64 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCronNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone database is not available")
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		after      time.Time
+		expected   time.Time
+	}{
+		{"Every minute", "* * * * *", time.Date(2024, 3, 1, 10, 15, 30, 0, time.UTC), time.Date(2024, 3, 1, 10, 16, 0, 0, time.UTC)},
+		{"Weekly on Monday", "0 9 * * MON", time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)},
+		{"Steps", "*/15 * * * *", time.Date(2024, 3, 1, 10, 16, 0, 0, time.UTC), time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)},
+		{"Ranges and lists", "30 8-10 1,15 * *", time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC), time.Date(2024, 3, 15, 8, 30, 0, 0, time.UTC)},
+		{"Day of month or weekday", "0 0 13 * FRI", time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{"Leap day", "0 0 29 FEB *", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"Macro", "@monthly", time.Date(2024, 12, 15, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"Sunday as 7", "0 12 * * 7", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 3, 12, 0, 0, 0, time.UTC)},
+		{"Time zone", "0 9 * * *", time.Date(2024, 3, 1, 9, 0, 0, 0, berlin), time.Date(2024, 3, 2, 9, 0, 0, 0, berlin)},
+		{"Daylight saving gap", "30 2 * * *", time.Date(2024, 3, 30, 12, 0, 0, 0, berlin), time.Date(2024, 4, 1, 2, 30, 0, 0, berlin)},
+		{"Never", "0 0 30 FEB *", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseCron(tt.expression)
+
+			assert.NoError(t, err)
+			assert.True(t, tt.expected.Equal(schedule.Next(tt.after)), "got %v", schedule.Next(tt.after))
+		})
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+	}{
+		{"Too few fields", "* * * *"},
+		{"Out of range", "60 * * * *"},
+		{"Reversed range", "* 10-5 * * *"},
+		{"Invalid step", "*/0 * * * *"},
+		{"Unknown name", "* * * FOO *"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCron(tt.expression)
+
+			assert.Error(t, err)
+		})
+	}
+}
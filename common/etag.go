@@ -0,0 +1,92 @@
+package common
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errIfMatchMismatch = errors.New("workflow version mismatch")
+	errInvalidIfMatch  = errors.New("If-Match must be * or a list of quoted entity tags")
+	errIfMatchVersions = errors.New("If-Match must name a single workflow version")
+)
+
+// SetETag exposes the workflow version so clients can send it back in If-Match.
+func SetETag(ctx *gin.Context, version int64) {
+	ctx.Header("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// IfMatchVersion reads the expected workflow version from the If-Match header.
+// A nil version means the request is unconditional. When false is returned the
+// response has already been written and the handler must stop.
+func IfMatchVersion(ctx *gin.Context) (*int64, bool) {
+	ifMatch := ctx.GetHeader("If-Match")
+	if strings.TrimSpace(ifMatch) == "" && os.Getenv("REQUIRE_IF_MATCH") == "true" {
+		ResultJsonWithStatus(ctx, http.StatusPreconditionRequired, ERROR, "If-Match header is required", map[string]interface{}{})
+		return nil, false
+	}
+
+	version, err := ParseIfMatch(ifMatch)
+	if errors.Is(err, errIfMatchMismatch) {
+		ResultJsonWithStatus(ctx, http.StatusPreconditionFailed, ERROR, err.Error(), map[string]interface{}{})
+		return nil, false
+	}
+	if err != nil {
+		ResultJsonWithStatus(ctx, http.StatusBadRequest, ERROR, err.Error(), map[string]interface{}{})
+		return nil, false
+	}
+
+	return version, true
+}
+
+// ParseIfMatch returns the workflow version an If-Match header asks for, or
+// nil for an empty header and "*". If-Match uses the strong comparison of RFC
+// 9110, so weak tags and tags that are not versions never match and are
+// skipped; errIfMatchMismatch is returned when no tag is left. A list naming
+// several versions is rejected because a write checks a single version.
+func ParseIfMatch(header string) (*int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	var version *int64
+	for rest := header; rest != ""; {
+		weak := strings.HasPrefix(rest, "W/")
+		rest = strings.TrimPrefix(rest, "W/")
+		if !strings.HasPrefix(rest, `"`) {
+			return nil, errInvalidIfMatch
+		}
+		end := strings.IndexByte(rest[1:], '"')
+		if end < 0 {
+			return nil, errInvalidIfMatch
+		}
+		tag := rest[1 : end+1]
+		rest = strings.TrimLeft(rest[end+2:], " \t")
+		if rest != "" {
+			if rest[0] != ',' {
+				return nil, errInvalidIfMatch
+			}
+			rest = strings.TrimLeft(rest[1:], " \t,")
+		}
+
+		parsed, err := strconv.ParseInt(tag, 10, 64)
+		if weak || err != nil {
+			continue
+		}
+		if version != nil && parsed != *version {
+			return nil, errIfMatchVersions
+		}
+		version = &parsed
+	}
+
+	if version == nil {
+		return nil, errIfMatchMismatch
+	}
+	return version, nil
+}
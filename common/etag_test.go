@@ -0,0 +1,46 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		version int64
+		any     bool
+		err     string
+	}{
+		{name: "Empty", header: "", any: true},
+		{name: "Any", header: "*", any: true},
+		{name: "Single version", header: `"3"`, version: 3},
+		{name: "List of one version", header: `"abc", W/"4", "3"`, version: 3},
+		{name: "Repeated version", header: `"3","3"`, version: 3},
+		{name: "Several versions", header: `"3", "4"`, err: "If-Match must name a single workflow version"},
+		{name: "Weak tag", header: `W/"3"`, err: "workflow version mismatch"},
+		{name: "Not a version", header: `"abc"`, err: "workflow version mismatch"},
+		{name: "Unquoted", header: "3", err: "If-Match must be * or a list of quoted entity tags"},
+		{name: "Unterminated", header: `"3`, err: "If-Match must be * or a list of quoted entity tags"},
+		{name: "Missing comma", header: `"3" "4"`, err: "If-Match must be * or a list of quoted entity tags"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := ParseIfMatch(tt.header)
+
+			switch {
+			case tt.err != "":
+				assert.EqualError(t, err, tt.err)
+			case tt.any:
+				assert.NoError(t, err)
+				assert.Nil(t, version)
+			default:
+				assert.NoError(t, err)
+				assert.Equal(t, tt.version, *version)
+			}
+		})
+	}
+}
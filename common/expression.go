@@ -0,0 +1,473 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expressions are a small, side-effect free language used by gateway conditions:
+// literals (numbers, 'strings', true, false, null), variables (a.b.c),
+// arithmetic (+ - * /), comparison (== != < <= > >=), && || ! and parentheses.
+// There are no function calls, so evaluating untrusted input is safe.
+
+type expressionTokenKind int
+
+const (
+	tokenEnd expressionTokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdentifier
+	tokenOperator
+)
+
+type expressionToken struct {
+	kind  expressionTokenKind
+	value string
+}
+
+type expressionParser struct {
+	tokens    []expressionToken
+	position  int
+	variables map[string]interface{}
+	evaluate  bool
+}
+
+// ParseExpression checks the syntax of an expression without evaluating it.
+func ParseExpression(expression string) error {
+	_, err := runExpression(expression, nil, false)
+	return err
+}
+
+// EvaluateExpression evaluates an expression against the given variables.
+// Unknown variables evaluate to null.
+func EvaluateExpression(expression string, variables map[string]interface{}) (interface{}, error) {
+	return runExpression(expression, variables, true)
+}
+
+// EvaluateCondition evaluates an expression that must produce a boolean.
+func EvaluateCondition(expression string, variables map[string]interface{}) (bool, error) {
+	result, err := EvaluateExpression(expression, variables)
+	if err != nil {
+		return false, err
+	}
+
+	value, ok := result.(bool)
+	if !ok {
+		return false, errors.New("condition does not evaluate to a boolean: " + expression)
+	}
+
+	return value, nil
+}
+
+func runExpression(expression string, variables map[string]interface{}, evaluate bool) (interface{}, error) {
+	tokens, err := tokenizeExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &expressionParser{tokens: tokens, variables: variables, evaluate: evaluate}
+	result, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if parser.peek().kind != tokenEnd {
+		return nil, errors.New("unexpected token in expression: " + parser.peek().value)
+	}
+
+	return result, nil
+}
+
+func tokenizeExpression(expression string) ([]expressionToken, error) {
+	tokens := []expressionToken{}
+	runes := []rune(expression)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, expressionToken{kind: tokenNumber, value: string(runes[start:i])})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, expressionToken{kind: tokenIdentifier, value: string(runes[start:i])})
+		case r == '\'' || r == '"':
+			quote := r
+			i++
+			var builder strings.Builder
+			for i < len(runes) && runes[i] != quote {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				builder.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, errors.New("unterminated string in expression")
+			}
+			i++
+			tokens = append(tokens, expressionToken{kind: tokenString, value: builder.String()})
+		default:
+			if i+1 < len(runes) {
+				pair := string(runes[i : i+2])
+				switch pair {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					tokens = append(tokens, expressionToken{kind: tokenOperator, value: pair})
+					i += 2
+					continue
+				}
+			}
+			if strings.ContainsRune("+-*/<>!()", r) {
+				tokens = append(tokens, expressionToken{kind: tokenOperator, value: string(r)})
+				i++
+				continue
+			}
+			return nil, fmt.Errorf("unexpected character in expression: %q", r)
+		}
+	}
+
+	return append(tokens, expressionToken{kind: tokenEnd}), nil
+}
+
+func (parser *expressionParser) peek() expressionToken {
+	return parser.tokens[parser.position]
+}
+
+func (parser *expressionParser) next() expressionToken {
+	token := parser.tokens[parser.position]
+	if token.kind != tokenEnd {
+		parser.position++
+	}
+	return token
+}
+
+func (parser *expressionParser) acceptOperator(operators ...string) (string, bool) {
+	token := parser.peek()
+	if token.kind != tokenOperator {
+		return "", false
+	}
+	for _, operator := range operators {
+		if token.value == operator {
+			parser.position++
+			return operator, true
+		}
+	}
+	return "", false
+}
+
+func (parser *expressionParser) parseOr() (interface{}, error) {
+	left, err := parser.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, ok := parser.acceptOperator("||"); !ok {
+			return left, nil
+		}
+		right, err := parser.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left, err = parser.logical("||", left, right)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (parser *expressionParser) parseAnd() (interface{}, error) {
+	left, err := parser.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, ok := parser.acceptOperator("&&"); !ok {
+			return left, nil
+		}
+		right, err := parser.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left, err = parser.logical("&&", left, right)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (parser *expressionParser) parseNot() (interface{}, error) {
+	if _, ok := parser.acceptOperator("!"); ok {
+		value, err := parser.parseNot()
+		if err != nil || !parser.evaluate {
+			return nil, err
+		}
+		boolean, ok := value.(bool)
+		if !ok {
+			return nil, errors.New("operator ! requires a boolean")
+		}
+		return !boolean, nil
+	}
+
+	return parser.parseComparison()
+}
+
+func (parser *expressionParser) parseComparison() (interface{}, error) {
+	left, err := parser.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	operator, ok := parser.acceptOperator("==", "!=", "<=", ">=", "<", ">")
+	if !ok {
+		return left, nil
+	}
+
+	right, err := parser.parseAdditive()
+	if err != nil || !parser.evaluate {
+		return nil, err
+	}
+
+	return compareValues(operator, left, right)
+}
+
+func (parser *expressionParser) parseAdditive() (interface{}, error) {
+	left, err := parser.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		operator, ok := parser.acceptOperator("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := parser.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		if !parser.evaluate {
+			continue
+		}
+		if operator == "+" {
+			leftString, leftIsString := left.(string)
+			rightString, rightIsString := right.(string)
+			if leftIsString && rightIsString {
+				left = leftString + rightString
+				continue
+			}
+		}
+		left, err = arithmetic(operator, left, right)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (parser *expressionParser) parseMultiplicative() (interface{}, error) {
+	left, err := parser.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		operator, ok := parser.acceptOperator("*", "/")
+		if !ok {
+			return left, nil
+		}
+		right, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if !parser.evaluate {
+			continue
+		}
+		left, err = arithmetic(operator, left, right)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (parser *expressionParser) parseUnary() (interface{}, error) {
+	if _, ok := parser.acceptOperator("-"); ok {
+		value, err := parser.parseUnary()
+		if err != nil || !parser.evaluate {
+			return nil, err
+		}
+		return arithmetic("-", 0.0, value)
+	}
+
+	return parser.parsePrimary()
+}
+
+func (parser *expressionParser) parsePrimary() (interface{}, error) {
+	token := parser.next()
+	switch token.kind {
+	case tokenNumber:
+		number, err := strconv.ParseFloat(token.value, 64)
+		if err != nil {
+			return nil, errors.New("invalid number in expression: " + token.value)
+		}
+		return number, nil
+	case tokenString:
+		return token.value, nil
+	case tokenIdentifier:
+		switch token.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		if !parser.evaluate {
+			return nil, nil
+		}
+		return lookupVariable(parser.variables, token.value), nil
+	case tokenOperator:
+		if token.value == "(" {
+			value, err := parser.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := parser.acceptOperator(")"); !ok {
+				return nil, errors.New("missing closing parenthesis in expression")
+			}
+			return value, nil
+		}
+	}
+
+	if token.kind == tokenEnd {
+		return nil, errors.New("unexpected end of expression")
+	}
+	return nil, errors.New("unexpected token in expression: " + token.value)
+}
+
+func (parser *expressionParser) logical(operator string, left interface{}, right interface{}) (interface{}, error) {
+	if !parser.evaluate {
+		return nil, nil
+	}
+
+	leftBool, leftOk := left.(bool)
+	rightBool, rightOk := right.(bool)
+	if !leftOk || !rightOk {
+		return nil, errors.New("operator " + operator + " requires booleans")
+	}
+
+	if operator == "&&" {
+		return leftBool && rightBool, nil
+	}
+	return leftBool || rightBool, nil
+}
+
+func lookupVariable(variables map[string]interface{}, path string) interface{} {
+	var current interface{} = variables
+	for _, key := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			current = node[key]
+		default:
+			return nil
+		}
+	}
+	return normalizeExpressionValue(current)
+}
+
+// normalizeExpressionValue converts the numeric types coming from JSON and BSON
+// decoding to float64 so they can be compared with literals.
+func normalizeExpressionValue(value interface{}) interface{} {
+	switch number := value.(type) {
+	case int:
+		return float64(number)
+	case int32:
+		return float64(number)
+	case int64:
+		return float64(number)
+	case float32:
+		return float64(number)
+	default:
+		return value
+	}
+}
+
+func arithmetic(operator string, left interface{}, right interface{}) (interface{}, error) {
+	leftNumber, leftOk := normalizeExpressionValue(left).(float64)
+	rightNumber, rightOk := normalizeExpressionValue(right).(float64)
+	if !leftOk || !rightOk {
+		return nil, errors.New("operator " + operator + " requires numbers")
+	}
+
+	switch operator {
+	case "+":
+		return leftNumber + rightNumber, nil
+	case "-":
+		return leftNumber - rightNumber, nil
+	case "*":
+		return leftNumber * rightNumber, nil
+	default:
+		if rightNumber == 0 {
+			return nil, errors.New("division by zero in expression")
+		}
+		return leftNumber / rightNumber, nil
+	}
+}
+
+func compareValues(operator string, left interface{}, right interface{}) (interface{}, error) {
+	left = normalizeExpressionValue(left)
+	right = normalizeExpressionValue(right)
+
+	switch operator {
+	case "==":
+		return left == right, nil
+	case "!=":
+		return left != right, nil
+	}
+
+	switch leftValue := left.(type) {
+	case float64:
+		rightValue, ok := right.(float64)
+		if !ok {
+			return nil, errors.New("operator " + operator + " requires values of the same type")
+		}
+		switch operator {
+		case "<":
+			return leftValue < rightValue, nil
+		case "<=":
+			return leftValue <= rightValue, nil
+		case ">":
+			return leftValue > rightValue, nil
+		default:
+			return leftValue >= rightValue, nil
+		}
+	case string:
+		rightValue, ok := right.(string)
+		if !ok {
+			return nil, errors.New("operator " + operator + " requires values of the same type")
+		}
+		switch operator {
+		case "<":
+			return leftValue < rightValue, nil
+		case "<=":
+			return leftValue <= rightValue, nil
+		case ">":
+			return leftValue > rightValue, nil
+		default:
+			return leftValue >= rightValue, nil
+		}
+	}
+
+	return nil, errors.New("operator " + operator + " requires numbers or strings")
+}
@@ -0,0 +1,68 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateCondition(t *testing.T) {
+	variables := map[string]interface{}{
+		"approved": true,
+		"amount":   int32(1200),
+		"region":   "EU",
+		"customer": map[string]interface{}{"tier": "gold"},
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		expected   bool
+	}{
+		{"Boolean variable", "approved", true},
+		{"Numeric comparison", "amount > 1000", true},
+		{"Arithmetic", "amount * 2 >= 2400", true},
+		{"String equality", "region == 'EU'", true},
+		{"Nested variable", `customer.tier == "gold"`, true},
+		{"Logical operators", "approved && (amount < 100 || region != 'US')", true},
+		{"Negation", "!approved", false},
+		{"Missing variable", "manager == null", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := EvaluateCondition(tt.expression, variables)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestEvaluateConditionErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+	}{
+		{"Not a boolean", "amount + 1"},
+		{"Type mismatch", "region > 10"},
+		{"Division by zero", "amount / 0 > 1"},
+		{"Unbalanced parenthesis", "(approved"},
+		{"Function call", "exit(1)"},
+		{"Unknown character", "amount = 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := EvaluateCondition(tt.expression, map[string]interface{}{"amount": 1.0, "region": "EU"})
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseExpression(t *testing.T) {
+	assert.NoError(t, ParseExpression("amount > 10 && status == 'open'"))
+	assert.Error(t, ParseExpression("amount >"))
+	assert.Error(t, ParseExpression("'unterminated"))
+}
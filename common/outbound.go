@@ -0,0 +1,115 @@
+package common
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// MaxOutboundRedirects limits how many redirects outbound calls follow.
+const MaxOutboundRedirects = 3
+
+// nonPublicNetworks are the ranges not covered by the net.IP helpers that must
+// not be reached from outbound calls either.
+var nonPublicNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("192.0.0.0/24"),
+	mustParseCIDR("198.18.0.0/15"),
+	mustParseCIDR("64:ff9b::/96"),
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+// NewOutboundClient returns an HTTP client for calls to URLs chosen by users.
+// It only connects to public addresses, checked when dialing so that a host
+// cannot resolve to the internal network, and follows at most
+// MaxOutboundRedirects redirects to URLs that pass CheckOutboundURL.
+func NewOutboundClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: refuseNonPublicAddress,
+	}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			if len(via) >= MaxOutboundRedirects {
+				return errors.New("stopped after too many redirects")
+			}
+			return CheckOutboundURL(request.URL.String())
+		},
+	}
+}
+
+// CheckOutboundURL accepts http and https URLs whose host is listed in
+// OUTBOUND_ALLOWED_HOSTS, a comma separated list of host names where
+// "*.example.com" matches any subdomain. Any host is accepted while the list
+// is empty.
+func CheckOutboundURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return errors.New("invalid url")
+	}
+	if scheme := strings.ToLower(parsed.Scheme); scheme != "http" && scheme != "https" {
+		return errors.New("url scheme must be http or https")
+	}
+
+	allowedHosts := os.Getenv("OUTBOUND_ALLOWED_HOSTS")
+	if strings.TrimSpace(allowedHosts) == "" {
+		return nil
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	for _, pattern := range strings.Split(allowedHosts, ",") {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == host || (strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:])) {
+			return nil
+		}
+	}
+
+	return errors.New("url host is not allowed: " + host)
+}
+
+// IsPublicIP reports whether ip may be reached from outbound calls.
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func refuseNonPublicAddress(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !IsPublicIP(ip) {
+		return errors.New("refusing to connect to non-public address " + host)
+	}
+	return nil
+}
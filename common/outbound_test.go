@@ -0,0 +1,89 @@
+package common
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckOutboundURL(t *testing.T) {
+	tests := []struct {
+		name         string
+		allowedHosts string
+		url          string
+		wantErr      string
+	}{
+		{name: "Any host without a list", url: "https://hooks.example.com/run"},
+		{name: "Listed host", allowedHosts: "api.example.com, hooks.example.com", url: "https://hooks.example.com/run"},
+		{name: "Listed subdomain", allowedHosts: "*.example.com", url: "http://a.b.example.com/run"},
+		{name: "Unlisted host", allowedHosts: "*.example.com", url: "https://example.org/run", wantErr: "url host is not allowed: example.org"},
+		{name: "Wildcard needs a subdomain", allowedHosts: "*.example.com", url: "https://example.com/run", wantErr: "url host is not allowed: example.com"},
+		{name: "File url", url: "file:///etc/passwd", wantErr: "invalid url"},
+		{name: "Gopher", url: "gopher://example.com/", wantErr: "url scheme must be http or https"},
+		{name: "No host", url: "/relative", wantErr: "invalid url"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OUTBOUND_ALLOWED_HOSTS", tt.allowedHosts)
+
+			err := CheckOutboundURL(tt.url)
+
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{ip: "93.184.216.34", public: true},
+		{ip: "2606:2800:220:1::", public: true},
+		{ip: "127.0.0.1"},
+		{ip: "10.1.2.3"},
+		{ip: "172.16.0.1"},
+		{ip: "192.168.1.1"},
+		{ip: "169.254.169.254"},
+		{ip: "100.64.0.1"},
+		{ip: "0.0.0.0"},
+		{ip: "::1"},
+		{ip: "fd00::1"},
+		{ip: "fe80::1"},
+		{ip: "::ffff:127.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.public, IsPublicIP(net.ParseIP(tt.ip)))
+		})
+	}
+}
+
+func TestOutboundClientRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := NewOutboundClient(0).Get(server.URL)
+
+	assert.ErrorContains(t, err, "refusing to connect to non-public address 127.0.0.1")
+}
+
+func TestOutboundClientLimitsRedirects(t *testing.T) {
+	client := NewOutboundClient(0)
+	request := httptest.NewRequest(http.MethodGet, "https://example.com/next", nil)
+
+	assert.NoError(t, client.CheckRedirect(request, make([]*http.Request, MaxOutboundRedirects-1)))
+	assert.EqualError(t, client.CheckRedirect(request, make([]*http.Request, MaxOutboundRedirects)), "stopped after too many redirects")
+
+	internal := httptest.NewRequest(http.MethodGet, "ftp://example.com/", nil)
+	assert.EqualError(t, client.CheckRedirect(internal, nil), "url scheme must be http or https")
+}
@@ -0,0 +1,316 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+type JSONPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// ApplyMergePatch applies an RFC 7396 JSON Merge Patch to the original document.
+func ApplyMergePatch(original []byte, patch []byte) ([]byte, error) {
+	var target interface{}
+	if err := json.Unmarshal(original, &target); err != nil {
+		return nil, errors.New("invalid original document")
+	}
+
+	var patchValue interface{}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, errors.New("invalid merge patch document")
+	}
+
+	return json.Marshal(mergePatch(target, patchValue))
+}
+
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+
+	return targetObject
+}
+
+// ApplyJSONPatch applies an RFC 6902 JSON Patch to the original document.
+// Operations are applied in order and the whole patch fails if any of them does.
+func ApplyJSONPatch(original []byte, patch []byte) ([]byte, error) {
+	var document interface{}
+	if err := json.Unmarshal(original, &document); err != nil {
+		return nil, errors.New("invalid original document")
+	}
+
+	var operations []JSONPatchOperation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, errors.New("invalid JSON patch document")
+	}
+
+	for _, operation := range operations {
+		var err error
+		document, err = applyJSONPatchOperation(document, operation)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(document)
+}
+
+func applyJSONPatchOperation(document interface{}, operation JSONPatchOperation) (interface{}, error) {
+	switch operation.Op {
+	case "add", "replace", "test":
+		if operation.Value == nil {
+			return nil, errors.New("missing value for " + operation.Op + " operation")
+		}
+		var value interface{}
+		if err := json.Unmarshal(operation.Value, &value); err != nil {
+			return nil, errors.New("invalid value for " + operation.Op + " operation")
+		}
+		switch operation.Op {
+		case "add":
+			return addJSONPointer(document, operation.Path, value)
+		case "replace":
+			if operation.Path == "" {
+				return value, nil
+			}
+			document, err := removeJSONPointer(document, operation.Path)
+			if err != nil {
+				return nil, err
+			}
+			return addJSONPointer(document, operation.Path, value)
+		default:
+			current, err := getJSONPointer(document, operation.Path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, errors.New("test operation failed for " + operation.Path)
+			}
+			return document, nil
+		}
+	case "remove":
+		return removeJSONPointer(document, operation.Path)
+	case "move", "copy":
+		value, err := getJSONPointer(document, operation.From)
+		if err != nil {
+			return nil, err
+		}
+		if operation.Op == "move" {
+			if strings.HasPrefix(operation.Path, operation.From+"/") {
+				return nil, errors.New("cannot move a value into one of its children")
+			}
+			document, err = removeJSONPointer(document, operation.From)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			value = deepCopyJSON(value)
+		}
+		return addJSONPointer(document, operation.Path, value)
+	default:
+		return nil, errors.New("unsupported JSON patch operation: " + operation.Op)
+	}
+}
+
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, errors.New("invalid JSON pointer: " + pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, errors.New("invalid array index: " + token)
+	}
+
+	limit := length - 1
+	if allowEnd {
+		limit = length
+	}
+	if index > limit {
+		return 0, errors.New("array index out of bounds: " + token)
+	}
+
+	return index, nil
+}
+
+func getJSONPointer(document interface{}, pointer string) (interface{}, error) {
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	current := document
+	for _, token := range tokens {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, errors.New("path does not exist: " + pointer)
+			}
+			current = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			current = node[index]
+		default:
+			return nil, errors.New("path does not exist: " + pointer)
+		}
+	}
+
+	return current, nil
+}
+
+func addJSONPointer(document interface{}, pointer string, value interface{}) (interface{}, error) {
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	return updateJSONPointer(document, tokens, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[token] = value
+			return node, nil
+		case []interface{}:
+			index, err := arrayIndex(token, len(node), true)
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[index+1:], node[index:])
+			node[index] = value
+			return node, nil
+		default:
+			return nil, errors.New("path does not exist: " + pointer)
+		}
+	})
+}
+
+func removeJSONPointer(document interface{}, pointer string) (interface{}, error) {
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("cannot remove the whole document")
+	}
+
+	return updateJSONPointer(document, tokens, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := node[token]; !ok {
+				return nil, errors.New("path does not exist: " + pointer)
+			}
+			delete(node, token)
+			return node, nil
+		case []interface{}:
+			index, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			return append(node[:index], node[index+1:]...), nil
+		default:
+			return nil, errors.New("path does not exist: " + pointer)
+		}
+	})
+}
+
+// updateJSONPointer walks to the parent of the last token and replaces it with
+// the result of fn, rebuilding the path so that slice growth is preserved.
+func updateJSONPointer(document interface{}, tokens []string, fn func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return fn(document, tokens[0])
+	}
+
+	switch node := document.(type) {
+	case map[string]interface{}:
+		child, ok := node[tokens[0]]
+		if !ok {
+			return nil, errors.New("path does not exist: /" + strings.Join(tokens, "/"))
+		}
+		updated, err := updateJSONPointer(child, tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[tokens[0]] = updated
+		return node, nil
+	case []interface{}:
+		index, err := arrayIndex(tokens[0], len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := updateJSONPointer(node[index], tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = updated
+		return node, nil
+	default:
+		return nil, errors.New("path does not exist: /" + strings.Join(tokens, "/"))
+	}
+}
+
+func deepCopyJSON(value interface{}) interface{} {
+	switch node := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(node))
+		for key, child := range node {
+			copied[key] = deepCopyJSON(child)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(node))
+		for i, child := range node {
+			copied[i] = deepCopyJSON(child)
+		}
+		return copied
+	default:
+		return value
+	}
+}
@@ -0,0 +1,64 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		patch    string
+		expected string
+	}{
+		{"Replace field", `{"name":"a","status":"Pending"}`, `{"name":"b"}`, `{"name":"b","status":"Pending"}`},
+		{"Remove field", `{"name":"a","description":"d"}`, `{"description":null}`, `{"name":"a"}`},
+		{"Nested object", `{"a":{"b":1,"c":2}}`, `{"a":{"c":null,"d":3}}`, `{"a":{"b":1,"d":3}}`},
+		{"Replace document", `{"a":1}`, `[1,2]`, `[1,2]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ApplyMergePatch([]byte(tt.original), []byte(tt.patch))
+
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(result))
+		})
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		patch    string
+		expected string
+		err      string
+	}{
+		{"Replace", `{"name":"a"}`, `[{"op":"replace","path":"/name","value":"b"}]`, `{"name":"b"}`, ""},
+		{"Add and remove", `{"name":"a","list":[1,3]}`, `[{"op":"add","path":"/list/1","value":2},{"op":"remove","path":"/name"}]`, `{"list":[1,2,3]}`, ""},
+		{"Append", `{"list":[1]}`, `[{"op":"add","path":"/list/-","value":2}]`, `{"list":[1,2]}`, ""},
+		{"Move", `{"a":1}`, `[{"op":"move","from":"/a","path":"/b"}]`, `{"b":1}`, ""},
+		{"Copy", `{"a":{"x":1}}`, `[{"op":"copy","from":"/a","path":"/b"}]`, `{"a":{"x":1},"b":{"x":1}}`, ""},
+		{"Escaped pointer", `{"a/b":1}`, `[{"op":"replace","path":"/a~1b","value":2}]`, `{"a/b":2}`, ""},
+		{"Test passes", `{"order":1}`, `[{"op":"test","path":"/order","value":1}]`, `{"order":1}`, ""},
+		{"Test fails", `{"order":1}`, `[{"op":"test","path":"/order","value":2}]`, "", "test operation failed for /order"},
+		{"Replace missing path", `{"name":"a"}`, `[{"op":"replace","path":"/status","value":"b"}]`, "", "path does not exist: /status"},
+		{"Unsupported operation", `{}`, `[{"op":"merge","path":"/a"}]`, "", "unsupported JSON patch operation: merge"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ApplyJSONPatch([]byte(tt.original), []byte(tt.patch))
+
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(result))
+		})
+	}
+}
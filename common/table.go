@@ -0,0 +1,143 @@
+package common
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	CSVContentType  = "text/csv; charset=utf-8"
+	XLSXContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// TableWriter writes the rows of a table one at a time. Close finishes the
+// document; rows written after an error are dropped.
+type TableWriter interface {
+	Write(record []string) error
+	Close() error
+}
+
+// CSVWriter writes rows as CSV. Cells a spreadsheet would read as a formula
+// are prefixed with a quote, so that opening a report never runs one.
+type CSVWriter struct {
+	writer *csv.Writer
+}
+
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{writer: csv.NewWriter(w)}
+}
+
+func (writer *CSVWriter) Write(record []string) error {
+	escaped := make([]string, len(record))
+	for i, cell := range record {
+		if cell != "" && strings.IndexByte("=+-@\t\r", cell[0]) >= 0 {
+			cell = "'" + cell
+		}
+		escaped[i] = cell
+	}
+	return writer.writer.Write(escaped)
+}
+
+func (writer *CSVWriter) Close() error {
+	writer.writer.Flush()
+	return writer.writer.Error()
+}
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+	xlsxRelationships = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbookRelationships = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// XLSXWriter streams rows into the single sheet of an Office Open XML
+// workbook. Every cell is an inline string, so no shared string table has to
+// be kept in memory and no cell is ever evaluated as a formula.
+type XLSXWriter struct {
+	archive *zip.Writer
+	sheet   io.Writer
+	rows    int
+	err     error
+}
+
+// NewXLSXWriter starts a workbook with one sheet of the given name. Sheet names
+// are at most 31 characters long.
+func NewXLSXWriter(w io.Writer, sheetName string) (*XLSXWriter, error) {
+	archive := zip.NewWriter(w)
+
+	var name bytes.Buffer
+	if err := xml.EscapeText(&name, []byte(sheetName)); err != nil {
+		return nil, err
+	}
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRelationships},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, name.String())},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRelationships},
+	}
+	for _, part := range parts {
+		file, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(file, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(sheet, xlsxSheetStart); err != nil {
+		return nil, err
+	}
+
+	return &XLSXWriter{archive: archive, sheet: sheet}, nil
+}
+
+func (writer *XLSXWriter) Write(record []string) error {
+	if writer.err != nil {
+		return writer.err
+	}
+
+	writer.rows++
+	var row bytes.Buffer
+	row.WriteString(`<row r="` + strconv.Itoa(writer.rows) + `">`)
+	for _, cell := range record {
+		row.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+		// Characters XML cannot carry are replaced rather than failing the row.
+		_ = xml.EscapeText(&row, []byte(cell))
+		row.WriteString(`</t></is></c>`)
+	}
+	row.WriteString(`</row>`)
+
+	_, writer.err = writer.sheet.Write(row.Bytes())
+	return writer.err
+}
+
+func (writer *XLSXWriter) Close() error {
+	if writer.err != nil {
+		return writer.err
+	}
+	if _, err := io.WriteString(writer.sheet, xlsxSheetEnd); err != nil {
+		return err
+	}
+	return writer.archive.Close()
+}
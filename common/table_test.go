@@ -0,0 +1,53 @@
+package common
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVWriter(t *testing.T) {
+	var buffer bytes.Buffer
+	writer := NewCSVWriter(&buffer)
+
+	assert.NoError(t, writer.Write([]string{"name", "note"}))
+	assert.NoError(t, writer.Write([]string{"Review, then sign", "=HYPERLINK(\"x\")"}))
+	assert.NoError(t, writer.Write([]string{"-1", "@SUM(A1)"}))
+	assert.NoError(t, writer.Close())
+
+	assert.Equal(t, "name,note\n\"Review, then sign\",\"'=HYPERLINK(\"\"x\"\")\"\n'-1,'@SUM(A1)\n", buffer.String())
+}
+
+func TestXLSXWriter(t *testing.T) {
+	var buffer bytes.Buffer
+	writer, err := NewXLSXWriter(&buffer, "Tasks & more")
+	assert.NoError(t, err)
+
+	assert.NoError(t, writer.Write([]string{"name", "note"}))
+	assert.NoError(t, writer.Write([]string{"Review <draft>", "=1+1"}))
+	assert.NoError(t, writer.Close())
+
+	archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	assert.NoError(t, err)
+
+	files := map[string]string{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		assert.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		files[file.Name] = string(content)
+	}
+
+	assert.Contains(t, files, "[Content_Types].xml")
+	assert.Contains(t, files, "_rels/.rels")
+	assert.Contains(t, files, "xl/_rels/workbook.xml.rels")
+	assert.Contains(t, files["xl/workbook.xml"], `<sheet name="Tasks &amp; more" sheetId="1" r:id="rId1"/>`)
+	assert.Contains(t, files["xl/worksheets/sheet1.xml"], `<sheetData>`+
+		`<row r="1"><c t="inlineStr"><is><t xml:space="preserve">name</t></is></c><c t="inlineStr"><is><t xml:space="preserve">note</t></is></c></row>`+
+		`<row r="2"><c t="inlineStr"><is><t xml:space="preserve">Review &lt;draft&gt;</t></is></c><c t="inlineStr"><is><t xml:space="preserve">=1+1</t></is></c></row>`+
+		`</sheetData></worksheet>`)
+}
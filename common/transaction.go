@@ -10,7 +10,14 @@ import (
 
 type TransactionFunc func(context.Context, mongo.Session) error
 
+// WithTransaction runs fn in a transaction. If ctx already carries a session,
+// fn joins its transaction instead, and the caller that started it commits or
+// aborts the work.
 func WithTransaction(ctx context.Context, client *mongo.Client, fn TransactionFunc) (err error) {
+	if session := mongo.SessionFromContext(ctx); session != nil {
+		return fn(ctx, session)
+	}
+
 	session, err := client.StartSession()
 	if err != nil {
 		return err
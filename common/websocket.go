@@ -0,0 +1,112 @@
+package common
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteTimeout = 10 * time.Second
+	// MaxWebSocketMessage limits the size of messages read from clients.
+	MaxWebSocketMessage = 64 * 1024
+)
+
+var (
+	ErrNotWebSocket     = errors.New("websocket upgrade required")
+	ErrOriginNotAllowed = errors.New("websocket origin not allowed")
+)
+
+var webSocketUpgrader = websocket.Upgrader{
+	HandshakeTimeout: wsWriteTimeout,
+	CheckOrigin:      webSocketOriginAllowed,
+}
+
+// WebSocket is the server side of a WebSocket connection. Messages are
+// written from one goroutine only; pings and Close may be sent from any.
+type WebSocket struct {
+	conn *websocket.Conn
+}
+
+// IsWebSocketUpgrade reports whether the request asks for a WebSocket.
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return websocket.IsWebSocketUpgrade(r)
+}
+
+// UpgradeWebSocket completes the opening handshake and takes the connection
+// over from the HTTP server. Nothing may be written to w before. A request
+// from another origin gets ErrOriginNotAllowed with nothing written; other
+// handshake failures have been answered when the error is returned.
+func UpgradeWebSocket(w http.ResponseWriter, r *http.Request) (*WebSocket, error) {
+	if !IsWebSocketUpgrade(r) {
+		return nil, ErrNotWebSocket
+	}
+	if !webSocketOriginAllowed(r) {
+		return nil, ErrOriginNotAllowed
+	}
+
+	conn, err := webSocketUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetReadLimit(MaxWebSocketMessage)
+
+	return &WebSocket{conn: conn}, nil
+}
+
+// webSocketOriginAllowed keeps pages of other sites from opening WebSockets
+// with the credentials of their visitors. Browsers always send an Origin; it
+// must be the API itself or one listed in WEBSOCKET_ALLOWED_ORIGINS, a comma
+// separated list such as "https://app.example.com". Other clients send none.
+func webSocketOriginAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(parsed.Host, r.Host) {
+		return true
+	}
+
+	for _, allowed := range strings.Split(os.Getenv("WEBSOCKET_ALLOWED_ORIGINS"), ",") {
+		if allowed = strings.TrimSpace(allowed); allowed != "" && strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText sends a text message.
+func (ws *WebSocket) WriteText(message []byte) error {
+	ws.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return ws.conn.WriteMessage(websocket.TextMessage, message)
+}
+
+// Ping sends a ping. The client answers with a pong, which ReadMessage skips.
+func (ws *WebSocket) Ping() error {
+	return ws.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout))
+}
+
+// ReadMessage returns the next text or binary message. Pings are answered and
+// pongs skipped. It fails once the client closed the connection.
+func (ws *WebSocket) ReadMessage() ([]byte, error) {
+	_, message, err := ws.conn.ReadMessage()
+	return message, err
+}
+
+// Close sends a close frame and closes the connection. It is safe to call
+// more than once.
+func (ws *WebSocket) Close() error {
+	closing := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	ws.conn.WriteControl(websocket.CloseMessage, closing, time.Now().Add(wsWriteTimeout))
+	return ws.conn.Close()
+}
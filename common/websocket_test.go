@@ -0,0 +1,117 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpgradeWebSocketRejectsPlainRequests(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+
+	_, err := UpgradeWebSocket(httptest.NewRecorder(), r)
+
+	assert.Equal(t, ErrNotWebSocket, err)
+}
+
+func TestUpgradeWebSocketChecksOrigin(t *testing.T) {
+	t.Setenv("WEBSOCKET_ALLOWED_ORIGINS", "https://app.example.com")
+
+	tests := []struct {
+		name    string
+		origin  string
+		allowed bool
+	}{
+		{name: "No origin", allowed: true},
+		{name: "Same origin", origin: "https://api.example.com", allowed: true},
+		{name: "Listed origin", origin: "https://app.example.com", allowed: true},
+		{name: "Other origin", origin: "https://evil.example.net"},
+		{name: "Listed host with another scheme", origin: "http://app.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "https://api.example.com/events/ws", nil)
+			r.Header.Set("Connection", "Upgrade")
+			r.Header.Set("Upgrade", "websocket")
+			r.Header.Set("Sec-WebSocket-Version", "13")
+			r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+
+			_, err := UpgradeWebSocket(httptest.NewRecorder(), r)
+
+			if tt.allowed {
+				// The recorder cannot be hijacked, so allowed requests get
+				// as far as taking over the connection.
+				assert.ErrorContains(t, err, "http.Hijacker")
+			} else {
+				assert.Equal(t, ErrOriginNotAllowed, err)
+			}
+		})
+	}
+}
+
+func TestWebSocketExchange(t *testing.T) {
+	received := make(chan string, 1)
+	closed := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := UpgradeWebSocket(w, r)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		ws.WriteText([]byte("hello"))
+		message, err := ws.ReadMessage()
+		if err == nil {
+			received <- string(message)
+		}
+		_, err = ws.ReadMessage()
+		closed <- err
+	}))
+	defer server.Close()
+
+	client, response, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/events", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, response.StatusCode)
+	defer client.Close()
+
+	messageType, message, err := client.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, websocket.TextMessage, messageType)
+	assert.Equal(t, "hello", string(message))
+
+	assert.NoError(t, client.WriteMessage(websocket.TextMessage, []byte("hi there")))
+	assert.Equal(t, "hi there", <-received)
+
+	assert.NoError(t, client.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")))
+	assert.True(t, websocket.IsCloseError(<-closed, websocket.CloseNormalClosure))
+}
+
+func TestWebSocketLimitsMessageSize(t *testing.T) {
+	readErr := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := UpgradeWebSocket(w, r)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		_, err = ws.ReadMessage()
+		readErr <- err
+	}))
+	defer server.Close()
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	client.WriteMessage(websocket.TextMessage, make([]byte, MaxWebSocketMessage+1))
+	assert.ErrorIs(t, <-readErr, websocket.ErrReadLimit)
+}
@@ -0,0 +1,70 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"gopkg.in/yaml.v3"
+)
+
+const YAMLContentType = "application/yaml"
+
+// IsYAMLContentType tells whether a media type names a YAML document. YAML has
+// gone by several names before application/yaml was registered.
+func IsYAMLContentType(contentType string) bool {
+	switch contentType {
+	case YAMLContentType, "application/x-yaml", "text/yaml", "text/x-yaml":
+		return true
+	default:
+		return false
+	}
+}
+
+// YAMLToJSON converts a single YAML document to JSON. Mappings must have
+// string keys.
+func YAMLToJSON(document []byte) ([]byte, error) {
+	var value interface{}
+	if err := yaml.Unmarshal(document, &value); err != nil {
+		return nil, errors.New("invalid yaml document")
+	}
+
+	converted, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.New("invalid yaml document")
+	}
+
+	return converted, nil
+}
+
+// JSONToYAML converts a JSON document to block style YAML, keeping the order of
+// object keys.
+func JSONToYAML(document []byte) ([]byte, error) {
+	// JSON is YAML, so the document parses as is.
+	var node yaml.Node
+	if err := yaml.Unmarshal(document, &node); err != nil {
+		return nil, errors.New("invalid json document")
+	}
+	blockStyle(&node)
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, errors.New("invalid json document")
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, errors.New("invalid json document")
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// blockStyle drops the flow and quoting styles of the JSON syntax. Strings that
+// would read as another type unquoted are still quoted by the encoder.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
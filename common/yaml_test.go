@@ -0,0 +1,46 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsYAMLContentType(t *testing.T) {
+	assert.True(t, IsYAMLContentType("application/yaml"))
+	assert.True(t, IsYAMLContentType("application/x-yaml"))
+	assert.True(t, IsYAMLContentType("text/yaml"))
+	assert.False(t, IsYAMLContentType("application/json"))
+}
+
+func TestYAMLToJSON(t *testing.T) {
+	document, err := YAMLToJSON([]byte("name: Onboarding\nversion: 1\nlabels:\n  - hr\ndone: false\n"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"Onboarding","version":1,"labels":["hr"],"done":false}`, string(document))
+
+	// Timestamps stay strings, as they would in JSON.
+	document, err = YAMLToJSON([]byte("exported_at: 2024-01-02T03:04:05Z\n"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"exported_at":"2024-01-02T03:04:05Z"}`, string(document))
+
+	_, err = YAMLToJSON([]byte("name: [unclosed\n"))
+	assert.Error(t, err)
+
+	_, err = YAMLToJSON([]byte("? [a, b]\n: value\n"))
+	assert.Error(t, err)
+}
+
+func TestJSONToYAML(t *testing.T) {
+	original := `{"version":1,"name":"Onboarding","flags":{"enabled":"true","count":"10","empty":""},"tasks":[{"key":"task-1","depends_on":[]}],"note":"a: b"}`
+
+	document, err := JSONToYAML([]byte(original))
+	assert.NoError(t, err)
+	assert.Equal(t, "version: 1\nname: Onboarding\nflags:\n  enabled: \"true\"\n  count: \"10\"\n  empty: \"\"\ntasks:\n  - key: task-1\n    depends_on: []\nnote: 'a: b'\n", string(document))
+
+	roundTrip, err := YAMLToJSON(document)
+	assert.NoError(t, err)
+	assert.JSONEq(t, original, string(roundTrip))
+
+	_, err = JSONToYAML([]byte(`{"name":`))
+	assert.Error(t, err)
+}
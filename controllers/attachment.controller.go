@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/requests"
+	"virtual_workflow_management_system_gin/responses"
+	"virtual_workflow_management_system_gin/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxMultipartOverhead leaves room for the multipart framing and the other
+// form fields of an upload.
+const maxMultipartOverhead = 1 << 20
+
+type AttachmentController struct {
+	AttachmentService services.IAttachmentService
+	WorkflowService   services.IWorkflowService
+}
+
+func NewAttachmentController(resource *databases.Resource) *AttachmentController {
+	attachmentService := services.NewAttachmentService(resource)
+	workflowService := services.NewWorkflowService(resource)
+	return &AttachmentController{AttachmentService: attachmentService, WorkflowService: workflowService}
+}
+
+// checkAccess applies the access rule of task edits to attachments. When false
+// is returned the response has already been written.
+func (controller *AttachmentController) checkAccess(c *gin.Context) bool {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(c.Param("id"))
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return false
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return false
+	}
+
+	return true
+}
+
+// @Security access_token
+// @Summary Get the attachments of a task
+// @Tags Attachments
+// @version 1.0
+// @Description Get the files attached to a task, oldest first
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param taskID path string true "Task ID"
+// @Success 200 {object} string "OK"
+// @Router /workflows/{id}/tasks/{taskID}/attachments [get]
+func (controller *AttachmentController) GetAttachments(c *gin.Context) {
+	if !controller.checkAccess(c) {
+		return
+	}
+
+	attachments, err := controller.AttachmentService.GetAttachments(c.Param("id"), c.Param("taskID"))
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"attachments": attachments,
+	})
+}
+
+// @Security access_token
+// @Summary Attach a file to a task
+// @Tags Attachments
+// @version 1.0
+// @Description Upload a file of up to 25 MiB as multipart/form-data. PDFs, images, text, CSV, JSON, zip and office documents are accepted. The SHA-256 of the file is returned as checksum; a checksum sent along must match it
+// @Accept  multipart/form-data
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param taskID path string true "Task ID"
+// @Param file formData file true "File"
+// @Param checksum formData string false "Hex encoded SHA-256 of the file"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Failure 413 {object} string "attachment is too large"
+// @Failure 415 {object} string "unsupported attachment content type"
+// @Router /workflows/{id}/tasks/{taskID}/attachments [post]
+func (controller *AttachmentController) UploadAttachment(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	if !controller.checkAccess(c) {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, models.MaxAttachmentSize+maxMultipartOverhead)
+
+	var req requests.UploadAttachmentRequest
+	if err := c.ShouldBind(&req); err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			responses.ErrorWithStatus(c, http.StatusRequestEntityTooLarge, services.ErrAttachmentTooLarge.Error())
+			return
+		}
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	attachment, err := controller.AttachmentService.UploadAttachment(user, c.Param("id"), c.Param("taskID"), req)
+	switch {
+	case errors.Is(err, services.ErrAttachmentTooLarge):
+		responses.ErrorWithStatus(c, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	case errors.Is(err, services.ErrUnsupportedContentType):
+		responses.ErrorWithStatus(c, http.StatusUnsupportedMediaType, err.Error())
+		return
+	case err != nil:
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"attachment": attachment,
+	})
+}
+
+// @Security access_token
+// @Summary Download an attachment
+// @Tags Attachments
+// @version 1.0
+// @Description Download the content of an attachment. The ETag is the SHA-256 of the file
+// @Produce  application/octet-stream
+// @Param id path string true "Workflow ID"
+// @Param taskID path string true "Task ID"
+// @Param attachmentID path string true "Attachment ID"
+// @Success 200 {file} file "Attachment content"
+// @Router /workflows/{id}/tasks/{taskID}/attachments/{attachmentID} [get]
+func (controller *AttachmentController) DownloadAttachment(c *gin.Context) {
+	if !controller.checkAccess(c) {
+		return
+	}
+
+	attachment, err := controller.AttachmentService.GetAttachment(c.Param("id"), c.Param("taskID"), c.Param("attachmentID"))
+	if err != nil {
+		responses.Error(c, "failed to get attachment")
+		return
+	}
+
+	content, err := controller.AttachmentService.OpenAttachment(attachment)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+	defer content.Close()
+
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, content, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}),
+		"ETag":                   strconv.Quote(attachment.Checksum),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// @Security access_token
+// @Summary Delete an attachment
+// @Tags Attachments
+// @version 1.0
+// @Description Delete an attachment and its content
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param taskID path string true "Task ID"
+// @Param attachmentID path string true "Attachment ID"
+// @Success 200 {object} string "OK"
+// @Router /workflows/{id}/tasks/{taskID}/attachments/{attachmentID} [delete]
+func (controller *AttachmentController) DeleteAttachment(c *gin.Context) {
+	if !controller.checkAccess(c) {
+		return
+	}
+
+	err := controller.AttachmentService.DeleteAttachment(c.Param("id"), c.Param("taskID"), c.Param("attachmentID"))
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.Ok(c)
+}
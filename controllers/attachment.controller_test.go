@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/requests"
+	"virtual_workflow_management_system_gin/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockAttachmentService struct {
+	GetAttachmentsError   error
+	GetAttachmentError    error
+	UploadAttachmentError error
+	OpenAttachmentError   error
+	DeleteAttachmentError error
+}
+
+var _ services.IAttachmentService = &MockAttachmentService{}
+
+func (m *MockAttachmentService) GetAttachments(workflowID string, taskID string) ([]models.Attachment, error) {
+	if m.GetAttachmentsError != nil {
+		return nil, m.GetAttachmentsError
+	}
+	return []models.Attachment{{Filename: "spec.pdf", ContentType: "application/pdf", Size: 7}}, nil
+}
+
+func (m *MockAttachmentService) GetAttachment(workflowID string, taskID string, attachmentID string) (*models.Attachment, error) {
+	if m.GetAttachmentError != nil {
+		return nil, m.GetAttachmentError
+	}
+	return &models.Attachment{Filename: "spec.pdf", ContentType: "application/pdf", Size: 7, Checksum: "checksum"}, nil
+}
+
+func (m *MockAttachmentService) UploadAttachment(user models.JWTUser, workflowID string, taskID string, req requests.UploadAttachmentRequest) (*models.Attachment, error) {
+	if m.UploadAttachmentError != nil {
+		return nil, m.UploadAttachmentError
+	}
+	return &models.Attachment{Filename: req.File.Filename, Size: req.File.Size, UploadedBy: user.Username}, nil
+}
+
+func (m *MockAttachmentService) OpenAttachment(attachment *models.Attachment) (io.ReadCloser, error) {
+	if m.OpenAttachmentError != nil {
+		return nil, m.OpenAttachmentError
+	}
+	return io.NopCloser(strings.NewReader("content")), nil
+}
+
+func (m *MockAttachmentService) DeleteAttachment(workflowID string, taskID string, attachmentID string) error {
+	return m.DeleteAttachmentError
+}
+
+var (
+	mockAttachmentService = new(MockAttachmentService)
+	attachmentController  = AttachmentController{AttachmentService: mockAttachmentService, WorkflowService: mockWorkflowService}
+)
+
+// newUploadRequest builds a multipart upload of a file with the given form
+// fields.
+func newUploadRequest(filename string, content string, fields map[string]string) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if filename != "" {
+		part, _ := writer.CreateFormFile("file", filename)
+		part.Write([]byte(content))
+	}
+	for name, value := range fields {
+		writer.WriteField(name, value)
+	}
+	writer.Close()
+
+	request, _ := http.NewRequest(http.MethodPost, "/workflows/some_id/tasks/task_id/attachments", body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	return request
+}
+
+func TestNewAttachmentController(t *testing.T) {
+	mockResource := &databases.Resource{}
+	controller := NewAttachmentController(mockResource)
+
+	assert.NotNil(t, controller)
+	assert.NotNil(t, controller.AttachmentService)
+	assert.NotNil(t, controller.WorkflowService)
+}
+
+func TestGetAttachments(t *testing.T) {
+	t.Run("Successful GetAttachments", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/tasks/task_id/attachments", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		attachmentController.GetAttachments(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "spec.pdf")
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/tasks/task_id/attachments", nil)
+		c.Set("user", models.JWTUser{Username: "testWrongUser"})
+
+		attachmentController.GetAttachments(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
+
+func TestUploadAttachment(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		request  *http.Request
+		expected int
+		message  string
+	}{
+		{"Valid upload", "testUser", newUploadRequest("spec.pdf", "%PDF-1.7", nil), HTTPStatusOK, `"filename":"spec.pdf"`},
+		{"Valid checksum", "testUser", newUploadRequest("spec.pdf", "%PDF-1.7", map[string]string{"checksum": strings.Repeat("ab", 32)}), HTTPStatusOK, `"filename":"spec.pdf"`},
+		{"No file", "testUser", newUploadRequest("", "", nil), HTTPStatusOK, InvalidInput},
+		{"Invalid checksum", "testUser", newUploadRequest("spec.pdf", "%PDF-1.7", map[string]string{"checksum": "not a checksum"}), HTTPStatusOK, InvalidInput},
+		{"Not the owner", "testWrongUser", newUploadRequest("spec.pdf", "%PDF-1.7", nil), HTTPStatusOK, "unauthorized"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = tt.request
+			c.Set("user", models.JWTUser{Username: tt.user})
+
+			attachmentController.UploadAttachment(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+
+	serviceErrors := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"Too large", services.ErrAttachmentTooLarge, http.StatusRequestEntityTooLarge},
+		{"Unsupported content type", services.ErrUnsupportedContentType, http.StatusUnsupportedMediaType},
+		{"Checksum mismatch", errors.New("attachment checksum does not match"), HTTPStatusOK},
+	}
+
+	for _, tt := range serviceErrors {
+		t.Run(tt.name, func(t *testing.T) {
+			attachmentController := AttachmentController{AttachmentService: &MockAttachmentService{UploadAttachmentError: tt.err}, WorkflowService: mockWorkflowService}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = newUploadRequest("spec.pdf", "%PDF-1.7", nil)
+			c.Set("user", models.JWTUser{Username: "testUser"})
+
+			attachmentController.UploadAttachment(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.err.Error())
+		})
+	}
+}
+
+func TestDownloadAttachment(t *testing.T) {
+	t.Run("Successful DownloadAttachment", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/tasks/task_id/attachments/attachment_id", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		attachmentController.DownloadAttachment(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "content", w.Body.String())
+		assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename=spec.pdf`, w.Header().Get("Content-Disposition"))
+		assert.Equal(t, `"checksum"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("Attachment not found", func(t *testing.T) {
+		attachmentController := AttachmentController{AttachmentService: &MockAttachmentService{GetAttachmentError: errors.New("attachment does not exist")}, WorkflowService: mockWorkflowService}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/tasks/task_id/attachments/attachment_id", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		attachmentController.DownloadAttachment(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to get attachment")
+	})
+}
+
+func TestDeleteAttachment(t *testing.T) {
+	t.Run("Successful DeleteAttachment", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodDelete, "/workflows/some_id/tasks/task_id/attachments/attachment_id", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		attachmentController.DeleteAttachment(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "unauthorized")
+	})
+
+	t.Run("Not the owner", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodDelete, "/workflows/some_id/tasks/task_id/attachments/attachment_id", nil)
+		c.Set("user", models.JWTUser{Username: "testWrongUser"})
+
+		attachmentController.DeleteAttachment(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/requests"
+	"virtual_workflow_management_system_gin/responses"
+	"virtual_workflow_management_system_gin/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CommentController struct {
+	CommentService  services.ICommentService
+	WorkflowService services.IWorkflowService
+}
+
+func NewCommentController(resource *databases.Resource) *CommentController {
+	commentService := services.NewCommentService(resource)
+	workflowService := services.NewWorkflowService(resource)
+	return &CommentController{CommentService: commentService, WorkflowService: workflowService}
+}
+
+// @Security access_token
+// @Summary Get the comments of a task
+// @Tags Comments
+// @version 1.0
+// @Description Get the comments of a task as threads, oldest first. Replies are nested under the comment they answer. Deleted comments only show up to keep their replies in place
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param taskID path string true "Task ID"
+// @Success 200 {object} string "OK"
+// @Router /workflows/{id}/tasks/{taskID}/comments [get]
+func (controller *CommentController) GetComments(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+	taskID := c.Param("taskID")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	comments, err := controller.CommentService.GetComments(workflowID, taskID)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"comments": comments,
+	})
+}
+
+// @Security access_token
+// @Summary Get the activity of a task
+// @Tags Comments
+// @version 1.0
+// @Description Get the comments and status changes of a task in the order they happened
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param taskID path string true "Task ID"
+// @Success 200 {object} string "OK"
+// @Router /workflows/{id}/tasks/{taskID}/comments/activity [get]
+func (controller *CommentController) GetActivity(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+	taskID := c.Param("taskID")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	activity, err := controller.CommentService.GetActivity(workflowID, taskID)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"activity": activity,
+	})
+}
+
+// @Security access_token
+// @Summary Comment on a task
+// @Tags Comments
+// @version 1.0
+// @Description Add a Markdown comment to a task, or a reply to one of its comments with parent_id. Every @username mentioned outside of code must exist and is notified
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param taskID path string true "Task ID"
+// @Param comment body requests.CreateCommentRequest true "Comment"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Router /workflows/{id}/tasks/{taskID}/comments [post]
+func (controller *CommentController) CreateComment(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+	taskID := c.Param("taskID")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	var req requests.CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	comment, err := controller.CommentService.CreateComment(user, workflowID, taskID, req)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"comment": comment,
+	})
+}
+
+// @Security access_token
+// @Summary Edit a comment
+// @Tags Comments
+// @version 1.0
+// @Description Replace the text of a comment. Only its author can edit it. Users mentioned for the first time are notified
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param taskID path string true "Task ID"
+// @Param commentID path string true "Comment ID"
+// @Param comment body requests.EditCommentRequest true "Comment"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Router /workflows/{id}/tasks/{taskID}/comments/{commentID} [put]
+func (controller *CommentController) EditComment(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+	taskID := c.Param("taskID")
+	commentID := c.Param("commentID")
+
+	comment, err := controller.CommentService.GetComment(workflowID, taskID, commentID)
+	if err != nil {
+		responses.Error(c, "failed to get comment")
+		return
+	}
+
+	if !comment.CheckCommentAccess(user) {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	var req requests.EditCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	comment, err = controller.CommentService.EditComment(workflowID, taskID, commentID, req)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"comment": comment,
+	})
+}
+
+// @Security access_token
+// @Summary Delete a comment
+// @Tags Comments
+// @version 1.0
+// @Description Delete a comment. Only its author can delete it. Replies to it stay in the thread
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param taskID path string true "Task ID"
+// @Param commentID path string true "Comment ID"
+// @Success 200 {object} string "OK"
+// @Router /workflows/{id}/tasks/{taskID}/comments/{commentID} [delete]
+func (controller *CommentController) DeleteComment(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+	taskID := c.Param("taskID")
+	commentID := c.Param("commentID")
+
+	comment, err := controller.CommentService.GetComment(workflowID, taskID, commentID)
+	if err != nil {
+		responses.Error(c, "failed to get comment")
+		return
+	}
+
+	if !comment.CheckCommentAccess(user) {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	err = controller.CommentService.DeleteComment(workflowID, taskID, commentID)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.Ok(c)
+}
@@ -0,0 +1,290 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/requests"
+	"virtual_workflow_management_system_gin/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockCommentService struct {
+	GetCommentsError   error
+	GetCommentError    error
+	GetActivityError   error
+	CreateCommentError error
+	EditCommentError   error
+	DeleteCommentError error
+}
+
+var _ services.ICommentService = &MockCommentService{}
+
+func (m *MockCommentService) GetComments(workflowID string, taskID string) ([]models.Comment, error) {
+	if m.GetCommentsError != nil {
+		return nil, m.GetCommentsError
+	}
+	return []models.Comment{{Author: "testUser", Body: "Looks good", Replies: []models.Comment{{Author: "otherUser", Body: "Thanks @testUser"}}}}, nil
+}
+
+func (m *MockCommentService) GetComment(workflowID string, taskID string, commentID string) (*models.Comment, error) {
+	if m.GetCommentError != nil {
+		return nil, m.GetCommentError
+	}
+	return &models.Comment{Author: "testUser", Body: "Looks good"}, nil
+}
+
+func (m *MockCommentService) GetActivity(workflowID string, taskID string) ([]models.Activity, error) {
+	if m.GetActivityError != nil {
+		return nil, m.GetActivityError
+	}
+	return []models.Activity{
+		{Type: models.StatusChangeActivity, StatusChange: &models.TaskStatusChange{PreviousStatus: models.Pending, Status: models.InProgress}},
+		{Type: models.CommentActivity, Comment: &models.Comment{Author: "testUser", Body: "Looks good"}},
+	}, nil
+}
+
+func (m *MockCommentService) CreateComment(user models.JWTUser, workflowID string, taskID string, req requests.CreateCommentRequest) (*models.Comment, error) {
+	if m.CreateCommentError != nil {
+		return nil, m.CreateCommentError
+	}
+	return &models.Comment{Author: user.Username, Body: req.Body, Mentions: models.ParseMentions(req.Body)}, nil
+}
+
+func (m *MockCommentService) EditComment(workflowID string, taskID string, commentID string, req requests.EditCommentRequest) (*models.Comment, error) {
+	if m.EditCommentError != nil {
+		return nil, m.EditCommentError
+	}
+	return &models.Comment{Author: "testUser", Body: req.Body, Edited: true}, nil
+}
+
+func (m *MockCommentService) DeleteComment(workflowID string, taskID string, commentID string) error {
+	return m.DeleteCommentError
+}
+
+var (
+	mockCommentService = new(MockCommentService)
+	commentController  = CommentController{CommentService: mockCommentService, WorkflowService: mockWorkflowService}
+)
+
+func TestNewCommentController(t *testing.T) {
+	mockResource := &databases.Resource{}
+	controller := NewCommentController(mockResource)
+
+	assert.NotNil(t, controller)
+	assert.NotNil(t, controller.CommentService)
+	assert.NotNil(t, controller.WorkflowService)
+}
+
+func TestGetComments(t *testing.T) {
+	t.Run("Successful GetComments", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/tasks/task_id/comments", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		commentController.GetComments(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"replies":[{`)
+		assert.Contains(t, w.Body.String(), "Thanks @testUser")
+	})
+
+	t.Run("Task not found", func(t *testing.T) {
+		commentController := CommentController{CommentService: &MockCommentService{GetCommentsError: errors.New("task does not exist")}, WorkflowService: mockWorkflowService}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/tasks/task_id/comments", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		commentController.GetComments(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "task does not exist")
+	})
+
+	t.Run("Not the owner", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/tasks/task_id/comments", nil)
+		c.Set("user", models.JWTUser{Username: "otherUser"})
+
+		commentController.GetComments(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+		assert.NotContains(t, w.Body.String(), "Looks good")
+	})
+
+	t.Run("Workflow not found", func(t *testing.T) {
+		commentController := CommentController{CommentService: mockCommentService, WorkflowService: &MockWorkflowService{GetWorkflowByIDError: errors.New("workflow does not exist")}}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/tasks/task_id/comments", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		commentController.GetComments(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to get workflow")
+	})
+}
+
+func TestGetActivity(t *testing.T) {
+	t.Run("Successful GetActivity", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/tasks/task_id/comments/activity", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		commentController.GetActivity(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"type":"status_change"`)
+		assert.Contains(t, w.Body.String(), `"type":"comment"`)
+	})
+
+	t.Run("Not the owner", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/tasks/task_id/comments/activity", nil)
+		c.Set("user", models.JWTUser{Username: "otherUser"})
+
+		commentController.GetActivity(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+		assert.NotContains(t, w.Body.String(), `"type":"comment"`)
+	})
+}
+
+func TestCreateComment(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+		message  string
+	}{
+		{"Valid comment", `{"body":"Ready for **review**, @otherUser"}`, HTTPStatusOK, `"mentions":["otherUser"]`},
+		{"Valid reply", `{"body":"Done","parent_id":"64b7f3f0c2a4e1a2b3c4d5e6"}`, HTTPStatusOK, `"body":"Done"`},
+		{"Empty body", `{"body":""}`, HTTPStatusOK, InvalidInput},
+		{"Invalid parent", `{"body":"Done","parent_id":"not an id"}`, HTTPStatusOK, InvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/tasks/task_id/comments", strings.NewReader(tt.body))
+			c.Set("user", models.JWTUser{Username: "testUser"})
+
+			commentController.CreateComment(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+
+	t.Run("Not the owner", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/tasks/task_id/comments", strings.NewReader(`{"body":"Hello"}`))
+		c.Set("user", models.JWTUser{Username: "otherUser"})
+
+		commentController.CreateComment(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+
+	t.Run("Unknown mention", func(t *testing.T) {
+		commentController := CommentController{CommentService: &MockCommentService{CreateCommentError: errors.New("mentioned user does not exist: nobody")}, WorkflowService: mockWorkflowService}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/tasks/task_id/comments", strings.NewReader(`{"body":"@nobody"}`))
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		commentController.CreateComment(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "mentioned user does not exist: nobody")
+	})
+}
+
+func TestEditComment(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		body     string
+		expected int
+		message  string
+	}{
+		{"Valid edit", "testUser", `{"body":"Looks good to me"}`, HTTPStatusOK, `"edited":true`},
+		{"Empty body", "testUser", `{"body":""}`, HTTPStatusOK, InvalidInput},
+		{"Not the author", "testWrongUser", `{"body":"Looks good to me"}`, HTTPStatusOK, "unauthorized"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPut, "/workflows/some_id/tasks/task_id/comments/comment_id", strings.NewReader(tt.body))
+			c.Set("user", models.JWTUser{Username: tt.user})
+
+			commentController.EditComment(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+
+	t.Run("Comment not found", func(t *testing.T) {
+		commentController := CommentController{CommentService: &MockCommentService{GetCommentError: errors.New("comment does not exist")}, WorkflowService: mockWorkflowService}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPut, "/workflows/some_id/tasks/task_id/comments/comment_id", strings.NewReader(`{"body":"Looks good to me"}`))
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		commentController.EditComment(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to get comment")
+	})
+}
+
+func TestDeleteComment(t *testing.T) {
+	t.Run("Successful DeleteComment", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodDelete, "/workflows/some_id/tasks/task_id/comments/comment_id", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		commentController.DeleteComment(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "unauthorized")
+	})
+
+	t.Run("Not the author", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodDelete, "/workflows/some_id/tasks/task_id/comments/comment_id", nil)
+		c.Set("user", models.JWTUser{Username: "testWrongUser"})
+
+		commentController.DeleteComment(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
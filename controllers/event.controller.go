@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"virtual_workflow_management_system_gin/common"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/middlewares"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/responses"
+	"virtual_workflow_management_system_gin/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// eventHeartbeat keeps idle streams open through proxies that close quiet
+// connections.
+const eventHeartbeat = 25 * time.Second
+
+var (
+	// workflowDeleted ends the streams of the workflow.
+	workflowDeleted = models.WorkflowDeleted{}.EventName()
+	// workflowTransferred may take the workflow away from a subscriber.
+	workflowTransferred = models.WorkflowTransferred{}.EventName()
+)
+
+type EventController struct {
+	WorkflowService services.IWorkflowService
+	ChangeService   services.IChangeService
+	StreamService   services.IStreamService
+}
+
+func NewEventController(resource *databases.Resource) *EventController {
+	workflowService := services.NewWorkflowService(resource)
+	streamService := services.NewStreamService(resource)
+	return &EventController{WorkflowService: workflowService, ChangeService: services.Changes, StreamService: streamService}
+}
+
+// @Security access_token
+// @Summary Get a ticket for an event stream
+// @Tags Events
+// @version 1.0
+// @Description Get a ticket that opens one event stream of the workflow within 30 seconds. Browsers pass it in the ticket query parameter, since EventSource and WebSocket requests cannot carry the Authorization header
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Success 200 {object} string "OK"
+// @Router /workflows/{id}/events/ticket [post]
+func (controller *EventController) CreateStreamTicket(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	ticket, err := controller.StreamService.IssueStreamTicket(user, workflowID)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"ticket":     ticket,
+		"expires_in": int(middlewares.StreamTicketTTL.Seconds()),
+	})
+}
+
+// @Security access_token
+// @Summary Stream the changes of a workflow
+// @Tags Events
+// @version 1.0
+// @Description Push workflow and task change events as Server-Sent Events. The event name is the change type and the data is the change as JSON. The stream ends after workflow.deleted, and when a transfer takes the workflow away from the user. EventSource clients pass a ticket from /workflows/{id}/events/ticket in the ticket query parameter
+// @Produce  text/event-stream
+// @Param id path string true "Workflow ID"
+// @Param ticket query string false "Stream ticket, if no Authorization header is sent"
+// @Success 200 {object} string "OK"
+// @Router /workflows/{id}/events [get]
+func (controller *EventController) StreamEvents(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	events, unsubscribe := controller.ChangeService.Subscribe(workflowID)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprint(c.Writer, ": connected\n\n")
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+		case event, ok := <-events:
+			if !ok || !controller.keepsAccess(user, workflowID, event) {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				logrus.Error(err)
+				continue
+			}
+			fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			if event.Type == workflowDeleted {
+				c.Writer.Flush()
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// @Security access_token
+// @Summary Stream the changes of a workflow over a WebSocket
+// @Tags Events
+// @version 1.0
+// @Description Push workflow and task change events as JSON text messages over a WebSocket. Messages from the client are ignored. The connection is closed after workflow.deleted, and when a transfer takes the workflow away from the user. Browsers pass a ticket from /workflows/{id}/events/ticket in the ticket query parameter, from the origin of the API or one listed in WEBSOCKET_ALLOWED_ORIGINS
+// @Param id path string true "Workflow ID"
+// @Param ticket query string false "Stream ticket, if no Authorization header is sent"
+// @Success 101 {object} string "Switching Protocols"
+// @Failure 400 {object} string "websocket upgrade required"
+// @Failure 403 {object} string "websocket origin not allowed"
+// @Router /workflows/{id}/events/ws [get]
+func (controller *EventController) StreamEventsWebSocket(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+
+	if !common.IsWebSocketUpgrade(c.Request) {
+		responses.ErrorWithStatus(c, http.StatusBadRequest, common.ErrNotWebSocket.Error())
+		return
+	}
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	ws, err := common.UpgradeWebSocket(c.Writer, c.Request)
+	if errors.Is(err, common.ErrOriginNotAllowed) {
+		responses.ErrorWithStatus(c, http.StatusForbidden, err.Error())
+		return
+	}
+	if err != nil {
+		// The upgrader has already answered the failed handshake.
+		return
+	}
+	defer ws.Close()
+
+	events, unsubscribe := controller.ChangeService.Subscribe(workflowID)
+	defer unsubscribe()
+
+	// Reading answers pings and notices when the client goes away.
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-gone:
+			return
+		case <-heartbeat.C:
+			if err := ws.Ping(); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok || !controller.keepsAccess(user, workflowID, event) {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				logrus.Error(err)
+				continue
+			}
+			if err := ws.WriteText(data); err != nil || event.Type == workflowDeleted {
+				return
+			}
+		}
+	}
+}
+
+// keepsAccess checks the access of the subscriber again once the workflow was
+// transferred, so that the previous owner stops receiving its changes.
+func (controller *EventController) keepsAccess(user models.JWTUser, workflowID string, event models.ChangeEvent) bool {
+	if event.Type != workflowTransferred {
+		return true
+	}
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	return err == nil && workflow.CheckWorkflowAccess(user, "delete")
+}
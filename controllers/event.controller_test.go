@@ -0,0 +1,297 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockChangeService hands every subscriber the configured events and then ends
+// the subscription.
+type MockChangeService struct {
+	Events []models.ChangeEvent
+}
+
+var _ services.IChangeService = &MockChangeService{}
+
+func (m *MockChangeService) Publish(event models.ChangeEvent) {
+	m.Events = append(m.Events, event)
+}
+
+func (m *MockChangeService) Subscribe(workflowID string) (<-chan models.ChangeEvent, func()) {
+	events := make(chan models.ChangeEvent, len(m.Events))
+	for _, event := range m.Events {
+		events <- event
+	}
+	close(events)
+	return events, func() {}
+}
+
+type MockStreamService struct {
+	IssueStreamTicketError error
+}
+
+var _ services.IStreamService = &MockStreamService{}
+
+func (m *MockStreamService) IssueStreamTicket(user models.JWTUser, workflowID string) (string, error) {
+	if m.IssueStreamTicketError != nil {
+		return "", m.IssueStreamTicketError
+	}
+	return "ticket-for-" + user.Username, nil
+}
+
+// transferringWorkflowService hands the workflow to another owner after it was
+// read once.
+type transferringWorkflowService struct {
+	MockWorkflowService
+	reads int
+}
+
+func (m *transferringWorkflowService) GetWorkflowByID(workflowID string) (*models.Workflow, error) {
+	m.reads++
+	if m.reads > 1 {
+		return &models.Workflow{Owner: "newOwner"}, nil
+	}
+	return m.MockWorkflowService.GetWorkflowByID(workflowID)
+}
+
+var (
+	mockChangeService = &MockChangeService{Events: []models.ChangeEvent{
+		{ID: "1", Type: models.TaskUpdated{}.EventName(), WorkflowID: "some_id", Data: map[string]interface{}{"workflow_id": "some_id"}},
+		{ID: "2", Type: models.TasksReordered{}.EventName(), WorkflowID: "some_id"},
+	}}
+	eventController = EventController{WorkflowService: mockWorkflowService, ChangeService: mockChangeService, StreamService: new(MockStreamService)}
+)
+
+func TestNewEventController(t *testing.T) {
+	mockResource := &databases.Resource{}
+	controller := NewEventController(mockResource)
+
+	assert.NotNil(t, controller)
+	assert.NotNil(t, controller.WorkflowService)
+	assert.NotNil(t, controller.ChangeService)
+	assert.NotNil(t, controller.StreamService)
+}
+
+func TestCreateStreamTicket(t *testing.T) {
+	createStreamTicket := func(controller EventController, user models.JWTUser) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/events/ticket", nil)
+		c.Params = gin.Params{{Key: "id", Value: "some_id"}}
+		c.Set("user", user)
+
+		controller.CreateStreamTicket(c)
+		return w
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		w := createStreamTicket(eventController, models.JWTUser{Username: "testUser"})
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"ticket":"ticket-for-testUser"`)
+		assert.Contains(t, w.Body.String(), `"expires_in":30`)
+	})
+
+	t.Run("Not the owner", func(t *testing.T) {
+		w := createStreamTicket(eventController, models.JWTUser{Username: "otherUser"})
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+		assert.NotContains(t, w.Body.String(), "ticket-for")
+	})
+
+	t.Run("Workflow not found", func(t *testing.T) {
+		eventController := EventController{WorkflowService: &MockWorkflowService{GetWorkflowByIDError: errors.New("workflow does not exist")}, StreamService: new(MockStreamService)}
+
+		w := createStreamTicket(eventController, models.JWTUser{Username: "testUser"})
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to get workflow")
+	})
+
+	t.Run("Service error", func(t *testing.T) {
+		eventController := EventController{WorkflowService: mockWorkflowService, StreamService: &MockStreamService{IssueStreamTicketError: errors.New("failed to generate stream ticket")}}
+
+		w := createStreamTicket(eventController, models.JWTUser{Username: "testUser"})
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to generate stream ticket")
+	})
+}
+
+func TestStreamEvents(t *testing.T) {
+	t.Run("Successful StreamEvents", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/events", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		eventController.StreamEvents(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), "id: 1\nevent: task.updated\ndata: {\"id\":\"1\",\"type\":\"task.updated\",\"workflow_id\":\"some_id\"")
+		assert.Contains(t, w.Body.String(), "event: tasks.reordered\n")
+	})
+
+	t.Run("Stream ends with the workflow", func(t *testing.T) {
+		changeService := &MockChangeService{Events: []models.ChangeEvent{
+			{ID: "1", Type: models.WorkflowDeleted{}.EventName(), WorkflowID: "some_id"},
+			{ID: "2", Type: models.TaskUpdated{}.EventName(), WorkflowID: "some_id"},
+		}}
+		eventController := EventController{WorkflowService: mockWorkflowService, ChangeService: changeService}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/events", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		eventController.StreamEvents(c)
+
+		assert.Contains(t, w.Body.String(), "event: workflow.deleted\n")
+		assert.NotContains(t, w.Body.String(), "event: task.updated\n")
+	})
+
+	t.Run("Stream ends when the workflow is transferred away", func(t *testing.T) {
+		changeService := &MockChangeService{Events: []models.ChangeEvent{
+			{ID: "1", Type: models.TaskUpdated{}.EventName(), WorkflowID: "some_id"},
+			{ID: "2", Type: models.WorkflowTransferred{}.EventName(), WorkflowID: "some_id"},
+			{ID: "3", Type: models.TaskUpdated{}.EventName(), WorkflowID: "some_id"},
+		}}
+		eventController := EventController{WorkflowService: new(transferringWorkflowService), ChangeService: changeService}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/events", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		eventController.StreamEvents(c)
+
+		assert.Contains(t, w.Body.String(), "id: 1\n")
+		assert.NotContains(t, w.Body.String(), "event: workflow.transferred\n")
+		assert.NotContains(t, w.Body.String(), "id: 3\n")
+	})
+
+	t.Run("Stream goes on after a transfer the user keeps access to", func(t *testing.T) {
+		changeService := &MockChangeService{Events: []models.ChangeEvent{
+			{ID: "1", Type: models.WorkflowTransferred{}.EventName(), WorkflowID: "some_id"},
+			{ID: "2", Type: models.TaskUpdated{}.EventName(), WorkflowID: "some_id"},
+		}}
+		eventController := EventController{WorkflowService: mockWorkflowService, ChangeService: changeService}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/events", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		eventController.StreamEvents(c)
+
+		assert.Contains(t, w.Body.String(), "event: workflow.transferred\n")
+		assert.Contains(t, w.Body.String(), "id: 2\n")
+	})
+
+	t.Run("Workflow not found", func(t *testing.T) {
+		mockWorkflowService := &MockWorkflowService{GetWorkflowByIDError: errors.New("workflow does not exist")}
+		eventController := EventController{WorkflowService: mockWorkflowService, ChangeService: mockChangeService}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/events", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		eventController.StreamEvents(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to get workflow")
+	})
+
+	t.Run("Not the owner", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/events", nil)
+		c.Set("user", models.JWTUser{Username: "otherUser"})
+
+		eventController.StreamEvents(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+		assert.NotEqual(t, "text/event-stream", w.Header().Get("Content-Type"))
+	})
+}
+
+func TestStreamEventsWebSocket(t *testing.T) {
+	t.Run("Successful StreamEventsWebSocket", func(t *testing.T) {
+		router := gin.New()
+		router.GET("/workflows/:id/events/ws", func(c *gin.Context) {
+			c.Set("user", models.JWTUser{Username: "testUser"})
+			eventController.StreamEventsWebSocket(c)
+		})
+		server := httptest.NewServer(router)
+		defer server.Close()
+
+		client, response, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/workflows/some_id/events/ws", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusSwitchingProtocols, response.StatusCode)
+		defer client.Close()
+
+		messageType, message, err := client.ReadMessage()
+		assert.NoError(t, err)
+		assert.Equal(t, websocket.TextMessage, messageType)
+		assert.Contains(t, string(message), `"type":"task.updated"`)
+	})
+
+	t.Run("Other origin", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/events/ws", nil)
+		c.Request.Host = "api.example.com"
+		c.Request.Header.Set("Connection", "Upgrade")
+		c.Request.Header.Set("Upgrade", "websocket")
+		c.Request.Header.Set("Sec-WebSocket-Version", "13")
+		c.Request.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		c.Request.Header.Set("Origin", "https://evil.example.net")
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		eventController.StreamEventsWebSocket(c)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "websocket origin not allowed")
+	})
+
+	t.Run("Not the owner", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/events/ws", nil)
+		c.Request.Header.Set("Connection", "Upgrade")
+		c.Request.Header.Set("Upgrade", "websocket")
+		c.Set("user", models.JWTUser{Username: "otherUser"})
+
+		eventController.StreamEventsWebSocket(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+
+	t.Run("Not a WebSocket request", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/events/ws", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		eventController.StreamEventsWebSocket(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "websocket upgrade required")
+	})
+}
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/requests"
+	"virtual_workflow_management_system_gin/responses"
+	"virtual_workflow_management_system_gin/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JobController struct {
+	JobService services.IJobService
+}
+
+func NewJobController(resource *databases.Resource) *JobController {
+	jobService := services.NewJobService(resource)
+	return &JobController{JobService: jobService}
+}
+
+// @Security access_token
+// @Summary Get jobs
+// @Tags Admin
+// @version 1.0
+// @Description Get the most recent background jobs, optionally filtered by status and type. Admins only
+// @Accept  application/json
+// @Produce  application/json
+// @Param status query string false "Job status (Queued, Running or Succeeded)"
+// @Param type query string false "Job type"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Router /admin/jobs [get]
+func (controller *JobController) GetJobs(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	if user.Role != models.Admin {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	var req requests.JobQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	jobs, err := controller.JobService.GetJobs(req.Status, req.Type)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"jobs": jobs,
+	})
+}
+
+// @Security access_token
+// @Summary Get a job
+// @Tags Admin
+// @version 1.0
+// @Description Get a background job by ID, including dead jobs. Admins only
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Job ID"
+// @Success 200 {object} string "OK"
+// @Router /admin/jobs/{id} [get]
+func (controller *JobController) GetJob(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	if user.Role != models.Admin {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	job, err := controller.JobService.GetJobByID(c.Param("id"))
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"job": job,
+	})
+}
+
+// @Security access_token
+// @Summary Get dead jobs
+// @Tags Admin
+// @version 1.0
+// @Description Get the jobs that ran out of attempts, optionally filtered by type. Admins only
+// @Accept  application/json
+// @Produce  application/json
+// @Param type query string false "Job type"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Router /admin/dead-letter-jobs [get]
+func (controller *JobController) GetDeadLetterJobs(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	if user.Role != models.Admin {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	var req requests.JobQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	jobs, err := controller.JobService.GetDeadLetterJobs(req.Type)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"jobs": jobs,
+	})
+}
+
+// @Security access_token
+// @Summary Retry a dead job
+// @Tags Admin
+// @version 1.0
+// @Description Move a dead job back to the queue with a fresh set of attempts. Admins only
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Job ID"
+// @Success 200 {object} string "OK"
+// @Router /admin/jobs/{id}/retry [post]
+func (controller *JobController) RetryJob(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	if user.Role != models.Admin {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	job, err := controller.JobService.RetryJob(c.Param("id"))
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"job": job,
+	})
+}
@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockJobService struct {
+	EnqueueJobError        error
+	GetJobsError           error
+	GetJobByIDError        error
+	GetDeadLetterJobsError error
+	RetryJobError          error
+}
+
+var _ services.IJobService = &MockJobService{}
+
+func (m *MockJobService) EnqueueJob(jobType string, payload map[string]interface{}, scheduledAt time.Time) (*string, error) {
+	if m.EnqueueJobError != nil {
+		return nil, m.EnqueueJobError
+	}
+	id := "newID"
+	return &id, nil
+}
+
+func (m *MockJobService) GetJobs(status models.JobStatus, jobType string) ([]models.Job, error) {
+	if m.GetJobsError != nil {
+		return nil, m.GetJobsError
+	}
+	return []models.Job{{Type: "reminder", Status: models.JobQueued}}, nil
+}
+
+func (m *MockJobService) GetJobByID(jobID string) (*models.Job, error) {
+	if m.GetJobByIDError != nil {
+		return nil, m.GetJobByIDError
+	}
+	return &models.Job{Type: "reminder", Status: models.JobRunning}, nil
+}
+
+func (m *MockJobService) GetDeadLetterJobs(jobType string) ([]models.Job, error) {
+	if m.GetDeadLetterJobsError != nil {
+		return nil, m.GetDeadLetterJobsError
+	}
+	return []models.Job{{Type: "reminder", Status: models.JobDead, LastError: "mail server unavailable"}}, nil
+}
+
+func (m *MockJobService) RetryJob(jobID string) (*models.Job, error) {
+	if m.RetryJobError != nil {
+		return nil, m.RetryJobError
+	}
+	return &models.Job{Type: "reminder", Status: models.JobQueued}, nil
+}
+
+var (
+	mockJobService = new(MockJobService)
+	jobController  = JobController{JobService: mockJobService}
+)
+
+func TestNewJobController(t *testing.T) {
+	mockResource := &databases.Resource{}
+	controller := NewJobController(mockResource)
+
+	assert.NotNil(t, controller)
+	assert.NotNil(t, controller.JobService)
+}
+
+func TestGetJobs(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		role     models.UserRole
+		expected int
+		message  string
+	}{
+		{"All jobs", "/admin/jobs", models.Admin, HTTPStatusOK, `"status":"Queued"`},
+		{"Filtered jobs", "/admin/jobs?status=Running&type=reminder", models.Admin, HTTPStatusOK, OKStatus},
+		{"Unknown status", "/admin/jobs?status=Dead", models.Admin, HTTPStatusOK, InvalidInput},
+		{"Not an admin", "/admin/jobs", models.Employer, HTTPStatusOK, "unauthorized"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodGet, tt.path, nil)
+			c.Set("user", models.JWTUser{Username: "testUser", Role: tt.role})
+
+			jobController.GetJobs(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+
+	t.Run("Failed GetJobs", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/admin/jobs", nil)
+		c.Set("user", models.JWTUser{Username: "testUser", Role: models.Admin})
+
+		jobController := JobController{JobService: &MockJobService{GetJobsError: errors.New("failed to retrieve jobs")}}
+		jobController.GetJobs(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to retrieve jobs")
+	})
+}
+
+func TestGetJob(t *testing.T) {
+	t.Run("Successful GetJob", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/admin/jobs/job_id", nil)
+		c.Set("user", models.JWTUser{Username: "testUser", Role: models.Admin})
+
+		jobController.GetJob(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"Running"`)
+	})
+
+	t.Run("Failed GetJob", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/admin/jobs/job_id", nil)
+		c.Set("user", models.JWTUser{Username: "testUser", Role: models.Admin})
+
+		jobController := JobController{JobService: &MockJobService{GetJobByIDError: errors.New("job does not exist")}}
+		jobController.GetJob(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "job does not exist")
+	})
+}
+
+func TestGetDeadLetterJobs(t *testing.T) {
+	t.Run("Successful GetDeadLetterJobs", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/admin/dead-letter-jobs?type=reminder", nil)
+		c.Set("user", models.JWTUser{Username: "testUser", Role: models.Admin})
+
+		jobController.GetDeadLetterJobs(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "mail server unavailable")
+	})
+
+	t.Run("Not an admin", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/admin/dead-letter-jobs", nil)
+		c.Set("user", models.JWTUser{Username: "testUser", Role: models.Employer})
+
+		jobController.GetDeadLetterJobs(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
+
+func TestRetryJob(t *testing.T) {
+	t.Run("Successful RetryJob", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/admin/jobs/job_id/retry", nil)
+		c.Set("user", models.JWTUser{Username: "testUser", Role: models.Admin})
+
+		jobController.RetryJob(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"Queued"`)
+	})
+
+	t.Run("Job is not dead", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/admin/jobs/job_id/retry", nil)
+		c.Set("user", models.JWTUser{Username: "testUser", Role: models.Admin})
+
+		jobController := JobController{JobService: &MockJobService{RetryJobError: errors.New("only dead jobs can be retried")}}
+		jobController.RetryJob(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "only dead jobs can be retried")
+	})
+
+	t.Run("Not an admin", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/admin/jobs/job_id/retry", nil)
+		c.Set("user", models.JWTUser{Username: "testUser", Role: models.Employer})
+
+		jobController.RetryJob(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/requests"
+	"virtual_workflow_management_system_gin/responses"
+	"virtual_workflow_management_system_gin/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationController struct {
+	NotificationService services.INotificationService
+}
+
+func NewNotificationController(resource *databases.Resource) *NotificationController {
+	notificationService := services.NewNotificationService(resource)
+	return &NotificationController{NotificationService: notificationService}
+}
+
+// @Security access_token
+// @Summary Get my notifications
+// @Tags Notifications
+// @version 1.0
+// @Description Get the most recent notifications of the current user, such as mentions and overdue tasks, newest first
+// @Accept  application/json
+// @Produce  application/json
+// @Param unread query bool false "Only unread notifications"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Router /notifications [get]
+func (controller *NotificationController) GetNotifications(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	var req requests.NotificationQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	notifications, err := controller.NotificationService.GetNotifications(user, req.Unread)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"notifications": notifications,
+	})
+}
+
+// @Security access_token
+// @Summary Read a notification
+// @Tags Notifications
+// @version 1.0
+// @Description Mark a notification of the current user as read
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Notification ID"
+// @Success 200 {object} string "OK"
+// @Router /notifications/{id}/read [post]
+func (controller *NotificationController) ReadNotification(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	notification, err := controller.NotificationService.ReadNotification(user, c.Param("id"))
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"notification": notification,
+	})
+}
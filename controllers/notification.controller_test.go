@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockNotificationService struct {
+	GetNotificationsError error
+	ReadNotificationError error
+}
+
+var _ services.INotificationService = &MockNotificationService{}
+
+func (m *MockNotificationService) GetNotifications(user models.JWTUser, unreadOnly bool) ([]models.Notification, error) {
+	if m.GetNotificationsError != nil {
+		return nil, m.GetNotificationsError
+	}
+	readAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	notifications := []models.Notification{
+		{Username: user.Username, Subject: "otherUser mentioned you in a comment"},
+		{Username: user.Username, Subject: "Task \"Review\" in workflow \"Launch\" is overdue", ReadAt: &readAt},
+	}
+	if unreadOnly {
+		notifications = notifications[:1]
+	}
+	return notifications, nil
+}
+
+func (m *MockNotificationService) ReadNotification(user models.JWTUser, notificationID string) (*models.Notification, error) {
+	if m.ReadNotificationError != nil {
+		return nil, m.ReadNotificationError
+	}
+	readAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &models.Notification{Username: user.Username, Subject: "otherUser mentioned you in a comment", ReadAt: &readAt}, nil
+}
+
+var notificationController = NotificationController{NotificationService: new(MockNotificationService)}
+
+func TestNewNotificationController(t *testing.T) {
+	mockResource := &databases.Resource{}
+	controller := NewNotificationController(mockResource)
+	assert.NotNil(t, controller)
+	assert.NotNil(t, controller.NotificationService)
+}
+
+func TestGetNotifications(t *testing.T) {
+	getNotifications := func(controller NotificationController, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/notifications"+query, nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		controller.GetNotifications(c)
+		return w
+	}
+
+	t.Run("All", func(t *testing.T) {
+		w := getNotifications(notificationController, "")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "mentioned you in a comment")
+		assert.Contains(t, w.Body.String(), "is overdue")
+	})
+
+	t.Run("Unread", func(t *testing.T) {
+		w := getNotifications(notificationController, "?unread=true")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "mentioned you in a comment")
+		assert.NotContains(t, w.Body.String(), "is overdue")
+	})
+
+	t.Run("Invalid filter", func(t *testing.T) {
+		w := getNotifications(notificationController, "?unread=maybe")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), InvalidInput)
+	})
+
+	t.Run("Service error", func(t *testing.T) {
+		controller := NotificationController{NotificationService: &MockNotificationService{GetNotificationsError: errors.New("failed to retrieve notifications")}}
+
+		w := getNotifications(controller, "")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to retrieve notifications")
+	})
+}
+
+func TestReadNotification(t *testing.T) {
+	readNotification := func(controller NotificationController) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/notifications/some_id/read", nil)
+		c.Params = gin.Params{{Key: "id", Value: "some_id"}}
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		controller.ReadNotification(c)
+		return w
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		w := readNotification(notificationController)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"read_at":"2024-01-02T03:04:05Z"`)
+	})
+
+	t.Run("Someone else's notification", func(t *testing.T) {
+		controller := NotificationController{NotificationService: &MockNotificationService{ReadNotificationError: errors.New("notification does not exist")}}
+
+		w := readNotification(controller)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "notification does not exist")
+	})
+}
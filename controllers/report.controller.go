@@ -0,0 +1,282 @@
+package controllers
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+	"virtual_workflow_management_system_gin/common"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/requests"
+	"virtual_workflow_management_system_gin/responses"
+	"virtual_workflow_management_system_gin/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type ReportController struct {
+	ReportService   services.IReportService
+	WorkflowService services.IWorkflowService
+}
+
+func NewReportController(resource *databases.Resource) *ReportController {
+	reportService := services.NewReportService(resource)
+	workflowService := services.NewWorkflowService(resource)
+	return &ReportController{ReportService: reportService, WorkflowService: workflowService}
+}
+
+// reportResponse sends the headers of a download with its first bytes. Until
+// then the request can still be answered with an error instead.
+type reportResponse struct {
+	c           *gin.Context
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (response *reportResponse) Write(p []byte) (int, error) {
+	if !response.started {
+		response.started = true
+		response.c.Header("Content-Type", response.contentType)
+		response.c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": response.filename}))
+		response.c.Header("X-Content-Type-Options", "nosniff")
+		response.c.Status(http.StatusOK)
+	}
+	return response.c.Writer.Write(p)
+}
+
+// streamTasks writes a task report as CSV, or as a spreadsheet when the route
+// ends in .xlsx. Once rows have been sent an error can only cut the download
+// short, so it is logged instead.
+func (controller *ReportController) streamTasks(c *gin.Context, name string, export func(writer common.TableWriter) error) {
+	response := &reportResponse{c: c, contentType: common.CSVContentType, filename: name + ".csv"}
+
+	var writer common.TableWriter
+	if strings.HasSuffix(c.FullPath(), ".xlsx") {
+		response.contentType = common.XLSXContentType
+		response.filename = name + ".xlsx"
+
+		xlsxWriter, err := common.NewXLSXWriter(response, "Tasks")
+		if err != nil {
+			logrus.Error(err)
+			responses.Error(c, "failed to export tasks")
+			return
+		}
+		writer = xlsxWriter
+	} else {
+		writer = common.NewCSVWriter(response)
+	}
+
+	if err := export(writer); err != nil {
+		if !response.started {
+			responses.Error(c, err.Error())
+		}
+		return
+	}
+
+	if err := writer.Close(); err != nil {
+		logrus.Error(err)
+		if !response.started {
+			responses.Error(c, "failed to export tasks")
+		}
+	}
+}
+
+// @Security access_token
+// @Summary Export the tasks of a workflow
+// @Tags Reports
+// @version 1.0
+// @Description Download the tasks of a workflow in order, one row per task with its workflow, status, order, timestamps and assignee. Rows are streamed as CSV, or as an XLSX spreadsheet from the .xlsx path. Cells starting like a formula are quoted in CSV and stored as text in XLSX
+// @Produce  text/csv
+// @Produce  application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param id path string true "Workflow ID"
+// @Success 200 {file} file "Task report"
+// @Router /workflows/{id}/tasks/export.csv [get]
+// @Router /workflows/{id}/tasks/export.xlsx [get]
+func (controller *ReportController) ExportWorkflowTasks(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	controller.streamTasks(c, "tasks-"+workflowID, func(writer common.TableWriter) error {
+		return controller.ReportService.ExportWorkflowTasks(workflowID, writer)
+	})
+}
+
+// @Security access_token
+// @Summary Export the tasks of the account
+// @Tags Reports
+// @version 1.0
+// @Description Download the tasks of every workflow the user may see, oldest workflow first, in the columns of the workflow task export. Rows are streamed as CSV, or as an XLSX spreadsheet from the .xlsx path
+// @Produce  text/csv
+// @Produce  application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Success 200 {file} file "Task report"
+// @Router /reports/tasks.csv [get]
+// @Router /reports/tasks.xlsx [get]
+func (controller *ReportController) ExportTasks(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	controller.streamTasks(c, "tasks", func(writer common.TableWriter) error {
+		return controller.ReportService.ExportTasks(user, writer)
+	})
+}
+
+// @Security access_token
+// @Summary Get the lead time of workflows
+// @Tags Reports
+// @version 1.0
+// @Description Summarize the time from the creation of the workflows the user may see until their last task was done, over the workflows done in the range. With a team, only workflows owned by members of the team count
+// @Accept  application/json
+// @Produce  application/json
+// @Param from query string false "Start of the range, RFC 3339 (default 90 days before to)"
+// @Param to query string false "End of the range, RFC 3339 (default now)"
+// @Param team query string false "Team"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Router /reports/lead-time [get]
+func (controller *ReportController) GetLeadTime(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	var req requests.ReportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	report, err := controller.ReportService.GetLeadTime(user, req)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"lead_time": report,
+	})
+}
+
+// @Security access_token
+// @Summary Get the cycle time of tasks
+// @Tags Reports
+// @version 1.0
+// @Description Summarize, per task name, the time tasks completed in the range took from first being in progress to being completed, slowest first. With a team, only tasks assigned to members of the team count
+// @Accept  application/json
+// @Produce  application/json
+// @Param from query string false "Start of the range, RFC 3339 (default 90 days before to)"
+// @Param to query string false "End of the range, RFC 3339 (default now)"
+// @Param team query string false "Team"
+// @Param limit query int false "Task names, up to 100 (default 10)"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Router /reports/cycle-time [get]
+func (controller *ReportController) GetCycleTimes(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	var req requests.ReportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	cycleTimes, err := controller.ReportService.GetCycleTimes(user, req)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"cycle_times": cycleTimes,
+	})
+}
+
+// @Security access_token
+// @Summary Get the weekly throughput
+// @Tags Reports
+// @version 1.0
+// @Description Count the tasks completed per ISO week of the range, oldest first, including the weeks without completions. Weeks start on Monday, midnight UTC. With a team, only tasks assigned to members of the team count
+// @Accept  application/json
+// @Produce  application/json
+// @Param from query string false "Start of the range, RFC 3339 (default 90 days before to)"
+// @Param to query string false "End of the range, RFC 3339 (default now)"
+// @Param team query string false "Team"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Router /reports/throughput [get]
+func (controller *ReportController) GetThroughput(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	var req requests.ReportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	weeks, err := controller.ReportService.GetThroughput(user, req)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"weeks": weeks,
+	})
+}
+
+// @Security access_token
+// @Summary Get the slowest steps of a workflow
+// @Tags Reports
+// @version 1.0
+// @Description List the steps of a workflow whose tasks took longest to be done, across the workflow and the runs its schedules created in the range, slowest first. The time of each step is split over the statuses it waited in; time in a status a task is still in counts up to now. With a team, only tasks assigned to members of the team count
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param from query string false "Start of the range, RFC 3339 (default 90 days before to)"
+// @Param to query string false "End of the range, RFC 3339 (default now)"
+// @Param team query string false "Team"
+// @Param limit query int false "Steps, up to 100 (default 10)"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Router /reports/workflows/{id}/steps [get]
+func (controller *ReportController) GetSlowestSteps(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	var req requests.ReportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	steps, err := controller.ReportService.GetSlowestSteps(workflowID, req)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"steps": steps,
+	})
+}
@@ -0,0 +1,365 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"virtual_workflow_management_system_gin/common"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/requests"
+	"virtual_workflow_management_system_gin/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MockReportService struct {
+	ExportError    error
+	AnalyticsError error
+	// Request is the last request an analytics report was asked for.
+	Request requests.ReportRequest
+}
+
+var _ services.IReportService = &MockReportService{}
+
+// reportRow is the single task the mock reports.
+func reportRow(owner string) models.TaskRow {
+	workflowID, _ := primitive.ObjectIDFromHex("65a000000000000000000001")
+	taskID, _ := primitive.ObjectIDFromHex("65a000000000000000000002")
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return models.TaskRow{
+		WorkflowID:   workflowID,
+		WorkflowName: "=Invoice approval",
+		TaskID:       taskID,
+		TaskName:     "Check invoice",
+		Status:       models.InProgress,
+		Order:        1,
+		CreatedAt:    created,
+		UpdatedAt:    created.Add(time.Hour),
+		Assignee:     owner,
+	}
+}
+
+func (m *MockReportService) export(owner string, writer common.TableWriter) error {
+	if m.ExportError != nil {
+		return m.ExportError
+	}
+	row := reportRow(owner)
+	if err := writer.Write(models.TaskRowHeader); err != nil {
+		return err
+	}
+	return writer.Write(row.Record())
+}
+
+func (m *MockReportService) ExportWorkflowTasks(workflowID string, writer common.TableWriter) error {
+	return m.export("testUser", writer)
+}
+
+func (m *MockReportService) ExportTasks(user models.JWTUser, writer common.TableWriter) error {
+	return m.export(user.Username, writer)
+}
+
+func (m *MockReportService) GetLeadTime(user models.JWTUser, req requests.ReportRequest) (*models.LeadTimeReport, error) {
+	m.Request = req
+	if m.AnalyticsError != nil {
+		return nil, m.AnalyticsError
+	}
+	return &models.LeadTimeReport{Workflows: 2, AverageSeconds: 5400, MinSeconds: 3600, MaxSeconds: 7200}, nil
+}
+
+func (m *MockReportService) GetCycleTimes(user models.JWTUser, req requests.ReportRequest) ([]models.CycleTime, error) {
+	m.Request = req
+	if m.AnalyticsError != nil {
+		return nil, m.AnalyticsError
+	}
+	return []models.CycleTime{{Task: "Check invoice", Completed: 3, AverageSeconds: 1800, MinSeconds: 600, MaxSeconds: 3600}}, nil
+}
+
+func (m *MockReportService) GetThroughput(user models.JWTUser, req requests.ReportRequest) ([]models.WeeklyThroughput, error) {
+	m.Request = req
+	if m.AnalyticsError != nil {
+		return nil, m.AnalyticsError
+	}
+	return []models.WeeklyThroughput{{Week: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Completed: 4}}, nil
+}
+
+func (m *MockReportService) GetSlowestSteps(workflowID string, req requests.ReportRequest) ([]models.StepDuration, error) {
+	m.Request = req
+	if m.AnalyticsError != nil {
+		return nil, m.AnalyticsError
+	}
+	return []models.StepDuration{{Step: "Check invoice", Runs: 2, AverageSeconds: 7200, MaxSeconds: 9000, AveragePendingSeconds: 5400, AverageInProgressSeconds: 1800}}, nil
+}
+
+var (
+	mockReportService = new(MockReportService)
+	reportController  = ReportController{ReportService: mockReportService, WorkflowService: mockWorkflowService}
+)
+
+// performReportRequest serves a request as the user through the report routes,
+// next to the task route they share a path with.
+func performReportRequest(path string, username string) *httptest.ResponseRecorder {
+	reportRouter := gin.New()
+	reportRouter.Use(func(c *gin.Context) {
+		c.Set("user", models.JWTUser{Username: username})
+	})
+	reportRouter.GET("/workflows/:id/tasks/:taskID", workflowController.GetTask)
+	reportRouter.GET("/workflows/:id/tasks/export.csv", reportController.ExportWorkflowTasks)
+	reportRouter.GET("/workflows/:id/tasks/export.xlsx", reportController.ExportWorkflowTasks)
+	reportRouter.GET("/reports/tasks.csv", reportController.ExportTasks)
+	reportRouter.GET("/reports/tasks.xlsx", reportController.ExportTasks)
+	reportRouter.GET("/reports/lead-time", reportController.GetLeadTime)
+	reportRouter.GET("/reports/cycle-time", reportController.GetCycleTimes)
+	reportRouter.GET("/reports/throughput", reportController.GetThroughput)
+	reportRouter.GET("/reports/workflows/:id/steps", reportController.GetSlowestSteps)
+
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, path, nil)
+	reportRouter.ServeHTTP(w, request)
+	return w
+}
+
+const reportCSV = "workflow_id,workflow,task_id,task,status,order,created_at,updated_at,assignee\n" +
+	"65a000000000000000000001,'=Invoice approval,65a000000000000000000002,Check invoice,In Progress,1,2024-01-02T03:04:05Z,2024-01-02T04:04:05Z,testUser\n"
+
+func TestNewReportController(t *testing.T) {
+	mockResource := &databases.Resource{}
+	controller := NewReportController(mockResource)
+
+	assert.NotNil(t, controller)
+	assert.NotNil(t, controller.ReportService)
+	assert.NotNil(t, controller.WorkflowService)
+}
+
+func TestExportWorkflowTasks(t *testing.T) {
+	t.Run("CSV", func(t *testing.T) {
+		w := performReportRequest("/workflows/some_id/tasks/export.csv", "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Equal(t, common.CSVContentType, w.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename=tasks-some_id.csv`, w.Header().Get("Content-Disposition"))
+		assert.Equal(t, reportCSV, w.Body.String())
+	})
+
+	t.Run("XLSX", func(t *testing.T) {
+		w := performReportRequest("/workflows/some_id/tasks/export.xlsx", "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Equal(t, common.XLSXContentType, w.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename=tasks-some_id.xlsx`, w.Header().Get("Content-Disposition"))
+
+		archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		assert.NoError(t, err)
+		var sheet string
+		for _, file := range archive.File {
+			if file.Name == "xl/worksheets/sheet1.xml" {
+				reader, _ := file.Open()
+				content, _ := io.ReadAll(reader)
+				sheet = string(content)
+			}
+		}
+		assert.Contains(t, sheet, `<t xml:space="preserve">=Invoice approval</t>`)
+		assert.Contains(t, sheet, `<t xml:space="preserve">2024-01-02T03:04:05Z</t>`)
+	})
+
+	t.Run("Task routes are unaffected", func(t *testing.T) {
+		w := performReportRequest("/workflows/some_id/tasks/task_id", "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), OKStatus)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := performReportRequest("/workflows/some_id/tasks/export.csv", "testWrongUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+		assert.Empty(t, w.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("Workflow not found", func(t *testing.T) {
+		mockWorkflowService.GetWorkflowByIDError = errors.New("not found")
+		defer func() { mockWorkflowService.GetWorkflowByIDError = nil }()
+
+		w := performReportRequest("/workflows/some_id/tasks/export.csv", "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to get workflow")
+	})
+
+	t.Run("Service error before any row", func(t *testing.T) {
+		mockReportService.ExportError = errors.New("failed to retrieve tasks")
+		defer func() { mockReportService.ExportError = nil }()
+
+		w := performReportRequest("/workflows/some_id/tasks/export.xlsx", "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+		assert.Empty(t, w.Header().Get("Content-Disposition"))
+		assert.Contains(t, w.Body.String(), "failed to retrieve tasks")
+	})
+}
+
+func TestExportTasks(t *testing.T) {
+	t.Run("CSV", func(t *testing.T) {
+		w := performReportRequest("/reports/tasks.csv", "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Equal(t, `attachment; filename=tasks.csv`, w.Header().Get("Content-Disposition"))
+		assert.Equal(t, reportCSV, w.Body.String())
+	})
+
+	t.Run("XLSX", func(t *testing.T) {
+		w := performReportRequest("/reports/tasks.xlsx", "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Equal(t, common.XLSXContentType, w.Header().Get("Content-Type"))
+		_, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		assert.NoError(t, err)
+	})
+
+	t.Run("Service error", func(t *testing.T) {
+		mockReportService.ExportError = errors.New("failed to retrieve tasks")
+		defer func() { mockReportService.ExportError = nil }()
+
+		w := performReportRequest("/reports/tasks.csv", "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to retrieve tasks")
+	})
+}
+
+func TestGetLeadTime(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		w := performReportRequest("/reports/lead-time?from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z&team=finance", "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"lead_time":{"workflows":2,"average_seconds":5400,"min_seconds":3600,"max_seconds":7200}`)
+		assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), *mockReportService.Request.From)
+		assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), *mockReportService.Request.To)
+		assert.Equal(t, "finance", mockReportService.Request.Team)
+	})
+
+	t.Run("Defaults", func(t *testing.T) {
+		w := performReportRequest("/reports/lead-time", "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Nil(t, mockReportService.Request.From)
+		assert.Nil(t, mockReportService.Request.To)
+	})
+
+	t.Run("Invalid date", func(t *testing.T) {
+		w := performReportRequest("/reports/lead-time?from=yesterday", "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), InvalidInput)
+	})
+
+	t.Run("Service error", func(t *testing.T) {
+		mockReportService.AnalyticsError = errors.New("from must be before to")
+		defer func() { mockReportService.AnalyticsError = nil }()
+
+		w := performReportRequest("/reports/lead-time", "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "from must be before to")
+	})
+}
+
+func TestGetCycleTimes(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		w := performReportRequest("/reports/cycle-time?limit=5", "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"cycle_times":[{"task":"Check invoice","completed":3,"average_seconds":1800,"min_seconds":600,"max_seconds":3600}]`)
+		assert.Equal(t, 5, mockReportService.Request.Limit)
+	})
+
+	t.Run("Limit too high", func(t *testing.T) {
+		w := performReportRequest("/reports/cycle-time?limit=1000", "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), InvalidInput)
+	})
+
+	t.Run("Service error", func(t *testing.T) {
+		mockReportService.AnalyticsError = errors.New("failed to compute report")
+		defer func() { mockReportService.AnalyticsError = nil }()
+
+		w := performReportRequest("/reports/cycle-time", "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to compute report")
+	})
+}
+
+func TestGetThroughput(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		w := performReportRequest("/reports/throughput", "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"weeks":[{"week":"2024-01-01T00:00:00Z","completed":4}]`)
+	})
+
+	t.Run("Service error", func(t *testing.T) {
+		mockReportService.AnalyticsError = errors.New("failed to compute report")
+		defer func() { mockReportService.AnalyticsError = nil }()
+
+		w := performReportRequest("/reports/throughput", "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to compute report")
+	})
+}
+
+func TestGetSlowestSteps(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		w := performReportRequest("/reports/workflows/some_id/steps?limit=3", "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"steps":[{"step":"Check invoice","runs":2,"average_seconds":7200,"max_seconds":9000,"average_pending_seconds":5400,"average_in_progress_seconds":1800,"average_failed_seconds":0}]`)
+		assert.Equal(t, 3, mockReportService.Request.Limit)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := performReportRequest("/reports/workflows/some_id/steps", "testWrongUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+
+	t.Run("Workflow not found", func(t *testing.T) {
+		mockWorkflowService.GetWorkflowByIDError = errors.New("not found")
+		defer func() { mockWorkflowService.GetWorkflowByIDError = nil }()
+
+		w := performReportRequest("/reports/workflows/some_id/steps", "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to get workflow")
+	})
+
+	t.Run("Invalid input", func(t *testing.T) {
+		w := performReportRequest("/reports/workflows/some_id/steps?limit=0x", "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), InvalidInput)
+	})
+
+	t.Run("Service error", func(t *testing.T) {
+		mockReportService.AnalyticsError = errors.New("failed to compute report")
+		defer func() { mockReportService.AnalyticsError = nil }()
+
+		w := performReportRequest("/reports/workflows/some_id/steps", "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to compute report")
+	})
+}
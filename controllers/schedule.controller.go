@@ -0,0 +1,287 @@
+package controllers
+
+import (
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/requests"
+	"virtual_workflow_management_system_gin/responses"
+	"virtual_workflow_management_system_gin/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ScheduleController struct {
+	ScheduleService services.IScheduleService
+	WorkflowService services.IWorkflowService
+}
+
+func NewScheduleController(resource *databases.Resource) *ScheduleController {
+	scheduleService := services.NewScheduleService(resource)
+	workflowService := services.NewWorkflowService(resource)
+	return &ScheduleController{ScheduleService: scheduleService, WorkflowService: workflowService}
+}
+
+// @Security access_token
+// @Summary Get the schedules of a workflow
+// @Tags Schedules
+// @version 1.0
+// @Description Get the schedules creating runs of a workflow
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Success 200 {object} string "OK"
+// @Router /workflows/{id}/schedules [get]
+func (controller *ScheduleController) GetSchedules(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	schedules, err := controller.ScheduleService.GetSchedules(workflowID)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"schedules": schedules,
+	})
+}
+
+// @Security access_token
+// @Summary Schedule a workflow
+// @Tags Schedules
+// @version 1.0
+// @Description Create a copy of the workflow with fresh pending tasks at every occurrence of a cron expression
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param schedule body requests.CreateScheduleRequest true "Schedule details"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Router /workflows/{id}/schedules [post]
+func (controller *ScheduleController) CreateSchedule(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	var req requests.CreateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	scheduleID, err := controller.ScheduleService.CreateSchedule(workflowID, user.Username, req)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"schedule_id": scheduleID,
+	})
+}
+
+// @Security access_token
+// @Summary Get a schedule
+// @Tags Schedules
+// @version 1.0
+// @Description Get a schedule by ID, including its most recent runs
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Schedule ID"
+// @Success 200 {object} string "OK"
+// @Router /schedules/{id} [get]
+func (controller *ScheduleController) GetSchedule(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	schedule, err := controller.ScheduleService.GetScheduleByID(c.Param("id"))
+	if err != nil {
+		responses.Error(c, "failed to get schedule")
+		return
+	}
+
+	if !schedule.CheckScheduleAccess(user) {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"schedule": schedule,
+	})
+}
+
+// @Security access_token
+// @Summary Delete a schedule
+// @Tags Schedules
+// @version 1.0
+// @Description Delete a schedule by ID. Runs already created are kept
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Schedule ID"
+// @Success 200 {object} string "OK"
+// @Router /schedules/{id} [delete]
+func (controller *ScheduleController) DeleteSchedule(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	scheduleID := c.Param("id")
+
+	schedule, err := controller.ScheduleService.GetScheduleByID(scheduleID)
+	if err != nil {
+		responses.Error(c, "failed to get schedule")
+		return
+	}
+
+	if !schedule.CheckScheduleAccess(user) {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	err = controller.ScheduleService.DeleteSchedule(scheduleID)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.Ok(c)
+}
+
+// @Security access_token
+// @Summary Pause a schedule
+// @Tags Schedules
+// @version 1.0
+// @Description Stop a schedule from creating runs until it is resumed
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Schedule ID"
+// @Success 200 {object} string "OK"
+// @Router /schedules/{id}/pause [post]
+func (controller *ScheduleController) PauseSchedule(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	scheduleID := c.Param("id")
+
+	schedule, err := controller.ScheduleService.GetScheduleByID(scheduleID)
+	if err != nil {
+		responses.Error(c, "failed to get schedule")
+		return
+	}
+
+	if !schedule.CheckScheduleAccess(user) {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	updatedSchedule, err := controller.ScheduleService.PauseSchedule(scheduleID)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"schedule": updatedSchedule,
+	})
+}
+
+// @Security access_token
+// @Summary Resume a schedule
+// @Tags Schedules
+// @version 1.0
+// @Description Resume a paused schedule from its next occurrence. Occurrences missed while paused are not run
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Schedule ID"
+// @Success 200 {object} string "OK"
+// @Router /schedules/{id}/resume [post]
+func (controller *ScheduleController) ResumeSchedule(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	scheduleID := c.Param("id")
+
+	schedule, err := controller.ScheduleService.GetScheduleByID(scheduleID)
+	if err != nil {
+		responses.Error(c, "failed to get schedule")
+		return
+	}
+
+	if !schedule.CheckScheduleAccess(user) {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	updatedSchedule, err := controller.ScheduleService.ResumeSchedule(scheduleID)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"schedule": updatedSchedule,
+	})
+}
+
+// @Security access_token
+// @Summary Preview the upcoming runs of a schedule
+// @Tags Schedules
+// @version 1.0
+// @Description List the next occurrences of a schedule, in its time zone
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Schedule ID"
+// @Param count query int false "Number of occurrences (1-50, default 10)"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Router /schedules/{id}/upcoming [get]
+func (controller *ScheduleController) GetUpcomingRuns(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	scheduleID := c.Param("id")
+
+	schedule, err := controller.ScheduleService.GetScheduleByID(scheduleID)
+	if err != nil {
+		responses.Error(c, "failed to get schedule")
+		return
+	}
+
+	if !schedule.CheckScheduleAccess(user) {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	var req requests.UpcomingRunsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	upcoming, err := controller.ScheduleService.GetUpcomingRuns(scheduleID, req.Count)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"upcoming": upcoming,
+	})
+}
@@ -0,0 +1,289 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/requests"
+	"virtual_workflow_management_system_gin/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockScheduleService struct {
+	GetSchedulesError    error
+	GetScheduleByIDError error
+	CreateScheduleError  error
+	PauseScheduleError   error
+	ResumeScheduleError  error
+	DeleteScheduleError  error
+	GetUpcomingRunsError error
+}
+
+var _ services.IScheduleService = &MockScheduleService{}
+
+func (m *MockScheduleService) GetSchedules(workflowID string) ([]models.Schedule, error) {
+	if m.GetSchedulesError != nil {
+		return nil, m.GetSchedulesError
+	}
+	return []models.Schedule{{Owner: "testUser", Expression: "0 9 * * MON-FRI", Timezone: "UTC"}}, nil
+}
+
+func (m *MockScheduleService) GetScheduleByID(scheduleID string) (*models.Schedule, error) {
+	if m.GetScheduleByIDError != nil {
+		return nil, m.GetScheduleByIDError
+	}
+	return &models.Schedule{Owner: "testUser", Expression: "0 9 * * MON-FRI", Timezone: "UTC"}, nil
+}
+
+func (m *MockScheduleService) CreateSchedule(workflowID string, username string, req requests.CreateScheduleRequest) (*string, error) {
+	if m.CreateScheduleError != nil {
+		return nil, m.CreateScheduleError
+	}
+	id := "newID"
+	return &id, nil
+}
+
+func (m *MockScheduleService) PauseSchedule(scheduleID string) (*models.Schedule, error) {
+	if m.PauseScheduleError != nil {
+		return nil, m.PauseScheduleError
+	}
+	return &models.Schedule{Owner: "testUser", Paused: true}, nil
+}
+
+func (m *MockScheduleService) ResumeSchedule(scheduleID string) (*models.Schedule, error) {
+	if m.ResumeScheduleError != nil {
+		return nil, m.ResumeScheduleError
+	}
+	return &models.Schedule{Owner: "testUser", Paused: false}, nil
+}
+
+func (m *MockScheduleService) DeleteSchedule(scheduleID string) error {
+	return m.DeleteScheduleError
+}
+
+func (m *MockScheduleService) GetUpcomingRuns(scheduleID string, count int) ([]time.Time, error) {
+	if m.GetUpcomingRunsError != nil {
+		return nil, m.GetUpcomingRunsError
+	}
+	return []time.Time{time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)}, nil
+}
+
+var (
+	mockScheduleService = new(MockScheduleService)
+	scheduleController  = ScheduleController{ScheduleService: mockScheduleService, WorkflowService: mockWorkflowService}
+)
+
+func TestNewScheduleController(t *testing.T) {
+	mockResource := &databases.Resource{}
+	controller := NewScheduleController(mockResource)
+
+	assert.NotNil(t, controller)
+	assert.NotNil(t, controller.ScheduleService)
+	assert.NotNil(t, controller.WorkflowService)
+}
+
+func TestGetSchedules(t *testing.T) {
+	t.Run("Successful GetSchedules", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/schedules", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		scheduleController.GetSchedules(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "0 9 * * MON-FRI")
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/schedules", nil)
+		c.Set("user", models.JWTUser{Username: "testWrongUser"})
+
+		scheduleController.GetSchedules(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
+
+func TestCreateSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		body     string
+		expected int
+		message  string
+	}{
+		{"Valid schedule", "testUser", `{"expression":"0 9 * * MON-FRI","timezone":"Europe/Berlin","catch_up":"Once"}`, HTTPStatusOK, "schedule_id"},
+		{"Missing expression", "testUser", `{"timezone":"UTC"}`, HTTPStatusOK, InvalidInput},
+		{"Unknown catch-up policy", "testUser", `{"expression":"@daily","catch_up":"Never"}`, HTTPStatusOK, InvalidInput},
+		{"Not the owner", "testWrongUser", `{"expression":"@daily"}`, HTTPStatusOK, "unauthorized"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/schedules", strings.NewReader(tt.body))
+			c.Set("user", models.JWTUser{Username: tt.user})
+
+			scheduleController.CreateSchedule(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+
+	t.Run("Invalid expression", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/schedules", strings.NewReader(`{"expression":"61 * * * *"}`))
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		scheduleController := ScheduleController{ScheduleService: &MockScheduleService{CreateScheduleError: errors.New("invalid cron field: 61")}, WorkflowService: mockWorkflowService}
+		scheduleController.CreateSchedule(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid cron field: 61")
+	})
+}
+
+func TestGetSchedule(t *testing.T) {
+	t.Run("Successful GetSchedule", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/schedules/schedule_id", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		scheduleController.GetSchedule(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"timezone":"UTC"`)
+	})
+
+	t.Run("Admin", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/schedules/schedule_id", nil)
+		c.Set("user", models.JWTUser{Username: "admin", Role: models.Admin})
+
+		scheduleController.GetSchedule(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), OKStatus)
+	})
+
+	t.Run("Schedule does not exist", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/schedules/schedule_id", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		scheduleController := ScheduleController{ScheduleService: &MockScheduleService{GetScheduleByIDError: errors.New("schedule does not exist")}}
+		scheduleController.GetSchedule(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to get schedule")
+	})
+}
+
+func TestDeleteSchedule(t *testing.T) {
+	t.Run("Successful DeleteSchedule", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodDelete, "/schedules/schedule_id", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		scheduleController.DeleteSchedule(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), OKStatus)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodDelete, "/schedules/schedule_id", nil)
+		c.Set("user", models.JWTUser{Username: "testWrongUser"})
+
+		scheduleController.DeleteSchedule(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
+
+func TestPauseAndResumeSchedule(t *testing.T) {
+	t.Run("Successful PauseSchedule", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/schedules/schedule_id/pause", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		scheduleController.PauseSchedule(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"paused":true`)
+	})
+
+	t.Run("Successful ResumeSchedule", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/schedules/schedule_id/resume", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		scheduleController.ResumeSchedule(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"paused":false`)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/schedules/schedule_id/pause", nil)
+		c.Set("user", models.JWTUser{Username: "testWrongUser"})
+
+		scheduleController.PauseSchedule(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
+
+func TestGetUpcomingRuns(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		expected int
+		message  string
+	}{
+		{"Default count", "/schedules/schedule_id/upcoming", HTTPStatusOK, "2024-03-04T09:00:00Z"},
+		{"With count", "/schedules/schedule_id/upcoming?count=5", HTTPStatusOK, OKStatus},
+		{"Count too large", "/schedules/schedule_id/upcoming?count=500", HTTPStatusOK, InvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodGet, tt.path, nil)
+			c.Set("user", models.JWTUser{Username: "testUser"})
+
+			scheduleController.GetUpcomingRuns(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+}
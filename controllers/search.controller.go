@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/requests"
+	"virtual_workflow_management_system_gin/responses"
+	"virtual_workflow_management_system_gin/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SearchController struct {
+	SearchService services.ISearchService
+}
+
+func NewSearchController(resource *databases.Resource) *SearchController {
+	searchService := services.NewSearchService(resource)
+	return &SearchController{SearchService: searchService}
+}
+
+// @Security access_token
+// @Summary Search workflows and tasks
+// @Tags Search
+// @version 1.0
+// @Description Full-text search over the names, labels and descriptions of the workflows and tasks the user may see, and over task comments. Workflow and task hits are ranked separately, best first. Label and status facets count the hits before the filters apply
+// @Accept  application/json
+// @Produce  application/json
+// @Param q query string true "Search text. Quote phrases and prefix words with - to exclude them"
+// @Param label query []string false "Only hits carrying every label; tasks carry the labels of their workflow too" collectionFormat(multi)
+// @Param status query []string false "Only tasks in one of the statuses" collectionFormat(multi)
+// @Param limit query int false "Hits per kind, up to 100 (default 20)"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Router /search [get]
+func (controller *SearchController) Search(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	var req requests.SearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	result, err := controller.SearchService.Search(user, req)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"workflows": result.Workflows,
+		"tasks":     result.Tasks,
+		"facets":    result.Facets,
+	})
+}
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/requests"
+	"virtual_workflow_management_system_gin/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockSearchService struct {
+	SearchError error
+}
+
+var _ services.ISearchService = &MockSearchService{}
+
+func (m *MockSearchService) Search(user models.JWTUser, req requests.SearchRequest) (*models.SearchResult, error) {
+	if m.SearchError != nil {
+		return nil, m.SearchError
+	}
+	return &models.SearchResult{
+		Workflows: []models.WorkflowHit{{Name: "Invoice approval", Owner: user.Username, Labels: []string{"finance"}, Score: 10.5}},
+		Tasks:     []models.TaskHit{{WorkflowName: "Invoice approval", Name: "Check invoice", Status: models.Pending, Labels: []string{}, Score: 5}},
+		Facets: models.SearchFacets{
+			Labels:   []models.FacetCount{{Value: "finance", Count: 2}},
+			Statuses: []models.FacetCount{{Value: string(models.Pending), Count: 1}},
+		},
+	}, nil
+}
+
+var (
+	mockSearchService = new(MockSearchService)
+	searchController  = SearchController{SearchService: mockSearchService}
+)
+
+func TestNewSearchController(t *testing.T) {
+	mockResource := &databases.Resource{}
+	controller := NewSearchController(mockResource)
+
+	assert.NotNil(t, controller)
+	assert.NotNil(t, controller.SearchService)
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		expected int
+		message  string
+	}{
+		{"Valid query", "/search?q=invoice", HTTPStatusOK, `"name":"Invoice approval"`},
+		{"Facets", "/search?q=invoice", HTTPStatusOK, `"labels":[{"value":"finance","count":2}]`},
+		{"Label and status filters", "/search?q=invoice&label=finance&label=q3&status=In+Progress&status=Pending", HTTPStatusOK, `"name":"Check invoice"`},
+		{"Missing query", "/search", HTTPStatusOK, InvalidInput},
+		{"Query too short", "/search?q=a", HTTPStatusOK, InvalidInput},
+		{"Unknown status", "/search?q=invoice&status=Archived", HTTPStatusOK, InvalidInput},
+		{"Limit too large", "/search?q=invoice&limit=1000", HTTPStatusOK, InvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodGet, tt.url, nil)
+			c.Set("user", models.JWTUser{Username: "testUser"})
+
+			searchController.Search(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+
+	t.Run("Failed Search", func(t *testing.T) {
+		searchController := SearchController{SearchService: &MockSearchService{SearchError: errors.New("failed to search workflows")}}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/search?q=invoice", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		searchController.Search(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to search workflows")
+	})
+}
@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/requests"
+	"virtual_workflow_management_system_gin/responses"
+	"virtual_workflow_management_system_gin/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TemplateController struct {
+	TemplateService services.ITemplateService
+	WorkflowService services.IWorkflowService
+}
+
+func NewTemplateController(resource *databases.Resource) *TemplateController {
+	templateService := services.NewTemplateService(resource)
+	workflowService := services.NewWorkflowService(resource)
+	return &TemplateController{TemplateService: templateService, WorkflowService: workflowService}
+}
+
+// @Security access_token
+// @Summary Get all templates
+// @Tags Templates
+// @version 1.0
+// @Description Get the global, organization and personal templates visible to the user
+// @Accept  application/json
+// @Produce  application/json
+// @Success 200 {object} string "OK"
+// @Router /templates [get]
+func (controller *TemplateController) GetTemplates(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	templates, err := controller.TemplateService.GetTemplates(user)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"templates": templates,
+	})
+}
+
+// @Security access_token
+// @Summary Get a template
+// @Tags Templates
+// @version 1.0
+// @Description Get a template by ID
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Template ID"
+// @Success 200 {object} string "OK"
+// @Router /templates/{id} [get]
+func (controller *TemplateController) GetTemplate(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	templateID := c.Param("id")
+
+	template, err := controller.TemplateService.GetTemplateByID(templateID)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	if !template.CheckTemplateAccess(user, models.View) {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"template": template,
+	})
+}
+
+// @Security access_token
+// @Summary Create a template
+// @Tags Templates
+// @version 1.0
+// @Description Create a global, organization or personal template. Task names and descriptions may contain {{parameter}} placeholders
+// @Accept  application/json
+// @Produce  application/json
+// @Param template body requests.CreateTemplateRequest true "Template for creation"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Router /templates [post]
+func (controller *TemplateController) CreateTemplate(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	var req requests.CreateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	insertedID, err := controller.TemplateService.CreateTemplate(user, req)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"template_id": insertedID,
+	})
+}
+
+// @Security access_token
+// @Summary Delete a template
+// @Tags Templates
+// @version 1.0
+// @Description Delete a template by ID
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Template ID"
+// @Success 200 {object} string "OK"
+// @Router /templates/{id} [delete]
+func (controller *TemplateController) DeleteTemplate(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	templateID := c.Param("id")
+
+	template, err := controller.TemplateService.GetTemplateByID(templateID)
+	if err != nil {
+		responses.Error(c, "failed to get template")
+		return
+	}
+
+	if !template.CheckTemplateAccess(user, models.Delete) {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	err = controller.TemplateService.DeleteTemplateByID(templateID)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.Ok(c)
+}
+
+// @Security access_token
+// @Summary Instantiate a template
+// @Tags Templates
+// @version 1.0
+// @Description Create a new workflow with all tasks and dependencies of a template
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Template ID"
+// @Param instantiate body requests.InstantiateTemplateRequest true "Workflow name and template parameters"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Router /templates/{id}/instantiate [post]
+func (controller *TemplateController) InstantiateTemplate(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	templateID := c.Param("id")
+
+	template, err := controller.TemplateService.GetTemplateByID(templateID)
+	if err != nil {
+		responses.Error(c, "failed to get template")
+		return
+	}
+
+	if !template.CheckTemplateAccess(user, models.View) {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	var req requests.InstantiateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	workflowID, err := controller.TemplateService.InstantiateTemplate(templateID, user.Username, req)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"workflow_id": workflowID,
+	})
+}
+
+// @Security access_token
+// @Summary Save a workflow as a template
+// @Tags Templates
+// @version 1.0
+// @Description Create a template from the tasks and dependencies of an existing workflow
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param template body requests.SaveWorkflowAsTemplateRequest true "Template details"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Router /workflows/{id}/template [post]
+func (controller *TemplateController) SaveWorkflowAsTemplate(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	var req requests.SaveWorkflowAsTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	templateID, err := controller.TemplateService.SaveWorkflowAsTemplate(workflowID, user, req)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"template_id": templateID,
+	})
+}
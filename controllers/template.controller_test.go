@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/requests"
+	"virtual_workflow_management_system_gin/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockTemplateService struct {
+	GetTemplatesError           error
+	GetTemplateByIDError        error
+	CreateTemplateError         error
+	DeleteTemplateByIDError     error
+	InstantiateTemplateError    error
+	SaveWorkflowAsTemplateError error
+}
+
+var _ services.ITemplateService = &MockTemplateService{}
+
+func (m *MockTemplateService) GetTemplates(user models.JWTUser) ([]models.Template, error) {
+	if m.GetTemplatesError != nil {
+		return nil, m.GetTemplatesError
+	}
+	return []models.Template{}, nil
+}
+
+func (m *MockTemplateService) GetTemplateByID(templateID string) (*models.Template, error) {
+	if m.GetTemplateByIDError != nil {
+		return nil, m.GetTemplateByIDError
+	}
+	return &models.Template{Owner: "testUser", Scope: models.PersonalTemplate}, nil
+}
+
+func (m *MockTemplateService) CreateTemplate(user models.JWTUser, req requests.CreateTemplateRequest) (*string, error) {
+	if m.CreateTemplateError != nil {
+		return nil, m.CreateTemplateError
+	}
+	id := "newID"
+	return &id, nil
+}
+
+func (m *MockTemplateService) DeleteTemplateByID(templateID string) error {
+	if m.DeleteTemplateByIDError != nil {
+		return m.DeleteTemplateByIDError
+	}
+	return nil
+}
+
+func (m *MockTemplateService) InstantiateTemplate(templateID string, username string, req requests.InstantiateTemplateRequest) (*string, error) {
+	if m.InstantiateTemplateError != nil {
+		return nil, m.InstantiateTemplateError
+	}
+	id := "newID"
+	return &id, nil
+}
+
+func (m *MockTemplateService) SaveWorkflowAsTemplate(workflowID string, user models.JWTUser, req requests.SaveWorkflowAsTemplateRequest) (*string, error) {
+	if m.SaveWorkflowAsTemplateError != nil {
+		return nil, m.SaveWorkflowAsTemplateError
+	}
+	id := "newID"
+	return &id, nil
+}
+
+var (
+	mockTemplateService = new(MockTemplateService)
+	templateController  = TemplateController{TemplateService: mockTemplateService, WorkflowService: mockWorkflowService}
+)
+
+func TestNewTemplateController(t *testing.T) {
+	mockResource := &databases.Resource{}
+	controller := NewTemplateController(mockResource)
+
+	assert.NotNil(t, controller)
+	assert.NotNil(t, controller.TemplateService)
+	assert.NotNil(t, controller.WorkflowService)
+}
+
+func TestGetTemplates(t *testing.T) {
+	t.Run("Successful GetTemplates", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/templates", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		templateController.GetTemplates(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), OKStatus)
+	})
+
+	t.Run("Failed GetTemplates", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/templates", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		templateController := TemplateController{TemplateService: &MockTemplateService{GetTemplatesError: errors.New("failed to retrieve templates")}}
+
+		templateController.GetTemplates(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to retrieve templates")
+	})
+}
+
+func TestGetTemplate(t *testing.T) {
+	t.Run("Successful GetTemplate", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/templates/some_id", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		templateController.GetTemplate(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), OKStatus)
+	})
+
+	t.Run("Personal template of another user", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/templates/some_id", nil)
+		c.Set("user", models.JWTUser{Username: "testWrongUser"})
+
+		templateController.GetTemplate(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
+
+func TestCreateTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    requests.CreateTemplateRequest
+		expected int
+		message  string
+	}{
+		{"Valid input", requests.CreateTemplateRequest{
+			Name:  "Onboarding",
+			Scope: models.PersonalTemplate,
+			Tasks: []requests.TemplateTaskRequest{{Key: "account", Name: "Create account for {{employee}}"}},
+		}, HTTPStatusOK, OKStatus},
+		{"Invalid scope", requests.CreateTemplateRequest{Name: "Onboarding", Scope: "Team"}, HTTPStatusOK, InvalidInput},
+		{"Invalid task", requests.CreateTemplateRequest{
+			Name:  "Onboarding",
+			Scope: models.PersonalTemplate,
+			Tasks: []requests.TemplateTaskRequest{{Name: "Create account"}},
+		}, HTTPStatusOK, InvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			requestBody, _ := json.Marshal(tt.input)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/templates", bytes.NewBuffer(requestBody))
+			c.Set("user", models.JWTUser{Username: "testUser"})
+
+			templateController.CreateTemplate(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+
+	t.Run("Failed CreateTemplate", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/templates", strings.NewReader(`{"name":"Release","scope":"Global"}`))
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		templateController := TemplateController{TemplateService: &MockTemplateService{CreateTemplateError: errors.New("only admins can create global templates")}}
+
+		templateController.CreateTemplate(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "only admins can create global templates")
+	})
+}
+
+func TestDeleteTemplate(t *testing.T) {
+	t.Run("Successful DeleteTemplate", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodDelete, "/templates/some_id", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		templateController.DeleteTemplate(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), OKStatus)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodDelete, "/templates/some_id", nil)
+		c.Set("user", models.JWTUser{Username: "testWrongUser", Role: models.Admin})
+
+		templateController.DeleteTemplate(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
+
+func TestInstantiateTemplate(t *testing.T) {
+	t.Run("Successful InstantiateTemplate", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/templates/some_id/instantiate", strings.NewReader(`{"name":"Onboarding Alice","parameters":{"employee":"Alice"}}`))
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		templateController.InstantiateTemplate(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "workflow_id")
+	})
+
+	t.Run("Invalid input", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/templates/some_id/instantiate", strings.NewReader(`{}`))
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		templateController.InstantiateTemplate(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), InvalidInput)
+	})
+
+	t.Run("Failed InstantiateTemplate", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/templates/some_id/instantiate", strings.NewReader(`{"name":"Onboarding"}`))
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		templateController := TemplateController{TemplateService: &MockTemplateService{InstantiateTemplateError: errors.New("missing template parameter: employee")}}
+
+		templateController.InstantiateTemplate(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "missing template parameter: employee")
+	})
+}
+
+func TestSaveWorkflowAsTemplate(t *testing.T) {
+	t.Run("Successful SaveWorkflowAsTemplate", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/template", strings.NewReader(`{"name":"Release","scope":"Personal"}`))
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		templateController.SaveWorkflowAsTemplate(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "template_id")
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/template", strings.NewReader(`{"name":"Release","scope":"Personal"}`))
+		c.Set("user", models.JWTUser{Username: "testWrongUser"})
+
+		templateController.SaveWorkflowAsTemplate(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
@@ -97,3 +97,40 @@ func (controller *UserController) Logout(c *gin.Context) {
 
 	responses.Ok(c)
 }
+
+// @Security access_token
+// @Summary Update a user
+// @Tags Admin
+// @version 1.0
+// @Description Grant a user a role, move them to an organization or set their teams. Fields left out keep their value. The sessions of the user end, so the change applies from their next login. Admins only
+// @Accept  application/json
+// @Produce  application/json
+// @Param username path string true "Username"
+// @Param user body requests.UpdateUserRequest true "Role, organization and teams"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Router /admin/users/{username} [patch]
+func (controller *UserController) UpdateUser(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	if user.Role != models.Admin {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	var req requests.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	updated, err := controller.UserService.UpdateUser(c.Param("username"), req)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"user": updated.Profile(),
+	})
+}
@@ -27,6 +27,7 @@ const (
 type MockUserService struct {
 	LogoutError             error
 	GetUsersByUsernameError error
+	UpdateUserError         error
 }
 
 var _ services.IUserService = &MockUserService{}
@@ -63,6 +64,23 @@ func (m *MockUserService) GetUsersByUsername(username string) (*models.User, err
 	return &models.User{}, nil
 }
 
+func (m *MockUserService) UpdateUser(username string, req requests.UpdateUserRequest) (*models.User, error) {
+	if m.UpdateUserError != nil {
+		return nil, m.UpdateUserError
+	}
+	user := &models.User{Username: username, Password: "hashed", Role: models.Employer}
+	if req.Role != nil {
+		user.Role = *req.Role
+	}
+	if req.Organization != nil {
+		user.Organization = *req.Organization
+	}
+	if req.Teams != nil {
+		user.Teams = *req.Teams
+	}
+	return user, nil
+}
+
 var (
 	mockUserService = new(MockUserService)
 	userController  = UserController{UserService: mockUserService}
@@ -116,9 +134,9 @@ func TestRegister(t *testing.T) {
 		expected int
 		message  string
 	}{
-		{"Valid input", requests.RegisterRequest{Username: "test", Password: "test123456", Role: "Admin"}, HTTPStatusOK, OKStatus},
-		{"Missing Username", requests.RegisterRequest{Username: "", Password: "test123456", Role: "Admin"}, HTTPStatusOK, InvalidInput},
-		{"Missing Password", requests.RegisterRequest{Username: "test", Password: "", Role: "Admin"}, HTTPStatusOK, InvalidInput},
+		{"Valid input", requests.RegisterRequest{Username: "test", Password: "test123456"}, HTTPStatusOK, OKStatus},
+		{"Missing Username", requests.RegisterRequest{Username: "", Password: "test123456"}, HTTPStatusOK, InvalidInput},
+		{"Missing Password", requests.RegisterRequest{Username: "test", Password: ""}, HTTPStatusOK, InvalidInput},
 	}
 
 	for _, tt := range tests {
@@ -192,3 +210,62 @@ func TestLogout(t *testing.T) {
 		assert.Contains(t, w.Body.String(), "failed to logout")
 	})
 }
+
+func TestUpdateUser(t *testing.T) {
+	updateUser := func(controller UserController, user models.JWTUser, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPatch, "/admin/users/alice", bytes.NewBufferString(body))
+		c.Params = gin.Params{{Key: "username", Value: "alice"}}
+		c.Set("user", user)
+
+		controller.UpdateUser(c)
+		return w
+	}
+	admin := models.JWTUser{Username: "root", Role: models.Admin}
+
+	t.Run("Success", func(t *testing.T) {
+		w := updateUser(userController, admin, `{"role":"Admin","organization":"acme"}`)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"user":{"username":"alice","role":"Admin","organization":"acme","teams":null}`)
+		assert.NotContains(t, w.Body.String(), "hashed")
+	})
+
+	t.Run("Teams", func(t *testing.T) {
+		w := updateUser(userController, admin, `{"teams":["ops","finance"]}`)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"teams":["ops","finance"]`)
+	})
+
+	t.Run("Empty team name", func(t *testing.T) {
+		w := updateUser(userController, admin, `{"teams":[""]}`)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), InvalidInput)
+	})
+
+	t.Run("Unknown role", func(t *testing.T) {
+		w := updateUser(userController, admin, `{"role":"Owner"}`)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), InvalidInput)
+	})
+
+	t.Run("Not an admin", func(t *testing.T) {
+		w := updateUser(userController, models.JWTUser{Username: "alice", Role: models.Employer}, `{"role":"Admin"}`)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+
+	t.Run("Service error", func(t *testing.T) {
+		controller := UserController{UserService: &MockUserService{UpdateUserError: errors.New("username does not exist")}}
+
+		w := updateUser(controller, admin, `{"organization":"acme"}`)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "username does not exist")
+	})
+}
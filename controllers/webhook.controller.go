@@ -0,0 +1,308 @@
+package controllers
+
+import (
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/requests"
+	"virtual_workflow_management_system_gin/responses"
+	"virtual_workflow_management_system_gin/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookController struct {
+	WebhookService  services.IWebhookService
+	WorkflowService services.IWorkflowService
+}
+
+func NewWebhookController(resource *databases.Resource) *WebhookController {
+	webhookService := services.NewWebhookService(resource)
+	workflowService := services.NewWorkflowService(resource)
+	return &WebhookController{WebhookService: webhookService, WorkflowService: workflowService}
+}
+
+// @Security access_token
+// @Summary Get the webhooks of a workflow
+// @Tags Webhooks
+// @version 1.0
+// @Description Get the webhooks subscribed to the events of a workflow
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Success 200 {object} string "OK"
+// @Router /workflows/{id}/webhooks [get]
+func (controller *WebhookController) GetWorkflowWebhooks(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	webhooks, err := controller.WebhookService.GetWorkflowWebhooks(workflowID)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"webhooks": webhooks,
+	})
+}
+
+// @Security access_token
+// @Summary Subscribe a webhook to a workflow
+// @Tags Webhooks
+// @version 1.0
+// @Description Post the selected events of a workflow to a URL. Payloads are signed with HMAC-SHA256 in the X-Webhook-Signature header. The secret is only returned here
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param webhook body requests.CreateWebhookRequest true "Webhook details"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Router /workflows/{id}/webhooks [post]
+func (controller *WebhookController) CreateWorkflowWebhook(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	var req requests.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	webhook, err := controller.WebhookService.CreateWebhook(user, workflowID, req)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"webhook": webhook,
+		"secret":  webhook.Secret,
+	})
+}
+
+// @Security access_token
+// @Summary Get the webhooks of the organization
+// @Tags Webhooks
+// @version 1.0
+// @Description Get the webhooks subscribed to the events of every workflow of the organization. Admins only
+// @Accept  application/json
+// @Produce  application/json
+// @Success 200 {object} string "OK"
+// @Router /webhooks [get]
+func (controller *WebhookController) GetOrganizationWebhooks(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	if user.Role != models.Admin {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	webhooks, err := controller.WebhookService.GetOrganizationWebhooks(user.Organization)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"webhooks": webhooks,
+	})
+}
+
+// @Security access_token
+// @Summary Subscribe a webhook to the organization
+// @Tags Webhooks
+// @version 1.0
+// @Description Post the selected events of every workflow owned by a member of the organization to a URL. The secret is only returned here. Admins only
+// @Accept  application/json
+// @Produce  application/json
+// @Param webhook body requests.CreateWebhookRequest true "Webhook details"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Router /webhooks [post]
+func (controller *WebhookController) CreateOrganizationWebhook(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	if user.Role != models.Admin {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	var req requests.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	webhook, err := controller.WebhookService.CreateWebhook(user, "", req)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"webhook": webhook,
+		"secret":  webhook.Secret,
+	})
+}
+
+// @Security access_token
+// @Summary Get a webhook
+// @Tags Webhooks
+// @version 1.0
+// @Description Get a webhook by ID
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} string "OK"
+// @Router /webhooks/{id} [get]
+func (controller *WebhookController) GetWebhook(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	webhook, err := controller.WebhookService.GetWebhookByID(c.Param("id"))
+	if err != nil {
+		responses.Error(c, "failed to get webhook")
+		return
+	}
+
+	if !webhook.CheckWebhookAccess(user) {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"webhook": webhook,
+	})
+}
+
+// @Security access_token
+// @Summary Delete a webhook
+// @Tags Webhooks
+// @version 1.0
+// @Description Delete a webhook and its delivery log
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} string "OK"
+// @Router /webhooks/{id} [delete]
+func (controller *WebhookController) DeleteWebhook(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	webhookID := c.Param("id")
+
+	webhook, err := controller.WebhookService.GetWebhookByID(webhookID)
+	if err != nil {
+		responses.Error(c, "failed to get webhook")
+		return
+	}
+
+	if !webhook.CheckWebhookAccess(user) {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	err = controller.WebhookService.DeleteWebhook(webhookID)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.Ok(c)
+}
+
+// @Security access_token
+// @Summary Get the deliveries of a webhook
+// @Tags Webhooks
+// @version 1.0
+// @Description Get the most recent delivery attempts of a webhook, newest first
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} string "OK"
+// @Router /webhooks/{id}/deliveries [get]
+func (controller *WebhookController) GetDeliveries(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	webhookID := c.Param("id")
+
+	webhook, err := controller.WebhookService.GetWebhookByID(webhookID)
+	if err != nil {
+		responses.Error(c, "failed to get webhook")
+		return
+	}
+
+	if !webhook.CheckWebhookAccess(user) {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	deliveries, err := controller.WebhookService.GetDeliveries(webhookID)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"deliveries": deliveries,
+	})
+}
+
+// @Security access_token
+// @Summary Send a test event
+// @Tags Webhooks
+// @version 1.0
+// @Description Post a webhook.test event to the webhook right away and return the delivery
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} string "OK"
+// @Router /webhooks/{id}/test [post]
+func (controller *WebhookController) SendTestEvent(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	webhookID := c.Param("id")
+
+	webhook, err := controller.WebhookService.GetWebhookByID(webhookID)
+	if err != nil {
+		responses.Error(c, "failed to get webhook")
+		return
+	}
+
+	if !webhook.CheckWebhookAccess(user) {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	delivery, err := controller.WebhookService.SendTestEvent(webhookID)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"delivery": delivery,
+	})
+}
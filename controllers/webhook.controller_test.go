@@ -0,0 +1,301 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/requests"
+	"virtual_workflow_management_system_gin/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockWebhookService struct {
+	GetWorkflowWebhooksError     error
+	GetOrganizationWebhooksError error
+	GetWebhookByIDError          error
+	CreateWebhookError           error
+	DeleteWebhookError           error
+	GetDeliveriesError           error
+	SendTestEventError           error
+}
+
+var _ services.IWebhookService = &MockWebhookService{}
+
+func (m *MockWebhookService) GetWorkflowWebhooks(workflowID string) ([]models.Webhook, error) {
+	if m.GetWorkflowWebhooksError != nil {
+		return nil, m.GetWorkflowWebhooksError
+	}
+	return []models.Webhook{{Owner: "testUser", URL: "https://example.com/hook", Events: []models.WebhookEvent{models.TaskCreatedEvent}}}, nil
+}
+
+func (m *MockWebhookService) GetOrganizationWebhooks(organization string) ([]models.Webhook, error) {
+	if m.GetOrganizationWebhooksError != nil {
+		return nil, m.GetOrganizationWebhooksError
+	}
+	return []models.Webhook{{Owner: "admin", Organization: organization, URL: "https://example.com/hook"}}, nil
+}
+
+func (m *MockWebhookService) GetWebhookByID(webhookID string) (*models.Webhook, error) {
+	if m.GetWebhookByIDError != nil {
+		return nil, m.GetWebhookByIDError
+	}
+	return &models.Webhook{Owner: "testUser", URL: "https://example.com/hook", Secret: "stored secret", Active: true}, nil
+}
+
+func (m *MockWebhookService) CreateWebhook(user models.JWTUser, workflowID string, req requests.CreateWebhookRequest) (*models.Webhook, error) {
+	if m.CreateWebhookError != nil {
+		return nil, m.CreateWebhookError
+	}
+	return &models.Webhook{Owner: user.Username, URL: req.URL, Events: req.Events, Secret: "generated secret", Active: true}, nil
+}
+
+func (m *MockWebhookService) DeleteWebhook(webhookID string) error {
+	return m.DeleteWebhookError
+}
+
+func (m *MockWebhookService) GetDeliveries(webhookID string) ([]models.WebhookDelivery, error) {
+	if m.GetDeliveriesError != nil {
+		return nil, m.GetDeliveriesError
+	}
+	return []models.WebhookDelivery{{Event: models.TaskCreatedEvent, StatusCode: 500, Error: "endpoint responded with status 500"}}, nil
+}
+
+func (m *MockWebhookService) SendTestEvent(webhookID string) (*models.WebhookDelivery, error) {
+	if m.SendTestEventError != nil {
+		return nil, m.SendTestEventError
+	}
+	return &models.WebhookDelivery{Event: models.WebhookTestEvent, StatusCode: 200, Succeeded: true}, nil
+}
+
+var (
+	mockWebhookService = new(MockWebhookService)
+	webhookController  = WebhookController{WebhookService: mockWebhookService, WorkflowService: mockWorkflowService}
+)
+
+func TestNewWebhookController(t *testing.T) {
+	mockResource := &databases.Resource{}
+	controller := NewWebhookController(mockResource)
+
+	assert.NotNil(t, controller)
+	assert.NotNil(t, controller.WebhookService)
+	assert.NotNil(t, controller.WorkflowService)
+}
+
+func TestGetWorkflowWebhooks(t *testing.T) {
+	t.Run("Successful GetWorkflowWebhooks", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/webhooks", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		webhookController.GetWorkflowWebhooks(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "https://example.com/hook")
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/webhooks", nil)
+		c.Set("user", models.JWTUser{Username: "testWrongUser"})
+
+		webhookController.GetWorkflowWebhooks(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
+
+func TestCreateWorkflowWebhook(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		body     string
+		expected int
+		message  string
+	}{
+		{"Valid webhook", "testUser", `{"url":"https://example.com/hook","events":["task.created","task.status_changed"]}`, HTTPStatusOK, `"secret":"generated secret"`},
+		{"Invalid URL", "testUser", `{"url":"not a url","events":["task.created"]}`, HTTPStatusOK, InvalidInput},
+		{"No events", "testUser", `{"url":"https://example.com/hook","events":[]}`, HTTPStatusOK, InvalidInput},
+		{"Unknown event", "testUser", `{"url":"https://example.com/hook","events":["task.deleted"]}`, HTTPStatusOK, InvalidInput},
+		{"Short secret", "testUser", `{"url":"https://example.com/hook","events":["task.created"],"secret":"short"}`, HTTPStatusOK, InvalidInput},
+		{"Not the owner", "testWrongUser", `{"url":"https://example.com/hook","events":["task.created"]}`, HTTPStatusOK, "unauthorized"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/webhooks", strings.NewReader(tt.body))
+			c.Set("user", models.JWTUser{Username: tt.user})
+
+			webhookController.CreateWorkflowWebhook(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+}
+
+func TestOrganizationWebhooks(t *testing.T) {
+	t.Run("Successful GetOrganizationWebhooks", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/webhooks", nil)
+		c.Set("user", models.JWTUser{Username: "admin", Role: models.Admin, Organization: "acme"})
+
+		webhookController.GetOrganizationWebhooks(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"organization":"acme"`)
+	})
+
+	t.Run("Successful CreateOrganizationWebhook", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(`{"url":"https://example.com/hook","events":["workflow.created"]}`))
+		c.Set("user", models.JWTUser{Username: "admin", Role: models.Admin, Organization: "acme"})
+
+		webhookController.CreateOrganizationWebhook(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "secret")
+	})
+
+	t.Run("Not an admin", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(`{"url":"https://example.com/hook","events":["workflow.created"]}`))
+		c.Set("user", models.JWTUser{Username: "testUser", Role: models.Employer, Organization: "acme"})
+
+		webhookController.CreateOrganizationWebhook(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+
+	t.Run("No organization", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(`{"url":"https://example.com/hook","events":["workflow.created"]}`))
+		c.Set("user", models.JWTUser{Username: "admin", Role: models.Admin})
+
+		webhookController := WebhookController{WebhookService: &MockWebhookService{CreateWebhookError: errors.New("user does not belong to an organization")}}
+		webhookController.CreateOrganizationWebhook(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "user does not belong to an organization")
+	})
+}
+
+func TestGetWebhook(t *testing.T) {
+	t.Run("Successful GetWebhook", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/webhooks/webhook_id", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		webhookController.GetWebhook(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "https://example.com/hook")
+		assert.NotContains(t, w.Body.String(), "stored secret")
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/webhooks/webhook_id", nil)
+		c.Set("user", models.JWTUser{Username: "testWrongUser"})
+
+		webhookController.GetWebhook(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+
+	t.Run("Webhook does not exist", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/webhooks/webhook_id", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		webhookController := WebhookController{WebhookService: &MockWebhookService{GetWebhookByIDError: errors.New("webhook does not exist")}}
+		webhookController.GetWebhook(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to get webhook")
+	})
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	t.Run("Successful DeleteWebhook", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodDelete, "/webhooks/webhook_id", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		webhookController.DeleteWebhook(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), OKStatus)
+	})
+
+	t.Run("Admin", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodDelete, "/webhooks/webhook_id", nil)
+		c.Set("user", models.JWTUser{Username: "admin", Role: models.Admin})
+
+		webhookController.DeleteWebhook(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), OKStatus)
+	})
+}
+
+func TestGetDeliveries(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/webhooks/webhook_id/deliveries", nil)
+	c.Set("user", models.JWTUser{Username: "testUser"})
+
+	webhookController.GetDeliveries(c)
+
+	assert.Equal(t, HTTPStatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "endpoint responded with status 500")
+}
+
+func TestSendTestEvent(t *testing.T) {
+	t.Run("Successful SendTestEvent", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/webhooks/webhook_id/test", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		webhookController.SendTestEvent(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"event":"webhook.test"`)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/webhooks/webhook_id/test", nil)
+		c.Set("user", models.JWTUser{Username: "testWrongUser"})
+
+		webhookController.SendTestEvent(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
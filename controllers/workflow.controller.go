@@ -1,8 +1,16 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"virtual_workflow_management_system_gin/common"
 	"virtual_workflow_management_system_gin/databases"
 	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/repositories"
 	"virtual_workflow_management_system_gin/requests"
 	"virtual_workflow_management_system_gin/responses"
 	"virtual_workflow_management_system_gin/services"
@@ -53,6 +61,7 @@ func (controller *WorkflowController) GetWorkflows(c *gin.Context) {
 // @Produce  application/json
 // @Param id path string true "Workflow ID"
 // @Success 200 {object} string "OK"
+// @Header 200 {string} ETag "Workflow version"
 // @Router /workflows/{id} [get]
 func (controller *WorkflowController) GetWorkflow(c *gin.Context) {
 	workflowID := c.Param("id")
@@ -63,6 +72,7 @@ func (controller *WorkflowController) GetWorkflow(c *gin.Context) {
 		return
 	}
 
+	common.SetETag(c, workflow.Version)
 	responses.OkWithData(c, gin.H{
 		"workflow": workflow,
 	})
@@ -109,8 +119,11 @@ func (controller *WorkflowController) CreateWorkflow(c *gin.Context) {
 // @Produce  application/json
 // @Param id path string true "Workflow ID"
 // @Param workflow body requests.EditWorkflowRequest true "Workflow for editing"
+// @Param If-Match header string false "Workflow version from the ETag header"
 // @Success 200 {object} string "OK"
 // @Failure 400 {object} string "Invalid input"
+// @Failure 412 {object} string "Workflow version mismatch"
+// @Failure 428 {object} string "If-Match header is required"
 // @Router /workflows/{id} [put]
 func (controller *WorkflowController) EditWorkflow(c *gin.Context) {
 	user := c.MustGet("user").(models.JWTUser)
@@ -128,17 +141,24 @@ func (controller *WorkflowController) EditWorkflow(c *gin.Context) {
 		return
 	}
 
+	version, ok := common.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
 	var req requests.EditWorkflowRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		responses.Error(c, "Invalid input")
 		return
 	}
-	updatedWorkflow, err := controller.WorkflowService.EditWorkflowByID(workflowID, req)
+	updatedWorkflow, err := controller.WorkflowService.EditWorkflowByID(workflowID, req, version)
 	if err != nil {
-		responses.Error(c, err.Error())
+		respondWorkflowError(c, err)
 		return
 	}
 
+	common.SetETag(c, updatedWorkflow.Version)
+
 	responses.OkWithData(c, gin.H{
 		"workflow": updatedWorkflow,
 	})
@@ -148,12 +168,15 @@ func (controller *WorkflowController) EditWorkflow(c *gin.Context) {
 // @Summary Delete a workflow
 // @Tags Workflows
 // @version 1.0
-// @Description Delete a workflow by ID
+// @Description Delete a workflow by ID, together with its schedules
 // @Accept  application/json
 // @Produce  application/json
 // @Param id path string true "Workflow ID"
+// @Param If-Match header string false "Workflow version from the ETag header"
 // @Success 200 {object} string "OK"
 // @Failure 400 {object} string "Invalid input"
+// @Failure 412 {object} string "Workflow version mismatch"
+// @Failure 428 {object} string "If-Match header is required"
 // @Router /workflows/{id} [delete]
 func (controller *WorkflowController) DeleteWorkflow(c *gin.Context) {
 	user := c.MustGet("user").(models.JWTUser)
@@ -171,9 +194,14 @@ func (controller *WorkflowController) DeleteWorkflow(c *gin.Context) {
 		return
 	}
 
-	err = controller.WorkflowService.DeleteWorkflowByID(workflowID)
+	version, ok := common.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	err = controller.WorkflowService.DeleteWorkflowByID(workflowID, version)
 	if err != nil {
-		responses.Error(c, err.Error())
+		respondWorkflowError(c, err)
 		return
 	}
 
@@ -189,8 +217,11 @@ func (controller *WorkflowController) DeleteWorkflow(c *gin.Context) {
 // @Produce  application/json
 // @Param id path string true "Workflow ID"
 // @Param username path string true "Username"
+// @Param If-Match header string false "Workflow version from the ETag header"
 // @Success 200 {object} string "OK"
 // @Failure 400 {object} string "Invalid input"
+// @Failure 412 {object} string "Workflow version mismatch"
+// @Failure 428 {object} string "If-Match header is required"
 // @Router /workflows/{id}/transfer/{username} [put]
 func (controller *WorkflowController) TransferWorkflow(c *gin.Context) {
 	user := c.MustGet("user").(models.JWTUser)
@@ -209,18 +240,25 @@ func (controller *WorkflowController) TransferWorkflow(c *gin.Context) {
 		return
 	}
 
+	version, ok := common.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
 	_, err = controller.UserService.GetUsersByUsername(newOwner)
 	if err != nil {
 		responses.Error(c, "user does not exist")
 		return
 	}
 
-	transferedWorkflow, err := controller.WorkflowService.TransferWorkflowByID(workflowID, newOwner)
+	transferedWorkflow, err := controller.WorkflowService.TransferWorkflowByID(workflowID, newOwner, version)
 	if err != nil {
-		responses.Error(c, err.Error())
+		respondWorkflowError(c, err)
 		return
 	}
 
+	common.SetETag(c, transferedWorkflow.Version)
+
 	responses.OkWithData(c, gin.H{
 		"workflow": transferedWorkflow,
 	})
@@ -230,7 +268,7 @@ func (controller *WorkflowController) TransferWorkflow(c *gin.Context) {
 // @Summary Get all tasks
 // @Tags Workflows
 // @version 1.0
-// @Description Get all tasks
+// @Description Get all tasks. Tasks with an SLA include its state: On Track, At Risk or Breached
 // @Accept  application/json
 // @Produce  application/json
 // @Param id path string true "Workflow ID"
@@ -287,6 +325,7 @@ func (controller *WorkflowController) GetTask(c *gin.Context) {
 // @Param task body requests.CreateTaskRequest true "Task for creation"
 // @Success 200 {object} string "OK"
 // @Failure 400 {object} string "Invalid input"
+// @Failure 409 {object} string "Workflow is being changed by another request"
 // @Router /workflows/{id}/tasks [post]
 func (controller *WorkflowController) CreateTask(c *gin.Context) {
 	user := c.MustGet("user").(models.JWTUser)
@@ -311,7 +350,7 @@ func (controller *WorkflowController) CreateTask(c *gin.Context) {
 	}
 	createdTaskID, err := controller.WorkflowService.CreateTaskByWorkflowID(workflowID, req)
 	if err != nil {
-		responses.Error(c, err.Error())
+		respondWorkflowError(c, err)
 		return
 	}
 
@@ -330,8 +369,11 @@ func (controller *WorkflowController) CreateTask(c *gin.Context) {
 // @Param workflow_id path string true "Workflow ID"
 // @Param task_id path string true "Task ID"
 // @Param task body requests.EditTaskRequest true "Task for editing"
+// @Param If-Match header string false "Workflow version from the ETag header"
 // @Success 200 {object} string "OK"
 // @Failure 400 {object} string "Invalid input"
+// @Failure 412 {object} string "Workflow version mismatch"
+// @Failure 428 {object} string "If-Match header is required"
 // @Router /workflows/{workflow_id}/tasks/{task_id} [put]
 func (controller *WorkflowController) EditTask(c *gin.Context) {
 	user := c.MustGet("user").(models.JWTUser)
@@ -350,14 +392,19 @@ func (controller *WorkflowController) EditTask(c *gin.Context) {
 		return
 	}
 
+	version, ok := common.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
 	var req requests.EditTaskRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		responses.Error(c, "Invalid input")
 		return
 	}
-	task, err := controller.WorkflowService.EditTaskByID(workflowID, taskID, req)
+	task, err := controller.WorkflowService.EditTaskByID(workflowID, taskID, req, version)
 	if err != nil {
-		responses.Error(c, err.Error())
+		respondWorkflowError(c, err)
 		return
 	}
 
@@ -375,8 +422,11 @@ func (controller *WorkflowController) EditTask(c *gin.Context) {
 // @Produce  application/json
 // @Param id path string true "Workflow ID"
 // @Param taskID path string true "Task ID"
+// @Param If-Match header string false "Workflow version from the ETag header"
 // @Success 200 {object} string "OK"
 // @Failure 400 {object} string "Invalid input"
+// @Failure 412 {object} string "Workflow version mismatch"
+// @Failure 428 {object} string "If-Match header is required"
 // @Router /workflows/{id}/tasks/{taskID} [delete]
 func (controller *WorkflowController) DeleteTask(c *gin.Context) {
 	user := c.MustGet("user").(models.JWTUser)
@@ -395,11 +445,1266 @@ func (controller *WorkflowController) DeleteTask(c *gin.Context) {
 		return
 	}
 
-	err = controller.WorkflowService.DeleteTaskByID(workflowID, taskID)
+	version, ok := common.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	err = controller.WorkflowService.DeleteTaskByID(workflowID, taskID, version)
 	if err != nil {
-		responses.Error(c, err.Error())
+		respondWorkflowError(c, err)
 		return
 	}
 
 	responses.Ok(c)
 }
+
+// @Security access_token
+// @Summary Reorder tasks
+// @Tags Workflows
+// @version 1.0
+// @Description Reorder all tasks of a workflow at once, either with the full ordered list of task IDs or by moving one task before or after another
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param order body requests.ReorderTasksRequest true "New task order"
+// @Param If-Match header string false "Workflow version from the ETag header"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Failure 412 {object} string "Workflow version mismatch"
+// @Failure 428 {object} string "If-Match header is required"
+// @Router /workflows/{id}/tasks/order [put]
+func (controller *WorkflowController) ReorderTasks(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	version, ok := common.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	var req requests.ReorderTasksRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+	tasks, err := controller.WorkflowService.ReorderTasksByWorkflowID(workflowID, req, version)
+	if err != nil {
+		respondWorkflowError(c, err)
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"tasks": tasks,
+	})
+}
+
+// @Security access_token
+// @Summary Create, update and delete tasks in one request
+// @Tags Workflows
+// @version 1.0
+// @Description Apply up to 100 task operations in order. In atomic mode (default) they run in one transaction and every operation is applied or none; in best_effort mode each runs in its own transaction and the operations that succeed are applied. Every operation gets a result
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param batch body requests.BatchTasksRequest true "Task operations"
+// @Param If-Match header string false "Workflow version from the ETag header"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Failure 404 {object} string "Not found"
+// @Failure 412 {object} string "Workflow version mismatch"
+// @Failure 428 {object} string "If-Match header is required"
+// @Router /workflows/{id}/tasks:batch [post]
+func (controller *WorkflowController) BatchTasks(c *gin.Context) {
+	// Gin cannot escape the colon of the route, which captures whatever
+	// follows "tasks" as the method.
+	if c.Param("method") != ":batch" {
+		responses.ErrorWithStatus(c, http.StatusNotFound, "not found")
+		return
+	}
+
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	version, ok := common.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	var req requests.BatchTasksRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	result, err := controller.WorkflowService.BatchTasks(workflowID, req, version)
+	if err != nil {
+		respondWorkflowError(c, err)
+		return
+	}
+
+	data := gin.H{
+		"mode":      result.Mode,
+		"committed": result.Committed,
+		"results":   result.Results,
+	}
+	if !result.Committed {
+		responses.ErrorWithData(c, "batch was rolled back", data)
+		return
+	}
+
+	responses.OkWithData(c, data)
+}
+
+// @Security access_token
+// @Summary Patch a workflow
+// @Tags Workflows
+// @version 1.0
+// @Description Partially update a workflow with a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902)
+// @Accept  application/merge-patch+json
+// @Accept  application/json-patch+json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param If-Match header string false "Workflow version from the ETag header"
+// @Param patch body object true "Patch document"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Failure 412 {object} string "Workflow version mismatch"
+// @Failure 415 {object} string "Unsupported patch content type"
+// @Failure 428 {object} string "If-Match header is required"
+// @Router /workflows/{id} [patch]
+func (controller *WorkflowController) PatchWorkflow(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	version, ok := common.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	req, ok := bindPatchRequest(c)
+	if !ok {
+		return
+	}
+	updatedWorkflow, err := controller.WorkflowService.PatchWorkflowByID(workflowID, req, version)
+	if err != nil {
+		respondWorkflowError(c, err)
+		return
+	}
+
+	common.SetETag(c, updatedWorkflow.Version)
+	responses.OkWithData(c, gin.H{
+		"workflow": updatedWorkflow,
+	})
+}
+
+// @Security access_token
+// @Summary Patch a task
+// @Tags Workflows
+// @version 1.0
+// @Description Partially update a task with a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902)
+// @Accept  application/merge-patch+json
+// @Accept  application/json-patch+json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param taskID path string true "Task ID"
+// @Param If-Match header string false "Workflow version from the ETag header"
+// @Param patch body object true "Patch document"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Failure 412 {object} string "Workflow version mismatch"
+// @Failure 415 {object} string "Unsupported patch content type"
+// @Failure 428 {object} string "If-Match header is required"
+// @Router /workflows/{id}/tasks/{taskID} [patch]
+func (controller *WorkflowController) PatchTask(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+	taskID := c.Param("taskID")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	version, ok := common.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	req, ok := bindPatchRequest(c)
+	if !ok {
+		return
+	}
+	task, err := controller.WorkflowService.PatchTaskByID(workflowID, taskID, req, version)
+	if err != nil {
+		respondWorkflowError(c, err)
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"task": task,
+	})
+}
+
+// @Security access_token
+// @Summary Get workflow revisions
+// @Tags Workflows
+// @version 1.0
+// @Description Get all stored revisions of a workflow definition
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Success 200 {object} string "OK"
+// @Router /workflows/{id}/revisions [get]
+func (controller *WorkflowController) GetRevisions(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	revisions, err := controller.WorkflowService.GetRevisionsByWorkflowID(workflowID)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"revisions": revisions,
+	})
+}
+
+// @Security access_token
+// @Summary Get a workflow revision
+// @Tags Workflows
+// @version 1.0
+// @Description Get a single revision of a workflow definition
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param revision path int true "Revision number"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Router /workflows/{id}/revisions/{revision} [get]
+func (controller *WorkflowController) GetRevision(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	workflowRevision, err := controller.WorkflowService.GetRevision(workflowID, revision)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"revision": workflowRevision,
+	})
+}
+
+// @Security access_token
+// @Summary Diff two workflow revisions
+// @Tags Workflows
+// @version 1.0
+// @Description Compute a structured diff between two revisions of a workflow definition
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param from query int true "Base revision"
+// @Param to query int true "Target revision"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Router /workflows/{id}/revisions/diff [get]
+func (controller *WorkflowController) DiffRevisions(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	to, err := strconv.Atoi(c.Query("to"))
+	if err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	diff, err := controller.WorkflowService.DiffRevisions(workflowID, from, to)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"diff": diff,
+	})
+}
+
+// @Security access_token
+// @Summary Restore a workflow revision
+// @Tags Workflows
+// @version 1.0
+// @Description Restore an earlier revision as the new head of the workflow definition. Tasks that still exist keep their status
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param revision path int true "Revision number"
+// @Param If-Match header string false "Workflow version from the ETag header"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Failure 412 {object} string "Workflow version mismatch"
+// @Failure 428 {object} string "If-Match header is required"
+// @Router /workflows/{id}/revisions/{revision}/restore [post]
+func (controller *WorkflowController) RestoreRevision(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	version, ok := common.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	restoredWorkflow, err := controller.WorkflowService.RestoreRevision(workflowID, revision, version)
+	if err != nil {
+		respondWorkflowError(c, err)
+		return
+	}
+
+	common.SetETag(c, restoredWorkflow.Version)
+	responses.OkWithData(c, gin.H{
+		"workflow": restoredWorkflow,
+	})
+}
+
+// @Security access_token
+// @Summary Set workflow variables
+// @Tags Workflows
+// @version 1.0
+// @Description Set run variables of a workflow and evaluate the gateways that depend on them
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param variables body requests.SetVariablesRequest true "Variables to set"
+// @Param If-Match header string false "Workflow version from the ETag header"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Failure 412 {object} string "Workflow version mismatch"
+// @Failure 428 {object} string "If-Match header is required"
+// @Router /workflows/{id}/variables [put]
+func (controller *WorkflowController) SetVariables(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	version, ok := common.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	var req requests.SetVariablesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	updatedWorkflow, err := controller.WorkflowService.SetVariables(workflowID, req, version)
+	if err != nil {
+		respondWorkflowError(c, err)
+		return
+	}
+
+	common.SetETag(c, updatedWorkflow.Version)
+	responses.OkWithData(c, gin.H{
+		"workflow": updatedWorkflow,
+	})
+}
+
+// @Security access_token
+// @Summary Start a workflow run
+// @Tags Workflows
+// @version 1.0
+// @Description Start the run of a workflow. Gateways nothing leads to wait for the start, then decide on the run variables; gateways after other tasks decide once those are done
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param If-Match header string false "Workflow version from the ETag header"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Failure 412 {object} string "Workflow version mismatch"
+// @Failure 428 {object} string "If-Match header is required"
+// @Router /workflows/{id}/start [post]
+func (controller *WorkflowController) StartWorkflow(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	version, ok := common.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	startedWorkflow, err := controller.WorkflowService.StartWorkflow(workflowID, version)
+	if err != nil {
+		respondWorkflowError(c, err)
+		return
+	}
+
+	common.SetETag(c, startedWorkflow.Version)
+	responses.OkWithData(c, gin.H{
+		"workflow": startedWorkflow,
+	})
+}
+
+// @Security access_token
+// @Summary Declare workflow variables
+// @Tags Workflows
+// @version 1.0
+// @Description Declare the typed run variables of a workflow. Task forms must agree with the declared types
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param definitions body requests.SetVariableDefinitionsRequest true "Variable definitions"
+// @Param If-Match header string false "Workflow version from the ETag header"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Failure 412 {object} string "Workflow version mismatch"
+// @Failure 428 {object} string "If-Match header is required"
+// @Router /workflows/{id}/variables/definitions [put]
+func (controller *WorkflowController) SetVariableDefinitions(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	version, ok := common.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	var req requests.SetVariableDefinitionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	updatedWorkflow, err := controller.WorkflowService.SetVariableDefinitions(workflowID, req, version)
+	if err != nil {
+		respondWorkflowError(c, err)
+		return
+	}
+
+	common.SetETag(c, updatedWorkflow.Version)
+	responses.OkWithData(c, gin.H{
+		"workflow": updatedWorkflow,
+	})
+}
+
+// @Security access_token
+// @Summary Set a task form
+// @Tags Workflows
+// @version 1.0
+// @Description Replace the form fields a user fills in to complete the task
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param taskID path string true "Task ID"
+// @Param form body requests.SetTaskFormRequest true "Form fields"
+// @Param If-Match header string false "Workflow version from the ETag header"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Failure 412 {object} string "Workflow version mismatch"
+// @Failure 428 {object} string "If-Match header is required"
+// @Router /workflows/{id}/tasks/{taskID}/form [put]
+func (controller *WorkflowController) SetTaskForm(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+	taskID := c.Param("taskID")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	version, ok := common.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	var req requests.SetTaskFormRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	task, err := controller.WorkflowService.SetTaskForm(workflowID, taskID, req, version)
+	if err != nil {
+		respondWorkflowError(c, err)
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"task": task,
+	})
+}
+
+// @Security access_token
+// @Summary Complete a task
+// @Tags Workflows
+// @version 1.0
+// @Description Submit the task form and complete the task. Values are validated against the form and stored as run variables
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param taskID path string true "Task ID"
+// @Param values body requests.CompleteTaskRequest false "Form values"
+// @Param If-Match header string false "Workflow version from the ETag header"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Failure 412 {object} string "Workflow version mismatch"
+// @Failure 428 {object} string "If-Match header is required"
+// @Router /workflows/{id}/tasks/{taskID}/complete [post]
+func (controller *WorkflowController) CompleteTask(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+	taskID := c.Param("taskID")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	version, ok := common.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	var req requests.CompleteTaskRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			responses.Error(c, "Invalid input")
+			return
+		}
+	}
+
+	task, err := controller.WorkflowService.CompleteTask(workflowID, taskID, req, version)
+	if err != nil {
+		respondWorkflowError(c, err)
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"task": task,
+	})
+}
+
+// @Security access_token
+// @Summary Retry a failed task
+// @Tags Workflows
+// @version 1.0
+// @Description Queue a failed automated task again with a fresh set of attempts
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param taskID path string true "Task ID"
+// @Param If-Match header string false "Workflow version from the ETag header"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Failure 412 {object} string "Workflow version mismatch"
+// @Failure 428 {object} string "If-Match header is required"
+// @Router /workflows/{id}/tasks/{taskID}/retry [post]
+func (controller *WorkflowController) RetryTask(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+	taskID := c.Param("taskID")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	version, ok := common.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	task, err := controller.WorkflowService.RetryTask(workflowID, taskID, version)
+	if err != nil {
+		respondWorkflowError(c, err)
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"task": task,
+	})
+}
+
+// @Security access_token
+// @Summary Add a checklist item
+// @Tags Workflows
+// @version 1.0
+// @Description Append an item to the checklist of a task. A task cannot be completed while one of its required items is not done
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param taskID path string true "Task ID"
+// @Param item body requests.ChecklistItemRequest true "Checklist item"
+// @Param If-Match header string false "Workflow version from the ETag header"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Failure 412 {object} string "Workflow version mismatch"
+// @Failure 428 {object} string "If-Match header is required"
+// @Router /workflows/{id}/tasks/{taskID}/checklist [post]
+func (controller *WorkflowController) AddChecklistItem(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+	taskID := c.Param("taskID")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	version, ok := common.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	var req requests.ChecklistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	task, err := controller.WorkflowService.AddChecklistItem(workflowID, taskID, req, version)
+	if err != nil {
+		respondWorkflowError(c, err)
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"task": task,
+	})
+}
+
+// @Security access_token
+// @Summary Toggle a checklist item
+// @Tags Workflows
+// @version 1.0
+// @Description Mark a checklist item done by the current user, or not done again
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param taskID path string true "Task ID"
+// @Param itemID path string true "Checklist item ID"
+// @Param If-Match header string false "Workflow version from the ETag header"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Failure 412 {object} string "Workflow version mismatch"
+// @Failure 428 {object} string "If-Match header is required"
+// @Router /workflows/{id}/tasks/{taskID}/checklist/{itemID}/toggle [post]
+func (controller *WorkflowController) ToggleChecklistItem(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+	taskID := c.Param("taskID")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	version, ok := common.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	task, err := controller.WorkflowService.ToggleChecklistItem(workflowID, taskID, c.Param("itemID"), user, version)
+	if err != nil {
+		respondWorkflowError(c, err)
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"task": task,
+	})
+}
+
+// @Security access_token
+// @Summary Reorder a checklist
+// @Tags Workflows
+// @version 1.0
+// @Description Put the checklist items of a task in the given order. Every item must be listed exactly once
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param taskID path string true "Task ID"
+// @Param order body requests.ReorderChecklistRequest true "Checklist item IDs in their new order"
+// @Param If-Match header string false "Workflow version from the ETag header"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Failure 412 {object} string "Workflow version mismatch"
+// @Failure 428 {object} string "If-Match header is required"
+// @Router /workflows/{id}/tasks/{taskID}/checklist/order [put]
+func (controller *WorkflowController) ReorderChecklist(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+	taskID := c.Param("taskID")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	version, ok := common.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	var req requests.ReorderChecklistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	task, err := controller.WorkflowService.ReorderChecklist(workflowID, taskID, req, version)
+	if err != nil {
+		respondWorkflowError(c, err)
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"task": task,
+	})
+}
+
+// @Security access_token
+// @Summary Remove a checklist item
+// @Tags Workflows
+// @version 1.0
+// @Description Remove an item from the checklist of a task
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param taskID path string true "Task ID"
+// @Param itemID path string true "Checklist item ID"
+// @Param If-Match header string false "Workflow version from the ETag header"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Failure 412 {object} string "Workflow version mismatch"
+// @Failure 428 {object} string "If-Match header is required"
+// @Router /workflows/{id}/tasks/{taskID}/checklist/{itemID} [delete]
+func (controller *WorkflowController) RemoveChecklistItem(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+	taskID := c.Param("taskID")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	version, ok := common.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	task, err := controller.WorkflowService.RemoveChecklistItem(workflowID, taskID, c.Param("itemID"), version)
+	if err != nil {
+		respondWorkflowError(c, err)
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"task": task,
+	})
+}
+
+// @Security access_token
+// @Summary Validate a workflow definition
+// @Tags Workflows
+// @version 1.0
+// @Description Report unreachable tasks, dangling references and gateways without outgoing paths
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Success 200 {object} string "OK"
+// @Router /workflows/{id}/validate [get]
+func (controller *WorkflowController) ValidateWorkflow(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	problems, err := controller.WorkflowService.ValidateWorkflowDefinition(workflowID)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"valid":    len(problems) == 0,
+		"problems": problems,
+	})
+}
+
+// @Security access_token
+// @Summary Export a workflow
+// @Tags Workflows
+// @version 1.0
+// @Description Export the definition of a workflow, i.e. its tasks, their order and dependencies and its metadata, without run state. Tasks refer to each other by key, so the definition does not depend on IDs. The JSON and YAML format is described by /schemas/workflow-definition.json. BPMN 2.0 XML keeps the tasks, gateways and flows only
+// @Produce  application/json
+// @Produce  application/yaml
+// @Produce  application/xml
+// @Param id path string true "Workflow ID"
+// @Param format query string false "Document format" Enums(json, yaml, bpmn)
+// @Success 200 {object} models.WorkflowDefinition "Workflow definition"
+// @Failure 400 {object} string "Invalid input"
+// @Router /workflows/{id}/export [get]
+func (controller *WorkflowController) ExportWorkflow(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(workflowID)
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	var req requests.ExportWorkflowRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	definition, err := controller.WorkflowService.ExportWorkflow(workflowID)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	document, err := json.MarshalIndent(definition, "", "  ")
+	if err != nil {
+		responses.Error(c, "failed to export workflow")
+		return
+	}
+
+	contentType := "application/json"
+	filename := "workflow-" + workflowID + ".json"
+	switch req.Format {
+	case "yaml":
+		document, err = common.JSONToYAML(document)
+		contentType = common.YAMLContentType
+		filename = "workflow-" + workflowID + ".yaml"
+	case "bpmn":
+		document, err = models.EncodeBPMN(*definition)
+		contentType = "application/xml"
+		filename = "workflow-" + workflowID + ".bpmn"
+	}
+	if err != nil {
+		responses.Error(c, "failed to export workflow")
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Data(http.StatusOK, contentType, document)
+}
+
+// @Security access_token
+// @Summary Render a workflow as a graph
+// @Tags Workflows
+// @version 1.0
+// @Description Render the tasks of a workflow in order, with their dependencies and gateway flows, as Mermaid flowchart or Graphviz DOT text. Nodes are coloured by task status; gateway flows are labelled with their conditions
+// @Produce  text/plain
+// @Produce  text/vnd.graphviz
+// @Param id path string true "Workflow ID"
+// @Param format query string false "mermaid (default) or dot"
+// @Success 200 {string} string "Graph"
+// @Router /workflows/{id}/graph [get]
+func (controller *WorkflowController) GetWorkflowGraph(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflow, err := controller.WorkflowService.GetWorkflowByID(c.Param("id"))
+	if err != nil {
+		responses.Error(c, "failed to get workflow")
+		return
+	}
+
+	if !workflow.CheckWorkflowAccess(user, "delete") {
+		responses.Error(c, "unauthorized")
+		return
+	}
+
+	var req requests.WorkflowGraphRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	if req.Format == "dot" {
+		c.Data(http.StatusOK, models.DOTContentType, models.RenderDOT(*workflow))
+		return
+	}
+	c.Data(http.StatusOK, models.MermaidContentType, models.RenderMermaid(*workflow))
+}
+
+// @Security access_token
+// @Summary Import a workflow
+// @Tags Workflows
+// @version 1.0
+// @Description Validate an exported workflow definition and recreate it, with all of its tasks, as a new workflow of the user. The document is JSON, YAML or BPMN 2.0 XML, as told by the content type. BPMN elements a workflow cannot express are reported as warnings
+// @Accept  application/json
+// @Accept  application/yaml
+// @Accept  application/xml
+// @Produce  application/json
+// @Param definition body models.WorkflowDefinition true "Workflow definition"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Failure 413 {object} string "Definition too large"
+// @Failure 415 {object} string "Unsupported content type"
+// @Router /workflows/import [post]
+func (controller *WorkflowController) ImportWorkflow(c *gin.Context) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	contentType := c.ContentType()
+	if contentType != "application/json" && !common.IsYAMLContentType(contentType) && !models.IsBPMNContentType(contentType) {
+		responses.ErrorWithStatus(c, http.StatusUnsupportedMediaType, "unsupported definition content type")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, models.MaxWorkflowDefinitionSize)
+	document, err := c.GetRawData()
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		responses.ErrorWithStatus(c, http.StatusRequestEntityTooLarge, "definition is too large")
+		return
+	}
+	if err != nil || len(document) == 0 {
+		responses.Error(c, "Invalid input")
+		return
+	}
+
+	req := requests.ImportWorkflowRequest{ContentType: contentType, Document: document}
+	if models.IsBPMNContentType(contentType) {
+		insertedID, warnings, err := controller.WorkflowService.ImportBPMN(user.Username, req)
+		if err != nil {
+			responses.Error(c, err.Error())
+			return
+		}
+
+		responses.OkWithData(c, gin.H{
+			"workflow_id": insertedID,
+			"warnings":    warnings,
+		})
+		return
+	}
+
+	insertedID, err := controller.WorkflowService.ImportWorkflow(user.Username, req)
+	if err != nil {
+		responses.Error(c, err.Error())
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"workflow_id": insertedID,
+	})
+}
+
+// @Summary Get the workflow definition schema
+// @Tags Workflows
+// @version 1.0
+// @Description Get the JSON Schema of the documents exported from and imported into workflows
+// @Produce  application/schema+json
+// @Success 200 {object} string "JSON Schema"
+// @Router /schemas/workflow-definition.json [get]
+func (controller *WorkflowController) GetWorkflowDefinitionSchema(c *gin.Context) {
+	c.Data(http.StatusOK, "application/schema+json", models.WorkflowDefinitionSchema)
+}
+
+// @Security access_token
+// @Summary Approve a task
+// @Tags Workflows
+// @version 1.0
+// @Description Vote to approve an approval task. Only listed approvers may vote, once every task before it is done; the task completes once its quorum rule is met
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param taskID path string true "Task ID"
+// @Param vote body requests.VoteRequest false "Optional comment"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Router /workflows/{id}/tasks/{taskID}/approve [post]
+func (controller *WorkflowController) ApproveTask(c *gin.Context) {
+	controller.voteOnTask(c, models.ApprovedDecision)
+}
+
+// @Security access_token
+// @Summary Reject a task
+// @Tags Workflows
+// @version 1.0
+// @Description Vote to reject an approval task. The task is rejected once its quorum rule can no longer be met
+// @Accept  application/json
+// @Produce  application/json
+// @Param id path string true "Workflow ID"
+// @Param taskID path string true "Task ID"
+// @Param vote body requests.VoteRequest false "Optional comment"
+// @Success 200 {object} string "OK"
+// @Failure 400 {object} string "Invalid input"
+// @Router /workflows/{id}/tasks/{taskID}/reject [post]
+func (controller *WorkflowController) RejectTask(c *gin.Context) {
+	controller.voteOnTask(c, models.RejectedDecision)
+}
+
+func (controller *WorkflowController) voteOnTask(c *gin.Context, decision models.ApprovalDecision) {
+	user := c.MustGet("user").(models.JWTUser)
+
+	workflowID := c.Param("id")
+	taskID := c.Param("taskID")
+
+	var req requests.VoteRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			responses.Error(c, "Invalid input")
+			return
+		}
+	}
+
+	task, err := controller.WorkflowService.VoteOnTask(workflowID, taskID, user, decision, req)
+	if err != nil {
+		respondWorkflowError(c, err)
+		return
+	}
+
+	responses.OkWithData(c, gin.H{
+		"task": task,
+	})
+}
+
+// bindPatchRequest reads the raw patch document. Plain application/json is
+// treated as a merge patch.
+func bindPatchRequest(c *gin.Context) (requests.PatchRequest, bool) {
+	contentType := c.ContentType()
+	switch contentType {
+	case common.MergePatchContentType, common.JSONPatchContentType, "application/json":
+	default:
+		responses.ErrorWithStatus(c, http.StatusUnsupportedMediaType, "unsupported patch content type")
+		return requests.PatchRequest{}, false
+	}
+
+	document, err := c.GetRawData()
+	if err != nil || len(document) == 0 {
+		responses.Error(c, "Invalid input")
+		return requests.PatchRequest{}, false
+	}
+
+	return requests.PatchRequest{ContentType: contentType, Document: document}, true
+}
+
+func respondWorkflowError(c *gin.Context, err error) {
+	if errors.Is(err, repositories.ErrVersionMismatch) {
+		responses.ErrorWithStatus(c, http.StatusPreconditionFailed, err.Error())
+		return
+	}
+	if errors.Is(err, repositories.ErrWorkflowConflict) {
+		responses.ErrorWithStatus(c, http.StatusConflict, err.Error())
+		return
+	}
+
+	responses.Error(c, err.Error())
+}
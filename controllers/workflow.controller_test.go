@@ -8,14 +8,18 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"virtual_workflow_management_system_gin/common"
 	"virtual_workflow_management_system_gin/databases"
 	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/repositories"
 	"virtual_workflow_management_system_gin/requests"
 	"virtual_workflow_management_system_gin/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type MockWorkflowService struct {
@@ -30,10 +34,39 @@ type MockWorkflowService struct {
 	CreateTaskByWorkflowIDError error
 	EditTaskByIDError           error
 	DeleteTaskByIDError         error
+	ReorderTasksError           error
+	BatchTasksError             error
+	PatchWorkflowByIDError      error
+	PatchTaskByIDError          error
+	GetRevisionsError           error
+	GetRevisionError            error
+	DiffRevisionsError          error
+	RestoreRevisionError        error
+	SetVariablesError           error
+	StartWorkflowError          error
+	ValidateDefinitionError     error
+	VoteOnTaskError             error
+	SetDefinitionsError         error
+	SetTaskFormError            error
+	CompleteTaskError           error
+	RetryTaskError              error
+	AddChecklistItemError       error
+	ToggleChecklistItemError    error
+	ReorderChecklistError       error
+	RemoveChecklistItemError    error
+	ExportWorkflowError         error
+	ImportWorkflowError         error
+	// Imported is the workflow created by the last import, which export and
+	// GetWorkflowByID return from then on.
+	Imported *models.Workflow
 }
 
 var _ services.IWorkflowService = &MockWorkflowService{}
 
+func (m *MockWorkflowService) EnsureIndexes() error {
+	return nil
+}
+
 func (m *MockWorkflowService) GetWorkflows(username string) ([]models.Workflow, error) {
 	if m.GetWorkflowsError != nil {
 		return nil, m.GetWorkflowsError
@@ -45,7 +78,10 @@ func (m *MockWorkflowService) GetWorkflowByID(workflowID string) (*models.Workfl
 	if m.GetWorkflowByIDError != nil {
 		return nil, m.GetWorkflowByIDError
 	}
-	return &models.Workflow{Owner: "testUser"}, nil
+	if m.Imported != nil {
+		return m.Imported, nil
+	}
+	return &models.Workflow{Owner: "testUser", Version: 3}, nil
 }
 
 func (m *MockWorkflowService) CreateWorkflow(username string, req requests.CreateWorkflowRequest) (*string, error) {
@@ -56,21 +92,21 @@ func (m *MockWorkflowService) CreateWorkflow(username string, req requests.Creat
 	return &id, nil
 }
 
-func (m *MockWorkflowService) EditWorkflowByID(workflowID string, req requests.EditWorkflowRequest) (*models.Workflow, error) {
+func (m *MockWorkflowService) EditWorkflowByID(workflowID string, req requests.EditWorkflowRequest, version *int64) (*models.Workflow, error) {
 	if m.EditWorkflowByIDError != nil {
 		return nil, m.EditWorkflowByIDError
 	}
 	return &models.Workflow{}, nil
 }
 
-func (m *MockWorkflowService) DeleteWorkflowByID(workflowID string) error {
+func (m *MockWorkflowService) DeleteWorkflowByID(workflowID string, version *int64) error {
 	if m.DeleteWorkflowByIDError != nil {
 		return m.DeleteWorkflowByIDError
 	}
 	return nil
 }
 
-func (m *MockWorkflowService) TransferWorkflowByID(workflowID string, username string) (*models.Workflow, error) {
+func (m *MockWorkflowService) TransferWorkflowByID(workflowID string, username string, version *int64) (*models.Workflow, error) {
 	if m.TransferWorkflowByIDError != nil {
 		return nil, m.TransferWorkflowByIDError
 	}
@@ -99,20 +135,228 @@ func (m *MockWorkflowService) CreateTaskByWorkflowID(workflowID string, req requ
 	return &id, nil
 }
 
-func (m *MockWorkflowService) EditTaskByID(workflowID string, taskID string, req requests.EditTaskRequest) (*models.Task, error) {
+func (m *MockWorkflowService) EditTaskByID(workflowID string, taskID string, req requests.EditTaskRequest, version *int64) (*models.Task, error) {
 	if m.EditTaskByIDError != nil {
 		return nil, m.EditTaskByIDError
 	}
 	return &models.Task{}, nil
 }
 
-func (m *MockWorkflowService) DeleteTaskByID(workflowID string, taskID string) error {
+func (m *MockWorkflowService) DeleteTaskByID(workflowID string, taskID string, version *int64) error {
 	if m.DeleteTaskByIDError != nil {
 		return m.DeleteTaskByIDError
 	}
 	return nil
 }
 
+func (m *MockWorkflowService) ReorderTasksByWorkflowID(workflowID string, req requests.ReorderTasksRequest, version *int64) ([]models.Task, error) {
+	if m.ReorderTasksError != nil {
+		return nil, m.ReorderTasksError
+	}
+	return []models.Task{}, nil
+}
+
+func (m *MockWorkflowService) BatchTasks(workflowID string, req requests.BatchTasksRequest, version *int64) (*models.BatchResult, error) {
+	if m.BatchTasksError != nil {
+		return nil, m.BatchTasksError
+	}
+	mode := req.Mode
+	if mode == "" {
+		mode = models.AtomicBatch
+	}
+	result := &models.BatchResult{Mode: mode, Committed: true}
+	for i, operation := range req.Operations {
+		result.Results = append(result.Results, models.BatchOperationResult{Index: i, Op: operation.Op, TaskID: operation.TaskID, Status: models.OperationApplied})
+	}
+	if len(req.Operations) > 1 && req.Operations[1].TaskID == "missing" {
+		result.Fail(1, errors.New("task does not exist"))
+		result.Committed = mode == models.BestEffortBatch
+	}
+	return result, nil
+}
+
+func (m *MockWorkflowService) PatchWorkflowByID(workflowID string, req requests.PatchRequest, version *int64) (*models.Workflow, error) {
+	if m.PatchWorkflowByIDError != nil {
+		return nil, m.PatchWorkflowByIDError
+	}
+	return &models.Workflow{}, nil
+}
+
+func (m *MockWorkflowService) PatchTaskByID(workflowID string, taskID string, req requests.PatchRequest, version *int64) (*models.Task, error) {
+	if m.PatchTaskByIDError != nil {
+		return nil, m.PatchTaskByIDError
+	}
+	return &models.Task{}, nil
+}
+
+func (m *MockWorkflowService) GetRevisionsByWorkflowID(workflowID string) ([]models.WorkflowRevision, error) {
+	if m.GetRevisionsError != nil {
+		return nil, m.GetRevisionsError
+	}
+	return []models.WorkflowRevision{}, nil
+}
+
+func (m *MockWorkflowService) GetRevision(workflowID string, revision int) (*models.WorkflowRevision, error) {
+	if m.GetRevisionError != nil {
+		return nil, m.GetRevisionError
+	}
+	return &models.WorkflowRevision{Revision: revision}, nil
+}
+
+func (m *MockWorkflowService) DiffRevisions(workflowID string, from int, to int) (*models.WorkflowDiff, error) {
+	if m.DiffRevisionsError != nil {
+		return nil, m.DiffRevisionsError
+	}
+	return &models.WorkflowDiff{From: from, To: to}, nil
+}
+
+func (m *MockWorkflowService) RestoreRevision(workflowID string, revision int, version *int64) (*models.Workflow, error) {
+	if m.RestoreRevisionError != nil {
+		return nil, m.RestoreRevisionError
+	}
+	return &models.Workflow{Version: 4}, nil
+}
+
+func (m *MockWorkflowService) SetVariables(workflowID string, req requests.SetVariablesRequest, version *int64) (*models.Workflow, error) {
+	if m.SetVariablesError != nil {
+		return nil, m.SetVariablesError
+	}
+	return &models.Workflow{Version: 4, Variables: req.Variables}, nil
+}
+
+func (m *MockWorkflowService) StartWorkflow(workflowID string, version *int64) (*models.Workflow, error) {
+	if m.StartWorkflowError != nil {
+		return nil, m.StartWorkflowError
+	}
+	startedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &models.Workflow{Version: 5, StartedAt: &startedAt}, nil
+}
+
+func (m *MockWorkflowService) ValidateWorkflowDefinition(workflowID string) ([]models.DefinitionProblem, error) {
+	if m.ValidateDefinitionError != nil {
+		return nil, m.ValidateDefinitionError
+	}
+	return []models.DefinitionProblem{{Message: "task is unreachable"}}, nil
+}
+
+func (m *MockWorkflowService) ExportWorkflow(workflowID string) (*models.WorkflowDefinition, error) {
+	if m.ExportWorkflowError != nil {
+		return nil, m.ExportWorkflowError
+	}
+	workflow := exportedWorkflow()
+	if m.Imported != nil {
+		workflow = *m.Imported
+	}
+	definition := models.NewWorkflowDefinition(workflow, time.Now())
+	return &definition, nil
+}
+
+func (m *MockWorkflowService) ImportWorkflow(username string, req requests.ImportWorkflowRequest) (*string, error) {
+	if m.ImportWorkflowError != nil {
+		return nil, m.ImportWorkflowError
+	}
+	definition, err := models.ParseWorkflowDefinition(req.ContentType, req.Document)
+	if err != nil {
+		return nil, err
+	}
+	workflow, err := definition.Instantiate(username)
+	if err != nil {
+		return nil, err
+	}
+	m.Imported = workflow
+	id := "importedID"
+	return &id, nil
+}
+
+func (m *MockWorkflowService) ImportBPMN(username string, req requests.ImportWorkflowRequest) (*string, []string, error) {
+	if m.ImportWorkflowError != nil {
+		return nil, nil, m.ImportWorkflowError
+	}
+	definition, warnings, err := models.ParseBPMN(req.Document)
+	if err != nil {
+		return nil, nil, err
+	}
+	workflow, err := definition.Instantiate(username)
+	if err != nil {
+		return nil, nil, err
+	}
+	m.Imported = workflow
+	id := "importedID"
+	return &id, warnings, nil
+}
+
+func (m *MockWorkflowService) VoteOnTask(workflowID string, taskID string, user models.JWTUser, decision models.ApprovalDecision, req requests.VoteRequest) (*models.Task, error) {
+	if m.VoteOnTaskError != nil {
+		return nil, m.VoteOnTaskError
+	}
+	approval := &models.Approval{Votes: []models.ApprovalVote{{Username: user.Username, Decision: decision, Comment: req.Comment}}}
+	return &models.Task{Type: models.ApprovalTask, Status: models.InProgress, Approval: approval}, nil
+}
+
+func (m *MockWorkflowService) SetVariableDefinitions(workflowID string, req requests.SetVariableDefinitionsRequest, version *int64) (*models.Workflow, error) {
+	if m.SetDefinitionsError != nil {
+		return nil, m.SetDefinitionsError
+	}
+	definitions := []models.FieldDefinition{}
+	for _, field := range req.Variables {
+		definitions = append(definitions, models.FieldDefinition{Name: field.Name, Type: field.Type, Options: field.Options})
+	}
+	return &models.Workflow{Version: 4, VariableDefinitions: definitions}, nil
+}
+
+func (m *MockWorkflowService) SetTaskForm(workflowID string, taskID string, req requests.SetTaskFormRequest, version *int64) (*models.Task, error) {
+	if m.SetTaskFormError != nil {
+		return nil, m.SetTaskFormError
+	}
+	form := []models.FieldDefinition{}
+	for _, field := range req.Fields {
+		form = append(form, models.FieldDefinition{Name: field.Name, Type: field.Type, Required: field.Required, Options: field.Options})
+	}
+	return &models.Task{Type: models.UserTask, Status: models.Pending, Form: form}, nil
+}
+
+func (m *MockWorkflowService) CompleteTask(workflowID string, taskID string, req requests.CompleteTaskRequest, version *int64) (*models.Task, error) {
+	if m.CompleteTaskError != nil {
+		return nil, m.CompleteTaskError
+	}
+	return &models.Task{Type: models.UserTask, Status: models.Completed, FormValues: req.Values}, nil
+}
+
+func (m *MockWorkflowService) RetryTask(workflowID string, taskID string, version *int64) (*models.Task, error) {
+	if m.RetryTaskError != nil {
+		return nil, m.RetryTaskError
+	}
+	return &models.Task{Type: models.AutomatedTask, Status: models.InProgress, Automation: &models.Automation{Executor: models.HandlerExecutor, Handler: "notify", MaxAttempts: 3}}, nil
+}
+
+func (m *MockWorkflowService) AddChecklistItem(workflowID string, taskID string, req requests.ChecklistItemRequest, version *int64) (*models.Task, error) {
+	if m.AddChecklistItemError != nil {
+		return nil, m.AddChecklistItemError
+	}
+	return &models.Task{Name: "Test Task", Checklist: []models.ChecklistItem{{Text: req.Text, Required: req.Required}}}, nil
+}
+
+func (m *MockWorkflowService) ToggleChecklistItem(workflowID string, taskID string, itemID string, user models.JWTUser, version *int64) (*models.Task, error) {
+	if m.ToggleChecklistItemError != nil {
+		return nil, m.ToggleChecklistItemError
+	}
+	return &models.Task{Name: "Test Task", Checklist: []models.ChecklistItem{{Text: "Sign", Required: true, Done: true, DoneBy: user.Username}}}, nil
+}
+
+func (m *MockWorkflowService) ReorderChecklist(workflowID string, taskID string, req requests.ReorderChecklistRequest, version *int64) (*models.Task, error) {
+	if m.ReorderChecklistError != nil {
+		return nil, m.ReorderChecklistError
+	}
+	return &models.Task{Name: "Test Task", Checklist: []models.ChecklistItem{{Text: "Review"}, {Text: "Sign", Required: true}}}, nil
+}
+
+func (m *MockWorkflowService) RemoveChecklistItem(workflowID string, taskID string, itemID string, version *int64) (*models.Task, error) {
+	if m.RemoveChecklistItemError != nil {
+		return nil, m.RemoveChecklistItemError
+	}
+	return &models.Task{Name: "Test Task"}, nil
+}
+
 var (
 	mockWorkflowService = new(MockWorkflowService)
 	workflowController  = WorkflowController{WorkflowService: mockWorkflowService, UserService: mockUserService}
@@ -124,13 +368,30 @@ func init() {
 	router.GET("/workflows/:id", workflowController.GetWorkflow)
 	router.POST("/workflows", workflowController.CreateWorkflow)
 	router.PUT("/workflows/:id", workflowController.EditWorkflow)
+	router.PATCH("/workflows/:id", workflowController.PatchWorkflow)
 	router.DELETE("/workflows/:id", workflowController.DeleteWorkflow)
 	router.POST("/workflows/:id/transfer", workflowController.TransferWorkflow)
+	router.POST("/workflows/:id/revisions/:revision/restore", workflowController.RestoreRevision)
+	router.PUT("/workflows/:id/variables", workflowController.SetVariables)
+	router.PUT("/workflows/:id/variables/definitions", workflowController.SetVariableDefinitions)
+	router.GET("/schemas/workflow-definition.json", workflowController.GetWorkflowDefinitionSchema)
 	router.GET("/workflows/:id/tasks", workflowController.GetTasks)
 	router.GET("/workflows/:id/tasks/:taskID", workflowController.GetTask)
 	router.POST("/workflows/:id/tasks", workflowController.CreateTask)
+	router.POST("/workflows/:id/tasks:method", workflowController.BatchTasks)
+	router.PUT("/workflows/:id/tasks/order", workflowController.ReorderTasks)
 	router.PUT("/workflows/:id/tasks/:taskID", workflowController.EditTask)
+	router.PATCH("/workflows/:id/tasks/:taskID", workflowController.PatchTask)
 	router.DELETE("/workflows/:id/tasks/:taskID", workflowController.DeleteTask)
+	router.POST("/workflows/:id/tasks/:taskID/approve", workflowController.ApproveTask)
+	router.POST("/workflows/:id/tasks/:taskID/reject", workflowController.RejectTask)
+	router.PUT("/workflows/:id/tasks/:taskID/form", workflowController.SetTaskForm)
+	router.POST("/workflows/:id/tasks/:taskID/complete", workflowController.CompleteTask)
+	router.POST("/workflows/:id/tasks/:taskID/retry", workflowController.RetryTask)
+	router.POST("/workflows/:id/tasks/:taskID/checklist", workflowController.AddChecklistItem)
+	router.PUT("/workflows/:id/tasks/:taskID/checklist/order", workflowController.ReorderChecklist)
+	router.POST("/workflows/:id/tasks/:taskID/checklist/:itemID/toggle", workflowController.ToggleChecklistItem)
+	router.DELETE("/workflows/:id/tasks/:taskID/checklist/:itemID", workflowController.RemoveChecklistItem)
 }
 
 func TestNewWorkflowController(t *testing.T) {
@@ -505,6 +766,96 @@ func TestCreateTask(t *testing.T) {
 	}{
 		{"Valid input", requests.CreateTaskRequest{Name: "test", Description: "description"}, HTTPStatusOK, OKStatus},
 		{"Invalid Input", requests.CreateTaskRequest{Description: "description"}, HTTPStatusOK, InvalidInput},
+		{"Valid gateway", requests.CreateTaskRequest{
+			Name: "Approved?",
+			Type: models.GatewayTask,
+			Gateway: &requests.GatewayRequest{
+				Kind:  models.ExclusiveGateway,
+				Flows: []requests.GatewayFlowRequest{{Target: "650000000000000000000001", Condition: "approved == true"}},
+			},
+		}, HTTPStatusOK, OKStatus},
+		{"Gateway without flows", requests.CreateTaskRequest{
+			Name:    "Approved?",
+			Type:    models.GatewayTask,
+			Gateway: &requests.GatewayRequest{Kind: models.ExclusiveGateway},
+		}, HTTPStatusOK, InvalidInput},
+		{"Gateway without definition", requests.CreateTaskRequest{Name: "Approved?", Type: models.GatewayTask}, HTTPStatusOK, InvalidInput},
+		{"Unknown task type", requests.CreateTaskRequest{Name: "test", Type: "Script"}, HTTPStatusOK, InvalidInput},
+		{"Valid SLA", requests.CreateTaskRequest{
+			Name:     "Review contract",
+			Assignee: "alice",
+			SLA: &requests.SLARequest{
+				DurationSeconds: 86400,
+				Escalation: []requests.EscalationStepRequest{
+					{Action: models.NotifyAssignee},
+					{Action: models.NotifyOwner, AfterSeconds: 3600},
+					{Action: models.ReassignToRole, AfterSeconds: 7200, Role: models.Admin},
+				},
+			},
+		}, HTTPStatusOK, OKStatus},
+		{"SLA shorter than a minute", requests.CreateTaskRequest{
+			Name: "Review contract",
+			SLA:  &requests.SLARequest{DurationSeconds: 30},
+		}, HTTPStatusOK, InvalidInput},
+		{"Reassignment without role", requests.CreateTaskRequest{
+			Name: "Review contract",
+			SLA: &requests.SLARequest{
+				DurationSeconds: 86400,
+				Escalation:      []requests.EscalationStepRequest{{Action: models.ReassignToRole}},
+			},
+		}, HTTPStatusOK, InvalidInput},
+		{"Valid approval", requests.CreateTaskRequest{
+			Name: "Sign-off",
+			Type: models.ApprovalTask,
+			Approval: &requests.ApprovalRequest{
+				Approvers: []requests.ApproverRequest{{Kind: models.UserApprover, Value: "alice"}, {Kind: models.TeamApprover, Value: "finance"}},
+				Quorum:    models.QuorumAll,
+			},
+		}, HTTPStatusOK, OKStatus},
+		{"Approval without approvers", requests.CreateTaskRequest{
+			Name:     "Sign-off",
+			Type:     models.ApprovalTask,
+			Approval: &requests.ApprovalRequest{Quorum: models.QuorumAny},
+		}, HTTPStatusOK, InvalidInput},
+		{"Count quorum without required", requests.CreateTaskRequest{
+			Name: "Sign-off",
+			Type: models.ApprovalTask,
+			Approval: &requests.ApprovalRequest{
+				Approvers: []requests.ApproverRequest{{Kind: models.RoleApprover, Value: "Admin"}},
+				Quorum:    models.QuorumCount,
+			},
+		}, HTTPStatusOK, InvalidInput},
+		{"Valid HTTP automation", requests.CreateTaskRequest{
+			Name: "Notify billing",
+			Type: models.AutomatedTask,
+			Automation: &requests.AutomationRequest{
+				Executor:       models.HTTPExecutor,
+				HTTP:           &requests.HTTPCallRequest{Method: "POST", URL: "https://billing.example.com/invoices", Body: `{"amount": {{ json .amount }}}`},
+				OutputVariable: "invoice",
+			},
+		}, HTTPStatusOK, OKStatus},
+		{"HTTP automation without url", requests.CreateTaskRequest{
+			Name: "Notify billing",
+			Type: models.AutomatedTask,
+			Automation: &requests.AutomationRequest{
+				Executor: models.HTTPExecutor,
+				HTTP:     &requests.HTTPCallRequest{Method: "POST"},
+			},
+		}, HTTPStatusOK, InvalidInput},
+		{"Handler automation without handler", requests.CreateTaskRequest{
+			Name:       "Generate report",
+			Type:       models.AutomatedTask,
+			Automation: &requests.AutomationRequest{Executor: models.HandlerExecutor},
+		}, HTTPStatusOK, InvalidInput},
+		{"Automation with too long timeout", requests.CreateTaskRequest{
+			Name: "Generate report",
+			Type: models.AutomatedTask,
+			Automation: &requests.AutomationRequest{
+				Executor:       models.HandlerExecutor,
+				Handler:        "report",
+				TimeoutSeconds: 3600,
+			},
+		}, HTTPStatusOK, InvalidInput},
 	}
 
 	for _, tt := range tests {
@@ -556,6 +907,25 @@ func TestCreateTask(t *testing.T) {
 		assert.Contains(t, w.Body.String(), "failed to create task")
 	})
 
+	t.Run("Concurrent writes", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(
+			http.MethodPost,
+			"/workflows/some_id/tasks",
+			strings.NewReader(`{"name":"test","description":"description"}`),
+		)
+		c.Set("user", models.JWTUser{Username: "user", Role: "admin"})
+
+		mockWorkflowService := &MockWorkflowService{CreateTaskByWorkflowIDError: repositories.ErrWorkflowConflict}
+		workflowController := WorkflowController{WorkflowService: mockWorkflowService, UserService: mockUserService}
+
+		workflowController.CreateTask(c)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "try again")
+	})
+
 	t.Run("Unauthorized", func(t *testing.T) {
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -725,3 +1095,1495 @@ func TestDeleteTask(t *testing.T) {
 		assert.Contains(t, w.Body.String(), "unauthorized")
 	})
 }
+
+func TestReorderTasks(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    requests.ReorderTasksRequest
+		expected int
+		message  string
+	}{
+		{"Valid task list", requests.ReorderTasksRequest{TaskIDs: []string{"first", "second"}}, HTTPStatusOK, OKStatus},
+		{"Valid move", requests.ReorderTasksRequest{Move: &requests.MoveTaskRequest{TaskID: "first", AfterID: "second"}}, HTTPStatusOK, OKStatus},
+		{"Missing task list and move", requests.ReorderTasksRequest{}, HTTPStatusOK, InvalidInput},
+		{"Move without anchor", requests.ReorderTasksRequest{Move: &requests.MoveTaskRequest{TaskID: "first"}}, HTTPStatusOK, InvalidInput},
+		{"Move with both anchors", requests.ReorderTasksRequest{Move: &requests.MoveTaskRequest{TaskID: "first", BeforeID: "second", AfterID: "third"}}, HTTPStatusOK, InvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			requestBody, _ := json.Marshal(tt.input)
+			c.Request, _ = http.NewRequest(http.MethodPut, "/workflows/some_id/tasks/order", bytes.NewBuffer(requestBody))
+			c.Set("user", models.JWTUser{Username: "testUser", Role: "admin"})
+
+			workflowController.ReorderTasks(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+
+	t.Run("Failed ReorderTasks", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPut, "/workflows/some_id/tasks/order", strings.NewReader(`{"task_ids":["first"]}`))
+		c.Set("user", models.JWTUser{Username: "testUser", Role: "admin"})
+
+		mockWorkflowService := &MockWorkflowService{ReorderTasksError: errors.New("task list must contain every task of the workflow exactly once")}
+		workflowController := WorkflowController{WorkflowService: mockWorkflowService, UserService: mockUserService}
+
+		workflowController.ReorderTasks(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "task list must contain every task of the workflow exactly once")
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPut, "/workflows/some_id/tasks/order", strings.NewReader(`{"task_ids":["first"]}`))
+		c.Set("user", models.JWTUser{Username: "testWrongUser"})
+
+		workflowController.ReorderTasks(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
+
+func TestWorkflowVersioning(t *testing.T) {
+	t.Run("GetWorkflow returns ETag", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		workflowController.GetWorkflow(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("Matching If-Match", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPut, "/workflows/some_id", strings.NewReader(`{"name":"edited"}`))
+		c.Request.Header.Set("If-Match", `"3"`)
+		c.Set("user", models.JWTUser{Username: "testUser", Role: "admin"})
+
+		workflowController.EditWorkflow(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), OKStatus)
+	})
+
+	t.Run("Version mismatch", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPut, "/workflows/some_id", strings.NewReader(`{"name":"edited"}`))
+		c.Request.Header.Set("If-Match", `"2"`)
+		c.Set("user", models.JWTUser{Username: "testUser", Role: "admin"})
+
+		mockWorkflowService := &MockWorkflowService{EditWorkflowByIDError: repositories.ErrVersionMismatch}
+		workflowController := WorkflowController{WorkflowService: mockWorkflowService, UserService: mockUserService}
+
+		workflowController.EditWorkflow(c)
+
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+		assert.Contains(t, w.Body.String(), "workflow version mismatch")
+	})
+
+	t.Run("Malformed If-Match", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodDelete, "/workflows/some_id", nil)
+		c.Request.Header.Set("If-Match", `"abc"`)
+		c.Set("user", models.JWTUser{Username: "testUser", Role: "admin"})
+
+		workflowController.DeleteWorkflow(c)
+
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	})
+
+	t.Run("If-Match with several versions", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodDelete, "/workflows/some_id", nil)
+		c.Request.Header.Set("If-Match", `"3", "4"`)
+		c.Set("user", models.JWTUser{Username: "testUser", Role: "admin"})
+
+		workflowController.DeleteWorkflow(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "If-Match must name a single workflow version")
+	})
+
+	t.Run("If-Match list with the current version", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodDelete, "/workflows/some_id", nil)
+		c.Request.Header.Set("If-Match", `W/"2", "3"`)
+		c.Set("user", models.JWTUser{Username: "testUser", Role: "admin"})
+
+		workflowController.DeleteWorkflow(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), OKStatus)
+	})
+
+	t.Run("Missing If-Match when required", func(t *testing.T) {
+		t.Setenv("REQUIRE_IF_MATCH", "true")
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodDelete, "/workflows/some_id/tasks/some_id", nil)
+		c.Set("user", models.JWTUser{Username: "testUser", Role: "admin"})
+
+		workflowController.DeleteTask(c)
+
+		assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+		assert.Contains(t, w.Body.String(), "If-Match header is required")
+	})
+}
+
+func TestPatchWorkflow(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		expected    int
+		message     string
+	}{
+		{"Merge patch", "application/merge-patch+json", `{"name":"patched"}`, HTTPStatusOK, OKStatus},
+		{"JSON patch", "application/json-patch+json", `[{"op":"replace","path":"/name","value":"patched"}]`, HTTPStatusOK, OKStatus},
+		{"Unsupported content type", "text/plain", `name=patched`, http.StatusUnsupportedMediaType, "unsupported patch content type"},
+		{"Empty body", "application/merge-patch+json", ``, HTTPStatusOK, InvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPatch, "/workflows/some_id", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", tt.contentType)
+			c.Set("user", models.JWTUser{Username: "testUser", Role: "admin"})
+
+			workflowController.PatchWorkflow(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+
+	t.Run("Failed PatchWorkflow", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPatch, "/workflows/some_id", strings.NewReader(`{"name":"x"}`))
+		c.Request.Header.Set("Content-Type", "application/merge-patch+json")
+		c.Set("user", models.JWTUser{Username: "testUser", Role: "admin"})
+
+		mockWorkflowService := &MockWorkflowService{PatchWorkflowByIDError: errors.New("Invalid input")}
+		workflowController := WorkflowController{WorkflowService: mockWorkflowService, UserService: mockUserService}
+
+		workflowController.PatchWorkflow(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), InvalidInput)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPatch, "/workflows/some_id", strings.NewReader(`{"name":"patched"}`))
+		c.Request.Header.Set("Content-Type", "application/merge-patch+json")
+		c.Set("user", models.JWTUser{Username: "testWrongUser"})
+
+		workflowController.PatchWorkflow(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
+
+func TestPatchTask(t *testing.T) {
+	t.Run("Successful PatchTask", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPatch, "/workflows/some_id/tasks/some_id", strings.NewReader(`{"status":"Completed"}`))
+		c.Request.Header.Set("Content-Type", "application/merge-patch+json")
+		c.Set("user", models.JWTUser{Username: "testUser", Role: "admin"})
+
+		workflowController.PatchTask(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), OKStatus)
+	})
+
+	t.Run("Version mismatch", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPatch, "/workflows/some_id/tasks/some_id", strings.NewReader(`[{"op":"replace","path":"/status","value":"Completed"}]`))
+		c.Request.Header.Set("Content-Type", "application/json-patch+json")
+		c.Request.Header.Set("If-Match", `"1"`)
+		c.Set("user", models.JWTUser{Username: "testUser", Role: "admin"})
+
+		mockWorkflowService := &MockWorkflowService{PatchTaskByIDError: repositories.ErrVersionMismatch}
+		workflowController := WorkflowController{WorkflowService: mockWorkflowService, UserService: mockUserService}
+
+		workflowController.PatchTask(c)
+
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	})
+
+	t.Run("Failed GetWorkflowByID", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPatch, "/workflows/some_id/tasks/some_id", strings.NewReader(`{"status":"Completed"}`))
+		c.Request.Header.Set("Content-Type", "application/merge-patch+json")
+		c.Set("user", models.JWTUser{Username: "testUser", Role: "admin"})
+
+		mockWorkflowService := &MockWorkflowService{GetWorkflowByIDError: errors.New("failed to get workflow")}
+		workflowController := WorkflowController{WorkflowService: mockWorkflowService, UserService: mockUserService}
+
+		workflowController.PatchTask(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to get workflow")
+	})
+}
+
+func TestGetRevisions(t *testing.T) {
+	getRevisions := func(controller WorkflowController, user string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/revisions", nil)
+		c.Params = gin.Params{{Key: "id", Value: "some_id"}}
+		c.Set("user", models.JWTUser{Username: user})
+
+		controller.GetRevisions(c)
+		return w
+	}
+
+	t.Run("Successful GetRevisions", func(t *testing.T) {
+		w := getRevisions(workflowController, "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "revisions")
+	})
+
+	t.Run("Failed GetRevisions", func(t *testing.T) {
+		mockWorkflowService := &MockWorkflowService{GetRevisionsError: errors.New("failed to retrieve workflow revisions")}
+		workflowController := WorkflowController{WorkflowService: mockWorkflowService, UserService: mockUserService}
+
+		w := getRevisions(workflowController, "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to retrieve workflow revisions")
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := getRevisions(workflowController, "testWrongUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
+
+func TestGetRevision(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		revision string
+		expected int
+		message  string
+	}{
+		{"Valid revision", "testUser", "2", HTTPStatusOK, `"revision":2`},
+		{"Invalid revision", "testUser", "latest", HTTPStatusOK, InvalidInput},
+		{"Unauthorized", "testWrongUser", "2", HTTPStatusOK, "unauthorized"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/revisions/"+tt.revision, nil)
+			c.Params = gin.Params{{Key: "id", Value: "some_id"}, {Key: "revision", Value: tt.revision}}
+			c.Set("user", models.JWTUser{Username: tt.user})
+
+			workflowController.GetRevision(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+}
+
+func TestDiffRevisions(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		query    string
+		expected int
+		message  string
+	}{
+		{"Valid range", "testUser", "from=1&to=3", HTTPStatusOK, `"to":3`},
+		{"Missing from", "testUser", "to=3", HTTPStatusOK, InvalidInput},
+		{"Invalid to", "testUser", "from=1&to=head", HTTPStatusOK, InvalidInput},
+		{"Unauthorized", "testWrongUser", "from=1&to=3", HTTPStatusOK, "unauthorized"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/revisions/diff?"+tt.query, nil)
+			c.Params = gin.Params{{Key: "id", Value: "some_id"}}
+			c.Set("user", models.JWTUser{Username: tt.user})
+
+			workflowController.DiffRevisions(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+}
+
+func TestRestoreRevision(t *testing.T) {
+	t.Run("Successful RestoreRevision", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/revisions/1/restore", nil)
+		c.Params = gin.Params{{Key: "id", Value: "some_id"}, {Key: "revision", Value: "1"}}
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		workflowController.RestoreRevision(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("Invalid revision", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/revisions/first/restore", nil)
+		c.Params = gin.Params{{Key: "id", Value: "some_id"}, {Key: "revision", Value: "first"}}
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		workflowController.RestoreRevision(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), InvalidInput)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/revisions/1/restore", nil)
+		c.Params = gin.Params{{Key: "id", Value: "some_id"}, {Key: "revision", Value: "1"}}
+		c.Set("user", models.JWTUser{Username: "testWrongUser"})
+
+		workflowController.RestoreRevision(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
+
+func TestSetVariables(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+		message  string
+	}{
+		{"Valid variables", `{"variables":{"approved":true,"amount":1200}}`, HTTPStatusOK, `"approved":true`},
+		{"Missing variables", `{}`, HTTPStatusOK, InvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPut, "/workflows/some_id/variables", strings.NewReader(tt.body))
+			c.Set("user", models.JWTUser{Username: "testUser"})
+
+			workflowController.SetVariables(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+
+	t.Run("Failed SetVariables", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPut, "/workflows/some_id/variables", strings.NewReader(`{"variables":{"a.b":1}}`))
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		mockWorkflowService := &MockWorkflowService{SetVariablesError: errors.New("invalid variable name: a.b")}
+		workflowController := WorkflowController{WorkflowService: mockWorkflowService, UserService: mockUserService}
+
+		workflowController.SetVariables(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid variable name: a.b")
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPut, "/workflows/some_id/variables", strings.NewReader(`{"variables":{"approved":true}}`))
+		c.Set("user", models.JWTUser{Username: "testWrongUser"})
+
+		workflowController.SetVariables(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
+
+func TestStartWorkflow(t *testing.T) {
+	startWorkflow := func(controller WorkflowController, username string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/start", nil)
+		c.Set("user", models.JWTUser{Username: username})
+
+		controller.StartWorkflow(c)
+		return w
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		w := startWorkflow(workflowController, "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"started_at":"2024-01-02T03:04:05Z"`)
+		assert.Equal(t, `"5"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("Already started", func(t *testing.T) {
+		controller := WorkflowController{WorkflowService: &MockWorkflowService{StartWorkflowError: errors.New("workflow has already started")}, UserService: mockUserService}
+
+		w := startWorkflow(controller, "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "workflow has already started")
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := startWorkflow(workflowController, "testWrongUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
+
+func TestValidateWorkflow(t *testing.T) {
+	validate := func(controller WorkflowController, user string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/validate", nil)
+		c.Params = gin.Params{{Key: "id", Value: "some_id"}}
+		c.Set("user", models.JWTUser{Username: user})
+
+		controller.ValidateWorkflow(c)
+		return w
+	}
+
+	t.Run("Successful ValidateWorkflow", func(t *testing.T) {
+		w := validate(workflowController, "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "task is unreachable")
+		assert.Contains(t, w.Body.String(), `"valid":false`)
+	})
+
+	t.Run("Failed ValidateWorkflow", func(t *testing.T) {
+		mockWorkflowService := &MockWorkflowService{ValidateDefinitionError: errors.New("workflow does not exist")}
+		workflowController := WorkflowController{WorkflowService: mockWorkflowService, UserService: mockUserService}
+
+		w := validate(workflowController, "testUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "workflow does not exist")
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := validate(workflowController, "testWrongUser")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+		assert.NotContains(t, w.Body.String(), "problems")
+	})
+}
+
+func TestVoteOnTask(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		expected int
+		message  string
+	}{
+		{"Approve with comment", "/workflows/some_id/tasks/task_id/approve", `{"comment":"Looks good"}`, HTTPStatusOK, `"decision":"Approved"`},
+		{"Reject without body", "/workflows/some_id/tasks/task_id/reject", "", HTTPStatusOK, `"decision":"Rejected"`},
+		{"Invalid body", "/workflows/some_id/tasks/task_id/approve", `{"comment":`, HTTPStatusOK, InvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			c.Set("user", models.JWTUser{Username: "testUser"})
+
+			if strings.HasSuffix(tt.path, "/reject") {
+				workflowController.RejectTask(c)
+			} else {
+				workflowController.ApproveTask(c)
+			}
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+
+	t.Run("Not an approver", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/tasks/task_id/approve", nil)
+		c.Set("user", models.JWTUser{Username: "testWrongUser"})
+
+		mockWorkflowService := &MockWorkflowService{VoteOnTaskError: errors.New("user is not an approver of this task")}
+		workflowController := WorkflowController{WorkflowService: mockWorkflowService, UserService: mockUserService}
+
+		workflowController.ApproveTask(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "user is not an approver of this task")
+	})
+}
+
+func TestSetVariableDefinitions(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+		message  string
+	}{
+		{"Valid definitions", `{"variables":[{"name":"amount","type":"Number"},{"name":"region","type":"Enum","options":["EU","US"]}]}`, HTTPStatusOK, `"name":"region"`},
+		{"Unknown type", `{"variables":[{"name":"amount","type":"Money"}]}`, HTTPStatusOK, InvalidInput},
+		{"Missing name", `{"variables":[{"type":"Number"}]}`, HTTPStatusOK, InvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPut, "/workflows/some_id/variables/definitions", strings.NewReader(tt.body))
+			c.Set("user", models.JWTUser{Username: "testUser"})
+
+			workflowController.SetVariableDefinitions(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+
+	t.Run("Failed SetVariableDefinitions", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPut, "/workflows/some_id/variables/definitions", strings.NewReader(`{"variables":[{"name":"amount","type":"String"}]}`))
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		mockWorkflowService := &MockWorkflowService{SetDefinitionsError: errors.New("form field does not match the declared variable: amount")}
+		workflowController := WorkflowController{WorkflowService: mockWorkflowService, UserService: mockUserService}
+
+		workflowController.SetVariableDefinitions(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "form field does not match the declared variable: amount")
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPut, "/workflows/some_id/variables/definitions", strings.NewReader(`{"variables":[]}`))
+		c.Set("user", models.JWTUser{Username: "testWrongUser"})
+
+		workflowController.SetVariableDefinitions(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
+
+func TestSetTaskForm(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+		message  string
+	}{
+		{"Valid form", `{"fields":[{"name":"amount","label":"Amount","type":"Number","required":true}]}`, HTTPStatusOK, `"required":true`},
+		{"Unknown type", `{"fields":[{"name":"amount","type":"Money"}]}`, HTTPStatusOK, InvalidInput},
+		{"Empty option", `{"fields":[{"name":"region","type":"Enum","options":[""]}]}`, HTTPStatusOK, InvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPut, "/workflows/some_id/tasks/task_id/form", strings.NewReader(tt.body))
+			c.Set("user", models.JWTUser{Username: "testUser"})
+
+			workflowController.SetTaskForm(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+
+	t.Run("Failed SetTaskForm", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPut, "/workflows/some_id/tasks/task_id/form", strings.NewReader(`{"fields":[{"name":"amount","type":"Number"},{"name":"amount","type":"Number"}]}`))
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		mockWorkflowService := &MockWorkflowService{SetTaskFormError: errors.New("duplicate form field: amount")}
+		workflowController := WorkflowController{WorkflowService: mockWorkflowService, UserService: mockUserService}
+
+		workflowController.SetTaskForm(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "duplicate form field: amount")
+	})
+}
+
+func TestCompleteTask(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+		message  string
+	}{
+		{"Complete with values", `{"values":{"amount":1200}}`, HTTPStatusOK, `"amount":1200`},
+		{"Complete without body", "", HTTPStatusOK, `"status":"Completed"`},
+		{"Invalid body", `{"values":`, HTTPStatusOK, InvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/tasks/task_id/complete", strings.NewReader(tt.body))
+			c.Set("user", models.JWTUser{Username: "testUser"})
+
+			workflowController.CompleteTask(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+
+	t.Run("Missing required field", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/tasks/task_id/complete", strings.NewReader(`{"values":{}}`))
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		mockWorkflowService := &MockWorkflowService{CompleteTaskError: errors.New("missing required form field: amount")}
+		workflowController := WorkflowController{WorkflowService: mockWorkflowService, UserService: mockUserService}
+
+		workflowController.CompleteTask(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "missing required form field: amount")
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/tasks/task_id/complete", nil)
+		c.Set("user", models.JWTUser{Username: "testWrongUser"})
+
+		workflowController.CompleteTask(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
+
+func TestRetryTask(t *testing.T) {
+	t.Run("Successful RetryTask", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/tasks/task_id/retry", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		workflowController.RetryTask(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"In Progress"`)
+	})
+
+	t.Run("Task has not failed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/tasks/task_id/retry", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		mockWorkflowService := &MockWorkflowService{RetryTaskError: errors.New("only failed tasks can be retried")}
+		workflowController := WorkflowController{WorkflowService: mockWorkflowService, UserService: mockUserService}
+
+		workflowController.RetryTask(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "only failed tasks can be retried")
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/tasks/task_id/retry", nil)
+		c.Set("user", models.JWTUser{Username: "testWrongUser"})
+
+		workflowController.RetryTask(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
+
+func TestAddChecklistItem(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		body     string
+		expected int
+		message  string
+	}{
+		{"Valid item", "testUser", `{"text":"Sign","required":true}`, HTTPStatusOK, `"text":"Sign"`},
+		{"Missing text", "testUser", `{"required":true}`, HTTPStatusOK, InvalidInput},
+		{"Text too long", "testUser", `{"text":"` + strings.Repeat("a", 501) + `"}`, HTTPStatusOK, InvalidInput},
+		{"Unauthorized", "testWrongUser", `{"text":"Sign"}`, HTTPStatusOK, "unauthorized"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/tasks/task_id/checklist", strings.NewReader(tt.body))
+			c.Set("user", models.JWTUser{Username: tt.user})
+
+			workflowController.AddChecklistItem(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+
+	t.Run("Checklist is full", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/tasks/task_id/checklist", strings.NewReader(`{"text":"Sign"}`))
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		mockWorkflowService := &MockWorkflowService{AddChecklistItemError: errors.New("checklist is full")}
+		workflowController := WorkflowController{WorkflowService: mockWorkflowService, UserService: mockUserService}
+
+		workflowController.AddChecklistItem(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "checklist is full")
+	})
+}
+
+func TestToggleChecklistItem(t *testing.T) {
+	t.Run("Successful ToggleChecklistItem", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/tasks/task_id/checklist/item_id/toggle", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		workflowController.ToggleChecklistItem(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"done_by":"testUser"`)
+	})
+
+	t.Run("Task is already done", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/tasks/task_id/checklist/item_id/toggle", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		mockWorkflowService := &MockWorkflowService{ToggleChecklistItemError: errors.New("task is already done")}
+		workflowController := WorkflowController{WorkflowService: mockWorkflowService, UserService: mockUserService}
+
+		workflowController.ToggleChecklistItem(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "task is already done")
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/tasks/task_id/checklist/item_id/toggle", nil)
+		c.Set("user", models.JWTUser{Username: "testWrongUser"})
+
+		workflowController.ToggleChecklistItem(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
+
+func TestReorderChecklist(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+		message  string
+	}{
+		{"Valid order", `{"item_ids":["64b7f0f0f0f0f0f0f0f0f0f1","64b7f0f0f0f0f0f0f0f0f0f2"]}`, HTTPStatusOK, `"text":"Review"`},
+		{"Missing item IDs", `{}`, HTTPStatusOK, InvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPut, "/workflows/some_id/tasks/task_id/checklist/order", strings.NewReader(tt.body))
+			c.Set("user", models.JWTUser{Username: "testUser"})
+
+			workflowController.ReorderChecklist(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+
+	t.Run("Item missing from order", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPut, "/workflows/some_id/tasks/task_id/checklist/order", strings.NewReader(`{"item_ids":["64b7f0f0f0f0f0f0f0f0f0f1"]}`))
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		mockWorkflowService := &MockWorkflowService{ReorderChecklistError: errors.New("checklist order must list every item once")}
+		workflowController := WorkflowController{WorkflowService: mockWorkflowService, UserService: mockUserService}
+
+		workflowController.ReorderChecklist(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "checklist order must list every item once")
+	})
+}
+
+func TestRemoveChecklistItem(t *testing.T) {
+	t.Run("Successful RemoveChecklistItem", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodDelete, "/workflows/some_id/tasks/task_id/checklist/item_id", nil)
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		workflowController.RemoveChecklistItem(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "checklist")
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodDelete, "/workflows/some_id/tasks/task_id/checklist/item_id", nil)
+		c.Set("user", models.JWTUser{Username: "testWrongUser"})
+
+		workflowController.RemoveChecklistItem(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
+
+func TestBatchTasks(t *testing.T) {
+	router := gin.New()
+	router.POST("/workflows/:id/tasks", workflowController.CreateTask)
+	router.POST("/workflows/:id/tasks:method", func(c *gin.Context) {
+		c.Set("user", models.JWTUser{Username: "testUser"})
+		workflowController.BatchTasks(c)
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		expected int
+		message  string
+	}{
+		{"Valid batch", "/workflows/some_id/tasks:batch", `{"operations":[{"op":"create","create":{"name":"Review"}},{"op":"update","task_id":"task_id","update":{"name":"Sign","status":"Pending","order":1}},{"op":"delete","task_id":"other_id"}]}`, HTTPStatusOK, `"committed":true`},
+		{"Atomic batch rolled back", "/workflows/some_id/tasks:batch", `{"operations":[{"op":"delete","task_id":"task_id"},{"op":"delete","task_id":"missing"},{"op":"delete","task_id":"other_id"}]}`, HTTPStatusOK, `"status":"rolled_back"`},
+		{"Best-effort batch", "/workflows/some_id/tasks:batch", `{"mode":"best_effort","operations":[{"op":"delete","task_id":"task_id"},{"op":"delete","task_id":"missing"}]}`, HTTPStatusOK, `"error":"task does not exist"`},
+		{"Unknown mode", "/workflows/some_id/tasks:batch", `{"mode":"sometimes","operations":[{"op":"delete","task_id":"task_id"}]}`, HTTPStatusOK, InvalidInput},
+		{"No operations", "/workflows/some_id/tasks:batch", `{"operations":[]}`, HTTPStatusOK, InvalidInput},
+		{"Create without task", "/workflows/some_id/tasks:batch", `{"operations":[{"op":"create"}]}`, HTTPStatusOK, InvalidInput},
+		{"Update without task ID", "/workflows/some_id/tasks:batch", `{"operations":[{"op":"update","update":{"name":"Sign","status":"Pending","order":1}}]}`, HTTPStatusOK, InvalidInput},
+		{"Invalid created task", "/workflows/some_id/tasks:batch", `{"operations":[{"op":"create","create":{"name":"R"}}]}`, HTTPStatusOK, InvalidInput},
+		{"Unknown method", "/workflows/some_id/tasks:purge", `{"operations":[{"op":"delete","task_id":"task_id"}]}`, http.StatusNotFound, "not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			request, _ := http.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+
+			router.ServeHTTP(w, request)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+
+	t.Run("Atomic batch reports the failure", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/workflows/some_id/tasks:batch", strings.NewReader(`{"operations":[{"op":"delete","task_id":"task_id"},{"op":"delete","task_id":"missing"},{"op":"delete","task_id":"other_id"}]}`))
+
+		router.ServeHTTP(w, request)
+
+		assert.Contains(t, w.Body.String(), "batch was rolled back")
+		assert.Contains(t, w.Body.String(), `"status":"skipped"`)
+		assert.Contains(t, w.Body.String(), `"committed":false`)
+	})
+
+	t.Run("Version mismatch", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/tasks:batch", strings.NewReader(`{"operations":[{"op":"delete","task_id":"task_id"}]}`))
+		c.Params = gin.Params{{Key: "method", Value: ":batch"}}
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		mockWorkflowService := &MockWorkflowService{BatchTasksError: repositories.ErrVersionMismatch}
+		workflowController := WorkflowController{WorkflowService: mockWorkflowService, UserService: mockUserService}
+
+		workflowController.BatchTasks(c)
+
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/some_id/tasks:batch", strings.NewReader(`{"operations":[{"op":"delete","task_id":"task_id"}]}`))
+		c.Params = gin.Params{{Key: "method", Value: ":batch"}}
+		c.Set("user", models.JWTUser{Username: "testWrongUser"})
+
+		workflowController.BatchTasks(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+}
+
+// exportedWorkflow is a workflow using every part of the definition format,
+// with some run state that export has to leave out.
+func exportedWorkflow() models.Workflow {
+	review, gateway, approval, deploy, notify := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	return models.Workflow{
+		Name:   "Release",
+		Owner:  "testUser",
+		Labels: []string{"ops", "release"},
+		VariableDefinitions: []models.FieldDefinition{
+			{Name: "risk", Label: "Risk", Type: models.EnumField, Required: true, Options: []string{"low", "high"}},
+		},
+		Variables: map[string]interface{}{"risk": "high"},
+		Tasks: []models.Task{
+			{
+				BaseModel:   common.BaseModel{ID: review},
+				Name:        "Review",
+				Description: "Review the changes",
+				Status:      models.Completed,
+				Order:       1,
+				DependsOn:   []primitive.ObjectID{},
+				Type:        models.UserTask,
+				Form:        []models.FieldDefinition{{Name: "risk", Label: "Risk", Type: models.EnumField, Required: true, Options: []string{"low", "high"}}},
+				FormValues:  map[string]interface{}{"risk": "high"},
+				Assignee:    "reviewer",
+				SLA:         &models.SLA{DurationSeconds: 3600, AtRiskPercent: 80, Steps: []models.EscalationStep{{Action: models.NotifyOwner, AfterSeconds: 600}}, Escalations: []models.Escalation{{Step: 0, Action: models.NotifyOwner}}},
+				Checklist:   []models.ChecklistItem{{ID: primitive.NewObjectID(), Text: "Read the diff", Done: true, Required: true, DoneBy: "reviewer"}},
+				Labels:      []string{"code"},
+			},
+			{
+				BaseModel: common.BaseModel{ID: gateway},
+				Name:      "Risk",
+				Status:    models.Completed,
+				Order:     2,
+				DependsOn: []primitive.ObjectID{review},
+				Type:      models.GatewayTask,
+				Gateway: &models.Gateway{Kind: models.ExclusiveGateway, Flows: []models.GatewayFlow{
+					{Target: approval, Condition: "risk == 'high'"},
+					{Target: deploy},
+				}, Taken: []primitive.ObjectID{approval}},
+			},
+			{
+				BaseModel: common.BaseModel{ID: approval},
+				Name:      "Sign off",
+				Status:    models.InProgress,
+				Order:     3,
+				DependsOn: []primitive.ObjectID{},
+				Type:      models.ApprovalTask,
+				Approval: &models.Approval{
+					Approvers: []models.Approver{{Kind: models.RoleApprover, Value: "Admin"}, {Kind: models.UserApprover, Value: "lead"}},
+					Quorum:    models.QuorumCount,
+					Required:  1,
+					Votes:     []models.ApprovalVote{{Username: "lead", Decision: models.ApprovedDecision}},
+				},
+			},
+			{
+				BaseModel: common.BaseModel{ID: deploy},
+				Name:      "Deploy",
+				Status:    models.Pending,
+				Order:     4,
+				DependsOn: []primitive.ObjectID{},
+				Type:      models.AutomatedTask,
+				Automation: &models.Automation{
+					Executor:       models.HTTPExecutor,
+					HTTP:           &models.HTTPCall{Method: "POST", URL: "https://deploy.example.com", Body: `{"risk":"{{.risk}}"}`},
+					MaxAttempts:    3,
+					BackoffSeconds: 10,
+					TimeoutSeconds: 30,
+					Attempts:       1,
+				},
+			},
+			{
+				BaseModel: common.BaseModel{ID: notify},
+				Name:      "Announce",
+				Status:    models.Pending,
+				Order:     5,
+				DependsOn: []primitive.ObjectID{approval, deploy},
+				Type:      models.UserTask,
+			},
+		},
+	}
+}
+
+func exportWorkflow(t *testing.T, controller WorkflowController, format string) []byte {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/export?format="+format, nil)
+	c.Params = gin.Params{{Key: "id", Value: "some_id"}}
+	c.Set("user", models.JWTUser{Username: "testUser"})
+
+	controller.ExportWorkflow(c)
+
+	assert.Equal(t, HTTPStatusOK, w.Code)
+	return w.Body.Bytes()
+}
+
+func importWorkflow(controller WorkflowController, contentType string, document []byte) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/workflows/import", bytes.NewReader(document))
+	c.Request.Header.Set("Content-Type", contentType)
+	c.Set("user", models.JWTUser{Username: "testUser"})
+
+	controller.ImportWorkflow(c)
+
+	return w
+}
+
+func TestExportWorkflow(t *testing.T) {
+	t.Run("JSON", func(t *testing.T) {
+		document := exportWorkflow(t, workflowController, "json")
+
+		var definition map[string]interface{}
+		assert.NoError(t, json.Unmarshal(document, &definition))
+		assert.Equal(t, float64(models.WorkflowDefinitionVersion), definition["version"])
+		assert.Contains(t, string(document), `"depends_on": [
+        "task-1"
+      ]`)
+		assert.Contains(t, string(document), `"target": "task-3"`)
+		assert.NotContains(t, string(document), "some_id")
+		for _, state := range []string{"status", "votes", "attempts", "escalations", "form_values", "done", "taken", "variables"} {
+			assert.NotContains(t, string(document), `"`+state+`"`)
+		}
+	})
+
+	t.Run("YAML", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/export?format=yaml", nil)
+		c.Params = gin.Params{{Key: "id", Value: "some_id"}}
+		c.Set("user", models.JWTUser{Username: "testUser"})
+
+		workflowController.ExportWorkflow(c)
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Equal(t, "application/yaml", w.Header().Get("Content-Type"))
+		assert.Equal(t, "attachment; filename=workflow-some_id.yaml", w.Header().Get("Content-Disposition"))
+		assert.True(t, strings.HasPrefix(w.Body.String(), "version: 1\nmetadata:\n  name: Release\n"))
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		user     string
+		mock     *MockWorkflowService
+		expected string
+	}{
+		{"Unknown format", "/workflows/some_id/export?format=xml", "testUser", mockWorkflowService, InvalidInput},
+		{"Unauthorized", "/workflows/some_id/export", "testWrongUser", mockWorkflowService, "unauthorized"},
+		{"Failed ExportWorkflow", "/workflows/some_id/export", "testUser", &MockWorkflowService{ExportWorkflowError: errors.New("failed to retrieve workflow")}, "failed to retrieve workflow"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodGet, tt.path, nil)
+			c.Set("user", models.JWTUser{Username: tt.user})
+
+			workflowController := WorkflowController{WorkflowService: tt.mock, UserService: mockUserService}
+			workflowController.ExportWorkflow(c)
+
+			assert.Equal(t, HTTPStatusOK, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expected)
+		})
+	}
+}
+
+func TestImportWorkflow(t *testing.T) {
+	definition := `{"version":1,"metadata":{"name":"Onboarding"},"tasks":[{"key":"a","name":"Prepare","type":"User"},{"key":"b","name":"Welcome","type":"User","depends_on":["a"]}]}`
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		expected    int
+		message     string
+	}{
+		{"JSON", "application/json", definition, HTTPStatusOK, `"workflow_id":"importedID"`},
+		{"YAML", "application/x-yaml", "version: 1\nmetadata:\n  name: Onboarding\ntasks:\n  - key: a\n    name: Prepare\n    type: User\n", HTTPStatusOK, `"workflow_id":"importedID"`},
+		{"Unsupported content type", "text/plain", definition, http.StatusUnsupportedMediaType, "unsupported definition content type"},
+		{"Empty body", "application/json", ``, HTTPStatusOK, InvalidInput},
+		{"Too large", "application/json", strings.Repeat(" ", models.MaxWorkflowDefinitionSize+1), http.StatusRequestEntityTooLarge, "definition is too large"},
+		{"Unknown field", "application/json", `{"version":1,"metadata":{"name":"Onboarding"},"tasks":[],"owner":"someone"}`, HTTPStatusOK, "invalid workflow definition"},
+		{"Unsupported version", "application/json", `{"version":2,"metadata":{"name":"Onboarding"},"tasks":[]}`, HTTPStatusOK, "unsupported workflow definition version: 2"},
+		{"Duplicate key", "application/json", `{"version":1,"metadata":{"name":"Onboarding"},"tasks":[{"key":"a","name":"Prepare","type":"User"},{"key":"a","name":"Welcome","type":"User"}]}`, HTTPStatusOK, "duplicate template task key: a"},
+		{"Unknown dependency", "application/json", `{"version":1,"metadata":{"name":"Onboarding"},"tasks":[{"key":"a","name":"Prepare","type":"User","depends_on":["z"]}]}`, HTTPStatusOK, "invalid dependency for template task: a"},
+		{"Type without configuration", "application/json", `{"version":1,"metadata":{"name":"Onboarding"},"tasks":[{"key":"a","name":"Prepare","type":"Approval"}]}`, HTTPStatusOK, "invalid type for task: a"},
+		{"Cycle", "application/json", `{"version":1,"metadata":{"name":"Onboarding"},"tasks":[{"key":"a","name":"Prepare","type":"User","depends_on":["b"]},{"key":"b","name":"Welcome","type":"User","depends_on":["a"]}]}`, HTTPStatusOK, "task is unreachable: a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workflowController := WorkflowController{WorkflowService: &MockWorkflowService{}, UserService: mockUserService}
+
+			w := importWorkflow(workflowController, tt.contentType, []byte(tt.body))
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+}
+
+func TestWorkflowDefinitionRoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		format      string
+		contentType string
+	}{
+		{"json", "application/json"},
+		{"yaml", "application/yaml"},
+	} {
+		t.Run(tt.format, func(t *testing.T) {
+			mock := &MockWorkflowService{}
+			workflowController := WorkflowController{WorkflowService: mock, UserService: mockUserService}
+
+			exported := exportWorkflow(t, workflowController, tt.format)
+			w := importWorkflow(workflowController, tt.contentType, exported)
+			assert.Equal(t, HTTPStatusOK, w.Code)
+			assert.NotNil(t, mock.Imported)
+			reexported := exportWorkflow(t, workflowController, tt.format)
+
+			first, err := models.ParseWorkflowDefinition(tt.contentType, exported)
+			assert.NoError(t, err)
+			second, err := models.ParseWorkflowDefinition(tt.contentType, reexported)
+			assert.NoError(t, err)
+			first.Metadata.ExportedAt, second.Metadata.ExportedAt = nil, nil
+			assert.Equal(t, first, second)
+
+			// The imported workflow starts afresh with new IDs.
+			for _, task := range mock.Imported.Tasks {
+				assert.Equal(t, models.Pending, task.Status)
+			}
+			assert.Equal(t, "testUser", mock.Imported.Owner)
+			assert.Equal(t, mock.Imported.Tasks[2].ID, mock.Imported.Tasks[1].Gateway.Flows[0].Target)
+			assert.False(t, mock.Imported.Tasks[0].Checklist[0].Done)
+			assert.Empty(t, mock.Imported.Tasks[2].Approval.Votes)
+			assert.Zero(t, mock.Imported.Tasks[3].Automation.Attempts)
+		})
+	}
+}
+
+func TestGetWorkflowDefinitionSchema(t *testing.T) {
+	w := performRequest(http.MethodGet, "/schemas/workflow-definition.json", nil)
+
+	assert.Equal(t, HTTPStatusOK, w.Code)
+	assert.Equal(t, "application/schema+json", w.Header().Get("Content-Type"))
+
+	var schema map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &schema))
+	assert.Equal(t, "https://json-schema.org/draft/2020-12/schema", schema["$schema"])
+}
+
+const orderProcess = `<?xml version="1.0" encoding="UTF-8"?>
+<definitions xmlns="http://www.omg.org/spec/BPMN/20100524/MODEL" xmlns:bpmndi="http://www.omg.org/spec/BPMN/20100524/DI" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" id="Definitions_1">
+  <message id="Message_1" name="Paid" />
+  <process id="Order" name="Order handling" isExecutable="true">
+    <startEvent id="Start" />
+    <userTask id="Check" name="Check&#10;order">
+      <documentation>Check stock and price</documentation>
+    </userTask>
+    <exclusiveGateway id="Amount" name="Amount?" default="Flow_small" />
+    <userTask id="Approve" name="Approve order" />
+    <serviceTask id="Invoice" name="Send invoice" />
+    <intermediateCatchEvent id="Wait" name="Paid">
+      <messageEventDefinition messageRef="Message_1" />
+    </intermediateCatchEvent>
+    <userTask id="Ship" name="Ship order" />
+    <textAnnotation id="Note"><text>Ask sales first</text></textAnnotation>
+    <endEvent id="End" />
+    <sequenceFlow id="Flow_1" sourceRef="Start" targetRef="Check" />
+    <sequenceFlow id="Flow_2" sourceRef="Check" targetRef="Amount" />
+    <sequenceFlow id="Flow_large" sourceRef="Amount" targetRef="Approve">
+      <conditionExpression xsi:type="tFormalExpression">${amount &gt; 100}</conditionExpression>
+    </sequenceFlow>
+    <sequenceFlow id="Flow_small" sourceRef="Amount" targetRef="Invoice" />
+    <sequenceFlow id="Flow_rejected" sourceRef="Amount" targetRef="End">
+      <conditionExpression xsi:type="tFormalExpression">${rejected}</conditionExpression>
+    </sequenceFlow>
+    <sequenceFlow id="Flow_3" sourceRef="Approve" targetRef="Invoice" />
+    <sequenceFlow id="Flow_4" sourceRef="Invoice" targetRef="Wait" />
+    <sequenceFlow id="Flow_5" sourceRef="Wait" targetRef="Ship" />
+    <sequenceFlow id="Flow_rework" sourceRef="Ship" targetRef="Check" />
+    <sequenceFlow id="Flow_6" sourceRef="Ship" targetRef="End" />
+  </process>
+  <bpmndi:BPMNDiagram id="Diagram_1" />
+</definitions>`
+
+func TestExportWorkflowBPMN(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/export?format=bpmn", nil)
+	c.Params = gin.Params{{Key: "id", Value: "some_id"}}
+	c.Set("user", models.JWTUser{Username: "testUser"})
+
+	workflowController.ExportWorkflow(c)
+
+	assert.Equal(t, HTTPStatusOK, w.Code)
+	assert.Equal(t, "application/xml", w.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=workflow-some_id.bpmn", w.Header().Get("Content-Disposition"))
+	body := w.Body.String()
+	assert.Contains(t, body, `<bpmn:definitions xmlns:bpmn="http://www.omg.org/spec/BPMN/20100524/MODEL"`)
+	assert.Contains(t, body, `<bpmn:process id="Process_1" name="Release" isExecutable="false">`)
+	assert.Contains(t, body, `<bpmn:userTask id="task-1" name="Review">`)
+	assert.Contains(t, body, `<bpmn:documentation>Review the changes</bpmn:documentation>`)
+	assert.Contains(t, body, `<bpmn:exclusiveGateway id="task-2" name="Risk" default="Flow_4">`)
+	assert.Contains(t, body, `<bpmn:userTask id="task-3" name="Sign off">`)
+	assert.Contains(t, body, `<bpmn:serviceTask id="task-4" name="Deploy">`)
+	assert.Contains(t, body, `<bpmn:sequenceFlow id="Flow_1" sourceRef="StartEvent_1" targetRef="task-1"></bpmn:sequenceFlow>`)
+	assert.Contains(t, body, `<bpmn:sequenceFlow id="Flow_3" sourceRef="task-2" targetRef="task-3">
+      <bpmn:conditionExpression xsi:type="bpmn:tFormalExpression">risk == &#39;high&#39;</bpmn:conditionExpression>`)
+	assert.Contains(t, body, `<bpmn:sequenceFlow id="Flow_7" sourceRef="task-5" targetRef="EndEvent_1"></bpmn:sequenceFlow>`)
+	assert.Contains(t, body, `<bpmndi:BPMNShape id="task-1_di" bpmnElement="task-1">`)
+	assert.Contains(t, body, `<bpmndi:BPMNEdge id="Flow_1_di" bpmnElement="Flow_1">`)
+}
+
+func TestImportBPMN(t *testing.T) {
+	mock := &MockWorkflowService{}
+	workflowController := WorkflowController{WorkflowService: mock, UserService: mockUserService}
+
+	w := importWorkflow(workflowController, "application/xml", []byte(orderProcess))
+
+	assert.Equal(t, HTTPStatusOK, w.Code)
+	var response struct {
+		Data struct {
+			WorkflowID string   `json:"workflow_id"`
+			Warnings   []string `json:"warnings"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "importedID", response.Data.WorkflowID)
+	assert.Equal(t, []string{
+		`unsupported BPMN element message "Message_1" was skipped`,
+		`serviceTask "Invoice" is imported as a user task`,
+		`unsupported BPMN element intermediateCatchEvent "Wait" was skipped; its sequence flows are joined`,
+		`messageEventDefinition of intermediateCatchEvent "Wait" is ignored`,
+		`unsupported BPMN element textAnnotation "Note" was skipped`,
+		`sequenceFlow "Flow_rejected" leads from gateway "Amount" straight to an end and was dropped`,
+		`a loop back to "Check" cannot be expressed by a workflow and was cut`,
+	}, response.Data.Warnings)
+
+	workflow := mock.Imported
+	assert.Equal(t, "Order handling", workflow.Name)
+	names := []string{}
+	for _, task := range workflow.Tasks {
+		names = append(names, task.Name)
+	}
+	assert.Equal(t, []string{"Check order", "Amount?", "Approve order", "Send invoice", "Ship order"}, names)
+
+	check, amount, approve, invoice, ship := workflow.Tasks[0], workflow.Tasks[1], workflow.Tasks[2], workflow.Tasks[3], workflow.Tasks[4]
+	assert.Equal(t, "Check stock and price", check.Description)
+	assert.Empty(t, check.DependsOn)
+	assert.Equal(t, models.GatewayTask, amount.Type)
+	assert.Equal(t, []primitive.ObjectID{check.ID}, amount.DependsOn)
+	assert.Equal(t, []models.GatewayFlow{{Target: approve.ID, Condition: "amount > 100"}, {Target: invoice.ID}}, amount.Gateway.Flows)
+	assert.Equal(t, []primitive.ObjectID{approve.ID}, invoice.DependsOn)
+	assert.Equal(t, []primitive.ObjectID{invoice.ID}, ship.DependsOn)
+
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"Not XML", `{"version":1}`, "invalid bpmn document"},
+		{"Not BPMN", `<definitions xmlns="urn:other"><process id="P" /></definitions>`, "not a bpmn document"},
+		{"No process", `<definitions xmlns="http://www.omg.org/spec/BPMN/20100524/MODEL" />`, "bpmn document has no process"},
+		{"Invalid condition", `<definitions xmlns="http://www.omg.org/spec/BPMN/20100524/MODEL"><process id="P" name="Process"><exclusiveGateway id="G" name="Decide" /><userTask id="A" name="Work" /><sequenceFlow id="F" sourceRef="G" targetRef="A"><conditionExpression>amount &gt;</conditionExpression></sequenceFlow></process></definitions>`, "expression"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := importWorkflow(workflowController, "text/xml", []byte(tt.body))
+
+			assert.Equal(t, HTTPStatusOK, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expected)
+		})
+	}
+}
+
+func TestBPMNRoundTrip(t *testing.T) {
+	mock := &MockWorkflowService{}
+	workflowController := WorkflowController{WorkflowService: mock, UserService: mockUserService}
+
+	// A workflow of the tasks and gateways BPMN can express.
+	assert.Equal(t, HTTPStatusOK, importWorkflow(workflowController, "application/xml", []byte(orderProcess)).Code)
+
+	exported := exportWorkflow(t, workflowController, "bpmn")
+	w := importWorkflow(workflowController, "application/bpmn+xml", exported)
+	assert.Equal(t, HTTPStatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"warnings":[]`)
+	reexported := exportWorkflow(t, workflowController, "bpmn")
+
+	assert.Equal(t, string(exported), string(reexported))
+}
+
+func TestGetWorkflowGraph(t *testing.T) {
+	workflow := exportedWorkflow()
+	controller := WorkflowController{WorkflowService: &MockWorkflowService{Imported: &workflow}, UserService: mockUserService}
+
+	renderGraph := func(controller WorkflowController, user string, format string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/workflows/some_id/graph?format="+format, nil)
+		c.Params = gin.Params{{Key: "id", Value: "some_id"}}
+		c.Set("user", models.JWTUser{Username: user})
+
+		controller.GetWorkflowGraph(c)
+		return w
+	}
+
+	t.Run("Mermaid", func(t *testing.T) {
+		w := renderGraph(controller, "testUser", "")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Equal(t, models.MermaidContentType, w.Header().Get("Content-Type"))
+		assert.Equal(t, `---
+title: "Release"
+---
+flowchart LR
+  t1("1. Review")
+  t2{"2. Risk"}
+  t3{{"3. Sign off"}}
+  t4[["4. Deploy"]]
+  t5("5. Announce")
+  t1 --> t2
+  t2 -->|"risk == 'high'"| t3
+  t2 -.->|default| t4
+  t3 --> t5
+  t4 --> t5
+  classDef pending fill:#eceff1,stroke:#90a4ae
+  class t4,t5 pending
+  classDef in_progress fill:#bbdefb,stroke:#1e88e5
+  class t3 in_progress
+  classDef completed fill:#c8e6c9,stroke:#43a047
+  class t1,t2 completed
+`, w.Body.String())
+	})
+
+	t.Run("DOT", func(t *testing.T) {
+		w := renderGraph(controller, "testUser", "dot")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Equal(t, models.DOTContentType, w.Header().Get("Content-Type"))
+		assert.Equal(t, `digraph workflow {
+  label="Release";
+  labelloc=t;
+  rankdir=LR;
+  node [shape=box, style="rounded,filled", fontname="Helvetica"];
+  edge [fontname="Helvetica"];
+  t1 [label="1. Review", fillcolor="#c8e6c9", color="#43a047"];
+  t2 [label="2. Risk", fillcolor="#c8e6c9", color="#43a047", shape=diamond, style=filled];
+  t3 [label="3. Sign off", fillcolor="#bbdefb", color="#1e88e5", shape=hexagon, style=filled];
+  t4 [label="4. Deploy", fillcolor="#eceff1", color="#90a4ae", peripheries=2];
+  t5 [label="5. Announce", fillcolor="#eceff1", color="#90a4ae"];
+  t1 -> t2;
+  t2 -> t3 [label="risk == 'high'"];
+  t2 -> t4 [label="default", style=dashed];
+  t3 -> t5;
+  t4 -> t5;
+}
+`, w.Body.String())
+	})
+
+	t.Run("Labels are escaped", func(t *testing.T) {
+		escaped := exportedWorkflow()
+		escaped.Name = `Release "v2"`
+		escaped.Tasks[0].Name = "Review <b>\"diff\"</b>\nnow"
+		controller := WorkflowController{WorkflowService: &MockWorkflowService{Imported: &escaped}, UserService: mockUserService}
+
+		w := renderGraph(controller, "testUser", "dot")
+		assert.Contains(t, w.Body.String(), `label="Release \"v2\"";`)
+		assert.Contains(t, w.Body.String(), `t1 [label="1. Review <b>\"diff\"</b>\nnow"`)
+
+		w = renderGraph(controller, "testUser", "mermaid")
+		assert.Contains(t, w.Body.String(), `title: "Release \"v2\""`)
+		assert.Contains(t, w.Body.String(), `t1("1. Review #lt;b#gt;#quot;diff#quot;#lt;/b#gt; now")`)
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		w := renderGraph(controller, "testUser", "svg")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), InvalidInput)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := renderGraph(controller, "testWrongUser", "dot")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized")
+	})
+
+	t.Run("Workflow not found", func(t *testing.T) {
+		mockWorkflowService.GetWorkflowByIDError = errors.New("not found")
+		defer func() { mockWorkflowService.GetWorkflowByIDError = nil }()
+
+		w := renderGraph(workflowController, "testUser", "")
+
+		assert.Equal(t, HTTPStatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to get workflow")
+	})
+}
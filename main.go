@@ -4,8 +4,16 @@ import (
 	"virtual_workflow_management_system_gin/databases"
 	"virtual_workflow_management_system_gin/middlewares"
 	"virtual_workflow_management_system_gin/routes"
+	"virtual_workflow_management_system_gin/services"
 
+	"context"
+	"errors"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -34,5 +42,67 @@ func main() {
 	defer resource.Close()
 	routes.InitUserRouter(publicRoute, resource)
 	routes.InitWorkflowRouter(publicRoute, resource)
-	r.Run(":" + os.Getenv("PORT"))
+	routes.InitTemplateRouter(publicRoute, resource)
+	routes.InitJobRouter(publicRoute, resource)
+	routes.InitScheduleRouter(publicRoute, resource)
+	routes.InitWebhookRouter(publicRoute, resource)
+	routes.InitEventRouter(publicRoute, resource)
+	routes.InitCommentRouter(publicRoute, resource)
+	routes.InitNotificationRouter(publicRoute, resource)
+	routes.InitAttachmentRouter(publicRoute, resource)
+	routes.InitSearchRouter(publicRoute, resource)
+	routes.InitReportRouter(publicRoute, resource)
+
+	if err := services.NewWorkflowService(resource).EnsureIndexes(); err != nil {
+		logrus.Error(err)
+	}
+	if err := services.NewSearchService(resource).EnsureIndexes(); err != nil {
+		logrus.Error(err)
+	}
+
+	services.Changes.Start(resource)
+	automationWorker := services.NewAutomationWorker(resource, envInt("AUTOMATION_WORKERS", 4))
+	automationWorker.Start()
+	jobQueue := services.NewJobQueue(resource, envInt("JOB_WORKERS", 4))
+	jobQueue.Start()
+	scheduler := services.NewScheduler(resource)
+	scheduler.Start()
+	slaMonitor := services.NewSLAMonitor(resource)
+	slaMonitor.Start()
+	outboxRelay := services.NewOutboxRelay(resource)
+	outboxRelay.Start()
+
+	server := &http.Server{Addr: ":" + os.Getenv("PORT"), Handler: r}
+	// Event streams only end when the change feed closes them.
+	server.RegisterOnShutdown(services.Changes.Stop)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logrus.Fatal(err)
+		}
+	}()
+
+	// Stop accepting requests first, then let the workers finish what they run.
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	logrus.Warning("Shutting down")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		logrus.Error(err)
+	}
+	outboxRelay.Stop()
+	automationWorker.Stop()
+	jobQueue.Stop()
+	scheduler.Stop()
+	slaMonitor.Stop()
+}
+
+func envInt(name string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+	return value
 }
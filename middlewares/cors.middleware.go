@@ -9,14 +9,15 @@ import (
 func NewCors(allowedOrigins []string) gin.HandlerFunc {
 	return cors.New(cors.Options{
 		AllowedOrigins: allowedOrigins,
-		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "HEAD"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS", "HEAD"},
 		AllowedHeaders: []string{
 			"Origin", "Host",
 			"Content-Type", "Content-Length",
 			"Accept-Encoding", "Accept-Language", "Accept",
 			"X-CSRF-Token", "Authorization", "X-Requested-With", "X-Access-Token",
+			"If-Match",
 		},
-		ExposedHeaders:   []string{"Content-Length"},
+		ExposedHeaders:   []string{"Content-Length", "ETag"},
 		AllowCredentials: true,
 	})
 }
@@ -17,8 +17,9 @@ import (
 )
 
 type Claims struct {
-	Username string          `json:"username"`
-	Role     models.UserRole `json:"role"`
+	Username     string          `json:"username"`
+	Role         models.UserRole `json:"role"`
+	Organization string          `json:"organization"`
 	jwt.StandardClaims
 }
 
@@ -57,8 +58,9 @@ func JWTAuthMiddleware(redisClient RedisClientInterface) func(ctx *gin.Context)
 		}
 
 		user := models.JWTUser{
-			Username: claims.Username,
-			Role:     claims.Role,
+			Username:     claims.Username,
+			Role:         claims.Role,
+			Organization: claims.Organization,
 		}
 
 		ctx.Set("user", user)
@@ -74,8 +76,9 @@ func GenerateJWTToken(user models.User, redisClient RedisClientInterface) (map[s
 
 	expirationTime := time.Now().Add(24 * time.Hour)
 	claims := &Claims{
-		Username: user.Username,
-		Role:     user.Role,
+		Username:     user.Username,
+		Role:         user.Role,
+		Organization: user.Organization,
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: expirationTime.Unix(),
 			Issuer:    "virtual_workflow_management_system_gin",
@@ -94,7 +97,9 @@ func GenerateJWTToken(user models.User, redisClient RedisClientInterface) (map[s
 
 	refreshExpirationTime := time.Now().Add(7 * 24 * time.Hour)
 	refreshClaims := &Claims{
-		Username: user.Username,
+		Username:     user.Username,
+		Role:         user.Role,
+		Organization: user.Organization,
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: refreshExpirationTime.Unix(),
 			Issuer:    "virtual_workflow_management_system_gin",
@@ -167,7 +172,7 @@ func RefreshJWTToken(refreshTokenString string, redisClient RedisClientInterface
 		return nil, errors.New("invalid or expired refresh token")
 	}
 
-	newToken, err := GenerateJWTToken(models.User{Username: claims.Username}, redisClient)
+	newToken, err := GenerateJWTToken(models.User{Username: claims.Username, Role: claims.Role, Organization: claims.Organization}, redisClient)
 	if err != nil {
 		return nil, err
 	}
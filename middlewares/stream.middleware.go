@@ -0,0 +1,96 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/responses"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// StreamTicketTTL is how long a stream ticket can be redeemed.
+const StreamTicketTTL = 30 * time.Second
+
+type streamTicket struct {
+	User       models.JWTUser `json:"user"`
+	WorkflowID string         `json:"workflow_id"`
+}
+
+// IssueStreamTicket stores a ticket that opens one event stream of the
+// workflow as the user. Browsers cannot set headers on EventSource and
+// WebSocket requests, so they put the ticket in the URL instead of their
+// access token.
+func IssueStreamTicket(user models.JWTUser, workflowID string, redisClient RedisClientInterface) (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		logrus.Error(err)
+		return "", errors.New("failed to generate stream ticket")
+	}
+	ticket := hex.EncodeToString(random)
+
+	value, err := json.Marshal(streamTicket{User: user, WorkflowID: workflowID})
+	if err != nil {
+		logrus.Error(err)
+		return "", errors.New("failed to generate stream ticket")
+	}
+
+	err = redisClient.Set(context.Background(), "stream_ticket_"+ticket, value, StreamTicketTTL).Err()
+	if err != nil {
+		logrus.Error("Could not set stream ticket in Redis: ", err)
+		return "", errors.New("failed to generate stream ticket")
+	}
+
+	return ticket, nil
+}
+
+// StreamAuthMiddleware authenticates event stream requests. Requests without
+// an Authorization header redeem the ticket in the ticket query parameter. A
+// ticket only opens a stream of the workflow it was issued for, once.
+func StreamAuthMiddleware(redisClient RedisClientInterface) func(ctx *gin.Context) {
+	authenticate := JWTAuthMiddleware(redisClient)
+	return func(ctx *gin.Context) {
+		ticket := ctx.Query("ticket")
+		if ctx.GetHeader("Authorization") != "" || ticket == "" {
+			authenticate(ctx)
+			return
+		}
+
+		user, err := redeemStreamTicket(ticket, ctx.Param("id"), redisClient)
+		if err != nil {
+			responses.Error(ctx, "unauthorized")
+			ctx.Abort()
+			return
+		}
+
+		ctx.Set("user", *user)
+		ctx.Next()
+	}
+}
+
+func redeemStreamTicket(ticket string, workflowID string, redisClient RedisClientInterface) (*models.JWTUser, error) {
+	key := "stream_ticket_" + ticket
+	value, err := redisClient.Get(context.Background(), key).Result()
+	if err != nil {
+		return nil, errors.New("invalid stream ticket")
+	}
+
+	// Only the request that deletes the ticket may use it.
+	deleted, err := redisClient.Del(context.Background(), key).Result()
+	if err != nil || deleted == 0 {
+		return nil, errors.New("invalid stream ticket")
+	}
+
+	var redeemed streamTicket
+	if err := json.Unmarshal([]byte(value), &redeemed); err != nil || redeemed.WorkflowID != workflowID {
+		return nil, errors.New("invalid stream ticket")
+	}
+
+	return &redeemed.User, nil
+}
@@ -0,0 +1,216 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+type ApproverKind string
+
+const (
+	UserApprover ApproverKind = "User"
+	RoleApprover ApproverKind = "Role"
+	TeamApprover ApproverKind = "Team"
+)
+
+type QuorumRule string
+
+const (
+	// QuorumAny needs a single approving approver.
+	QuorumAny QuorumRule = "Any"
+	// QuorumAll needs every listed approver to approve.
+	QuorumAll QuorumRule = "All"
+	// QuorumCount needs Required of the listed approvers to approve.
+	QuorumCount QuorumRule = "Count"
+)
+
+type ApprovalDecision string
+
+const (
+	ApprovedDecision ApprovalDecision = "Approved"
+	RejectedDecision ApprovalDecision = "Rejected"
+)
+
+type Approver struct {
+	Kind  ApproverKind `json:"kind" bson:"kind"`
+	Value string       `json:"value" bson:"value"`
+}
+
+type ApprovalVote struct {
+	Username string           `json:"username" bson:"username"`
+	Decision ApprovalDecision `json:"decision" bson:"decision"`
+	Comment  string           `json:"comment" bson:"comment"`
+	Approver int              `json:"approver" bson:"approver"`
+	VotedAt  time.Time        `json:"voted_at" bson:"voted_at"`
+}
+
+// Approval describes who has to sign off a task and records their votes. Each
+// entry of Approvers counts once towards the quorum, whether it names a user,
+// a role or a team.
+type Approval struct {
+	Approvers []Approver       `json:"approvers" bson:"approvers"`
+	Quorum    QuorumRule       `json:"quorum" bson:"quorum"`
+	Required  int              `json:"required" bson:"required"`
+	Variable  string           `json:"variable" bson:"variable"`
+	Votes     []ApprovalVote   `json:"votes" bson:"votes"`
+	Outcome   ApprovalDecision `json:"outcome,omitempty" bson:"outcome,omitempty"`
+	DecidedAt *time.Time       `json:"decided_at,omitempty" bson:"decided_at,omitempty"`
+}
+
+func (approval *Approval) Validate() error {
+	if len(approval.Approvers) == 0 {
+		return errors.New("approval task needs at least one approver")
+	}
+
+	for _, approver := range approval.Approvers {
+		switch approver.Kind {
+		case UserApprover, RoleApprover, TeamApprover:
+		default:
+			return errors.New("unknown approver kind: " + string(approver.Kind))
+		}
+		if approver.Value == "" {
+			return errors.New("approver value is required")
+		}
+	}
+
+	switch approval.Quorum {
+	case QuorumAny, QuorumAll:
+	case QuorumCount:
+		if approval.Required < 1 || approval.Required > len(approval.Approvers) {
+			return errors.New("required approvals must be between 1 and the number of approvers")
+		}
+	default:
+		return errors.New("unknown quorum rule: " + string(approval.Quorum))
+	}
+
+	if approval.Variable != "" && !VariableNamePattern.MatchString(approval.Variable) {
+		return errors.New("invalid variable name: " + approval.Variable)
+	}
+
+	return nil
+}
+
+// Definition returns the approval rule without any votes.
+func (approval *Approval) Definition() *Approval {
+	return &Approval{
+		Approvers: approval.Approvers,
+		Quorum:    approval.Quorum,
+		Required:  approval.Required,
+		Variable:  approval.Variable,
+		Votes:     []ApprovalVote{},
+	}
+}
+
+func (approval *Approval) requiredApprovals() int {
+	switch approval.Quorum {
+	case QuorumAll:
+		return len(approval.Approvers)
+	case QuorumCount:
+		return approval.Required
+	default:
+		return 1
+	}
+}
+
+func (approver Approver) matches(user JWTUser) bool {
+	switch approver.Kind {
+	case UserApprover:
+		return approver.Value == user.Username
+	case RoleApprover:
+		return approver.Value == string(user.Role)
+	case TeamApprover:
+		for _, team := range user.Teams {
+			if team == approver.Value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (approval *Approval) CanVote(user JWTUser) bool {
+	for _, approver := range approval.Approvers {
+		if approver.matches(user) {
+			return true
+		}
+	}
+	return false
+}
+
+// Vote records the decision of a user against the first listed approver entry
+// the user matches that nobody has voted for yet, and decides the approval as
+// soon as the quorum is reached or can no longer be reached.
+func (approval *Approval) Vote(user JWTUser, decision ApprovalDecision, comment string) error {
+	if approval.Outcome != "" {
+		return errors.New("approval has already been decided")
+	}
+
+	if !approval.CanVote(user) {
+		return errors.New("user is not an approver of this task")
+	}
+
+	taken := map[int]bool{}
+	for _, vote := range approval.Votes {
+		if vote.Username == user.Username {
+			return errors.New("user has already voted")
+		}
+		taken[vote.Approver] = true
+	}
+
+	entry := -1
+	for i, approver := range approval.Approvers {
+		if !taken[i] && approver.matches(user) {
+			entry = i
+			break
+		}
+	}
+	if entry == -1 {
+		return errors.New("every approver entry of the user has already voted")
+	}
+
+	now := time.Now()
+	approval.Votes = append(approval.Votes, ApprovalVote{
+		Username: user.Username,
+		Decision: decision,
+		Comment:  comment,
+		Approver: entry,
+		VotedAt:  now,
+	})
+
+	approvals, rejections := 0, 0
+	for _, vote := range approval.Votes {
+		if vote.Decision == ApprovedDecision {
+			approvals++
+		} else {
+			rejections++
+		}
+	}
+
+	required := approval.requiredApprovals()
+	switch {
+	case approvals >= required:
+		approval.Outcome = ApprovedDecision
+	case len(approval.Approvers)-rejections < required:
+		approval.Outcome = RejectedDecision
+	}
+	if approval.Outcome != "" {
+		approval.DecidedAt = &now
+	}
+
+	return nil
+}
+
+func sameApproval(a *Approval, b *Approval) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Quorum != b.Quorum || a.Required != b.Required || a.Variable != b.Variable || len(a.Approvers) != len(b.Approvers) {
+		return false
+	}
+	for i := range a.Approvers {
+		if a.Approvers[i] != b.Approvers[i] {
+			return false
+		}
+	}
+	return true
+}
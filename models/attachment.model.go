@@ -0,0 +1,46 @@
+package models
+
+import (
+	"virtual_workflow_management_system_gin/common"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxAttachmentSize is the largest file that can be attached to a task.
+const MaxAttachmentSize = 25 << 20
+
+// AttachmentContentTypes are the types of files that can be attached to a task.
+var AttachmentContentTypes = map[string]bool{
+	"application/pdf":    true,
+	"application/zip":    true,
+	"application/json":   true,
+	"application/msword": true,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": true,
+	"application/vnd.ms-excel": true,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         true,
+	"application/vnd.ms-powerpoint":                                             true,
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": true,
+	"application/vnd.oasis.opendocument.text":                                   true,
+	"application/vnd.oasis.opendocument.spreadsheet":                            true,
+	"text/plain":    true,
+	"text/csv":      true,
+	"text/markdown": true,
+	"image/png":     true,
+	"image/jpeg":    true,
+	"image/gif":     true,
+	"image/webp":    true,
+}
+
+// Attachment is a file attached to a task. The content lives in the blob
+// store under StorageKey; Checksum is its hex encoded SHA-256.
+type Attachment struct {
+	common.BaseModel `bson:",inline"`
+	WorkflowID       primitive.ObjectID `json:"workflow_id" bson:"workflow_id"`
+	TaskID           primitive.ObjectID `json:"task_id" bson:"task_id"`
+	Filename         string             `json:"filename" bson:"filename"`
+	ContentType      string             `json:"content_type" bson:"content_type"`
+	Size             int64              `json:"size" bson:"size"`
+	Checksum         string             `json:"checksum" bson:"checksum"`
+	StorageKey       string             `json:"-" bson:"storage_key"`
+	UploadedBy       string             `json:"uploaded_by" bson:"uploaded_by"`
+}
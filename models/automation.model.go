@@ -0,0 +1,194 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"text/template"
+	"time"
+)
+
+type ExecutorKind string
+
+const (
+	// HTTPExecutor calls an HTTP endpoint with a body rendered from the run variables.
+	HTTPExecutor ExecutorKind = "HTTP"
+	// HandlerExecutor runs a Go handler registered with the automation worker.
+	HandlerExecutor ExecutorKind = "Handler"
+)
+
+const maxRetryDelay = time.Hour
+
+type HTTPCall struct {
+	Method  string            `json:"method" bson:"method"`
+	URL     string            `json:"url" bson:"url"`
+	Headers map[string]string `json:"headers,omitempty" bson:"headers,omitempty"`
+	Body    string            `json:"body" bson:"body"`
+}
+
+// Automation describes how an automated task is executed and records the
+// attempts made so far. A task is retried with exponential backoff until
+// MaxAttempts is reached, after which it fails.
+type Automation struct {
+	Executor       ExecutorKind `json:"executor" bson:"executor"`
+	HTTP           *HTTPCall    `json:"http,omitempty" bson:"http,omitempty"`
+	Handler        string       `json:"handler,omitempty" bson:"handler,omitempty"`
+	MaxAttempts    int          `json:"max_attempts" bson:"max_attempts"`
+	BackoffSeconds int          `json:"backoff_seconds" bson:"backoff_seconds"`
+	TimeoutSeconds int          `json:"timeout_seconds" bson:"timeout_seconds"`
+	OutputVariable string       `json:"output_variable,omitempty" bson:"output_variable,omitempty"`
+	Attempts       int          `json:"attempts" bson:"attempts"`
+	LastError      string       `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	NextAttemptAt  *time.Time   `json:"next_attempt_at,omitempty" bson:"next_attempt_at,omitempty"`
+	FinishedAt     *time.Time   `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+	LeaseID        string       `json:"-" bson:"lease_id,omitempty"`
+	LeasedUntil    *time.Time   `json:"-" bson:"leased_until,omitempty"`
+}
+
+var bodyFunctions = template.FuncMap{
+	"json": func(value interface{}) (string, error) {
+		encoded, err := json.Marshal(value)
+		return string(encoded), err
+	},
+}
+
+func (automation *Automation) Validate() error {
+	switch automation.Executor {
+	case HTTPExecutor:
+		if automation.HTTP == nil || automation.HTTP.URL == "" {
+			return errors.New("http executor needs a url")
+		}
+		if _, err := template.New("body").Funcs(bodyFunctions).Parse(automation.HTTP.Body); err != nil {
+			return errors.New("invalid body template: " + err.Error())
+		}
+	case HandlerExecutor:
+		if automation.Handler == "" {
+			return errors.New("handler executor needs a handler name")
+		}
+	default:
+		return errors.New("unknown executor: " + string(automation.Executor))
+	}
+
+	if automation.MaxAttempts < 1 {
+		return errors.New("automated task needs at least one attempt")
+	}
+	if automation.BackoffSeconds < 0 || automation.TimeoutSeconds < 1 {
+		return errors.New("invalid backoff or timeout")
+	}
+
+	if automation.OutputVariable != "" && !VariableNamePattern.MatchString(automation.OutputVariable) {
+		return errors.New("invalid variable name: " + automation.OutputVariable)
+	}
+
+	return nil
+}
+
+// Definition returns the executor configuration without any attempts.
+func (automation *Automation) Definition() *Automation {
+	return &Automation{
+		Executor:       automation.Executor,
+		HTTP:           automation.HTTP,
+		Handler:        automation.Handler,
+		MaxAttempts:    automation.MaxAttempts,
+		BackoffSeconds: automation.BackoffSeconds,
+		TimeoutSeconds: automation.TimeoutSeconds,
+		OutputVariable: automation.OutputVariable,
+	}
+}
+
+func (automation *Automation) Timeout() time.Duration {
+	return time.Duration(automation.TimeoutSeconds) * time.Second
+}
+
+// Queue makes the task available to the automation worker.
+func (automation *Automation) Queue(now time.Time) {
+	automation.Attempts = 0
+	automation.LastError = ""
+	automation.NextAttemptAt = &now
+	automation.FinishedAt = nil
+	automation.LeaseID = ""
+	automation.LeasedUntil = nil
+}
+
+func (automation *Automation) RecordSuccess(now time.Time) {
+	automation.Attempts++
+	automation.LastError = ""
+	automation.NextAttemptAt = nil
+	automation.FinishedAt = &now
+	automation.LeaseID = ""
+	automation.LeasedUntil = nil
+}
+
+// RecordFailure records a failed attempt and schedules the next one. It reports
+// whether the task will be retried.
+func (automation *Automation) RecordFailure(failure error, now time.Time) bool {
+	automation.Attempts++
+	automation.LastError = failure.Error()
+	automation.LeaseID = ""
+	automation.LeasedUntil = nil
+
+	if automation.Attempts >= automation.MaxAttempts {
+		automation.NextAttemptAt = nil
+		automation.FinishedAt = &now
+		return false
+	}
+
+	next := now.Add(retryDelay(automation.BackoffSeconds, automation.Attempts))
+	automation.NextAttemptAt = &next
+
+	return true
+}
+
+// retryDelay doubles the backoff with every failed attempt, up to an hour.
+func retryDelay(backoffSeconds int, attempts int) time.Duration {
+	delay := time.Duration(backoffSeconds) * time.Second
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// RenderBody renders the body template with the run variables. The json
+// function encodes a value, e.g. {{ json .amount }}.
+func (call *HTTPCall) RenderBody(variables map[string]interface{}) (string, error) {
+	body, err := template.New("body").Funcs(bodyFunctions).Option("missingkey=zero").Parse(call.Body)
+	if err != nil {
+		return "", err
+	}
+
+	var rendered bytes.Buffer
+	if err := body.Execute(&rendered, variables); err != nil {
+		return "", err
+	}
+
+	return rendered.String(), nil
+}
+
+func (task *Task) IsAutomated() bool {
+	return task.Type == AutomatedTask && task.Automation != nil
+}
+
+func sameAutomation(a *Automation, b *Automation) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Executor != b.Executor || a.Handler != b.Handler || a.MaxAttempts != b.MaxAttempts ||
+		a.BackoffSeconds != b.BackoffSeconds || a.TimeoutSeconds != b.TimeoutSeconds || a.OutputVariable != b.OutputVariable {
+		return false
+	}
+	if a.HTTP == nil || b.HTTP == nil {
+		return a.HTTP == b.HTTP
+	}
+	if a.HTTP.Method != b.HTTP.Method || a.HTTP.URL != b.HTTP.URL || a.HTTP.Body != b.HTTP.Body || len(a.HTTP.Headers) != len(b.HTTP.Headers) {
+		return false
+	}
+	for name, value := range a.HTTP.Headers {
+		if otherValue, ok := b.HTTP.Headers[name]; !ok || otherValue != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package models
+
+type BatchMode string
+
+const (
+	// AtomicBatch applies every operation or none of them.
+	AtomicBatch BatchMode = "atomic"
+	// BestEffortBatch applies the operations that succeed and reports the
+	// others.
+	BestEffortBatch BatchMode = "best_effort"
+)
+
+type BatchOperation string
+
+const (
+	CreateOperation BatchOperation = "create"
+	UpdateOperation BatchOperation = "update"
+	DeleteOperation BatchOperation = "delete"
+)
+
+type BatchOperationStatus string
+
+const (
+	OperationApplied BatchOperationStatus = "applied"
+	OperationFailed  BatchOperationStatus = "failed"
+	// OperationRolledBack succeeded but was undone with the rest of an atomic
+	// batch.
+	OperationRolledBack BatchOperationStatus = "rolled_back"
+	// OperationSkipped was not tried because an atomic batch had already failed.
+	OperationSkipped BatchOperationStatus = "skipped"
+)
+
+type BatchOperationResult struct {
+	Index  int                  `json:"index"`
+	Op     BatchOperation       `json:"op"`
+	TaskID string               `json:"task_id,omitempty"`
+	Status BatchOperationStatus `json:"status"`
+	Error  string               `json:"error,omitempty"`
+	Task   *Task                `json:"task,omitempty"`
+}
+
+type BatchResult struct {
+	Mode      BatchMode              `json:"mode"`
+	Committed bool                   `json:"committed"`
+	Results   []BatchOperationResult `json:"results"`
+}
+
+// Fail marks the operation at index failed. In an atomic batch the operations
+// before it are rolled back and the ones after it skipped.
+func (result *BatchResult) Fail(index int, err error) {
+	result.Results[index].Status = OperationFailed
+	result.Results[index].Error = err.Error()
+	result.Results[index].Task = nil
+	if result.Mode != AtomicBatch {
+		return
+	}
+
+	for i := range result.Results {
+		switch {
+		case i < index && result.Results[i].Status == OperationApplied:
+			result.Results[i].Status = OperationRolledBack
+			result.Results[i].Task = nil
+		case i > index:
+			result.Results[i].Status = OperationSkipped
+		}
+	}
+}
+
+// Failed tells whether an operation of the batch failed.
+func (result *BatchResult) Failed() bool {
+	for _, operation := range result.Results {
+		if operation.Status == OperationFailed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,682 @@
+package models
+
+import (
+	"encoding/xml"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	BPMNModelNamespace = "http://www.omg.org/spec/BPMN/20100524/MODEL"
+	bpmnDINamespace    = "http://www.omg.org/spec/BPMN/20100524/DI"
+	dcNamespace        = "http://www.omg.org/spec/DD/20100524/DC"
+	diNamespace        = "http://www.omg.org/spec/DD/20100524/DI"
+	xsiNamespace       = "http://www.w3.org/2001/XMLSchema-instance"
+
+	bpmnStartEvent = "StartEvent_1"
+	bpmnEndEvent   = "EndEvent_1"
+)
+
+// IsBPMNContentType tells whether a media type names a BPMN XML document.
+func IsBPMNContentType(contentType string) bool {
+	switch contentType {
+	case "application/bpmn+xml", "application/xml", "text/xml":
+		return true
+	default:
+		return false
+	}
+}
+
+// bpmnNode is an element of a process as read from a BPMN document. Elements
+// of any namespace match, so documents using another prefix read the same.
+type bpmnNode struct {
+	XMLName       xml.Name
+	ID            string     `xml:"id,attr"`
+	Name          string     `xml:"name,attr"`
+	SourceRef     string     `xml:"sourceRef,attr"`
+	TargetRef     string     `xml:"targetRef,attr"`
+	Default       string     `xml:"default,attr"`
+	Documentation []string   `xml:"documentation"`
+	Condition     *string    `xml:"conditionExpression"`
+	Incoming      []string   `xml:"incoming"`
+	Outgoing      []string   `xml:"outgoing"`
+	Children      []bpmnNode `xml:",any"`
+}
+
+type bpmnProcess struct {
+	ID       string     `xml:"id,attr"`
+	Name     string     `xml:"name,attr"`
+	Elements []bpmnNode `xml:",any"`
+}
+
+type bpmnDocument struct {
+	XMLName   xml.Name
+	Name      string        `xml:"name,attr"`
+	Processes []bpmnProcess `xml:"process"`
+	Others    []bpmnNode    `xml:",any"`
+}
+
+// describe names an element in a warning.
+func (node *bpmnNode) describe() string {
+	if node.ID == "" {
+		return node.XMLName.Local
+	}
+	return node.XMLName.Local + " " + strconv.Quote(node.ID)
+}
+
+type bpmnNodeKind int
+
+const (
+	// bpmnPassThrough nodes are dropped and their sequence flows joined, so
+	// that what comes before them leads to what comes after them.
+	bpmnPassThrough bpmnNodeKind = iota
+	bpmnTask
+	bpmnGateway
+)
+
+var bpmnGatewayKinds = map[string]GatewayKind{
+	"exclusiveGateway": ExclusiveGateway,
+	"parallelGateway":  ParallelGateway,
+	"inclusiveGateway": InclusiveGateway,
+}
+
+// bpmnActivities are imported as user tasks, which keeps the process in one
+// piece even though their behaviour is lost.
+var bpmnActivities = map[string]bool{
+	"manualTask":       true,
+	"serviceTask":      true,
+	"scriptTask":       true,
+	"sendTask":         true,
+	"receiveTask":      true,
+	"businessRuleTask": true,
+	"callActivity":     true,
+	"subProcess":       true,
+	"adHocSubProcess":  true,
+	"transaction":      true,
+}
+
+// bpmnFlowNodes are the other flow nodes a workflow has no counterpart for.
+var bpmnFlowNodes = map[string]bool{
+	"intermediateCatchEvent": true,
+	"intermediateThrowEvent": true,
+	"boundaryEvent":          true,
+	"eventBasedGateway":      true,
+	"complexGateway":         true,
+}
+
+// ParseBPMN reads the first process of a BPMN 2.0 document as a workflow
+// definition. User tasks, exclusive, parallel and inclusive gateways, start and
+// end events and sequence flows carry over; BPMN ids become task keys. Anything
+// else is reported as a warning, never dropped silently. Loops cannot be
+// expressed by a workflow, so the flow closing a loop is cut with a warning.
+func ParseBPMN(document []byte) (*WorkflowDefinition, []string, error) {
+	var parsed bpmnDocument
+	if err := xml.Unmarshal(document, &parsed); err != nil {
+		return nil, nil, errors.New("invalid bpmn document")
+	}
+	if parsed.XMLName.Local != "definitions" || parsed.XMLName.Space != BPMNModelNamespace {
+		return nil, nil, errors.New("not a bpmn document")
+	}
+	if len(parsed.Processes) == 0 {
+		return nil, nil, errors.New("bpmn document has no process")
+	}
+
+	warnings := []string{}
+	for _, other := range parsed.Processes[1:] {
+		warnings = append(warnings, "only the first process is imported; process "+strconv.Quote(other.ID)+" was skipped")
+	}
+	for _, other := range parsed.Others {
+		// The diagram only lays out the process.
+		if other.XMLName.Space == bpmnDINamespace {
+			continue
+		}
+		warnings = append(warnings, "unsupported BPMN element "+other.describe()+" was skipped")
+	}
+
+	process := parsed.Processes[0]
+	kinds := map[string]bpmnNodeKind{}
+	nodes := []*bpmnNode{}
+	flows := []*bpmnNode{}
+	for i := range process.Elements {
+		element := &process.Elements[i]
+		local := element.XMLName.Local
+		var kind bpmnNodeKind
+		switch {
+		case local == "sequenceFlow":
+			flows = append(flows, element)
+			continue
+		case local == "userTask" || local == "task":
+			kind = bpmnTask
+		case bpmnGatewayKinds[local] != "":
+			kind = bpmnGateway
+		case bpmnActivities[local]:
+			kind = bpmnTask
+			warnings = append(warnings, element.describe()+" is imported as a user task")
+		case local == "startEvent" || local == "endEvent":
+			kind = bpmnPassThrough
+		case bpmnFlowNodes[local]:
+			kind = bpmnPassThrough
+			warnings = append(warnings, "unsupported BPMN element "+element.describe()+" was skipped; its sequence flows are joined")
+		default:
+			warnings = append(warnings, "unsupported BPMN element "+element.describe()+" was skipped")
+			continue
+		}
+
+		if element.ID == "" {
+			return nil, nil, errors.New("bpmn element without id: " + local)
+		}
+		if _, ok := kinds[element.ID]; ok {
+			return nil, nil, errors.New("duplicate bpmn element id: " + element.ID)
+		}
+		kinds[element.ID] = kind
+		for _, child := range element.Children {
+			warnings = append(warnings, child.XMLName.Local+" of "+element.describe()+" is ignored")
+		}
+		nodes = append(nodes, element)
+	}
+
+	outgoing := map[string][]*bpmnNode{}
+	for _, flow := range flows {
+		_, knownSource := kinds[flow.SourceRef]
+		_, knownTarget := kinds[flow.TargetRef]
+		if !knownSource || !knownTarget {
+			warnings = append(warnings, flow.describe()+" connects elements that were not imported and was skipped")
+			continue
+		}
+		if flow.Condition != nil && kinds[flow.SourceRef] != bpmnGateway {
+			warnings = append(warnings, "condition of "+flow.describe()+" is ignored")
+		}
+		outgoing[flow.SourceRef] = append(outgoing[flow.SourceRef], flow)
+	}
+
+	// forward follows a flow through pass-through nodes to the tasks and
+	// gateways it leads to.
+	var forward func(id string, visited map[string]bool) []string
+	forward = func(id string, visited map[string]bool) []string {
+		if kinds[id] != bpmnPassThrough {
+			return []string{id}
+		}
+		if visited[id] {
+			return nil
+		}
+		visited[id] = true
+		targets := []string{}
+		for _, flow := range outgoing[id] {
+			targets = append(targets, forward(flow.TargetRef, visited)...)
+		}
+		return targets
+	}
+
+	definitions := map[string]*TaskDefinition{}
+	for _, node := range nodes {
+		if kinds[node.ID] == bpmnPassThrough {
+			continue
+		}
+		name := strings.Join(strings.Fields(node.Name), " ")
+		if name == "" {
+			name = node.ID
+		}
+		task := &TaskDefinition{
+			Key:         node.ID,
+			Name:        name,
+			Description: strings.TrimSpace(strings.Join(node.Documentation, "\n")),
+			Type:        UserTask,
+		}
+		if kinds[node.ID] == bpmnGateway {
+			task.Type = GatewayTask
+			task.Gateway = &TemplateGateway{Kind: bpmnGatewayKinds[node.XMLName.Local], Flows: []TemplateGatewayFlow{}}
+		}
+		definitions[node.ID] = task
+	}
+
+	for _, node := range nodes {
+		source, ok := definitions[node.ID]
+		if !ok {
+			continue
+		}
+		for _, flow := range outgoing[node.ID] {
+			targets := forward(flow.TargetRef, map[string]bool{})
+			if source.Gateway != nil && len(targets) == 0 {
+				warnings = append(warnings, flow.describe()+" leads from gateway "+strconv.Quote(node.ID)+" straight to an end and was dropped")
+			}
+			for _, target := range targets {
+				if source.Gateway == nil {
+					addDependency(definitions[target], node.ID)
+					continue
+				}
+				condition := ""
+				if flow.Condition != nil && flow.ID != node.Default {
+					condition = bpmnCondition(*flow.Condition)
+				}
+				addGatewayFlow(source.Gateway, target, condition)
+			}
+		}
+	}
+
+	ordered, cut := orderBPMNTasks(nodes, definitions)
+	for _, key := range cut {
+		warnings = append(warnings, "a loop back to "+strconv.Quote(key)+" cannot be expressed by a workflow and was cut")
+	}
+
+	name := strings.TrimSpace(process.Name)
+	if name == "" {
+		name = strings.TrimSpace(parsed.Name)
+	}
+	if name == "" {
+		name = process.ID
+	}
+
+	return &WorkflowDefinition{
+		Version:  WorkflowDefinitionVersion,
+		Metadata: DefinitionMetadata{Name: name},
+		Tasks:    ordered,
+	}, warnings, nil
+}
+
+// bpmnCondition strips the ${...} or #{...} wrapper BPMN tools put around
+// expressions.
+func bpmnCondition(condition string) string {
+	condition = strings.TrimSpace(condition)
+	if (strings.HasPrefix(condition, "${") || strings.HasPrefix(condition, "#{")) && strings.HasSuffix(condition, "}") {
+		condition = strings.TrimSpace(condition[2 : len(condition)-1])
+	}
+	return condition
+}
+
+func addDependency(task *TaskDefinition, key string) {
+	if !containsString(task.DependsOn, key) {
+		task.DependsOn = append(task.DependsOn, key)
+	}
+}
+
+func addGatewayFlow(gateway *TemplateGateway, target string, condition string) {
+	for _, flow := range gateway.Flows {
+		if flow.Target == target {
+			return
+		}
+	}
+	gateway.Flows = append(gateway.Flows, TemplateGatewayFlow{Target: target, Condition: condition})
+}
+
+// orderBPMNTasks orders the tasks so that every task comes after the tasks it
+// waits for, keeping the document order otherwise. When only tasks waiting on
+// each other are left, the first of them has its waits on the others cut,
+// which breaks the loop; the keys of those tasks are returned.
+func orderBPMNTasks(nodes []*bpmnNode, definitions map[string]*TaskDefinition) ([]TaskDefinition, []string) {
+	keys := []string{}
+	for _, node := range nodes {
+		if _, ok := definitions[node.ID]; ok {
+			keys = append(keys, node.ID)
+		}
+	}
+
+	sources := map[string][]string{}
+	for _, key := range keys {
+		task := definitions[key]
+		sources[key] = append(sources[key], task.DependsOn...)
+		if task.Gateway != nil {
+			for _, flow := range task.Gateway.Flows {
+				sources[flow.Target] = append(sources[flow.Target], key)
+			}
+		}
+	}
+
+	placed := map[string]bool{}
+	ready := func(key string) bool {
+		for _, source := range sources[key] {
+			if !placed[source] {
+				return false
+			}
+		}
+		return true
+	}
+
+	ordered := make([]TaskDefinition, 0, len(keys))
+	cut := []string{}
+	for len(ordered) < len(keys) {
+		next := ""
+		for _, key := range keys {
+			if !placed[key] && ready(key) {
+				next = key
+				break
+			}
+		}
+		if next == "" {
+			for _, key := range keys {
+				if !placed[key] {
+					next = key
+					break
+				}
+			}
+			for _, source := range sources[next] {
+				if placed[source] {
+					continue
+				}
+				definitions[next].DependsOn = removeString(definitions[next].DependsOn, source)
+				if gateway := definitions[source].Gateway; gateway != nil {
+					flows := []TemplateGatewayFlow{}
+					for _, flow := range gateway.Flows {
+						if flow.Target != next {
+							flows = append(flows, flow)
+						}
+					}
+					gateway.Flows = flows
+				}
+			}
+			cut = append(cut, next)
+		}
+		placed[next] = true
+		ordered = append(ordered, *definitions[next])
+	}
+
+	return ordered, cut
+}
+
+func removeString(values []string, value string) []string {
+	kept := []string{}
+	for _, v := range values {
+		if v != value {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+type bpmnOutCondition struct {
+	XMLName xml.Name `xml:"bpmn:conditionExpression"`
+	Type    string   `xml:"xsi:type,attr"`
+	Body    string   `xml:",chardata"`
+}
+
+type bpmnOutFlow struct {
+	XMLName   xml.Name          `xml:"bpmn:sequenceFlow"`
+	ID        string            `xml:"id,attr"`
+	SourceRef string            `xml:"sourceRef,attr"`
+	TargetRef string            `xml:"targetRef,attr"`
+	Condition *bpmnOutCondition `xml:",omitempty"`
+}
+
+type bpmnOutNode struct {
+	XMLName       xml.Name
+	ID            string   `xml:"id,attr"`
+	Name          string   `xml:"name,attr,omitempty"`
+	Default       string   `xml:"default,attr,omitempty"`
+	Documentation string   `xml:"bpmn:documentation,omitempty"`
+	Incoming      []string `xml:"bpmn:incoming"`
+	Outgoing      []string `xml:"bpmn:outgoing"`
+}
+
+type bpmnOutProcess struct {
+	XMLName      xml.Name `xml:"bpmn:process"`
+	ID           string   `xml:"id,attr"`
+	Name         string   `xml:"name,attr,omitempty"`
+	IsExecutable bool     `xml:"isExecutable,attr"`
+	Nodes        []bpmnOutNode
+	Flows        []bpmnOutFlow
+}
+
+type bpmnOutBounds struct {
+	XMLName xml.Name `xml:"dc:Bounds"`
+	X       int      `xml:"x,attr"`
+	Y       int      `xml:"y,attr"`
+	Width   int      `xml:"width,attr"`
+	Height  int      `xml:"height,attr"`
+}
+
+type bpmnOutShape struct {
+	XMLName     xml.Name `xml:"bpmndi:BPMNShape"`
+	ID          string   `xml:"id,attr"`
+	BPMNElement string   `xml:"bpmnElement,attr"`
+	Bounds      bpmnOutBounds
+}
+
+type bpmnOutWaypoint struct {
+	XMLName xml.Name `xml:"di:waypoint"`
+	X       int      `xml:"x,attr"`
+	Y       int      `xml:"y,attr"`
+}
+
+type bpmnOutEdge struct {
+	XMLName     xml.Name `xml:"bpmndi:BPMNEdge"`
+	ID          string   `xml:"id,attr"`
+	BPMNElement string   `xml:"bpmnElement,attr"`
+	Waypoints   []bpmnOutWaypoint
+}
+
+type bpmnOutPlane struct {
+	XMLName     xml.Name `xml:"bpmndi:BPMNPlane"`
+	ID          string   `xml:"id,attr"`
+	BPMNElement string   `xml:"bpmnElement,attr"`
+	Shapes      []bpmnOutShape
+	Edges       []bpmnOutEdge
+}
+
+type bpmnOutDiagram struct {
+	XMLName xml.Name `xml:"bpmndi:BPMNDiagram"`
+	ID      string   `xml:"id,attr"`
+	Plane   bpmnOutPlane
+}
+
+type bpmnOutDocument struct {
+	XMLName         xml.Name `xml:"bpmn:definitions"`
+	BPMN            string   `xml:"xmlns:bpmn,attr"`
+	BPMNDI          string   `xml:"xmlns:bpmndi,attr"`
+	DC              string   `xml:"xmlns:dc,attr"`
+	DI              string   `xml:"xmlns:di,attr"`
+	XSI             string   `xml:"xmlns:xsi,attr"`
+	ID              string   `xml:"id,attr"`
+	TargetNamespace string   `xml:"targetNamespace,attr"`
+	Process         bpmnOutProcess
+	Diagram         bpmnOutDiagram
+}
+
+// Sizes and spacing of the diagram, close to what BPMN modelers draw.
+const (
+	bpmnColumnWidth = 200
+	bpmnRowHeight   = 140
+	bpmnMargin      = 100
+)
+
+var bpmnShapeSizes = map[string][2]int{
+	"bpmn:startEvent":  {36, 36},
+	"bpmn:endEvent":    {36, 36},
+	"bpmn:userTask":    {100, 80},
+	"bpmn:serviceTask": {100, 80},
+}
+
+// EncodeBPMN writes a workflow definition as a BPMN 2.0 document with a diagram
+// laid out left to right. User and approval tasks become user tasks, automated
+// tasks service tasks and gateways the gateways of their kind. A task waits
+// for all of its predecessors; their flows are drawn into the task itself.
+// Approvals, automations, forms, SLAs, checklists, assignees and labels have no
+// BPMN counterpart and are left out.
+func EncodeBPMN(definition WorkflowDefinition) ([]byte, error) {
+	type edge struct {
+		source, target, condition string
+	}
+
+	edges := []edge{}
+	edgeIndex := map[[2]string]int{}
+	addEdge := func(source string, target string, condition string) {
+		if i, ok := edgeIndex[[2]string{source, target}]; ok {
+			if condition != "" {
+				edges[i].condition = condition
+			}
+			return
+		}
+		edgeIndex[[2]string{source, target}] = len(edges)
+		edges = append(edges, edge{source: source, target: target, condition: condition})
+	}
+
+	keys := map[string]bool{}
+	for _, task := range definition.Tasks {
+		keys[task.Key] = true
+	}
+	for _, task := range definition.Tasks {
+		for _, dependency := range task.DependsOn {
+			if keys[dependency] {
+				addEdge(dependency, task.Key, "")
+			}
+		}
+		if task.Gateway != nil {
+			for _, flow := range task.Gateway.Flows {
+				if keys[flow.Target] {
+					addEdge(task.Key, flow.Target, flow.Condition)
+				}
+			}
+		}
+	}
+
+	hasIncoming := map[string]bool{}
+	hasOutgoing := map[string]bool{}
+	for _, e := range edges {
+		hasOutgoing[e.source] = true
+		hasIncoming[e.target] = true
+	}
+	all := []edge{}
+	for _, task := range definition.Tasks {
+		if !hasIncoming[task.Key] {
+			all = append(all, edge{source: bpmnStartEvent, target: task.Key})
+		}
+	}
+	all = append(all, edges...)
+	for _, task := range definition.Tasks {
+		if !hasOutgoing[task.Key] {
+			all = append(all, edge{source: task.Key, target: bpmnEndEvent})
+		}
+	}
+	if len(definition.Tasks) == 0 {
+		all = append(all, edge{source: bpmnStartEvent, target: bpmnEndEvent})
+	}
+
+	nodes := []bpmnOutNode{{XMLName: xml.Name{Local: "bpmn:startEvent"}, ID: bpmnStartEvent}}
+	for _, task := range definition.Tasks {
+		node := bpmnOutNode{ID: task.Key, Name: task.Name, Documentation: task.Description}
+		switch {
+		case task.Gateway != nil:
+			node.XMLName.Local = "bpmn:" + strings.ToLower(string(task.Gateway.Kind)) + "Gateway"
+		case task.Type == AutomatedTask:
+			node.XMLName.Local = "bpmn:serviceTask"
+		default:
+			node.XMLName.Local = "bpmn:userTask"
+		}
+		nodes = append(nodes, node)
+	}
+	nodes = append(nodes, bpmnOutNode{XMLName: xml.Name{Local: "bpmn:endEvent"}, ID: bpmnEndEvent})
+
+	nodeIndex := map[string]int{}
+	for i, node := range nodes {
+		nodeIndex[node.ID] = i
+	}
+
+	flows := make([]bpmnOutFlow, 0, len(all))
+	for i, e := range all {
+		id := "Flow_" + strconv.Itoa(i+1)
+		flow := bpmnOutFlow{ID: id, SourceRef: e.source, TargetRef: e.target}
+		if e.condition != "" {
+			flow.Condition = &bpmnOutCondition{Type: "bpmn:tFormalExpression", Body: e.condition}
+		}
+		flows = append(flows, flow)
+
+		source := &nodes[nodeIndex[e.source]]
+		source.Outgoing = append(source.Outgoing, id)
+		target := &nodes[nodeIndex[e.target]]
+		target.Incoming = append(target.Incoming, id)
+	}
+
+	// The flow without a condition is the default path of a deciding gateway.
+	for _, task := range definition.Tasks {
+		if task.Gateway == nil || task.Gateway.Kind == ParallelGateway {
+			continue
+		}
+		for _, flow := range flows {
+			if flow.SourceRef == task.Key && flow.Condition == nil {
+				nodes[nodeIndex[task.Key]].Default = flow.ID
+				break
+			}
+		}
+	}
+
+	process := bpmnOutProcess{ID: "Process_1", Name: definition.Metadata.Name, Nodes: nodes, Flows: flows}
+	document := bpmnOutDocument{
+		BPMN:            BPMNModelNamespace,
+		BPMNDI:          bpmnDINamespace,
+		DC:              dcNamespace,
+		DI:              diNamespace,
+		XSI:             xsiNamespace,
+		ID:              "Definitions_1",
+		TargetNamespace: "http://bpmn.io/schema/bpmn",
+		Process:         process,
+		Diagram: bpmnOutDiagram{
+			ID:    "BPMNDiagram_1",
+			Plane: layoutBPMN(nodes, flows),
+		},
+	}
+
+	encoded, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, errors.New("failed to encode bpmn document")
+	}
+
+	return append([]byte(xml.Header), append(encoded, '\n')...), nil
+}
+
+// layoutBPMN places every node one column right of the furthest node leading
+// to it and stacks the nodes of a column in process order. Flows leave their
+// source on the right and enter their target on the left.
+func layoutBPMN(nodes []bpmnOutNode, flows []bpmnOutFlow) bpmnOutPlane {
+	columns := make(map[string]int, len(nodes))
+	// Longest paths settle within one pass per node, even around a loop.
+	for range nodes {
+		for _, flow := range flows {
+			if column := columns[flow.SourceRef] + 1; column > columns[flow.TargetRef] && column < len(nodes) {
+				columns[flow.TargetRef] = column
+			}
+		}
+	}
+	last := 0
+	for _, column := range columns {
+		if column > last {
+			last = column
+		}
+	}
+	columns[bpmnEndEvent] = last
+
+	rows := map[int]int{}
+	centers := make(map[string][2]int, len(nodes))
+	sizes := make(map[string][2]int, len(nodes))
+	plane := bpmnOutPlane{ID: "BPMNPlane_1", BPMNElement: "Process_1"}
+	for _, node := range nodes {
+		size, ok := bpmnShapeSizes[node.XMLName.Local]
+		if !ok {
+			size = [2]int{50, 50}
+		}
+		column := columns[node.ID]
+		center := [2]int{bpmnMargin + column*bpmnColumnWidth, bpmnMargin + rows[column]*bpmnRowHeight}
+		rows[column]++
+		centers[node.ID] = center
+		sizes[node.ID] = size
+
+		plane.Shapes = append(plane.Shapes, bpmnOutShape{
+			ID:          node.ID + "_di",
+			BPMNElement: node.ID,
+			Bounds:      bpmnOutBounds{X: center[0] - size[0]/2, Y: center[1] - size[1]/2, Width: size[0], Height: size[1]},
+		})
+	}
+
+	for _, flow := range flows {
+		source, target := centers[flow.SourceRef], centers[flow.TargetRef]
+		start := [2]int{source[0] + sizes[flow.SourceRef][0]/2, source[1]}
+		end := [2]int{target[0] - sizes[flow.TargetRef][0]/2, target[1]}
+		waypoints := []bpmnOutWaypoint{{X: start[0], Y: start[1]}}
+		if start[1] != end[1] {
+			middle := (start[0] + end[0]) / 2
+			waypoints = append(waypoints, bpmnOutWaypoint{X: middle, Y: start[1]}, bpmnOutWaypoint{X: middle, Y: end[1]})
+		}
+		waypoints = append(waypoints, bpmnOutWaypoint{X: end[0], Y: end[1]})
+		plane.Edges = append(plane.Edges, bpmnOutEdge{ID: flow.ID + "_di", BPMNElement: flow.ID, Waypoints: waypoints})
+	}
+
+	return plane
+}
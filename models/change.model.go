@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// ChangeEvent tells clients watching a workflow that it or one of its tasks
+// changed. Type is the name of the domain event and Data its payload.
+type ChangeEvent struct {
+	ID         string      `json:"id"`
+	Type       EventName   `json:"type"`
+	WorkflowID string      `json:"workflow_id"`
+	Data       interface{} `json:"data,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
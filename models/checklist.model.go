@@ -0,0 +1,130 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxChecklistItems bounds the checklist of a task.
+const MaxChecklistItems = 100
+
+// ChecklistItem is a step of a task. A task cannot be completed while one of
+// its required items is not done.
+type ChecklistItem struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id"`
+	Text     string             `json:"text" bson:"text"`
+	Done     bool               `json:"done" bson:"done"`
+	Required bool               `json:"required" bson:"required"`
+	Assignee string             `json:"assignee,omitempty" bson:"assignee,omitempty"`
+	DoneBy   string             `json:"done_by,omitempty" bson:"done_by,omitempty"`
+	DoneAt   *time.Time         `json:"done_at,omitempty" bson:"done_at,omitempty"`
+}
+
+func (task *Task) checklistIndex(itemID primitive.ObjectID) int {
+	for i := range task.Checklist {
+		if task.Checklist[i].ID == itemID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (task *Task) AddChecklistItem(item ChecklistItem) error {
+	if len(task.Checklist) >= MaxChecklistItems {
+		return errors.New("checklist is full")
+	}
+
+	item.ID = primitive.NewObjectID()
+	item.Done = false
+	task.Checklist = append(task.Checklist, item)
+	return nil
+}
+
+// ToggleChecklistItem marks an item done by the user, or not done again.
+func (task *Task) ToggleChecklistItem(itemID primitive.ObjectID, username string, now time.Time) error {
+	index := task.checklistIndex(itemID)
+	if index == -1 {
+		return errors.New("checklist item does not exist")
+	}
+
+	item := &task.Checklist[index]
+	item.Done = !item.Done
+	if item.Done {
+		item.DoneBy = username
+		item.DoneAt = &now
+	} else {
+		item.DoneBy = ""
+		item.DoneAt = nil
+	}
+	return nil
+}
+
+// ReorderChecklist puts the items in the given order. Every item must be
+// listed exactly once.
+func (task *Task) ReorderChecklist(itemIDs []primitive.ObjectID) error {
+	if len(itemIDs) != len(task.Checklist) {
+		return errors.New("checklist order must list every item once")
+	}
+
+	checklist := make([]ChecklistItem, 0, len(itemIDs))
+	seen := map[primitive.ObjectID]bool{}
+	for _, itemID := range itemIDs {
+		index := task.checklistIndex(itemID)
+		if index == -1 || seen[itemID] {
+			return errors.New("checklist order must list every item once")
+		}
+		seen[itemID] = true
+		checklist = append(checklist, task.Checklist[index])
+	}
+
+	task.Checklist = checklist
+	return nil
+}
+
+func (task *Task) RemoveChecklistItem(itemID primitive.ObjectID) error {
+	index := task.checklistIndex(itemID)
+	if index == -1 {
+		return errors.New("checklist item does not exist")
+	}
+
+	task.Checklist = append(task.Checklist[:index], task.Checklist[index+1:]...)
+	return nil
+}
+
+// CheckChecklistDone fails when a required checklist item is not done, so the
+// task cannot be completed before it.
+func (task *Task) CheckChecklistDone() error {
+	for _, item := range task.Checklist {
+		if item.Required && !item.Done {
+			return errors.New("required checklist item is not done: " + item.Text)
+		}
+	}
+	return nil
+}
+
+// EvaluateChecklist sets ChecklistProgress to the percentage of done items.
+// Tasks without a checklist have no progress.
+func (task *Task) EvaluateChecklist() {
+	if len(task.Checklist) == 0 {
+		task.ChecklistProgress = nil
+		return
+	}
+
+	done := 0
+	for _, item := range task.Checklist {
+		if item.Done {
+			done++
+		}
+	}
+	progress := done * 100 / len(task.Checklist)
+	task.ChecklistProgress = &progress
+}
+
+// EvaluateChecklists sets the checklist progress of every task.
+func EvaluateChecklists(tasks []Task) {
+	for i := range tasks {
+		tasks[i].EvaluateChecklist()
+	}
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"virtual_workflow_management_system_gin/common"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxMentions bounds the number of users a comment can mention.
+const MaxMentions = 20
+
+// Comment is a Markdown comment on a task. A reply points to the comment it
+// answers. A deleted comment keeps its place in the thread without its text.
+type Comment struct {
+	common.BaseModel `bson:",inline"`
+	WorkflowID       primitive.ObjectID  `json:"workflow_id" bson:"workflow_id"`
+	TaskID           primitive.ObjectID  `json:"task_id" bson:"task_id"`
+	ParentID         *primitive.ObjectID `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
+	Author           string              `json:"author" bson:"author"`
+	Body             string              `json:"body" bson:"body"`
+	Mentions         []string            `json:"mentions" bson:"mentions"`
+	Edited           bool                `json:"edited" bson:"edited"`
+	Deleted          bool                `json:"deleted" bson:"deleted"`
+	Replies          []Comment           `json:"replies,omitempty" bson:"-"`
+}
+
+// CheckCommentAccess tells whether the user may edit or delete the comment.
+func (comment *Comment) CheckCommentAccess(user JWTUser) bool {
+	return user.Username == comment.Author
+}
+
+var (
+	mentionPattern = regexp.MustCompile(`(^|[^\w@])@([A-Za-z0-9_.\-]+)`)
+	codePattern    = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+)
+
+// ParseMentions returns the users mentioned with @username in a Markdown text,
+// in the order they are first mentioned. Mentions inside code are ignored.
+func ParseMentions(body string) []string {
+	mentions := []string{}
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(codePattern.ReplaceAllString(body, " "), -1) {
+		// A mention may end a sentence.
+		username := strings.TrimRight(match[2], ".-")
+		if username == "" || seen[username] {
+			continue
+		}
+		seen[username] = true
+		mentions = append(mentions, username)
+	}
+	return mentions
+}
+
+// BuildCommentThreads nests replies under the comments they answer. Deleted
+// comments without replies are left out.
+func BuildCommentThreads(comments []Comment) []Comment {
+	children := map[primitive.ObjectID][]Comment{}
+	roots := []Comment{}
+	for _, comment := range comments {
+		if comment.ParentID == nil {
+			roots = append(roots, comment)
+		} else {
+			children[*comment.ParentID] = append(children[*comment.ParentID], comment)
+		}
+	}
+
+	var nest func(comments []Comment) []Comment
+	nest = func(comments []Comment) []Comment {
+		threads := []Comment{}
+		for _, comment := range comments {
+			comment.Replies = nest(children[comment.ID])
+			if comment.Deleted && len(comment.Replies) == 0 {
+				continue
+			}
+			threads = append(threads, comment)
+		}
+		return threads
+	}
+
+	return nest(roots)
+}
+
+// TaskStatusChange records a task moving to another status. Its ID is the ID
+// of the event that reported the change.
+type TaskStatusChange struct {
+	ID             string             `json:"id" bson:"_id"`
+	WorkflowID     primitive.ObjectID `json:"workflow_id" bson:"workflow_id"`
+	TaskID         primitive.ObjectID `json:"task_id" bson:"task_id"`
+	PreviousStatus TaskStatus         `json:"previous_status" bson:"previous_status"`
+	Status         TaskStatus         `json:"status" bson:"status"`
+	ChangedAt      time.Time          `json:"changed_at" bson:"changed_at"`
+}
+
+type ActivityType string
+
+const (
+	CommentActivity      ActivityType = "comment"
+	StatusChangeActivity ActivityType = "status_change"
+)
+
+// Activity is an entry of the activity feed of a task.
+type Activity struct {
+	Type         ActivityType      `json:"type"`
+	At           time.Time         `json:"at"`
+	Comment      *Comment          `json:"comment,omitempty"`
+	StatusChange *TaskStatusChange `json:"status_change,omitempty"`
+}
+
+// BuildActivityFeed interleaves comments and status changes in the order
+// they happened. Deleted comments are left out.
+func BuildActivityFeed(comments []Comment, changes []TaskStatusChange) []Activity {
+	feed := []Activity{}
+	for i := range comments {
+		if comments[i].Deleted {
+			continue
+		}
+		feed = append(feed, Activity{Type: CommentActivity, At: comments[i].CreatedAt, Comment: &comments[i]})
+	}
+	for i := range changes {
+		feed = append(feed, Activity{Type: StatusChangeActivity, At: changes[i].ChangedAt, StatusChange: &changes[i]})
+	}
+
+	sort.SliceStable(feed, func(i, j int) bool {
+		return feed[i].At.Before(feed[j].At)
+	})
+	return feed
+}
@@ -0,0 +1,332 @@
+package models
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+	"unicode/utf8"
+	"virtual_workflow_management_system_gin/common"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WorkflowDefinitionVersion is the version of the definition format written by
+// export. Import only reads definitions of this version.
+const WorkflowDefinitionVersion = 1
+
+// MaxWorkflowDefinitionSize bounds the documents read by import.
+const MaxWorkflowDefinitionSize = 1 << 20
+
+// WorkflowDefinitionSchema is the JSON Schema of the definition format.
+//
+//go:embed workflow-definition.schema.json
+var WorkflowDefinitionSchema []byte
+
+// DefinitionMetadata describes the exported workflow.
+type DefinitionMetadata struct {
+	Name       string     `json:"name"`
+	Labels     []string   `json:"labels,omitempty"`
+	ExportedAt *time.Time `json:"exported_at,omitempty"`
+}
+
+type ApprovalDefinition struct {
+	Approvers []Approver `json:"approvers"`
+	Quorum    QuorumRule `json:"quorum"`
+	Required  int        `json:"required,omitempty"`
+	Variable  string     `json:"variable,omitempty"`
+}
+
+type AutomationDefinition struct {
+	Executor       ExecutorKind `json:"executor"`
+	HTTP           *HTTPCall    `json:"http,omitempty"`
+	Handler        string       `json:"handler,omitempty"`
+	MaxAttempts    int          `json:"max_attempts"`
+	BackoffSeconds int          `json:"backoff_seconds"`
+	TimeoutSeconds int          `json:"timeout_seconds"`
+	OutputVariable string       `json:"output_variable,omitempty"`
+}
+
+type SLADefinition struct {
+	DurationSeconds int64            `json:"duration_seconds"`
+	AtRiskPercent   int              `json:"at_risk_percent"`
+	Escalation      []EscalationStep `json:"escalation,omitempty"`
+}
+
+type ChecklistItemDefinition struct {
+	Text     string `json:"text" bson:"text"`
+	Required bool   `json:"required,omitempty" bson:"required,omitempty"`
+	Assignee string `json:"assignee,omitempty" bson:"assignee,omitempty"`
+}
+
+// TaskDefinition is a task without its run state. Dependencies and gateway
+// flows refer to the keys of other tasks, never to their IDs.
+type TaskDefinition struct {
+	Key         string                    `json:"key"`
+	Name        string                    `json:"name"`
+	Description string                    `json:"description,omitempty"`
+	Type        TaskType                  `json:"type"`
+	DependsOn   []string                  `json:"depends_on,omitempty"`
+	Gateway     *TemplateGateway          `json:"gateway,omitempty"`
+	Approval    *ApprovalDefinition       `json:"approval,omitempty"`
+	Automation  *AutomationDefinition     `json:"automation,omitempty"`
+	Form        []FieldDefinition         `json:"form,omitempty"`
+	Assignee    string                    `json:"assignee,omitempty"`
+	SLA         *SLADefinition            `json:"sla,omitempty"`
+	Checklist   []ChecklistItemDefinition `json:"checklist,omitempty"`
+	Labels      []string                  `json:"labels,omitempty"`
+}
+
+// WorkflowDefinition is the portable form of a workflow. Tasks are listed in
+// their order. Run state, i.e. statuses, votes, attempts, SLA clocks, form
+// values and variables, is left out, so an imported workflow starts afresh.
+type WorkflowDefinition struct {
+	Version             int                `json:"version"`
+	Metadata            DefinitionMetadata `json:"metadata"`
+	VariableDefinitions []FieldDefinition  `json:"variable_definitions,omitempty"`
+	Tasks               []TaskDefinition   `json:"tasks"`
+}
+
+// NewWorkflowDefinition captures the definition of a workflow. Task keys are
+// derived from the task order, like the keys of a template saved from it.
+func NewWorkflowDefinition(workflow Workflow, exportedAt time.Time) WorkflowDefinition {
+	template := NewTemplateFromWorkflow(workflow)
+	tasks := NormalizeTaskOrders(append([]Task{}, workflow.Tasks...))
+
+	definitions := make([]TaskDefinition, 0, len(template.Tasks))
+	for i, templateTask := range template.Tasks {
+		task := tasks[i]
+		definition := TaskDefinition{
+			Key:         templateTask.Key,
+			Name:        templateTask.Name,
+			Description: templateTask.Description,
+			Type:        templateTask.Type,
+			DependsOn:   templateTask.DependsOn,
+			Gateway:     templateTask.Gateway,
+			Form:        templateTask.Form,
+			Assignee:    task.Assignee,
+			Checklist:   templateTask.Checklist,
+			Labels:      templateTask.Labels,
+		}
+		if approval := templateTask.Approval; approval != nil {
+			definition.Approval = &ApprovalDefinition{
+				Approvers: approval.Approvers,
+				Quorum:    approval.Quorum,
+				Required:  approval.Required,
+				Variable:  approval.Variable,
+			}
+		}
+		if automation := templateTask.Automation; automation != nil {
+			definition.Automation = &AutomationDefinition{
+				Executor:       automation.Executor,
+				HTTP:           automation.HTTP,
+				Handler:        automation.Handler,
+				MaxAttempts:    automation.MaxAttempts,
+				BackoffSeconds: automation.BackoffSeconds,
+				TimeoutSeconds: automation.TimeoutSeconds,
+				OutputVariable: automation.OutputVariable,
+			}
+		}
+		if sla := templateTask.SLA; sla != nil {
+			definition.SLA = &SLADefinition{
+				DurationSeconds: sla.DurationSeconds,
+				AtRiskPercent:   sla.AtRiskPercent,
+				Escalation:      sla.Steps,
+			}
+		}
+		definitions = append(definitions, definition)
+	}
+
+	exportedAt = exportedAt.UTC().Truncate(time.Second)
+	return WorkflowDefinition{
+		Version: WorkflowDefinitionVersion,
+		Metadata: DefinitionMetadata{
+			Name:       workflow.Name,
+			Labels:     workflow.Labels,
+			ExportedAt: &exportedAt,
+		},
+		VariableDefinitions: workflow.VariableDefinitions,
+		Tasks:               definitions,
+	}
+}
+
+// ParseWorkflowDefinition reads a definition from a JSON or YAML document.
+// Unknown fields are rejected, so that a misspelt setting is not dropped
+// silently.
+func ParseWorkflowDefinition(contentType string, document []byte) (*WorkflowDefinition, error) {
+	if common.IsYAMLContentType(contentType) {
+		converted, err := common.YAMLToJSON(document)
+		if err != nil {
+			return nil, err
+		}
+		document = converted
+	}
+
+	var definition WorkflowDefinition
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&definition); err != nil {
+		return nil, errors.New("invalid workflow definition")
+	}
+
+	return &definition, nil
+}
+
+// Usernames returns the users the definition refers to as assignees, each once
+// and in order. Approvers are matched when votes are cast and may name users
+// that do not exist yet.
+func (definition *WorkflowDefinition) Usernames() []string {
+	usernames := []string{}
+	for _, task := range definition.Tasks {
+		if task.Assignee != "" && !containsString(usernames, task.Assignee) {
+			usernames = append(usernames, task.Assignee)
+		}
+		for _, item := range task.Checklist {
+			if item.Assignee != "" && !containsString(usernames, item.Assignee) {
+				usernames = append(usernames, item.Assignee)
+			}
+		}
+	}
+	return usernames
+}
+
+// template turns the definition into a template, which checks the keys and
+// the references between tasks and builds the workflow.
+func (definition *WorkflowDefinition) template() Template {
+	tasks := make([]TemplateTask, 0, len(definition.Tasks))
+	for i, task := range definition.Tasks {
+		templateTask := TemplateTask{
+			Key:         task.Key,
+			Name:        task.Name,
+			Description: task.Description,
+			Order:       i + 1,
+			DependsOn:   task.DependsOn,
+			Type:        task.Type,
+			Gateway:     task.Gateway,
+			Form:        task.Form,
+			Checklist:   task.Checklist,
+			Labels:      task.Labels,
+		}
+		if task.Approval != nil {
+			templateTask.Approval = &Approval{
+				Approvers: task.Approval.Approvers,
+				Quorum:    task.Approval.Quorum,
+				Required:  task.Approval.Required,
+				Variable:  task.Approval.Variable,
+			}
+		}
+		if task.Automation != nil {
+			templateTask.Automation = &Automation{
+				Executor:       task.Automation.Executor,
+				HTTP:           task.Automation.HTTP,
+				Handler:        task.Automation.Handler,
+				MaxAttempts:    task.Automation.MaxAttempts,
+				BackoffSeconds: task.Automation.BackoffSeconds,
+				TimeoutSeconds: task.Automation.TimeoutSeconds,
+				OutputVariable: task.Automation.OutputVariable,
+			}
+		}
+		if task.SLA != nil {
+			templateTask.SLA = &SLA{
+				DurationSeconds: task.SLA.DurationSeconds,
+				AtRiskPercent:   task.SLA.AtRiskPercent,
+				Steps:           task.SLA.Escalation,
+			}
+		}
+		tasks = append(tasks, templateTask)
+	}
+
+	return Template{
+		Name:                definition.Metadata.Name,
+		Parameters:          []TemplateParameter{},
+		Tasks:               tasks,
+		VariableDefinitions: definition.VariableDefinitions,
+	}
+}
+
+// validate checks the fields of a task the template leaves unchecked.
+func (task *TaskDefinition) validate() error {
+	if task.Key == "" || utf8.RuneCountInString(task.Key) > 50 {
+		return errors.New("invalid task key: " + task.Key)
+	}
+	if length := utf8.RuneCountInString(task.Name); length < 3 || length > 100 {
+		return errors.New("invalid name for task: " + task.Key)
+	}
+	if utf8.RuneCountInString(task.Assignee) > 100 {
+		return errors.New("invalid assignee for task: " + task.Key)
+	}
+
+	// The type must agree with the one configuration it calls for.
+	var expected TaskType
+	switch {
+	case task.Gateway != nil && task.Approval == nil && task.Automation == nil:
+		expected = GatewayTask
+	case task.Gateway == nil && task.Approval != nil && task.Automation == nil:
+		expected = ApprovalTask
+	case task.Gateway == nil && task.Approval == nil && task.Automation != nil:
+		expected = AutomatedTask
+	case task.Gateway == nil && task.Approval == nil && task.Automation == nil:
+		expected = UserTask
+	default:
+		return errors.New("task has more than one type: " + task.Key)
+	}
+	if task.Type != expected {
+		return errors.New("invalid type for task: " + task.Key)
+	}
+
+	if len(task.Checklist) > MaxChecklistItems {
+		return errors.New("checklist is full: " + task.Key)
+	}
+	for _, item := range task.Checklist {
+		if item.Text == "" || utf8.RuneCountInString(item.Text) > 500 || utf8.RuneCountInString(item.Assignee) > 100 {
+			return errors.New("invalid checklist item for task: " + task.Key)
+		}
+	}
+
+	return nil
+}
+
+// Instantiate validates the definition and builds a new workflow with fresh
+// IDs and Pending tasks from it.
+func (definition *WorkflowDefinition) Instantiate(owner string) (*Workflow, error) {
+	if definition.Version != WorkflowDefinitionVersion {
+		return nil, errors.New("unsupported workflow definition version: " + strconv.Itoa(definition.Version))
+	}
+	if length := utf8.RuneCountInString(definition.Metadata.Name); length < 3 || length > 100 {
+		return nil, errors.New("invalid workflow name")
+	}
+
+	labels, err := NormalizeLabels(definition.Metadata.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range definition.Tasks {
+		if err := definition.Tasks[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	template := definition.template()
+	workflow, err := template.Instantiate(definition.Metadata.Name, owner, nil)
+	if err != nil {
+		return nil, err
+	}
+	workflow.Labels = labels
+
+	// Tasks keep the order of the definition, so they line up with it.
+	keys := map[primitive.ObjectID]string{}
+	for i, task := range definition.Tasks {
+		keys[workflow.Tasks[i].ID] = task.Key
+
+		workflow.Tasks[i].Assignee = task.Assignee
+	}
+
+	if problems := workflow.ValidateDefinition(); len(problems) > 0 {
+		return nil, errors.New(problems[0].Message + ": " + keys[problems[0].TaskID])
+	}
+
+	return workflow, nil
+}
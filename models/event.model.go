@@ -0,0 +1,280 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type EventName string
+
+// Event is a typed domain event published by the service layer after a
+// mutation. Events are encoded as JSON when they go through the outbox.
+type Event interface {
+	EventName() EventName
+}
+
+// WorkflowScope is embedded in the events of a workflow and its tasks.
+type WorkflowScope struct {
+	WorkflowID string `json:"workflow_id"`
+}
+
+func (scope WorkflowScope) ScopeWorkflowID() string {
+	return scope.WorkflowID
+}
+
+// WorkflowEvent is an event about a workflow or one of its tasks.
+type WorkflowEvent interface {
+	Event
+	ScopeWorkflowID() string
+}
+
+// DomainEvent is an event as handed to subscribers.
+type DomainEvent struct {
+	ID         string    `json:"id"`
+	Name       EventName `json:"name"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Payload    Event     `json:"payload"`
+}
+
+// OutboxEntry is an event written in the transaction of the mutation that
+// caused it. The outbox relay hands it to the asynchronous subscribers.
+type OutboxEntry struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id"`
+	Event       EventName          `json:"event" bson:"event"`
+	Payload     string             `json:"payload" bson:"payload"`
+	OccurredAt  time.Time          `json:"occurred_at" bson:"occurred_at"`
+	LeaseID     string             `json:"-" bson:"lease_id,omitempty"`
+	LeasedUntil *time.Time         `json:"-" bson:"leased_until,omitempty"`
+}
+
+type WorkflowCreated struct {
+	WorkflowScope
+	Workflow Workflow `json:"workflow"`
+}
+
+type WorkflowUpdated struct {
+	WorkflowScope
+	Workflow Workflow `json:"workflow"`
+}
+
+type WorkflowDeleted struct {
+	WorkflowScope
+}
+
+type WorkflowTransferred struct {
+	WorkflowScope
+	Workflow Workflow `json:"workflow"`
+}
+
+type WorkflowRestored struct {
+	WorkflowScope
+	Workflow Workflow `json:"workflow"`
+	Revision int      `json:"revision"`
+}
+
+type WorkflowVariablesSet struct {
+	WorkflowScope
+	Workflow Workflow `json:"workflow"`
+}
+
+type WorkflowStarted struct {
+	WorkflowScope
+	Workflow Workflow `json:"workflow"`
+}
+
+type WorkflowVariableDefinitionsSet struct {
+	WorkflowScope
+	Workflow Workflow `json:"workflow"`
+}
+
+type TaskCreated struct {
+	WorkflowScope
+	Task Task `json:"task"`
+}
+
+type TaskUpdated struct {
+	WorkflowScope
+	Task Task `json:"task"`
+}
+
+type TaskDeleted struct {
+	WorkflowScope
+	TaskID string `json:"task_id"`
+}
+
+type TasksReordered struct {
+	WorkflowScope
+	Tasks []Task `json:"tasks"`
+}
+
+// TaskStatusChanged accompanies every event that moved a task to another
+// status.
+type TaskStatusChanged struct {
+	WorkflowScope
+	Task           Task       `json:"task"`
+	PreviousStatus TaskStatus `json:"previous_status"`
+}
+
+type TaskFormSet struct {
+	WorkflowScope
+	Task Task `json:"task"`
+}
+
+type TaskVoted struct {
+	WorkflowScope
+	Task     Task             `json:"task"`
+	Username string           `json:"username"`
+	Decision ApprovalDecision `json:"decision"`
+}
+
+type TaskCompleted struct {
+	WorkflowScope
+	Task Task `json:"task"`
+}
+
+type TaskRetried struct {
+	WorkflowScope
+	Task Task `json:"task"`
+}
+
+// TaskAttempted records an attempt of the automation worker at an automated
+// task, successful or not.
+type TaskAttempted struct {
+	WorkflowScope
+	Task Task `json:"task"`
+}
+
+type TaskEscalated struct {
+	WorkflowScope
+	Task        Task         `json:"task"`
+	Escalations []Escalation `json:"escalations"`
+}
+
+type TaskChecklistChanged struct {
+	WorkflowScope
+	Task Task `json:"task"`
+}
+
+type CommentAdded struct {
+	WorkflowScope
+	Comment Comment `json:"comment"`
+}
+
+// CommentEdited carries the users the edit mentioned for the first time.
+type CommentEdited struct {
+	WorkflowScope
+	Comment     Comment  `json:"comment"`
+	NewMentions []string `json:"new_mentions"`
+}
+
+type CommentDeleted struct {
+	WorkflowScope
+	Comment Comment `json:"comment"`
+}
+
+type AttachmentAdded struct {
+	WorkflowScope
+	Attachment Attachment `json:"attachment"`
+}
+
+type AttachmentDeleted struct {
+	WorkflowScope
+	Attachment Attachment `json:"attachment"`
+}
+
+type UserRegistered struct {
+	Username     string   `json:"username"`
+	Role         UserRole `json:"role"`
+	Organization string   `json:"organization"`
+	Teams        []string `json:"teams"`
+}
+
+type UserUpdated struct {
+	Username     string   `json:"username"`
+	Role         UserRole `json:"role"`
+	Organization string   `json:"organization"`
+	Teams        []string `json:"teams"`
+}
+
+type UserLoggedIn struct {
+	Username string `json:"username"`
+}
+
+type UserTokenRefreshed struct {
+	Username string `json:"username"`
+}
+
+type UserLoggedOut struct {
+	Username string `json:"username"`
+}
+
+func (WorkflowCreated) EventName() EventName      { return "workflow.created" }
+func (WorkflowUpdated) EventName() EventName      { return "workflow.updated" }
+func (WorkflowDeleted) EventName() EventName      { return "workflow.deleted" }
+func (WorkflowTransferred) EventName() EventName  { return "workflow.transferred" }
+func (WorkflowRestored) EventName() EventName     { return "workflow.restored" }
+func (WorkflowVariablesSet) EventName() EventName { return "workflow.variables_set" }
+func (WorkflowStarted) EventName() EventName      { return "workflow.started" }
+func (WorkflowVariableDefinitionsSet) EventName() EventName {
+	return "workflow.variable_definitions_set"
+}
+func (TaskCreated) EventName() EventName       { return "task.created" }
+func (TaskUpdated) EventName() EventName       { return "task.updated" }
+func (TaskDeleted) EventName() EventName       { return "task.deleted" }
+func (TasksReordered) EventName() EventName    { return "tasks.reordered" }
+func (TaskStatusChanged) EventName() EventName { return "task.status_changed" }
+func (TaskFormSet) EventName() EventName       { return "task.form_set" }
+func (TaskVoted) EventName() EventName         { return "task.voted" }
+func (TaskCompleted) EventName() EventName     { return "task.completed" }
+func (TaskRetried) EventName() EventName       { return "task.retried" }
+func (TaskAttempted) EventName() EventName     { return "task.attempted" }
+func (TaskEscalated) EventName() EventName     { return "task.escalated" }
+func (TaskChecklistChanged) EventName() EventName {
+	return "task.checklist_changed"
+}
+func (CommentAdded) EventName() EventName       { return "comment.added" }
+func (CommentEdited) EventName() EventName      { return "comment.edited" }
+func (CommentDeleted) EventName() EventName     { return "comment.deleted" }
+func (AttachmentAdded) EventName() EventName    { return "attachment.added" }
+func (AttachmentDeleted) EventName() EventName  { return "attachment.deleted" }
+func (UserRegistered) EventName() EventName     { return "user.registered" }
+func (UserUpdated) EventName() EventName        { return "user.updated" }
+func (UserLoggedIn) EventName() EventName       { return "user.logged_in" }
+func (UserTokenRefreshed) EventName() EventName { return "user.token_refreshed" }
+func (UserLoggedOut) EventName() EventName      { return "user.logged_out" }
+
+// eventTypes maps the name of every event to its type, to decode events read
+// back from the outbox.
+var eventTypes = map[EventName]reflect.Type{}
+
+func init() {
+	for _, event := range []Event{
+		WorkflowCreated{}, WorkflowUpdated{}, WorkflowDeleted{}, WorkflowTransferred{}, WorkflowRestored{},
+		WorkflowVariablesSet{}, WorkflowStarted{}, WorkflowVariableDefinitionsSet{},
+		TaskCreated{}, TaskUpdated{}, TaskDeleted{}, TasksReordered{}, TaskStatusChanged{}, TaskFormSet{},
+		TaskVoted{}, TaskCompleted{}, TaskRetried{}, TaskAttempted{}, TaskEscalated{}, TaskChecklistChanged{},
+		CommentAdded{}, CommentEdited{}, CommentDeleted{}, AttachmentAdded{}, AttachmentDeleted{},
+		UserRegistered{}, UserUpdated{}, UserLoggedIn{}, UserTokenRefreshed{}, UserLoggedOut{},
+	} {
+		eventTypes[event.EventName()] = reflect.TypeOf(event)
+	}
+}
+
+// DecodeEvent decodes the JSON payload of an event with the given name.
+func DecodeEvent(name EventName, payload []byte) (Event, error) {
+	eventType, ok := eventTypes[name]
+	if !ok {
+		return nil, errors.New("unknown event: " + string(name))
+	}
+
+	event := reflect.New(eventType)
+	if err := json.Unmarshal(payload, event.Interface()); err != nil {
+		return nil, err
+	}
+
+	return event.Elem().Interface().(Event), nil
+}
@@ -0,0 +1,253 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+type FieldType string
+
+const (
+	StringField FieldType = "String"
+	NumberField FieldType = "Number"
+	DateField   FieldType = "Date"
+	EnumField   FieldType = "Enum"
+	UserField   FieldType = "User"
+)
+
+// FieldDefinition declares a typed run variable, either on the workflow or as a
+// field of a task form. Form fields write to the run variable of the same name.
+// Required only applies to form fields.
+type FieldDefinition struct {
+	Name     string    `json:"name" bson:"name"`
+	Label    string    `json:"label" bson:"label"`
+	Type     FieldType `json:"type" bson:"type"`
+	Required bool      `json:"required" bson:"required"`
+	Options  []string  `json:"options,omitempty" bson:"options,omitempty"`
+}
+
+func (field *FieldDefinition) Validate() error {
+	if !VariableNamePattern.MatchString(field.Name) {
+		return errors.New("invalid variable name: " + field.Name)
+	}
+
+	switch field.Type {
+	case StringField, NumberField, DateField, UserField:
+	case EnumField:
+		if len(field.Options) == 0 {
+			return errors.New("enum field needs at least one option: " + field.Name)
+		}
+	default:
+		return errors.New("unknown field type: " + string(field.Type))
+	}
+
+	return nil
+}
+
+// NormalizeValue checks a submitted value against the field type and converts
+// it to the stored representation. Dates are stored as RFC 3339 strings in UTC
+// so they compare correctly in gateway conditions.
+func (field *FieldDefinition) NormalizeValue(value interface{}) (interface{}, error) {
+	invalid := errors.New("invalid value for field: " + field.Name)
+
+	switch field.Type {
+	case NumberField:
+		switch number := value.(type) {
+		case float64:
+			return number, nil
+		case float32:
+			return float64(number), nil
+		case int:
+			return float64(number), nil
+		case int32:
+			return float64(number), nil
+		case int64:
+			return float64(number), nil
+		}
+		return nil, invalid
+	}
+
+	text, ok := value.(string)
+	if !ok {
+		return nil, invalid
+	}
+
+	switch field.Type {
+	case DateField:
+		date, err := time.Parse(time.RFC3339, text)
+		if err != nil {
+			date, err = time.Parse("2006-01-02", text)
+		}
+		if err != nil {
+			return nil, invalid
+		}
+		return date.UTC().Format(time.RFC3339), nil
+	case EnumField:
+		for _, option := range field.Options {
+			if option == text {
+				return text, nil
+			}
+		}
+		return nil, invalid
+	case UserField:
+		if text == "" {
+			return nil, invalid
+		}
+	}
+
+	return text, nil
+}
+
+func findField(fields []FieldDefinition, name string) *FieldDefinition {
+	for i := range fields {
+		if fields[i].Name == name {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// ValidateVariableDefinitions checks every definition and that names are unique.
+func ValidateVariableDefinitions(definitions []FieldDefinition) error {
+	names := map[string]bool{}
+	for i := range definitions {
+		if err := definitions[i].Validate(); err != nil {
+			return err
+		}
+		if names[definitions[i].Name] {
+			return errors.New("duplicate variable definition: " + definitions[i].Name)
+		}
+		names[definitions[i].Name] = true
+	}
+
+	return nil
+}
+
+// CheckVariableDefinitions validates new variable definitions for the workflow
+// and checks that every task form agrees with the declared types.
+func (workflow *Workflow) CheckVariableDefinitions(definitions []FieldDefinition) error {
+	if err := ValidateVariableDefinitions(definitions); err != nil {
+		return err
+	}
+
+	for _, task := range workflow.Tasks {
+		if err := ValidateForm(task.Form, definitions); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateForm checks a task form on its own and against the variables
+// declared by the workflow.
+func ValidateForm(form []FieldDefinition, definitions []FieldDefinition) error {
+	names := map[string]bool{}
+	for i := range form {
+		field := form[i]
+		if err := field.Validate(); err != nil {
+			return err
+		}
+		if names[field.Name] {
+			return errors.New("duplicate form field: " + field.Name)
+		}
+		names[field.Name] = true
+
+		declared := findField(definitions, field.Name)
+		if declared != nil && (declared.Type != field.Type || !sameStrings(declared.Options, field.Options)) {
+			return errors.New("form field does not match the declared variable: " + field.Name)
+		}
+	}
+
+	return nil
+}
+
+// NormalizeVariables checks values against the declared variables. Variables
+// that are not declared are stored as given.
+func (workflow *Workflow) NormalizeVariables(values map[string]interface{}) (map[string]interface{}, error) {
+	normalized := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		if !VariableNamePattern.MatchString(name) {
+			return nil, errors.New("invalid variable name: " + name)
+		}
+		field := findField(workflow.VariableDefinitions, name)
+		if field == nil || value == nil {
+			normalized[name] = value
+			continue
+		}
+		normalizedValue, err := field.NormalizeValue(value)
+		if err != nil {
+			return nil, err
+		}
+		normalized[name] = normalizedValue
+	}
+
+	return normalized, nil
+}
+
+// NormalizeFormValues validates submitted values against the task form. Every
+// required field must be present and no fields outside the form are accepted.
+func (task *Task) NormalizeFormValues(values map[string]interface{}) (map[string]interface{}, error) {
+	normalized := map[string]interface{}{}
+	for name, value := range values {
+		field := findField(task.Form, name)
+		if field == nil {
+			return nil, errors.New("unknown form field: " + name)
+		}
+		if value == nil || value == "" {
+			continue
+		}
+		normalizedValue, err := field.NormalizeValue(value)
+		if err != nil {
+			return nil, err
+		}
+		normalized[name] = normalizedValue
+	}
+
+	if err := task.checkRequiredFields(normalized); err != nil {
+		return nil, err
+	}
+
+	return normalized, nil
+}
+
+// CheckFormSubmitted fails when the task has required form fields that have not
+// been submitted, so the task cannot be completed without its data.
+func (task *Task) CheckFormSubmitted() error {
+	return task.checkRequiredFields(task.FormValues)
+}
+
+func (task *Task) checkRequiredFields(values map[string]interface{}) error {
+	for _, field := range task.Form {
+		if _, ok := values[field.Name]; field.Required && !ok {
+			return errors.New("missing required form field: " + field.Name)
+		}
+	}
+	return nil
+}
+
+// UserReferences lists the usernames referenced by user fields of the values.
+func UserReferences(fields []FieldDefinition, values map[string]interface{}) []string {
+	usernames := []string{}
+	for _, field := range fields {
+		if field.Type != UserField {
+			continue
+		}
+		if username, ok := values[field.Name].(string); ok {
+			usernames = append(usernames, username)
+		}
+	}
+	return usernames
+}
+
+func sameStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,402 @@
+package models
+
+import (
+	"errors"
+	"time"
+	"virtual_workflow_management_system_gin/common"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type GatewayKind string
+
+const (
+	// ExclusiveGateway takes the first flow whose condition holds.
+	ExclusiveGateway GatewayKind = "Exclusive"
+	// ParallelGateway takes every flow; conditions are ignored.
+	ParallelGateway GatewayKind = "Parallel"
+	// InclusiveGateway takes every flow whose condition holds.
+	InclusiveGateway GatewayKind = "Inclusive"
+)
+
+// GatewayFlow is an outgoing path of a gateway. A flow without a condition is
+// the default path, taken when no conditional flow matches.
+type GatewayFlow struct {
+	Target    primitive.ObjectID `json:"target" bson:"target"`
+	Condition string             `json:"condition" bson:"condition"`
+}
+
+type Gateway struct {
+	Kind  GatewayKind          `json:"kind" bson:"kind"`
+	Flows []GatewayFlow        `json:"flows" bson:"flows"`
+	Taken []primitive.ObjectID `json:"taken" bson:"taken"`
+}
+
+type DefinitionProblem struct {
+	TaskID  primitive.ObjectID `json:"task_id"`
+	Message string             `json:"message"`
+}
+
+func (gateway *Gateway) Validate(taskID primitive.ObjectID) error {
+	switch gateway.Kind {
+	case ExclusiveGateway, ParallelGateway, InclusiveGateway:
+	default:
+		return errors.New("unknown gateway kind: " + string(gateway.Kind))
+	}
+
+	if len(gateway.Flows) == 0 {
+		return errors.New("gateway has no outgoing paths")
+	}
+
+	defaultFlows := 0
+	for _, flow := range gateway.Flows {
+		if flow.Target == taskID {
+			return errors.New("gateway flow cannot target the gateway itself")
+		}
+		if flow.Condition == "" {
+			defaultFlows++
+			continue
+		}
+		if err := common.ParseExpression(flow.Condition); err != nil {
+			return err
+		}
+	}
+
+	if defaultFlows > 1 && gateway.Kind != ParallelGateway {
+		return errors.New("gateway has more than one default path")
+	}
+
+	return nil
+}
+
+// evaluate returns the targets of the flows taken for the given variables. A
+// condition that cannot be evaluated, e.g. because a variable is missing, does
+// not hold.
+func (gateway *Gateway) evaluate(variables map[string]interface{}) []primitive.ObjectID {
+	taken := []primitive.ObjectID{}
+	var defaultTargets []primitive.ObjectID
+
+	for _, flow := range gateway.Flows {
+		if gateway.Kind == ParallelGateway {
+			taken = append(taken, flow.Target)
+			continue
+		}
+		if flow.Condition == "" {
+			defaultTargets = append(defaultTargets, flow.Target)
+			continue
+		}
+		matched, err := common.EvaluateCondition(flow.Condition, variables)
+		if err != nil || !matched {
+			continue
+		}
+		taken = append(taken, flow.Target)
+		if gateway.Kind == ExclusiveGateway {
+			break
+		}
+	}
+
+	if len(taken) == 0 {
+		taken = append(taken, defaultTargets...)
+	}
+
+	return taken
+}
+
+// takesSame reports whether the gateway took exactly the given targets.
+func (gateway *Gateway) takesSame(targets []primitive.ObjectID) bool {
+	if len(gateway.Taken) != len(targets) {
+		return false
+	}
+	for _, target := range targets {
+		if !gateway.took(target) {
+			return false
+		}
+	}
+	return true
+}
+
+func (gateway *Gateway) targets(taskID primitive.ObjectID) bool {
+	for _, flow := range gateway.Flows {
+		if flow.Target == taskID {
+			return true
+		}
+	}
+	return false
+}
+
+func (gateway *Gateway) took(taskID primitive.ObjectID) bool {
+	for _, target := range gateway.Taken {
+		if target == taskID {
+			return true
+		}
+	}
+	return false
+}
+
+func (task *Task) IsGateway() bool {
+	return task.Type == GatewayTask && task.Gateway != nil
+}
+
+// HasManagedStatus reports whether the status of the task is set by the
+// workflow itself rather than by editing the task.
+func (task *Task) HasManagedStatus() bool {
+	return task.Type == GatewayTask || task.Type == ApprovalTask || task.Type == AutomatedTask
+}
+
+// IsDone reports whether the run has moved past the task.
+func (task *Task) IsDone() bool {
+	return task.Status == Completed || task.Status == Skipped || task.Status == Rejected
+}
+
+// TaskReady reports whether the run has reached the task: it is pending or in
+// progress and every task it waits for is done.
+func (workflow *Workflow) TaskReady(taskID primitive.ObjectID) bool {
+	index := make(map[primitive.ObjectID]int, len(workflow.Tasks))
+	for i := range workflow.Tasks {
+		index[workflow.Tasks[i].ID] = i
+	}
+	i, ok := index[taskID]
+	if !ok || (workflow.Tasks[i].Status != Pending && workflow.Tasks[i].Status != InProgress) {
+		return false
+	}
+	for _, predecessorID := range predecessors(workflow.Tasks)[taskID] {
+		if j, ok := index[predecessorID]; ok && !workflow.Tasks[j].IsDone() {
+			return false
+		}
+	}
+	return true
+}
+
+// predecessors maps every task to the tasks it waits for: its dependencies and
+// the gateways with a flow leading to it.
+func predecessors(tasks []Task) map[primitive.ObjectID][]primitive.ObjectID {
+	incoming := make(map[primitive.ObjectID][]primitive.ObjectID, len(tasks))
+	for _, task := range tasks {
+		incoming[task.ID] = append(incoming[task.ID], task.DependsOn...)
+		if task.IsGateway() {
+			for _, flow := range task.Gateway.Flows {
+				incoming[flow.Target] = append(incoming[flow.Target], task.ID)
+			}
+		}
+	}
+	return incoming
+}
+
+// ValidateDefinition reports dangling references, invalid gateways and tasks
+// that can never become ready, e.g. because they are part of a cycle.
+func (workflow *Workflow) ValidateDefinition() []DefinitionProblem {
+	problems := []DefinitionProblem{}
+
+	tasksByID := make(map[primitive.ObjectID]bool, len(workflow.Tasks))
+	for _, task := range workflow.Tasks {
+		tasksByID[task.ID] = true
+	}
+
+	for _, task := range workflow.Tasks {
+		for _, dependencyID := range task.DependsOn {
+			if !tasksByID[dependencyID] {
+				problems = append(problems, DefinitionProblem{TaskID: task.ID, Message: "dependency task does not exist"})
+			}
+		}
+
+		if task.Type == ApprovalTask {
+			if task.Approval == nil {
+				problems = append(problems, DefinitionProblem{TaskID: task.ID, Message: "approval task needs at least one approver"})
+			} else if err := task.Approval.Validate(); err != nil {
+				problems = append(problems, DefinitionProblem{TaskID: task.ID, Message: err.Error()})
+			}
+		}
+
+		if task.Type == AutomatedTask {
+			if task.Automation == nil {
+				problems = append(problems, DefinitionProblem{TaskID: task.ID, Message: "automated task needs an executor"})
+			} else if err := task.Automation.Validate(); err != nil {
+				problems = append(problems, DefinitionProblem{TaskID: task.ID, Message: err.Error()})
+			}
+		}
+
+		if task.Type != GatewayTask {
+			continue
+		}
+		if task.Gateway == nil {
+			problems = append(problems, DefinitionProblem{TaskID: task.ID, Message: "gateway has no outgoing paths"})
+			continue
+		}
+		if err := task.Gateway.Validate(task.ID); err != nil {
+			problems = append(problems, DefinitionProblem{TaskID: task.ID, Message: err.Error()})
+		}
+		for _, flow := range task.Gateway.Flows {
+			if !tasksByID[flow.Target] {
+				problems = append(problems, DefinitionProblem{TaskID: task.ID, Message: "gateway flow target does not exist"})
+			}
+		}
+	}
+
+	// A task is reachable once every task it waits for is reachable.
+	incoming := predecessors(workflow.Tasks)
+	reachable := map[primitive.ObjectID]bool{}
+	for progress := true; progress; {
+		progress = false
+		for _, task := range workflow.Tasks {
+			if reachable[task.ID] {
+				continue
+			}
+			ready := true
+			for _, predecessorID := range incoming[task.ID] {
+				if tasksByID[predecessorID] && !reachable[predecessorID] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				reachable[task.ID] = true
+				progress = true
+			}
+		}
+	}
+
+	for _, task := range workflow.Tasks {
+		if !reachable[task.ID] {
+			problems = append(problems, DefinitionProblem{TaskID: task.ID, Message: "task is unreachable"})
+		}
+	}
+
+	return problems
+}
+
+// AdvanceGateways evaluates every gateway whose predecessors are done, or
+// that nothing leads to once the run has started, and reopens gateways the
+// variables have since changed for. It queues ready automated tasks for the
+// automation worker, skips pending tasks that can no longer be reached on the
+// path the run has taken and starts or stops SLA clocks. It reports whether
+// any task changed.
+func (workflow *Workflow) AdvanceGateways() bool {
+	incoming := predecessors(workflow.Tasks)
+
+	index := make(map[primitive.ObjectID]int, len(workflow.Tasks))
+	for i, task := range workflow.Tasks {
+		index[task.ID] = i
+	}
+
+	changed := workflow.reopenGateways(incoming, index)
+
+	for progress := true; progress; {
+		progress = false
+		for i := range workflow.Tasks {
+			task := &workflow.Tasks[i]
+			if task.Status != Pending || (len(incoming[task.ID]) == 0 && !task.IsGateway() && !task.IsAutomated()) {
+				continue
+			}
+			if task.IsGateway() && !workflow.gatewayMayDecide(incoming, task.ID) {
+				continue
+			}
+
+			ready, active := true, len(incoming[task.ID]) == 0
+			for _, predecessorID := range incoming[task.ID] {
+				predecessorIndex, ok := index[predecessorID]
+				if !ok {
+					continue
+				}
+				predecessor := workflow.Tasks[predecessorIndex]
+				if !predecessor.IsDone() {
+					ready = false
+					break
+				}
+				if predecessor.Status != Skipped &&
+					(!predecessor.IsGateway() || !predecessor.Gateway.targets(task.ID) || predecessor.Gateway.took(task.ID)) {
+					active = true
+				}
+			}
+			if !ready {
+				continue
+			}
+
+			now := time.Now()
+			switch {
+			case !active:
+				task.SetStatus(Skipped, now)
+			case task.IsGateway():
+				task.Gateway.Taken = task.Gateway.evaluate(workflow.Variables)
+				task.SetStatus(Completed, now)
+			case task.IsAutomated():
+				task.Automation.Queue(now)
+				task.SetStatus(InProgress, now)
+			default:
+				continue
+			}
+			task.SetUpdatedAt()
+			progress = true
+			changed = true
+		}
+	}
+
+	if workflow.trackSLAs(incoming, index, time.Now()) {
+		changed = true
+	}
+
+	return changed
+}
+
+// gatewayMayDecide reports whether the run has data for the gateway to decide
+// on: something leads to it, or the run has started.
+func (workflow *Workflow) gatewayMayDecide(incoming map[primitive.ObjectID][]primitive.ObjectID, gatewayID primitive.ObjectID) bool {
+	return len(incoming[gatewayID]) > 0 || workflow.StartedAt != nil
+}
+
+// reopenGateways takes back the decision of every gateway that would take
+// other flows with the current variables, as long as the run has not moved on
+// past it: every task after the gateway is still pending or was skipped. The
+// skipped tasks become pending again and the gateway is left to be evaluated
+// anew.
+func (workflow *Workflow) reopenGateways(incoming map[primitive.ObjectID][]primitive.ObjectID, index map[primitive.ObjectID]int) bool {
+	successors := make(map[primitive.ObjectID][]primitive.ObjectID, len(workflow.Tasks))
+	for taskID, predecessorIDs := range incoming {
+		for _, predecessorID := range predecessorIDs {
+			successors[predecessorID] = append(successors[predecessorID], taskID)
+		}
+	}
+
+	changed := false
+	for i := range workflow.Tasks {
+		gateway := &workflow.Tasks[i]
+		if !gateway.IsGateway() || gateway.Status != Completed || !workflow.gatewayMayDecide(incoming, gateway.ID) ||
+			gateway.Gateway.takesSame(gateway.Gateway.evaluate(workflow.Variables)) {
+			continue
+		}
+
+		after := []int{}
+		seen := map[primitive.ObjectID]bool{gateway.ID: true}
+		open := true
+		for queue := successors[gateway.ID]; len(queue) > 0 && open; queue = queue[1:] {
+			taskID := queue[0]
+			taskIndex, ok := index[taskID]
+			if !ok || seen[taskID] {
+				continue
+			}
+			seen[taskID] = true
+			if status := workflow.Tasks[taskIndex].Status; status != Pending && status != Skipped {
+				open = false
+			}
+			after = append(after, taskIndex)
+			queue = append(queue, successors[taskID]...)
+		}
+		if !open {
+			continue
+		}
+
+		now := time.Now()
+		for _, taskIndex := range after {
+			task := &workflow.Tasks[taskIndex]
+			if task.Status == Skipped {
+				task.SetStatus(Pending, now)
+				task.SetUpdatedAt()
+			}
+		}
+		gateway.Gateway.Taken = nil
+		gateway.SetStatus(Pending, now)
+		gateway.SetUpdatedAt()
+		changed = true
+	}
+
+	return changed
+}
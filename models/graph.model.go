@@ -0,0 +1,233 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DOTContentType = "text/vnd.graphviz; charset=utf-8"
+	// Mermaid has no registered media type; diagrams are plain text.
+	MermaidContentType = "text/plain; charset=utf-8"
+)
+
+// graphStatusStyles colour the task nodes of rendered graphs by status, in the
+// order the Mermaid classes are declared.
+var graphStatusStyles = []struct {
+	status TaskStatus
+	class  string
+	fill   string
+	stroke string
+}{
+	{Pending, "pending", "#eceff1", "#90a4ae"},
+	{InProgress, "in_progress", "#bbdefb", "#1e88e5"},
+	{Completed, "completed", "#c8e6c9", "#43a047"},
+	{Skipped, "skipped", "#f5f5f5", "#bdbdbd"},
+	{Rejected, "rejected", "#ffcdd2", "#e53935"},
+	{Failed, "failed", "#ffe0b2", "#fb8c00"},
+}
+
+// graphStyle returns the index of the style of a status. Unknown statuses look
+// pending.
+func graphStyle(status TaskStatus) int {
+	for i, style := range graphStatusStyles {
+		if style.status == status {
+			return i
+		}
+	}
+	return 0
+}
+
+type graphNode struct {
+	id    string
+	label string
+	task  Task
+}
+
+type graphEdge struct {
+	source    string
+	target    string
+	label     string
+	isDefault bool
+}
+
+// buildGraph lists the tasks of a workflow in order and the edges between
+// them: one from every dependency to its task and one for every gateway flow,
+// labelled with its condition. A flow that repeats a dependency is drawn once.
+func buildGraph(workflow Workflow) ([]graphNode, []graphEdge) {
+	tasks := make([]Task, len(workflow.Tasks))
+	copy(tasks, workflow.Tasks)
+	SortTasksByOrder(tasks)
+
+	nodes := make([]graphNode, 0, len(tasks))
+	ids := map[primitive.ObjectID]string{}
+	for i, task := range tasks {
+		id := "t" + strconv.Itoa(i+1)
+		ids[task.ID] = id
+		nodes = append(nodes, graphNode{id: id, label: strconv.Itoa(task.Order) + ". " + task.Name, task: task})
+	}
+
+	edges := []graphEdge{}
+	edgeIndex := map[[2]string]int{}
+	addEdge := func(edge graphEdge) {
+		if i, ok := edgeIndex[[2]string{edge.source, edge.target}]; ok {
+			if edge.label != "" || edge.isDefault {
+				edges[i] = edge
+			}
+			return
+		}
+		edgeIndex[[2]string{edge.source, edge.target}] = len(edges)
+		edges = append(edges, edge)
+	}
+
+	for _, node := range nodes {
+		for _, dependency := range node.task.DependsOn {
+			if source, ok := ids[dependency]; ok {
+				addEdge(graphEdge{source: source, target: node.id})
+			}
+		}
+		if node.task.Gateway == nil {
+			continue
+		}
+		for _, flow := range node.task.Gateway.Flows {
+			target, ok := ids[flow.Target]
+			if !ok {
+				continue
+			}
+			edge := graphEdge{source: node.id, target: target, label: flow.Condition}
+			if flow.Condition == "" && node.task.Gateway.Kind != ParallelGateway {
+				edge.isDefault = true
+			}
+			addEdge(edge)
+		}
+	}
+
+	return nodes, edges
+}
+
+// RenderDOT draws the tasks of a workflow as a Graphviz digraph, left to
+// right, with gateways as diamonds, approvals as hexagons and nodes filled by
+// status. Default paths of gateways are dashed.
+func RenderDOT(workflow Workflow) []byte {
+	nodes, edges := buildGraph(workflow)
+
+	var builder strings.Builder
+	builder.WriteString("digraph workflow {\n")
+	builder.WriteString("  label=" + dotQuote(workflow.Name) + ";\n")
+	builder.WriteString("  labelloc=t;\n")
+	builder.WriteString("  rankdir=LR;\n")
+	builder.WriteString("  node [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\"];\n")
+	builder.WriteString("  edge [fontname=\"Helvetica\"];\n")
+
+	for _, node := range nodes {
+		style := graphStatusStyles[graphStyle(node.task.Status)]
+		attributes := []string{
+			"label=" + dotQuote(node.label),
+			"fillcolor=" + dotQuote(style.fill),
+			"color=" + dotQuote(style.stroke),
+		}
+		switch node.task.Type {
+		case GatewayTask:
+			attributes = append(attributes, "shape=diamond", "style=filled")
+		case ApprovalTask:
+			attributes = append(attributes, "shape=hexagon", "style=filled")
+		case AutomatedTask:
+			attributes = append(attributes, "peripheries=2")
+		}
+		builder.WriteString("  " + node.id + " [" + strings.Join(attributes, ", ") + "];\n")
+	}
+
+	for _, edge := range edges {
+		attributes := []string{}
+		if edge.label != "" {
+			attributes = append(attributes, "label="+dotQuote(edge.label))
+		}
+		if edge.isDefault {
+			attributes = append(attributes, "label=\"default\"", "style=dashed")
+		}
+		builder.WriteString("  " + edge.source + " -> " + edge.target)
+		if len(attributes) > 0 {
+			builder.WriteString(" [" + strings.Join(attributes, ", ") + "]")
+		}
+		builder.WriteString(";\n")
+	}
+
+	builder.WriteString("}\n")
+	return []byte(builder.String())
+}
+
+// dotQuote quotes a DOT string. Backslashes are escaped too, as DOT reads
+// them as the start of label escapes.
+func dotQuote(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// RenderMermaid draws the tasks of a workflow as a Mermaid flowchart, left to
+// right, with gateways as rhombi, approvals as hexagons, automated tasks as
+// subroutines and nodes coloured by status. Default paths of gateways are
+// dotted.
+func RenderMermaid(workflow Workflow) []byte {
+	nodes, edges := buildGraph(workflow)
+
+	var builder strings.Builder
+	builder.WriteString("---\n")
+	builder.WriteString("title: " + strconv.Quote(workflow.Name) + "\n")
+	builder.WriteString("---\n")
+	builder.WriteString("flowchart LR\n")
+
+	classes := make([][]string, len(graphStatusStyles))
+	for _, node := range nodes {
+		label := mermaidQuote(node.label)
+		switch node.task.Type {
+		case GatewayTask:
+			builder.WriteString("  " + node.id + "{" + label + "}\n")
+		case ApprovalTask:
+			builder.WriteString("  " + node.id + "{{" + label + "}}\n")
+		case AutomatedTask:
+			builder.WriteString("  " + node.id + "[[" + label + "]]\n")
+		default:
+			builder.WriteString("  " + node.id + "(" + label + ")\n")
+		}
+		style := graphStyle(node.task.Status)
+		classes[style] = append(classes[style], node.id)
+	}
+
+	for _, edge := range edges {
+		switch {
+		case edge.isDefault:
+			builder.WriteString("  " + edge.source + " -.->|default| " + edge.target + "\n")
+		case edge.label != "":
+			builder.WriteString("  " + edge.source + " -->|" + mermaidQuote(edge.label) + "| " + edge.target + "\n")
+		default:
+			builder.WriteString("  " + edge.source + " --> " + edge.target + "\n")
+		}
+	}
+
+	for i, style := range graphStatusStyles {
+		if len(classes[i]) == 0 {
+			continue
+		}
+		builder.WriteString("  classDef " + style.class + " fill:" + style.fill + ",stroke:" + style.stroke + "\n")
+		builder.WriteString("  class " + strings.Join(classes[i], ",") + " " + style.class + "\n")
+	}
+
+	return []byte(builder.String())
+}
+
+// mermaidQuote quotes a Mermaid label. Quotes and markup become entity codes,
+// and line breaks spaces, so no label can end its statement early.
+func mermaidQuote(value string) string {
+	replacer := strings.NewReplacer(
+		"#", "#35;",
+		`"`, "#quot;",
+		"<", "#lt;",
+		">", "#gt;",
+		"\r\n", " ",
+		"\n", " ",
+		"\r", " ",
+	)
+	return `"` + replacer.Replace(value) + `"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+	"virtual_workflow_management_system_gin/common"
+)
+
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "Queued"
+	JobRunning   JobStatus = "Running"
+	JobSucceeded JobStatus = "Succeeded"
+	// JobDead marks a job that ran out of attempts. Dead jobs are moved to the
+	// dead-letter collection until an admin retries them.
+	JobDead JobStatus = "Dead"
+)
+
+// Job is a unit of deferred work run by the job queue. Payload is passed to
+// the handler registered for Type.
+type Job struct {
+	common.BaseModel `bson:",inline"`
+	Type             string                 `json:"type" bson:"type"`
+	Payload          map[string]interface{} `json:"payload" bson:"payload"`
+	Status           JobStatus              `json:"status" bson:"status"`
+	Attempts         int                    `json:"attempts" bson:"attempts"`
+	MaxAttempts      int                    `json:"max_attempts" bson:"max_attempts"`
+	BackoffSeconds   int                    `json:"backoff_seconds" bson:"backoff_seconds"`
+	ScheduledAt      time.Time              `json:"scheduled_at" bson:"scheduled_at"`
+	LastError        string                 `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	LeaseID          string                 `json:"-" bson:"lease_id,omitempty"`
+	LeasedUntil      *time.Time             `json:"leased_until,omitempty" bson:"leased_until,omitempty"`
+	FinishedAt       *time.Time             `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+}
+
+// RecordFailure records a failed attempt and reschedules the job with
+// exponential backoff. It reports whether the job will be retried. The attempt
+// was counted when the job was claimed.
+func (job *Job) RecordFailure(failure error, now time.Time) bool {
+	job.LastError = failure.Error()
+	job.LeaseID = ""
+	job.LeasedUntil = nil
+	job.UpdatedAt = now
+
+	if job.Attempts >= job.MaxAttempts {
+		job.Status = JobDead
+		job.FinishedAt = &now
+		return false
+	}
+
+	job.Status = JobQueued
+	job.ScheduledAt = now.Add(retryDelay(job.BackoffSeconds, job.Attempts))
+
+	return true
+}
+
+// Requeue gives a dead job a fresh set of attempts, due immediately.
+func (job *Job) Requeue(now time.Time) {
+	job.Status = JobQueued
+	job.Attempts = 0
+	job.LastError = ""
+	job.ScheduledAt = now
+	job.FinishedAt = nil
+	job.LeaseID = ""
+	job.LeasedUntil = nil
+	job.UpdatedAt = now
+}
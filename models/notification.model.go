@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+	"virtual_workflow_management_system_gin/common"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Notification tells a user about something that needs their attention, such
+// as a mention or an overdue task. It stays unread until the user reads it.
+type Notification struct {
+	common.BaseModel `bson:",inline"`
+	Username         string              `json:"username" bson:"username"`
+	Subject          string              `json:"subject" bson:"subject"`
+	WorkflowID       *primitive.ObjectID `json:"workflow_id,omitempty" bson:"workflow_id,omitempty"`
+	TaskID           *primitive.ObjectID `json:"task_id,omitempty" bson:"task_id,omitempty"`
+	CommentID        *primitive.ObjectID `json:"comment_id,omitempty" bson:"comment_id,omitempty"`
+	ReadAt           *time.Time          `json:"read_at,omitempty" bson:"read_at,omitempty"`
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TaskRowHeader names the columns of the records of task rows.
+var TaskRowHeader = []string{"workflow_id", "workflow", "task_id", "task", "status", "order", "created_at", "updated_at", "assignee"}
+
+// TaskRow is a task flattened with its workflow, as listed in task reports.
+type TaskRow struct {
+	WorkflowID   primitive.ObjectID `bson:"workflow_id"`
+	WorkflowName string             `bson:"workflow_name"`
+	TaskID       primitive.ObjectID `bson:"task_id"`
+	TaskName     string             `bson:"task_name"`
+	Status       TaskStatus         `bson:"status"`
+	Order        int                `bson:"order"`
+	CreatedAt    time.Time          `bson:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at"`
+	Assignee     string             `bson:"assignee"`
+}
+
+// Record returns the row in the columns of TaskRowHeader, with timestamps in
+// RFC 3339 and UTC. Timestamps a task has never had are left empty.
+func (row *TaskRow) Record() []string {
+	return []string{
+		row.WorkflowID.Hex(),
+		row.WorkflowName,
+		row.TaskID.Hex(),
+		row.TaskName,
+		string(row.Status),
+		strconv.Itoa(row.Order),
+		formatReportTime(row.CreatedAt),
+		formatReportTime(row.UpdatedAt),
+		row.Assignee,
+	}
+}
+
+func formatReportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// ReportFilter narrows workflow analytics to the time range [From, To), to
+// the workflows of Owner unless it is empty, and to the work of the members of
+// Team unless it is empty.
+type ReportFilter struct {
+	From  time.Time
+	To    time.Time
+	Owner string
+	Team  string
+}
+
+// LeadTimeReport summarizes the time from the creation of workflows to the
+// moment their last task was done, over the workflows done in a range.
+type LeadTimeReport struct {
+	Workflows      int     `json:"workflows" bson:"workflows"`
+	AverageSeconds float64 `json:"average_seconds" bson:"average_seconds"`
+	MinSeconds     float64 `json:"min_seconds" bson:"min_seconds"`
+	MaxSeconds     float64 `json:"max_seconds" bson:"max_seconds"`
+}
+
+// CycleTime summarizes the time tasks of a name took from first being in
+// progress to being completed.
+type CycleTime struct {
+	Task           string  `json:"task" bson:"_id"`
+	Completed      int     `json:"completed" bson:"completed"`
+	AverageSeconds float64 `json:"average_seconds" bson:"average_seconds"`
+	MinSeconds     float64 `json:"min_seconds" bson:"min_seconds"`
+	MaxSeconds     float64 `json:"max_seconds" bson:"max_seconds"`
+}
+
+// WeeklyThroughput counts the tasks completed in the ISO week starting on
+// Monday Week, midnight UTC.
+type WeeklyThroughput struct {
+	Week      time.Time `json:"week" bson:"_id"`
+	Completed int       `json:"completed" bson:"completed"`
+}
+
+// StepDuration summarizes the time a step of a workflow spent before it was
+// done, per run, and how that time splits over the statuses it waited in.
+type StepDuration struct {
+	Step                     string  `json:"step" bson:"_id"`
+	Runs                     int     `json:"runs" bson:"runs"`
+	AverageSeconds           float64 `json:"average_seconds" bson:"average_seconds"`
+	MaxSeconds               float64 `json:"max_seconds" bson:"max_seconds"`
+	AveragePendingSeconds    float64 `json:"average_pending_seconds" bson:"average_pending_seconds"`
+	AverageInProgressSeconds float64 `json:"average_in_progress_seconds" bson:"average_in_progress_seconds"`
+	AverageFailedSeconds     float64 `json:"average_failed_seconds" bson:"average_failed_seconds"`
+}
+
+// StartOfWeek returns the Monday midnight UTC that starts the ISO week of t.
+func StartOfWeek(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// FillThroughputWeeks lists every week the range [from, to) touches, oldest
+// first, with the counts of weeks and zero for the weeks without completions.
+func FillThroughputWeeks(weeks []WeeklyThroughput, from time.Time, to time.Time) []WeeklyThroughput {
+	completed := map[int64]int{}
+	for _, week := range weeks {
+		completed[week.Week.Unix()] = week.Completed
+	}
+
+	filled := []WeeklyThroughput{}
+	for week := StartOfWeek(from); week.Before(to); week = week.AddDate(0, 0, 7) {
+		filled = append(filled, WeeklyThroughput{Week: week, Completed: completed[week.Unix()]})
+	}
+	return filled
+}
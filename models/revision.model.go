@@ -0,0 +1,285 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RevisionTask struct {
+	ID          primitive.ObjectID   `json:"id" bson:"_id"`
+	Name        string               `json:"name" bson:"name"`
+	Description string               `json:"description" bson:"description"`
+	Order       int                  `json:"order" bson:"order"`
+	DependsOn   []primitive.ObjectID `json:"depends_on" bson:"depends_on"`
+	Type        TaskType             `json:"type" bson:"type"`
+	Gateway     *Gateway             `json:"gateway,omitempty" bson:"gateway,omitempty"`
+	Approval    *Approval            `json:"approval,omitempty" bson:"approval,omitempty"`
+	Automation  *Automation          `json:"automation,omitempty" bson:"automation,omitempty"`
+	Form        []FieldDefinition    `json:"form,omitempty" bson:"form,omitempty"`
+	SLA         *SLA                 `json:"sla,omitempty" bson:"sla,omitempty"`
+}
+
+// WorkflowRevision is an immutable snapshot of a workflow definition. Run state
+// such as task status is not part of the definition.
+type WorkflowRevision struct {
+	ID                  primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	WorkflowID          primitive.ObjectID `json:"workflow_id" bson:"workflow_id"`
+	Revision            int                `json:"revision" bson:"revision"`
+	Name                string             `json:"name" bson:"name"`
+	Tasks               []RevisionTask     `json:"tasks" bson:"tasks"`
+	VariableDefinitions []FieldDefinition  `json:"variable_definitions" bson:"variable_definitions"`
+	CreatedAt           time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+type TaskDiff struct {
+	TaskID  primitive.ObjectID     `json:"task_id"`
+	Name    string                 `json:"name"`
+	Changes map[string]FieldChange `json:"changes"`
+}
+
+type WorkflowDiff struct {
+	From         int            `json:"from"`
+	To           int            `json:"to"`
+	Name         *FieldChange   `json:"name,omitempty"`
+	Variables    *FieldChange   `json:"variable_definitions,omitempty"`
+	AddedTasks   []RevisionTask `json:"added_tasks"`
+	RemovedTasks []RevisionTask `json:"removed_tasks"`
+	ChangedTasks []TaskDiff     `json:"changed_tasks"`
+}
+
+func NewWorkflowRevision(workflow Workflow) WorkflowRevision {
+	tasks := make([]RevisionTask, 0, len(workflow.Tasks))
+	for _, task := range workflow.Tasks {
+		dependsOn := task.DependsOn
+		if dependsOn == nil {
+			dependsOn = []primitive.ObjectID{}
+		}
+		var gateway *Gateway
+		if task.Gateway != nil {
+			// Only the flows are part of the definition; the taken paths are run state.
+			gateway = &Gateway{Kind: task.Gateway.Kind, Flows: task.Gateway.Flows}
+		}
+		var approval *Approval
+		if task.Approval != nil {
+			approval = task.Approval.Definition()
+		}
+		var automation *Automation
+		if task.Automation != nil {
+			automation = task.Automation.Definition()
+		}
+		var sla *SLA
+		if task.SLA != nil {
+			sla = task.SLA.Definition()
+		}
+		tasks = append(tasks, RevisionTask{
+			ID:          task.ID,
+			Name:        task.Name,
+			Description: task.Description,
+			Order:       task.Order,
+			DependsOn:   dependsOn,
+			Type:        task.Type,
+			Gateway:     gateway,
+			Approval:    approval,
+			Automation:  automation,
+			Form:        task.Form,
+			SLA:         sla,
+		})
+	}
+
+	return WorkflowRevision{
+		WorkflowID:          workflow.ID,
+		Name:                workflow.Name,
+		Tasks:               tasks,
+		VariableDefinitions: workflow.VariableDefinitions,
+		CreatedAt:           time.Now(),
+	}
+}
+
+func (revision *WorkflowRevision) SameDefinition(other WorkflowRevision) bool {
+	if revision.Name != other.Name || len(revision.Tasks) != len(other.Tasks) ||
+		!sameFields(revision.VariableDefinitions, other.VariableDefinitions) {
+		return false
+	}
+
+	for i, task := range revision.Tasks {
+		otherTask := other.Tasks[i]
+		if task.ID != otherTask.ID || task.Name != otherTask.Name || task.Description != otherTask.Description ||
+			task.Order != otherTask.Order || !sameObjectIDs(task.DependsOn, otherTask.DependsOn) ||
+			task.Type != otherTask.Type || !sameGateway(task.Gateway, otherTask.Gateway) ||
+			!sameApproval(task.Approval, otherTask.Approval) || !sameAutomation(task.Automation, otherTask.Automation) ||
+			!sameFields(task.Form, otherTask.Form) || !sameSLA(task.SLA, otherTask.SLA) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sameObjectIDs(a []primitive.ObjectID, b []primitive.ObjectID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameFields(a []FieldDefinition, b []FieldDefinition) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Label != b[i].Label || a[i].Type != b[i].Type ||
+			a[i].Required != b[i].Required || !sameStrings(a[i].Options, b[i].Options) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameGateway(a *Gateway, b *Gateway) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Kind != b.Kind || len(a.Flows) != len(b.Flows) {
+		return false
+	}
+	for i := range a.Flows {
+		if a.Flows[i] != b.Flows[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// RestoreTasks rebuilds the task list of the revision, keeping the status of
+// tasks that still exist in the current workflow.
+func (revision *WorkflowRevision) RestoreTasks(current []Task) []Task {
+	currentTasks := map[primitive.ObjectID]Task{}
+	for _, task := range current {
+		currentTasks[task.ID] = task
+	}
+
+	tasks := make([]Task, 0, len(revision.Tasks))
+	for _, revisionTask := range revision.Tasks {
+		task, ok := currentTasks[revisionTask.ID]
+		if !ok {
+			task = Task{Status: Pending}
+			task.ID = revisionTask.ID
+			task.SetCreatedAt()
+		}
+		task.Name = revisionTask.Name
+		task.Description = revisionTask.Description
+		task.Order = revisionTask.Order
+		task.DependsOn = revisionTask.DependsOn
+		task.Type = revisionTask.Type
+		if revisionTask.Gateway == nil {
+			task.Gateway = nil
+		} else if task.Gateway == nil || !sameGateway(task.Gateway, revisionTask.Gateway) {
+			task.Gateway = revisionTask.Gateway
+		}
+		if revisionTask.Approval == nil {
+			task.Approval = nil
+		} else if task.Approval == nil || !sameApproval(task.Approval, revisionTask.Approval) {
+			task.Approval = revisionTask.Approval
+		}
+		if revisionTask.Automation == nil {
+			task.Automation = nil
+		} else if task.Automation == nil || !sameAutomation(task.Automation, revisionTask.Automation) {
+			task.Automation = revisionTask.Automation
+		}
+		task.Form = revisionTask.Form
+		if revisionTask.SLA == nil {
+			task.SLA = nil
+		} else if task.SLA == nil || !sameSLA(task.SLA, revisionTask.SLA) {
+			task.SLA = revisionTask.SLA
+		}
+		task.SetUpdatedAt()
+		tasks = append(tasks, task)
+	}
+
+	return NormalizeTaskOrders(tasks)
+}
+
+func DiffRevisions(from WorkflowRevision, to WorkflowRevision) WorkflowDiff {
+	diff := WorkflowDiff{
+		From:         from.Revision,
+		To:           to.Revision,
+		AddedTasks:   []RevisionTask{},
+		RemovedTasks: []RevisionTask{},
+		ChangedTasks: []TaskDiff{},
+	}
+
+	if from.Name != to.Name {
+		diff.Name = &FieldChange{From: from.Name, To: to.Name}
+	}
+	if !sameFields(from.VariableDefinitions, to.VariableDefinitions) {
+		diff.Variables = &FieldChange{From: from.VariableDefinitions, To: to.VariableDefinitions}
+	}
+
+	fromTasks := map[primitive.ObjectID]RevisionTask{}
+	for _, task := range from.Tasks {
+		fromTasks[task.ID] = task
+	}
+
+	for _, toTask := range to.Tasks {
+		fromTask, ok := fromTasks[toTask.ID]
+		if !ok {
+			diff.AddedTasks = append(diff.AddedTasks, toTask)
+			continue
+		}
+		delete(fromTasks, toTask.ID)
+
+		changes := map[string]FieldChange{}
+		if fromTask.Name != toTask.Name {
+			changes["name"] = FieldChange{From: fromTask.Name, To: toTask.Name}
+		}
+		if fromTask.Description != toTask.Description {
+			changes["description"] = FieldChange{From: fromTask.Description, To: toTask.Description}
+		}
+		if fromTask.Order != toTask.Order {
+			changes["order"] = FieldChange{From: fromTask.Order, To: toTask.Order}
+		}
+		if !sameObjectIDs(fromTask.DependsOn, toTask.DependsOn) {
+			changes["depends_on"] = FieldChange{From: fromTask.DependsOn, To: toTask.DependsOn}
+		}
+		if fromTask.Type != toTask.Type {
+			changes["type"] = FieldChange{From: fromTask.Type, To: toTask.Type}
+		}
+		if !sameGateway(fromTask.Gateway, toTask.Gateway) {
+			changes["gateway"] = FieldChange{From: fromTask.Gateway, To: toTask.Gateway}
+		}
+		if !sameApproval(fromTask.Approval, toTask.Approval) {
+			changes["approval"] = FieldChange{From: fromTask.Approval, To: toTask.Approval}
+		}
+		if !sameAutomation(fromTask.Automation, toTask.Automation) {
+			changes["automation"] = FieldChange{From: fromTask.Automation, To: toTask.Automation}
+		}
+		if !sameFields(fromTask.Form, toTask.Form) {
+			changes["form"] = FieldChange{From: fromTask.Form, To: toTask.Form}
+		}
+		if !sameSLA(fromTask.SLA, toTask.SLA) {
+			changes["sla"] = FieldChange{From: fromTask.SLA, To: toTask.SLA}
+		}
+		if len(changes) > 0 {
+			diff.ChangedTasks = append(diff.ChangedTasks, TaskDiff{TaskID: toTask.ID, Name: toTask.Name, Changes: changes})
+		}
+	}
+
+	for _, task := range from.Tasks {
+		if _, ok := fromTasks[task.ID]; ok {
+			diff.RemovedTasks = append(diff.RemovedTasks, task)
+		}
+	}
+
+	return diff
+}
@@ -0,0 +1,161 @@
+package models
+
+import (
+	"errors"
+	"time"
+	"virtual_workflow_management_system_gin/common"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CatchUpPolicy string
+
+const (
+	// CatchUpSkip drops occurrences missed while the server was down.
+	CatchUpSkip CatchUpPolicy = "Skip"
+	// CatchUpOnce runs a single copy for all missed occurrences.
+	CatchUpOnce CatchUpPolicy = "Once"
+	// CatchUpAll runs a copy for every missed occurrence, up to MaxCatchUpRuns.
+	CatchUpAll CatchUpPolicy = "All"
+)
+
+const (
+	ScheduleGracePeriod = 5 * time.Minute
+	MaxCatchUpRuns      = 50
+	MaxScheduleRuns     = 100
+	DefaultUpcomingRuns = 10
+)
+
+type ScheduleRun struct {
+	WorkflowID  primitive.ObjectID `json:"workflow_id" bson:"workflow_id"`
+	ScheduledAt time.Time          `json:"scheduled_at" bson:"scheduled_at"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// Schedule creates a copy of a workflow, with fresh pending tasks, at every
+// occurrence of a cron expression. Occurrences are computed in Timezone.
+type Schedule struct {
+	common.BaseModel `bson:",inline"`
+	WorkflowID       primitive.ObjectID `json:"workflow_id" bson:"workflow_id"`
+	Owner            string             `json:"owner" bson:"owner"`
+	Expression       string             `json:"expression" bson:"expression"`
+	Timezone         string             `json:"timezone" bson:"timezone"`
+	StartAt          *time.Time         `json:"start_at,omitempty" bson:"start_at,omitempty"`
+	EndAt            *time.Time         `json:"end_at,omitempty" bson:"end_at,omitempty"`
+	CatchUp          CatchUpPolicy      `json:"catch_up" bson:"catch_up"`
+	Paused           bool               `json:"paused" bson:"paused"`
+	NextRunAt        *time.Time         `json:"next_run_at,omitempty" bson:"next_run_at,omitempty"`
+	LastRunAt        *time.Time         `json:"last_run_at,omitempty" bson:"last_run_at,omitempty"`
+	Runs             []ScheduleRun      `json:"runs" bson:"runs"`
+	LeaseID          string             `json:"-" bson:"lease_id,omitempty"`
+	LeasedUntil      *time.Time         `json:"-" bson:"leased_until,omitempty"`
+}
+
+func (schedule *Schedule) Validate() error {
+	if _, err := common.ParseCron(schedule.Expression); err != nil {
+		return err
+	}
+	if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+		return errors.New("unknown time zone: " + schedule.Timezone)
+	}
+
+	switch schedule.CatchUp {
+	case CatchUpSkip, CatchUpOnce, CatchUpAll:
+	default:
+		return errors.New("unknown catch-up policy: " + string(schedule.CatchUp))
+	}
+
+	if schedule.StartAt != nil && schedule.EndAt != nil && !schedule.EndAt.After(*schedule.StartAt) {
+		return errors.New("schedule end must be after its start")
+	}
+
+	return nil
+}
+
+func (schedule *Schedule) CheckScheduleAccess(user JWTUser) bool {
+	return user.Username == schedule.Owner || user.Role == Admin
+}
+
+// occurrences calls visit with every occurrence after the given time that lies
+// within the start and end dates, until visit returns false.
+func (schedule *Schedule) occurrences(after time.Time, visit func(time.Time) bool) {
+	cron, err := common.ParseCron(schedule.Expression)
+	if err != nil {
+		return
+	}
+	location, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return
+	}
+
+	if schedule.StartAt != nil && after.Before(*schedule.StartAt) {
+		// Occurrences are strictly after the given time, so step back to
+		// include an occurrence falling exactly on the start.
+		after = schedule.StartAt.Add(-time.Nanosecond)
+	}
+
+	for {
+		occurrence := cron.Next(after.In(location))
+		if occurrence.IsZero() || (schedule.EndAt != nil && occurrence.After(*schedule.EndAt)) {
+			return
+		}
+		if !visit(occurrence) {
+			return
+		}
+		after = occurrence
+	}
+}
+
+// Upcoming lists up to count occurrences after the given time.
+func (schedule *Schedule) Upcoming(after time.Time, count int) []time.Time {
+	upcoming := []time.Time{}
+	schedule.occurrences(after, func(occurrence time.Time) bool {
+		upcoming = append(upcoming, occurrence)
+		return len(upcoming) < count
+	})
+	return upcoming
+}
+
+// Reschedule sets the next run to the first occurrence after the given time.
+func (schedule *Schedule) Reschedule(after time.Time) {
+	schedule.NextRunAt = nil
+	schedule.occurrences(after, func(occurrence time.Time) bool {
+		schedule.NextRunAt = &occurrence
+		return false
+	})
+}
+
+// DueRuns returns the occurrences to run now according to the catch-up policy
+// and moves NextRunAt past the given time. An occurrence up to
+// ScheduleGracePeriod old is on time; older ones were missed.
+func (schedule *Schedule) DueRuns(now time.Time) []time.Time {
+	if schedule.NextRunAt == nil || schedule.NextRunAt.After(now) {
+		return []time.Time{}
+	}
+
+	due := []time.Time{*schedule.NextRunAt}
+	schedule.occurrences(*schedule.NextRunAt, func(occurrence time.Time) bool {
+		if occurrence.After(now) {
+			return false
+		}
+		due = append(due, occurrence)
+		if len(due) > MaxCatchUpRuns {
+			due = due[1:]
+		}
+		return true
+	})
+	schedule.Reschedule(now)
+
+	latest := due[len(due)-1]
+	switch schedule.CatchUp {
+	case CatchUpAll:
+		return due
+	case CatchUpOnce:
+		return []time.Time{latest}
+	default:
+		if now.Sub(latest) > ScheduleGracePeriod {
+			return []time.Time{}
+		}
+		return []time.Time{latest}
+	}
+}
@@ -0,0 +1,332 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxLabels bounds the labels of a workflow or a task.
+const MaxLabels = 20
+
+var labelPattern = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N} _:./-]{0,49}$`)
+
+// Weights of the searchable fields. The text indexes use the same weights, so
+// task hits rank like the workflows MongoDB ranks.
+const (
+	WorkflowNameWeight    = 10
+	WorkflowLabelWeight   = 5
+	TaskNameWeight        = 5
+	TaskLabelWeight       = 3
+	TaskDescriptionWeight = 1
+)
+
+// NormalizeLabels trims and lowercases labels and drops duplicates, keeping the
+// first occurrence of each.
+func NormalizeLabels(labels []string) ([]string, error) {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, label := range labels {
+		label = strings.ToLower(strings.TrimSpace(label))
+		if !labelPattern.MatchString(label) {
+			return nil, errors.New("invalid label: " + label)
+		}
+		if seen[label] {
+			continue
+		}
+		seen[label] = true
+		normalized = append(normalized, label)
+	}
+
+	if len(normalized) > MaxLabels {
+		return nil, errors.New("too many labels")
+	}
+
+	return normalized, nil
+}
+
+type WorkflowHit struct {
+	WorkflowID primitive.ObjectID `json:"workflow_id"`
+	Name       string             `json:"name"`
+	Owner      string             `json:"owner"`
+	Labels     []string           `json:"labels"`
+	Score      float64            `json:"score"`
+}
+
+type TaskHit struct {
+	WorkflowID      primitive.ObjectID `json:"workflow_id"`
+	WorkflowName    string             `json:"workflow_name"`
+	TaskID          primitive.ObjectID `json:"task_id"`
+	Name            string             `json:"name"`
+	Status          TaskStatus         `json:"status"`
+	Labels          []string           `json:"labels"`
+	MatchedComments int                `json:"matched_comments"`
+	Score           float64            `json:"score"`
+	// workflowLabels are the labels the task inherits from its workflow.
+	workflowLabels []string
+}
+
+// EffectiveLabels are the labels of the task and of its workflow, which label
+// filters and facets go by.
+func (hit *TaskHit) EffectiveLabels() []string {
+	labels := append([]string{}, hit.Labels...)
+	for _, label := range hit.workflowLabels {
+		if !containsString(labels, label) {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type SearchFacets struct {
+	Labels   []FacetCount `json:"labels"`
+	Statuses []FacetCount `json:"statuses"`
+}
+
+type SearchResult struct {
+	Workflows []WorkflowHit `json:"workflows"`
+	Tasks     []TaskHit     `json:"tasks"`
+	Facets    SearchFacets  `json:"facets"`
+}
+
+// WorkflowMatch is a workflow found by the text index with its text score.
+type WorkflowMatch struct {
+	Workflow `bson:",inline"`
+	Score    float64 `bson:"score"`
+}
+
+// CommentMatch sums up the comments of a task found by the text index. Score is
+// the text score of the best matching comment.
+type CommentMatch struct {
+	WorkflowID primitive.ObjectID `bson:"workflow_id"`
+	TaskID     primitive.ObjectID `bson:"task_id"`
+	Score      float64            `bson:"score"`
+	Count      int                `bson:"count"`
+}
+
+// SearchTerms splits a text query into lowercase words. Words negated with a
+// leading '-' are left to MongoDB and never count as a match here.
+func SearchTerms(query string) []string {
+	terms := []string{}
+	for _, field := range strings.Fields(query) {
+		if strings.HasPrefix(field, "-") {
+			continue
+		}
+		words := strings.FieldsFunc(strings.ToLower(field), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		})
+		terms = append(terms, words...)
+	}
+	return terms
+}
+
+// matchesTerm tells whether a word of a field matches a search term. Prefixes
+// match both ways so that "invoice" finds "invoices" and the other way round,
+// close to the stemming of the text index.
+func matchesTerm(word string, term string) bool {
+	switch {
+	case word == term:
+		return true
+	case len(term) >= 3 && strings.HasPrefix(word, term):
+		return true
+	default:
+		return len(word) >= 3 && len(term)-len(word) <= 2 && strings.HasPrefix(term, word)
+	}
+}
+
+// scoreText weighs the terms found in text.
+func scoreText(terms []string, text string, weight int) float64 {
+	words := SearchTerms(text)
+	score := 0.0
+	for _, term := range terms {
+		for _, word := range words {
+			if matchesTerm(word, term) {
+				score += float64(weight)
+				break
+			}
+		}
+	}
+	return score
+}
+
+func scoreLabels(terms []string, labels []string, weight int) float64 {
+	return scoreText(terms, strings.Join(labels, " "), weight)
+}
+
+// ScoreWorkflow weighs the terms found in the name and labels of the workflow
+// itself, leaving out its tasks.
+func (workflow *Workflow) ScoreWorkflow(terms []string) float64 {
+	return scoreText(terms, workflow.Name, WorkflowNameWeight) + scoreLabels(terms, workflow.Labels, WorkflowLabelWeight)
+}
+
+func (task *Task) ScoreTask(terms []string) float64 {
+	return scoreText(terms, task.Name, TaskNameWeight) +
+		scoreLabels(terms, task.Labels, TaskLabelWeight) +
+		scoreText(terms, task.Description, TaskDescriptionWeight)
+}
+
+// BuildSearchHits turns matched workflows and comments into hits. A workflow
+// is a hit when its own name or labels match, or when the text index found it
+// but none of its tasks match on their own. A task is a hit when its fields or
+// its comments match; comment scores add to its own.
+func BuildSearchHits(terms []string, workflows []WorkflowMatch, comments []CommentMatch) ([]WorkflowHit, []TaskHit) {
+	commentsByTask := map[primitive.ObjectID]CommentMatch{}
+	for _, comment := range comments {
+		commentsByTask[comment.TaskID] = comment
+	}
+
+	workflowHits := []WorkflowHit{}
+	taskHits := []TaskHit{}
+	for _, match := range workflows {
+		workflow := match.Workflow
+		taskHitCount := 0
+		for _, task := range workflow.Tasks {
+			score := task.ScoreTask(terms)
+			comment, commented := commentsByTask[task.ID]
+			if commented && comment.WorkflowID == workflow.ID {
+				score += comment.Score
+			} else {
+				comment = CommentMatch{}
+			}
+			if score == 0 {
+				continue
+			}
+
+			taskHits = append(taskHits, TaskHit{
+				WorkflowID:      workflow.ID,
+				WorkflowName:    workflow.Name,
+				TaskID:          task.ID,
+				Name:            task.Name,
+				Status:          task.Status,
+				Labels:          nonNilLabels(task.Labels),
+				MatchedComments: comment.Count,
+				Score:           score,
+				workflowLabels:  workflow.Labels,
+			})
+			taskHitCount++
+		}
+
+		if match.Score > 0 && (workflow.ScoreWorkflow(terms) > 0 || taskHitCount == 0) {
+			workflowHits = append(workflowHits, WorkflowHit{
+				WorkflowID: workflow.ID,
+				Name:       workflow.Name,
+				Owner:      workflow.Owner,
+				Labels:     nonNilLabels(workflow.Labels),
+				Score:      match.Score,
+			})
+		}
+	}
+
+	sort.SliceStable(workflowHits, func(i, j int) bool {
+		return workflowHits[i].Score > workflowHits[j].Score
+	})
+	sort.SliceStable(taskHits, func(i, j int) bool {
+		return taskHits[i].Score > taskHits[j].Score
+	})
+
+	return workflowHits, taskHits
+}
+
+// CountSearchFacets counts the labels of the hits and the statuses of the task
+// hits.
+func CountSearchFacets(workflowHits []WorkflowHit, taskHits []TaskHit) SearchFacets {
+	labels := map[string]int{}
+	statuses := map[string]int{}
+	for _, hit := range workflowHits {
+		for _, label := range hit.Labels {
+			labels[label]++
+		}
+	}
+	for i := range taskHits {
+		for _, label := range taskHits[i].EffectiveLabels() {
+			labels[label]++
+		}
+		statuses[string(taskHits[i].Status)]++
+	}
+
+	return SearchFacets{Labels: facetCounts(labels), Statuses: facetCounts(statuses)}
+}
+
+// FilterSearchHits keeps the hits carrying every label and, for tasks, one of
+// the statuses. Workflows have no status, so a status filter leaves only tasks.
+func FilterSearchHits(workflowHits []WorkflowHit, taskHits []TaskHit, labels []string, statuses []TaskStatus) ([]WorkflowHit, []TaskHit) {
+	filteredWorkflows := []WorkflowHit{}
+	if len(statuses) == 0 {
+		for _, hit := range workflowHits {
+			if containsAll(hit.Labels, labels) {
+				filteredWorkflows = append(filteredWorkflows, hit)
+			}
+		}
+	}
+
+	filteredTasks := []TaskHit{}
+	for _, hit := range taskHits {
+		if !containsAll(hit.EffectiveLabels(), labels) {
+			continue
+		}
+		if len(statuses) > 0 && !containsStatus(statuses, hit.Status) {
+			continue
+		}
+		filteredTasks = append(filteredTasks, hit)
+	}
+
+	return filteredWorkflows, filteredTasks
+}
+
+// facetCounts orders facet values by count, then by value.
+func facetCounts(counts map[string]int) []FacetCount {
+	facets := make([]FacetCount, 0, len(counts))
+	for value, count := range counts {
+		facets = append(facets, FacetCount{Value: value, Count: count})
+	}
+	sort.Slice(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].Value < facets[j].Value
+	})
+	return facets
+}
+
+func nonNilLabels(labels []string) []string {
+	if labels == nil {
+		return []string{}
+	}
+	return labels
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAll(values []string, wanted []string) bool {
+	for _, value := range wanted {
+		if !containsString(values, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsStatus(statuses []TaskStatus, status TaskStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
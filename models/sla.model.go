@@ -0,0 +1,251 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SLAState string
+
+const (
+	SLAOnTrack  SLAState = "On Track"
+	SLAAtRisk   SLAState = "At Risk"
+	SLABreached SLAState = "Breached"
+)
+
+type EscalationAction string
+
+const (
+	NotifyAssignee EscalationAction = "NotifyAssignee"
+	NotifyOwner    EscalationAction = "NotifyOwner"
+	ReassignToRole EscalationAction = "ReassignToRole"
+)
+
+// DefaultAtRiskPercent is the share of the SLA duration after which a task is
+// at risk.
+const DefaultAtRiskPercent = 80
+
+// EscalationStep runs AfterSeconds after the task breached its SLA.
+type EscalationStep struct {
+	Action       EscalationAction `json:"action" bson:"action"`
+	AfterSeconds int64            `json:"after_seconds" bson:"after_seconds"`
+	Role         UserRole         `json:"role,omitempty" bson:"role,omitempty"`
+}
+
+// Escalation records an escalation step that was run and the user or role it
+// went to.
+type Escalation struct {
+	Step        int              `json:"step" bson:"step"`
+	Action      EscalationAction `json:"action" bson:"action"`
+	Username    string           `json:"username,omitempty" bson:"username,omitempty"`
+	Role        UserRole         `json:"role,omitempty" bson:"role,omitempty"`
+	EscalatedAt time.Time        `json:"escalated_at" bson:"escalated_at"`
+}
+
+// SLA limits how long a task may take once it is ready and escalates along the
+// configured steps when it takes longer. The clock starts when every task the
+// task waits for is done and stops when the task is done.
+type SLA struct {
+	DurationSeconds int64            `json:"duration_seconds" bson:"duration_seconds"`
+	AtRiskPercent   int              `json:"at_risk_percent" bson:"at_risk_percent"`
+	Steps           []EscalationStep `json:"escalation" bson:"escalation"`
+	StartedAt       *time.Time       `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	DueAt           *time.Time       `json:"due_at,omitempty" bson:"due_at,omitempty"`
+	StoppedAt       *time.Time       `json:"stopped_at,omitempty" bson:"stopped_at,omitempty"`
+	Escalations     []Escalation     `json:"escalations" bson:"escalations"`
+	NextCheckAt     *time.Time       `json:"-" bson:"next_check_at,omitempty"`
+	LeaseID         string           `json:"-" bson:"lease_id,omitempty"`
+	LeasedUntil     *time.Time       `json:"-" bson:"leased_until,omitempty"`
+	// State is derived when the task is read and never stored.
+	State SLAState `json:"state,omitempty" bson:"-"`
+}
+
+func (sla *SLA) Validate() error {
+	if sla.DurationSeconds < 1 {
+		return errors.New("SLA duration must be positive")
+	}
+	if sla.AtRiskPercent < 1 || sla.AtRiskPercent > 99 {
+		return errors.New("SLA at risk percent must be between 1 and 99")
+	}
+
+	var after int64
+	for _, step := range sla.Steps {
+		switch step.Action {
+		case NotifyAssignee, NotifyOwner:
+		case ReassignToRole:
+			if step.Role != Admin && step.Role != Employer {
+				return errors.New("unknown role: " + string(step.Role))
+			}
+		default:
+			return errors.New("unknown escalation action: " + string(step.Action))
+		}
+		if step.AfterSeconds < after {
+			return errors.New("escalation steps must be in order")
+		}
+		after = step.AfterSeconds
+	}
+
+	return nil
+}
+
+// Definition returns the SLA configuration without a running clock.
+func (sla *SLA) Definition() *SLA {
+	return &SLA{
+		DurationSeconds: sla.DurationSeconds,
+		AtRiskPercent:   sla.AtRiskPercent,
+		Steps:           sla.Steps,
+		Escalations:     []Escalation{},
+	}
+}
+
+func (sla *SLA) Duration() time.Duration {
+	return time.Duration(sla.DurationSeconds) * time.Second
+}
+
+// Start starts the clock and schedules the first check at the due time.
+func (sla *SLA) Start(now time.Time) {
+	dueAt := now.Add(sla.Duration())
+	sla.StartedAt = &now
+	sla.DueAt = &dueAt
+	sla.StoppedAt = nil
+	sla.Escalations = []Escalation{}
+	sla.LeaseID = ""
+	sla.LeasedUntil = nil
+	sla.scheduleNextCheck()
+}
+
+// Stop stops the clock. Escalations that did not run yet are dropped.
+func (sla *SLA) Stop(now time.Time) {
+	sla.StoppedAt = &now
+	sla.NextCheckAt = nil
+	sla.LeaseID = ""
+	sla.LeasedUntil = nil
+}
+
+// scheduleNextCheck sets the next check to the time of the next escalation
+// step, or clears it when every step ran.
+func (sla *SLA) scheduleNextCheck() {
+	sla.NextCheckAt = nil
+	if sla.DueAt == nil || len(sla.Escalations) >= len(sla.Steps) {
+		return
+	}
+	next := sla.DueAt.Add(time.Duration(sla.Steps[len(sla.Escalations)].AfterSeconds) * time.Second)
+	sla.NextCheckAt = &next
+}
+
+// Evaluate sets State for the given time. A task whose clock has not started
+// is on track.
+func (sla *SLA) Evaluate(now time.Time) SLAState {
+	sla.State = SLAOnTrack
+	if sla.StartedAt == nil || sla.DueAt == nil {
+		return sla.State
+	}
+
+	end := now
+	if sla.StoppedAt != nil {
+		end = *sla.StoppedAt
+	}
+
+	atRisk := sla.StartedAt.Add(sla.Duration() * time.Duration(sla.AtRiskPercent) / 100)
+	switch {
+	case end.After(*sla.DueAt):
+		sla.State = SLABreached
+	case sla.StoppedAt == nil && !end.Before(atRisk):
+		sla.State = SLAAtRisk
+	}
+
+	return sla.State
+}
+
+// EvaluateSLAs sets the SLA state of every task with an SLA.
+func EvaluateSLAs(tasks []Task, now time.Time) {
+	for i := range tasks {
+		if tasks[i].SLA != nil {
+			tasks[i].SLA.Evaluate(now)
+		}
+	}
+}
+
+// Escalate runs the escalation steps of the task that are due and returns the
+// escalations it made. Notifications go to the assignee and the workflow
+// owner; reassigning hands the task from its assignee to a role.
+func (task *Task) Escalate(owner string, now time.Time) []Escalation {
+	sla := task.SLA
+	escalations := []Escalation{}
+	for sla.NextCheckAt != nil && !sla.NextCheckAt.After(now) {
+		step := sla.Steps[len(sla.Escalations)]
+		escalation := Escalation{Step: len(sla.Escalations), Action: step.Action, EscalatedAt: now}
+		switch step.Action {
+		case NotifyAssignee:
+			escalation.Username = task.Assignee
+			escalation.Role = task.AssigneeRole
+		case NotifyOwner:
+			escalation.Username = owner
+		case ReassignToRole:
+			escalation.Role = step.Role
+			task.Assignee = ""
+			task.AssigneeRole = step.Role
+		}
+		sla.Escalations = append(sla.Escalations, escalation)
+		escalations = append(escalations, escalation)
+		sla.scheduleNextCheck()
+	}
+	sla.LeaseID = ""
+	sla.LeasedUntil = nil
+
+	return escalations
+}
+
+// trackSLAs starts the clock of tasks that became ready and stops the clock of
+// tasks that are done. It reports whether any task changed.
+func (workflow *Workflow) trackSLAs(incoming map[primitive.ObjectID][]primitive.ObjectID, index map[primitive.ObjectID]int, now time.Time) bool {
+	changed := false
+	for i := range workflow.Tasks {
+		task := &workflow.Tasks[i]
+		if task.SLA == nil {
+			continue
+		}
+
+		if task.SLA.StartedAt != nil {
+			if task.SLA.StoppedAt == nil && (task.IsDone() || task.Status == Failed) {
+				task.SLA.Stop(now)
+				changed = true
+			}
+			continue
+		}
+
+		if task.IsDone() || task.Status == Failed {
+			continue
+		}
+		ready := true
+		for _, predecessorID := range incoming[task.ID] {
+			if predecessorIndex, ok := index[predecessorID]; ok && !workflow.Tasks[predecessorIndex].IsDone() {
+				ready = false
+				break
+			}
+		}
+		if ready {
+			task.SLA.Start(now)
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+func sameSLA(a *SLA, b *SLA) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.DurationSeconds != b.DurationSeconds || a.AtRiskPercent != b.AtRiskPercent || len(a.Steps) != len(b.Steps) {
+		return false
+	}
+	for i := range a.Steps {
+		if a.Steps[i] != b.Steps[i] {
+			return false
+		}
+	}
+	return true
+}
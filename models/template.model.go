@@ -0,0 +1,312 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"virtual_workflow_management_system_gin/common"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TemplateScope string
+
+const (
+	GlobalTemplate       TemplateScope = "Global"
+	OrganizationTemplate TemplateScope = "Organization"
+	PersonalTemplate     TemplateScope = "Personal"
+)
+
+type TemplateParameter struct {
+	Name        string `json:"name" bson:"name"`
+	Description string `json:"description" bson:"description"`
+	Default     string `json:"default" bson:"default"`
+	Required    bool   `json:"required" bson:"required"`
+}
+
+type TemplateGatewayFlow struct {
+	Target    string `json:"target" bson:"target"`
+	Condition string `json:"condition" bson:"condition"`
+}
+
+// TemplateGateway is a gateway whose flows refer to template task keys.
+type TemplateGateway struct {
+	Kind  GatewayKind           `json:"kind" bson:"kind"`
+	Flows []TemplateGatewayFlow `json:"flows" bson:"flows"`
+}
+
+type TemplateTask struct {
+	Key         string                    `json:"key" bson:"key"`
+	Name        string                    `json:"name" bson:"name"`
+	Description string                    `json:"description" bson:"description"`
+	Order       int                       `json:"order" bson:"order"`
+	DependsOn   []string                  `json:"depends_on" bson:"depends_on"`
+	Type        TaskType                  `json:"type" bson:"type"`
+	Gateway     *TemplateGateway          `json:"gateway,omitempty" bson:"gateway,omitempty"`
+	Approval    *Approval                 `json:"approval,omitempty" bson:"approval,omitempty"`
+	Automation  *Automation               `json:"automation,omitempty" bson:"automation,omitempty"`
+	Form        []FieldDefinition         `json:"form,omitempty" bson:"form,omitempty"`
+	SLA         *SLA                      `json:"sla,omitempty" bson:"sla,omitempty"`
+	Checklist   []ChecklistItemDefinition `json:"checklist,omitempty" bson:"checklist,omitempty"`
+	Labels      []string                  `json:"labels,omitempty" bson:"labels,omitempty"`
+}
+
+type Template struct {
+	common.BaseModel    `bson:",inline"`
+	Name                string              `json:"name" bson:"name"`
+	Description         string              `json:"description" bson:"description"`
+	Scope               TemplateScope       `json:"scope" bson:"scope"`
+	Organization        string              `json:"organization" bson:"organization"`
+	Owner               string              `json:"owner" bson:"owner"`
+	Parameters          []TemplateParameter `json:"parameters" bson:"parameters"`
+	Tasks               []TemplateTask      `json:"tasks" bson:"tasks"`
+	VariableDefinitions []FieldDefinition   `json:"variable_definitions" bson:"variable_definitions"`
+}
+
+var templatePlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+func (template *Template) CheckTemplateAccess(user JWTUser, action UserAction) bool {
+	if user.Username == template.Owner {
+		return true
+	}
+
+	switch action {
+	case Edit, Delete:
+		return template.Scope == GlobalTemplate && user.Role == Admin
+	default:
+		switch template.Scope {
+		case GlobalTemplate:
+			return true
+		case OrganizationTemplate:
+			return template.Organization != "" && user.Organization == template.Organization
+		}
+	}
+
+	return false
+}
+
+// ValidateTasks checks that task keys are unique and every dependency and
+// gateway flow refers to another task of the template.
+func (template *Template) ValidateTasks() error {
+	if err := ValidateVariableDefinitions(template.VariableDefinitions); err != nil {
+		return err
+	}
+
+	keys := map[string]bool{}
+	for _, task := range template.Tasks {
+		if keys[task.Key] {
+			return errors.New("duplicate template task key: " + task.Key)
+		}
+		keys[task.Key] = true
+	}
+
+	for _, task := range template.Tasks {
+		for _, dependency := range task.DependsOn {
+			if dependency == task.Key || !keys[dependency] {
+				return errors.New("invalid dependency for template task: " + task.Key)
+			}
+		}
+		if task.Gateway != nil {
+			for _, flow := range task.Gateway.Flows {
+				if flow.Target == task.Key || !keys[flow.Target] {
+					return errors.New("invalid gateway flow for template task: " + task.Key)
+				}
+				if flow.Condition != "" {
+					if err := common.ParseExpression(flow.Condition); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if task.Approval != nil {
+			if err := task.Approval.Validate(); err != nil {
+				return err
+			}
+		}
+		if task.Automation != nil {
+			if err := task.Automation.Validate(); err != nil {
+				return err
+			}
+		}
+		if task.SLA != nil {
+			if err := task.SLA.Validate(); err != nil {
+				return err
+			}
+		}
+		if err := ValidateForm(task.Form, template.VariableDefinitions); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ResolveParameters merges the supplied values with the template defaults and
+// fails when a required parameter has no value.
+func (template *Template) ResolveParameters(values map[string]string) (map[string]string, error) {
+	resolved := map[string]string{}
+	for _, parameter := range template.Parameters {
+		value, ok := values[parameter.Name]
+		if !ok || value == "" {
+			value = parameter.Default
+		}
+		if value == "" && parameter.Required {
+			return nil, errors.New("missing template parameter: " + parameter.Name)
+		}
+		resolved[parameter.Name] = value
+	}
+
+	return resolved, nil
+}
+
+// RenderTemplateText replaces {{name}} placeholders with parameter values.
+// Unknown placeholders are left untouched.
+func RenderTemplateText(text string, parameters map[string]string) string {
+	return templatePlaceholder.ReplaceAllStringFunc(text, func(placeholder string) string {
+		name := templatePlaceholder.FindStringSubmatch(placeholder)[1]
+		if value, ok := parameters[name]; ok {
+			return value
+		}
+		return placeholder
+	})
+}
+
+// Instantiate builds a new workflow with fresh Pending tasks from the template.
+func (template *Template) Instantiate(name string, owner string, parameters map[string]string) (*Workflow, error) {
+	resolved, err := template.ResolveParameters(parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := template.ValidateTasks(); err != nil {
+		return nil, err
+	}
+
+	taskIDs := map[string]primitive.ObjectID{}
+	for _, task := range template.Tasks {
+		taskIDs[task.Key] = primitive.NewObjectID()
+	}
+
+	tasks := make([]Task, 0, len(template.Tasks))
+	for _, templateTask := range template.Tasks {
+		dependsOn := make([]primitive.ObjectID, 0, len(templateTask.DependsOn))
+		for _, dependency := range templateTask.DependsOn {
+			dependsOn = append(dependsOn, taskIDs[dependency])
+		}
+
+		task := Task{
+			Name:        RenderTemplateText(templateTask.Name, resolved),
+			Description: RenderTemplateText(templateTask.Description, resolved),
+			Status:      Pending,
+			Order:       templateTask.Order,
+			DependsOn:   dependsOn,
+			Type:        UserTask,
+		}
+		if templateTask.Gateway != nil {
+			gateway := &Gateway{Kind: templateTask.Gateway.Kind, Flows: []GatewayFlow{}, Taken: []primitive.ObjectID{}}
+			for _, flow := range templateTask.Gateway.Flows {
+				gateway.Flows = append(gateway.Flows, GatewayFlow{Target: taskIDs[flow.Target], Condition: flow.Condition})
+			}
+			task.Type = GatewayTask
+			task.Gateway = gateway
+		}
+		if templateTask.Approval != nil {
+			task.Type = ApprovalTask
+			task.Approval = templateTask.Approval.Definition()
+		}
+		if templateTask.Automation != nil {
+			task.Type = AutomatedTask
+			task.Automation = templateTask.Automation.Definition()
+		}
+		task.Form = templateTask.Form
+		if templateTask.SLA != nil {
+			task.SLA = templateTask.SLA.Definition()
+		}
+		task.Labels, err = NormalizeLabels(templateTask.Labels)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range templateTask.Checklist {
+			checklistItem := ChecklistItem{Text: RenderTemplateText(item.Text, resolved), Required: item.Required, Assignee: item.Assignee}
+			if err := task.AddChecklistItem(checklistItem); err != nil {
+				return nil, err
+			}
+		}
+		task.ID = taskIDs[templateTask.Key]
+		task.SetCreatedAt()
+		task.SetUpdatedAt()
+		tasks = append(tasks, task)
+	}
+
+	return &Workflow{
+		Name:                RenderTemplateText(name, resolved),
+		Tasks:               NormalizeTaskOrders(tasks),
+		Owner:               owner,
+		VariableDefinitions: template.VariableDefinitions,
+	}, nil
+}
+
+// NewTemplateFromWorkflow captures the tasks and dependencies of a workflow.
+// Task keys are derived from the task order. Checklists are kept without their
+// progress.
+func NewTemplateFromWorkflow(workflow Workflow) Template {
+	tasks := NormalizeTaskOrders(append([]Task{}, workflow.Tasks...))
+
+	keys := map[primitive.ObjectID]string{}
+	for _, task := range tasks {
+		keys[task.ID] = "task-" + strconv.Itoa(task.Order)
+	}
+
+	templateTasks := make([]TemplateTask, 0, len(tasks))
+	for _, task := range tasks {
+		dependsOn := []string{}
+		for _, dependencyID := range task.DependsOn {
+			if key, ok := keys[dependencyID]; ok {
+				dependsOn = append(dependsOn, key)
+			}
+		}
+
+		templateTask := TemplateTask{
+			Key:         keys[task.ID],
+			Name:        task.Name,
+			Description: task.Description,
+			Order:       task.Order,
+			DependsOn:   dependsOn,
+			Type:        task.Type,
+		}
+		if task.Gateway != nil {
+			templateTask.Gateway = &TemplateGateway{Kind: task.Gateway.Kind, Flows: []TemplateGatewayFlow{}}
+			for _, flow := range task.Gateway.Flows {
+				if key, ok := keys[flow.Target]; ok {
+					templateTask.Gateway.Flows = append(templateTask.Gateway.Flows, TemplateGatewayFlow{Target: key, Condition: flow.Condition})
+				}
+			}
+		}
+		if task.Approval != nil {
+			templateTask.Approval = task.Approval.Definition()
+		}
+		if task.Automation != nil {
+			templateTask.Automation = task.Automation.Definition()
+		}
+		templateTask.Form = task.Form
+		if task.SLA != nil {
+			templateTask.SLA = task.SLA.Definition()
+		}
+		for _, item := range task.Checklist {
+			templateTask.Checklist = append(templateTask.Checklist, ChecklistItemDefinition{
+				Text:     item.Text,
+				Required: item.Required,
+				Assignee: item.Assignee,
+			})
+		}
+		templateTask.Labels = task.Labels
+		templateTasks = append(templateTasks, templateTask)
+	}
+
+	return Template{
+		Name:                workflow.Name,
+		Parameters:          []TemplateParameter{},
+		Tasks:               templateTasks,
+		VariableDefinitions: workflow.VariableDefinitions,
+	}
+}
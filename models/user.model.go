@@ -17,6 +17,7 @@ const (
 	Edit     UserAction = "Edit"
 	Delete   UserAction = "Delete"
 	Transfer UserAction = "Transfer"
+	View     UserAction = "View"
 )
 
 type User struct {
@@ -24,9 +25,33 @@ type User struct {
 	Username         string   `json:"username" bson:"username"`
 	Password         string   `json:"password" bson:"password"`
 	Role             UserRole `json:"role" bson:"role"`
+	Organization     string   `json:"organization" bson:"organization"`
+	Teams            []string `json:"teams" bson:"teams"`
+}
+
+// UserProfile is a user as shown to admins, without the password.
+type UserProfile struct {
+	Username     string   `json:"username"`
+	Role         UserRole `json:"role"`
+	Organization string   `json:"organization"`
+	Teams        []string `json:"teams"`
+}
+
+func (user *User) Profile() UserProfile {
+	return UserProfile{Username: user.Username, Role: user.Role, Organization: user.Organization, Teams: user.Teams}
+}
+
+// Identity is the user as the user directory knows them, for checks that must
+// not trust the claims of a token.
+func (user *User) Identity() JWTUser {
+	return JWTUser{Username: user.Username, Role: user.Role, Organization: user.Organization, Teams: user.Teams}
 }
 
 type JWTUser struct {
-	Username string   `json:"username"`
-	Role     UserRole `json:"role"`
+	Username     string   `json:"username"`
+	Role         UserRole `json:"role"`
+	Organization string   `json:"organization"`
+	// Teams is only known for an Identity. Tokens do not carry teams, so a
+	// team change takes effect without waiting for old tokens to expire.
+	Teams []string `json:"teams"`
 }
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+	"virtual_workflow_management_system_gin/common"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WebhookEvent string
+
+const (
+	WorkflowCreatedEvent     WebhookEvent = "workflow.created"
+	WorkflowTransferredEvent WebhookEvent = "workflow.transferred"
+	TaskCreatedEvent         WebhookEvent = "task.created"
+	TaskStatusChangedEvent   WebhookEvent = "task.status_changed"
+	// WebhookTestEvent is only sent on request and cannot be subscribed to.
+	WebhookTestEvent WebhookEvent = "webhook.test"
+)
+
+const MaxListedDeliveries = 100
+
+// Webhook subscribes a URL to the events of one workflow, or of every workflow
+// owned by a member of an organization. Payloads are signed with Secret.
+type Webhook struct {
+	common.BaseModel `bson:",inline"`
+	WorkflowID       *primitive.ObjectID `json:"workflow_id,omitempty" bson:"workflow_id,omitempty"`
+	Organization     string              `json:"organization,omitempty" bson:"organization,omitempty"`
+	Owner            string              `json:"owner" bson:"owner"`
+	URL              string              `json:"url" bson:"url"`
+	Secret           string              `json:"-" bson:"secret"`
+	Events           []WebhookEvent      `json:"events" bson:"events"`
+	Active           bool                `json:"active" bson:"active"`
+}
+
+func (webhook *Webhook) CheckWebhookAccess(user JWTUser) bool {
+	return user.Username == webhook.Owner || user.Role == Admin
+}
+
+// Sign returns the value of the X-Webhook-Signature header for a payload: the
+// hex encoded HMAC-SHA256 of the payload keyed with the webhook secret.
+func (webhook *Webhook) Sign(payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(webhook.Secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookPayload is the JSON body posted to a webhook.
+type WebhookPayload struct {
+	ID        string                 `json:"id"`
+	Event     WebhookEvent           `json:"event"`
+	CreatedAt time.Time              `json:"created_at"`
+	Workflow  map[string]interface{} `json:"workflow,omitempty"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// WebhookDelivery logs one attempt to deliver an event to a webhook.
+type WebhookDelivery struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	WebhookID  primitive.ObjectID `json:"webhook_id" bson:"webhook_id"`
+	EventID    string             `json:"event_id" bson:"event_id"`
+	Event      WebhookEvent       `json:"event" bson:"event"`
+	Payload    string             `json:"payload" bson:"payload"`
+	StatusCode int                `json:"status_code,omitempty" bson:"status_code,omitempty"`
+	Response   string             `json:"response,omitempty" bson:"response,omitempty"`
+	Error      string             `json:"error,omitempty" bson:"error,omitempty"`
+	Succeeded  bool               `json:"succeeded" bson:"succeeded"`
+	DurationMS int64              `json:"duration_ms" bson:"duration_ms"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+}
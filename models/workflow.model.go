@@ -1,7 +1,12 @@
 package models
 
 import (
+	"regexp"
+	"sort"
+	"time"
 	"virtual_workflow_management_system_gin/common"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type TaskStatus string
@@ -10,21 +15,76 @@ const (
 	Pending    TaskStatus = "Pending"
 	InProgress TaskStatus = "In Progress"
 	Completed  TaskStatus = "Completed"
+	Skipped    TaskStatus = "Skipped"
+	Rejected   TaskStatus = "Rejected"
+	Failed     TaskStatus = "Failed"
+)
+
+type TaskType string
+
+const (
+	UserTask      TaskType = "User"
+	GatewayTask   TaskType = "Gateway"
+	ApprovalTask  TaskType = "Approval"
+	AutomatedTask TaskType = "Automated"
 )
 
+var VariableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 type Task struct {
 	common.BaseModel `bson:",inline"`
-	Name             string     `json:"name" bson:"name"`
-	Description      string     `json:"description" bson:"description"`
-	Status           TaskStatus `json:"status" bson:"status"`
-	Order            int        `json:"order" bson:"order"`
+	Name             string                 `json:"name" bson:"name"`
+	Description      string                 `json:"description" bson:"description"`
+	Status           TaskStatus             `json:"status" bson:"status"`
+	Order            int                    `json:"order" bson:"order"`
+	DependsOn        []primitive.ObjectID   `json:"depends_on" bson:"depends_on"`
+	Type             TaskType               `json:"type" bson:"type"`
+	Gateway          *Gateway               `json:"gateway,omitempty" bson:"gateway,omitempty"`
+	Approval         *Approval              `json:"approval,omitempty" bson:"approval,omitempty"`
+	Automation       *Automation            `json:"automation,omitempty" bson:"automation,omitempty"`
+	Form             []FieldDefinition      `json:"form,omitempty" bson:"form,omitempty"`
+	FormValues       map[string]interface{} `json:"form_values,omitempty" bson:"form_values,omitempty"`
+	Assignee         string                 `json:"assignee,omitempty" bson:"assignee,omitempty"`
+	AssigneeRole     UserRole               `json:"assignee_role,omitempty" bson:"assignee_role,omitempty"`
+	SLA              *SLA                   `json:"sla,omitempty" bson:"sla,omitempty"`
+	Checklist        []ChecklistItem        `json:"checklist,omitempty" bson:"checklist,omitempty"`
+	Labels           []string               `json:"labels,omitempty" bson:"labels,omitempty"`
+	StatusHistory    []StatusTransition     `json:"status_history,omitempty" bson:"status_history,omitempty"`
+	// ChecklistProgress is derived when the task is read and never stored.
+	ChecklistProgress *int `json:"checklist_progress,omitempty" bson:"-"`
+}
+
+// StatusTransition records a task moving from one status to another. A task
+// is in the status it was created with until its first transition.
+type StatusTransition struct {
+	From TaskStatus `json:"from" bson:"from"`
+	To   TaskStatus `json:"to" bson:"to"`
+	At   time.Time  `json:"at" bson:"at"`
+}
+
+// SetStatus moves the task to status, recording the transition when the
+// status changes.
+func (task *Task) SetStatus(status TaskStatus, at time.Time) {
+	if task.Status == status {
+		return
+	}
+	task.StatusHistory = append(task.StatusHistory, StatusTransition{From: task.Status, To: status, At: at})
+	task.Status = status
 }
 
 type Workflow struct {
-	common.BaseModel `bson:",inline"`
-	Name             string `json:"name" bson:"name"`
-	Tasks            []Task `json:"tasks" bson:"tasks"`
-	Owner            string `json:"owner" bson:"owner"`
+	common.BaseModel    `bson:",inline"`
+	Name                string                 `json:"name" bson:"name"`
+	Tasks               []Task                 `json:"tasks" bson:"tasks"`
+	Owner               string                 `json:"owner" bson:"owner"`
+	Version             int64                  `json:"version" bson:"version"`
+	Variables           map[string]interface{} `json:"variables" bson:"variables"`
+	VariableDefinitions []FieldDefinition      `json:"variable_definitions" bson:"variable_definitions"`
+	Labels              []string               `json:"labels,omitempty" bson:"labels,omitempty"`
+	// StartedAt is when the run was started. Gateways nothing leads to wait
+	// for it, so they decide on the run data rather than on what the workflow
+	// held when it was created.
+	StartedAt *time.Time `json:"started_at,omitempty" bson:"started_at,omitempty"`
 }
 
 func (workflow *Workflow) CheckWorkflowAccess(user JWTUser, action UserAction) bool {
@@ -44,3 +104,49 @@ func (workflow *Workflow) CheckWorkflowAccess(user JWTUser, action UserAction) b
 
 	return false
 }
+
+// CanSeeAllWorkflows reports whether the user may see the workflows of every
+// owner, which is when the access rule lets them into a workflow of nobody in
+// particular.
+func CanSeeAllWorkflows(user JWTUser) bool {
+	return (&Workflow{}).CheckWorkflowAccess(user, "delete")
+}
+
+func SortTasksByOrder(tasks []Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].Order < tasks[j].Order
+	})
+}
+
+func NormalizeTaskOrders(tasks []Task) []Task {
+	SortTasksByOrder(tasks)
+	for i := range tasks {
+		tasks[i].Order = i + 1
+	}
+	return tasks
+}
+
+// RemoveTaskDependency drops references to a removed task from every other
+// task, including gateway flows leading to it.
+func RemoveTaskDependency(tasks []Task, taskID primitive.ObjectID) []Task {
+	for i := range tasks {
+		dependsOn := []primitive.ObjectID{}
+		for _, dependencyID := range tasks[i].DependsOn {
+			if dependencyID != taskID {
+				dependsOn = append(dependsOn, dependencyID)
+			}
+		}
+		tasks[i].DependsOn = dependsOn
+
+		if tasks[i].Gateway != nil {
+			flows := []GatewayFlow{}
+			for _, flow := range tasks[i].Gateway.Flows {
+				if flow.Target != taskID {
+					flows = append(flows, flow)
+				}
+			}
+			tasks[i].Gateway.Flows = flows
+		}
+	}
+	return tasks
+}
@@ -0,0 +1,282 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+	"virtual_workflow_management_system_gin/models"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// doneStatuses are the statuses tasks end in.
+var doneStatuses = bson.A{models.Completed, models.Skipped, models.Rejected}
+
+// teamStages keep the documents whose user at the given field is a member of
+// team. An empty team keeps every document.
+func teamStages(field string, team string) mongo.Pipeline {
+	if team == "" {
+		return mongo.Pipeline{}
+	}
+	return mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.M{"from": "users", "localField": field, "foreignField": "username", "as": "team_member"}}},
+		{{Key: "$match", Value: bson.M{"team_member.teams": team}}},
+	}
+}
+
+// transitionTo picks a transition of the unwound task to status: the first at
+// position 0, the last at -1. The result is missing when the task never moved
+// to status.
+func transitionTo(status models.TaskStatus, position int) bson.M {
+	return bson.M{"$arrayElemAt": bson.A{
+		bson.M{"$filter": bson.M{
+			"input": bson.M{"$ifNull": bson.A{"$tasks.status_history", bson.A{}}},
+			"as":    "transition",
+			"cond":  bson.M{"$eq": bson.A{"$$transition.to", status}},
+		}},
+		position,
+	}}
+}
+
+// statusSegments splits the life of the unwound task into the periods it
+// spent in one status: from its creation to its first transition, between
+// transitions and from its last transition to now.
+func statusSegments(now time.Time) bson.M {
+	size := bson.M{"$size": "$$history"}
+	return bson.M{"$let": bson.M{
+		"vars": bson.M{"history": bson.M{"$ifNull": bson.A{"$tasks.status_history", bson.A{}}}},
+		"in": bson.M{"$map": bson.M{
+			"input": bson.M{"$range": bson.A{0, bson.M{"$add": bson.A{size, 1}}}},
+			"as":    "i",
+			"in": bson.M{
+				"status": bson.M{"$cond": bson.A{
+					bson.M{"$lt": bson.A{"$$i", size}},
+					bson.M{"$arrayElemAt": bson.A{"$$history.from", "$$i"}},
+					"$tasks.status",
+				}},
+				"start": bson.M{"$cond": bson.A{
+					bson.M{"$eq": bson.A{"$$i", 0}},
+					"$tasks.created_at",
+					bson.M{"$arrayElemAt": bson.A{"$$history.at", bson.M{"$subtract": bson.A{"$$i", 1}}}},
+				}},
+				"end": bson.M{"$cond": bson.A{
+					bson.M{"$lt": bson.A{"$$i", size}},
+					bson.M{"$arrayElemAt": bson.A{"$$history.at", "$$i"}},
+					now,
+				}},
+			},
+		}},
+	}}
+}
+
+// secondsBetween is the time from start to end in seconds.
+func secondsBetween(start interface{}, end interface{}) bson.M {
+	return bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{end, start}}, 1000}}
+}
+
+func inRange(filter models.ReportFilter) bson.M {
+	return bson.M{"$gte": filter.From, "$lt": filter.To}
+}
+
+func (entity *workflowEntity) aggregate(pipeline mongo.Pipeline, results interface{}) error {
+	ctx, cancel := entity.initContext()
+	defer cancel()
+
+	cursor, err := entity.repository.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("failed to compute report")
+	}
+
+	if err := cursor.All(ctx, results); err != nil {
+		logrus.Error(err)
+		return errors.New("failed to compute report")
+	}
+
+	return nil
+}
+
+// LeadTime summarizes the time workflows took from their creation until their
+// last task was done, over the workflows done in the range. Workflows without
+// tasks are never done. The team filter applies to the owner.
+func (entity *workflowEntity) LeadTime(filter models.ReportFilter) (*models.LeadTimeReport, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: ownerFilter(bson.M{
+			"created_at": bson.M{"$lt": filter.To},
+			"tasks.0":    bson.M{"$exists": true},
+			"tasks":      bson.M{"$not": bson.M{"$elemMatch": bson.M{"status": bson.M{"$nin": doneStatuses}}}},
+		}, filter.Owner)}},
+		// Tasks done before their history was recorded count from their last update.
+		{{Key: "$addFields", Value: bson.M{"done_at": bson.M{"$max": bson.M{"$map": bson.M{
+			"input": "$tasks",
+			"as":    "task",
+			"in":    bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$$task.status_history.at", -1}}, "$$task.updated_at"}},
+		}}}}}},
+		{{Key: "$match", Value: bson.M{"done_at": inRange(filter)}}},
+	}
+	pipeline = append(pipeline, teamStages("owner", filter.Team)...)
+	pipeline = append(pipeline,
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":             nil,
+			"workflows":       bson.M{"$sum": 1},
+			"average_seconds": bson.M{"$avg": secondsBetween("$created_at", "$done_at")},
+			"min_seconds":     bson.M{"$min": secondsBetween("$created_at", "$done_at")},
+			"max_seconds":     bson.M{"$max": secondsBetween("$created_at", "$done_at")},
+		}}},
+	)
+
+	reports := []models.LeadTimeReport{}
+	if err := entity.aggregate(pipeline, &reports); err != nil {
+		return nil, err
+	}
+	if len(reports) == 0 {
+		return &models.LeadTimeReport{}, nil
+	}
+
+	return &reports[0], nil
+}
+
+// completedTaskStages unwind the tasks completed in the range, with their last
+// completion as completed. The team filter applies to the assignee.
+func completedTaskStages(filter models.ReportFilter) mongo.Pipeline {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: ownerFilter(bson.M{
+			"created_at":   bson.M{"$lt": filter.To},
+			"tasks.status": models.Completed,
+		}, filter.Owner)}},
+		{{Key: "$unwind", Value: "$tasks"}},
+		{{Key: "$match", Value: bson.M{"tasks.status": models.Completed}}},
+		{{Key: "$addFields", Value: bson.M{"completed": transitionTo(models.Completed, -1)}}},
+		{{Key: "$match", Value: bson.M{"completed.at": inRange(filter)}}},
+	}
+	return append(pipeline, teamStages("tasks.assignee", filter.Team)...)
+}
+
+// CycleTimes summarizes, per task name, the time tasks completed in the range
+// took from first being in progress to their last completion, slowest first.
+// Tasks never in progress have no cycle time.
+func (entity *workflowEntity) CycleTimes(filter models.ReportFilter, limit int) ([]models.CycleTime, error) {
+	pipeline := completedTaskStages(filter)
+	pipeline = append(pipeline,
+		bson.D{{Key: "$addFields", Value: bson.M{"started": transitionTo(models.InProgress, 0)}}},
+		bson.D{{Key: "$match", Value: bson.M{"started": bson.M{"$ne": nil}}}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":             "$tasks.name",
+			"completed":       bson.M{"$sum": 1},
+			"average_seconds": bson.M{"$avg": secondsBetween("$started.at", "$completed.at")},
+			"min_seconds":     bson.M{"$min": secondsBetween("$started.at", "$completed.at")},
+			"max_seconds":     bson.M{"$max": secondsBetween("$started.at", "$completed.at")},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "average_seconds", Value: -1}, {Key: "_id", Value: 1}}}},
+		bson.D{{Key: "$limit", Value: limit}},
+	)
+
+	cycleTimes := []models.CycleTime{}
+	if err := entity.aggregate(pipeline, &cycleTimes); err != nil {
+		return nil, err
+	}
+
+	return cycleTimes, nil
+}
+
+// WeeklyThroughput counts the tasks completed in the range per ISO week,
+// oldest first. Weeks without completions are left out.
+func (entity *workflowEntity) WeeklyThroughput(filter models.ReportFilter) ([]models.WeeklyThroughput, error) {
+	pipeline := completedTaskStages(filter)
+	pipeline = append(pipeline,
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"$dateFromParts": bson.M{
+				"isoWeekYear": bson.M{"$isoWeekYear": "$completed.at"},
+				"isoWeek":     bson.M{"$isoWeek": "$completed.at"},
+			}},
+			"completed": bson.M{"$sum": 1},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	)
+
+	weeks := []models.WeeklyThroughput{}
+	if err := entity.aggregate(pipeline, &weeks); err != nil {
+		return nil, err
+	}
+
+	return weeks, nil
+}
+
+// SlowestSteps summarizes, per task name, the time the tasks of a workflow and
+// of the runs its schedules created spent before they were done, slowest
+// first. Only runs created in the range count; time in a status a task is
+// still in counts up to now. The team filter applies to the assignee.
+func (entity *workflowEntity) SlowestSteps(workflowID string, filter models.ReportFilter, limit int) ([]models.StepDuration, error) {
+	workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("invalid ObjectID format")
+	}
+
+	secondsIn := func(status models.TaskStatus) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$status", status}}, "$seconds", 0}}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: ownerFilter(bson.M{"_id": workflowObjectID}, filter.Owner)}},
+		{{Key: "$lookup", Value: bson.M{"from": "schedules", "localField": "_id", "foreignField": "workflow_id", "as": "schedules"}}},
+		{{Key: "$project", Value: bson.M{"run_ids": bson.M{"$concatArrays": bson.A{
+			bson.A{"$_id"},
+			bson.M{"$reduce": bson.M{
+				"input":        "$schedules.runs.workflow_id",
+				"initialValue": bson.A{},
+				"in":           bson.M{"$concatArrays": bson.A{"$$value", bson.M{"$ifNull": bson.A{"$$this", bson.A{}}}}},
+			}},
+		}}}}},
+		{{Key: "$lookup", Value: bson.M{"from": "workflows", "localField": "run_ids", "foreignField": "_id", "as": "run"}}},
+		{{Key: "$unwind", Value: "$run"}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$run"}}},
+		{{Key: "$match", Value: bson.M{"created_at": inRange(filter)}}},
+		{{Key: "$unwind", Value: "$tasks"}},
+	}
+	pipeline = append(pipeline, teamStages("tasks.assignee", filter.Team)...)
+	pipeline = append(pipeline,
+		bson.D{{Key: "$project", Value: bson.M{
+			"task_id":  "$tasks._id",
+			"name":     "$tasks.name",
+			"segments": statusSegments(time.Now()),
+		}}},
+		bson.D{{Key: "$unwind", Value: "$segments"}},
+		bson.D{{Key: "$match", Value: bson.M{"segments.status": bson.M{"$nin": doneStatuses}}}},
+		bson.D{{Key: "$project", Value: bson.M{
+			"task_id": 1,
+			"name":    1,
+			"status":  "$segments.status",
+			"seconds": secondsBetween("$segments.start", "$segments.end"),
+		}}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":         "$task_id",
+			"name":        bson.M{"$first": "$name"},
+			"total":       bson.M{"$sum": "$seconds"},
+			"pending":     secondsIn(models.Pending),
+			"in_progress": secondsIn(models.InProgress),
+			"failed":      secondsIn(models.Failed),
+		}}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":                         "$name",
+			"runs":                        bson.M{"$sum": 1},
+			"average_seconds":             bson.M{"$avg": "$total"},
+			"max_seconds":                 bson.M{"$max": "$total"},
+			"average_pending_seconds":     bson.M{"$avg": "$pending"},
+			"average_in_progress_seconds": bson.M{"$avg": "$in_progress"},
+			"average_failed_seconds":      bson.M{"$avg": "$failed"},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "average_seconds", Value: -1}, {Key: "_id", Value: 1}}}},
+		bson.D{{Key: "$limit", Value: limit}},
+	)
+
+	steps := []models.StepDuration{}
+	if err := entity.aggregate(pipeline, &steps); err != nil {
+		return nil, err
+	}
+
+	return steps, nil
+}
@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+	"virtual_workflow_management_system_gin/common"
+	"virtual_workflow_management_system_gin/models"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// VoteOnApproval records a vote and, once the quorum rule is decided, completes
+// or rejects the task and stores the outcome in the configured run variable.
+func (entity *workflowEntity) VoteOnApproval(workflowID string, taskID string, user models.JWTUser, decision models.ApprovalDecision, comment string) (*models.Task, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
+
+	var votedTask models.Task
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
+		workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid ObjectID format")
+		}
+
+		taskObjectID, err := primitive.ObjectIDFromHex(taskID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid ObjectID format")
+		}
+
+		filter := bson.M{"_id": workflowObjectID}
+		var workflow models.Workflow
+		err = entity.repository.FindOne(c, filter).Decode(&workflow)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("workflow does not exist")
+		}
+
+		index := -1
+		for i := range workflow.Tasks {
+			if workflow.Tasks[i].ID == taskObjectID {
+				index = i
+				break
+			}
+		}
+		if index == -1 {
+			return errors.New("task does not exist")
+		}
+
+		task := &workflow.Tasks[index]
+		if task.Type != models.ApprovalTask || task.Approval == nil {
+			return errors.New("task is not an approval task")
+		}
+		if task.Status == models.Skipped {
+			return errors.New("approval task was skipped")
+		}
+		if !workflow.CheckWorkflowAccess(user, "edit") && !task.Approval.CanVote(user) {
+			return errors.New("unauthorized")
+		}
+		if !workflow.TaskReady(task.ID) {
+			return errors.New("approval task is not ready for votes")
+		}
+
+		if err := task.Approval.Vote(user, decision, comment); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		set := bson.M{"updated_at": now}
+		switch task.Approval.Outcome {
+		case models.ApprovedDecision:
+			task.SetStatus(models.Completed, now)
+		case models.RejectedDecision:
+			task.SetStatus(models.Rejected, now)
+		default:
+			task.SetStatus(models.InProgress, now)
+		}
+		if task.Approval.Outcome != "" && task.Approval.Variable != "" {
+			set["variables."+task.Approval.Variable] = string(task.Approval.Outcome)
+		}
+		task.UpdatedAt = now
+		set["tasks"] = workflow.Tasks
+
+		update := bson.M{
+			"$set": set,
+			"$inc": bson.M{"version": 1},
+		}
+
+		updateResult, err := entity.repository.UpdateOne(c, withVersion(filter, &workflow.Version), update)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to record vote")
+		}
+
+		if updateResult.MatchedCount == 0 {
+			return ErrVersionMismatch
+		}
+
+		votedTask = *task
+
+		return entity.advanceGateways(c, workflowObjectID)
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return &votedTask, nil
+}
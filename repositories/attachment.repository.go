@@ -0,0 +1,136 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var AttachmentEntity IAttachment
+
+type attachmentEntity struct {
+	resource   *databases.Resource
+	repository *mongo.Collection
+	parent     context.Context
+}
+
+type IAttachment interface {
+	WithContext(ctx context.Context) IAttachment
+	FindAttachmentsByTaskID(workflowID string, taskID string) ([]models.Attachment, error)
+	FindAttachmentByID(attachmentID string) (*models.Attachment, error)
+	CreateAttachment(attachment models.Attachment) (*models.Attachment, error)
+	DeleteAttachment(attachmentID string) error
+}
+
+func NewAttachmentEntity(resource *databases.Resource) IAttachment {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &attachmentEntity{}
+	}
+	attachmentRepository := resource.MongoDB.Collection("task_attachments")
+	AttachmentEntity = &attachmentEntity{resource: resource, repository: attachmentRepository}
+	return AttachmentEntity
+}
+
+// WithContext returns a copy of the repository whose calls run with ctx, so
+// they take part in the transaction of the caller.
+func (entity *attachmentEntity) WithContext(ctx context.Context) IAttachment {
+	bound := *entity
+	bound.parent = ctx
+	return &bound
+}
+
+// FindAttachmentsByTaskID returns the attachments of a task, or of every task
+// of the workflow when taskID is empty, oldest first.
+func (entity *attachmentEntity) FindAttachmentsByTaskID(workflowID string, taskID string) ([]models.Attachment, error) {
+	ctx, cancel := initContextFrom(entity.parent)
+	defer cancel()
+
+	filter, err := taskFilter(workflowID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.M{"created_at": 1})
+	cursor, err := entity.repository.Find(ctx, filter, opts)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve attachments")
+	}
+	defer cursor.Close(ctx)
+
+	attachments := []models.Attachment{}
+	if err := cursor.All(ctx, &attachments); err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve attachments")
+	}
+
+	return attachments, nil
+}
+
+func (entity *attachmentEntity) FindAttachmentByID(attachmentID string) (*models.Attachment, error) {
+	ctx, cancel := initContextFrom(entity.parent)
+	defer cancel()
+
+	attachmentObjectID, err := primitive.ObjectIDFromHex(attachmentID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("invalid ObjectID format")
+	}
+
+	var attachment models.Attachment
+	err = entity.repository.FindOne(ctx, bson.M{"_id": attachmentObjectID}).Decode(&attachment)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("attachment does not exist")
+	}
+
+	return &attachment, nil
+}
+
+// CreateAttachment stores the metadata of an attachment whose content is
+// already in the blob store. The ID is set by the caller.
+func (entity *attachmentEntity) CreateAttachment(attachment models.Attachment) (*models.Attachment, error) {
+	ctx, cancel := initContextFrom(entity.parent)
+	defer cancel()
+
+	attachment.SetCreatedAt()
+	attachment.SetUpdatedAt()
+
+	_, err := entity.repository.InsertOne(ctx, attachment)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to create attachment")
+	}
+
+	return &attachment, nil
+}
+
+func (entity *attachmentEntity) DeleteAttachment(attachmentID string) error {
+	ctx, cancel := initContextFrom(entity.parent)
+	defer cancel()
+
+	attachmentObjectID, err := primitive.ObjectIDFromHex(attachmentID)
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("invalid ObjectID format")
+	}
+
+	result, err := entity.repository.DeleteOne(ctx, bson.M{"_id": attachmentObjectID})
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("failed to delete attachment")
+	}
+
+	if result.DeletedCount == 0 {
+		return errors.New("attachment does not exist")
+	}
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+	"virtual_workflow_management_system_gin/common"
+	"virtual_workflow_management_system_gin/models"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ClaimAutomatedTask leases the next automated task that is due. The lease
+// keeps other workers away until it expires, so a task whose worker died is
+// picked up again. It returns nil when no task is due.
+func (entity *workflowEntity) ClaimAutomatedTask(lease time.Duration) (*models.Workflow, *models.Task, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
+
+	now := time.Now()
+	leaseID := primitive.NewObjectID().Hex()
+	filter := bson.M{
+		"tasks": bson.M{"$elemMatch": bson.M{
+			"type":                       models.AutomatedTask,
+			"status":                     models.InProgress,
+			"automation.next_attempt_at": bson.M{"$lte": now},
+			"$or": bson.A{
+				bson.M{"automation.leased_until": nil},
+				bson.M{"automation.leased_until": bson.M{"$lte": now}},
+			},
+		}},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"tasks.$.automation.lease_id":     leaseID,
+			"tasks.$.automation.leased_until": now.Add(lease),
+		},
+	}
+
+	var workflow models.Workflow
+	err := entity.repository.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&workflow)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil, nil
+	}
+	if err != nil {
+		logrus.Error(err)
+		return nil, nil, errors.New("failed to claim automated task")
+	}
+
+	for i := range workflow.Tasks {
+		if workflow.Tasks[i].Automation != nil && workflow.Tasks[i].Automation.LeaseID == leaseID {
+			return &workflow, &workflow.Tasks[i], nil
+		}
+	}
+
+	return nil, nil, errors.New("failed to claim automated task")
+}
+
+// RecordAutomationResult stores the outcome of an attempt made under the given
+// lease. A successful attempt completes the task and writes its output to the
+// configured run variable; a failed one is retried or fails the task.
+func (entity *workflowEntity) RecordAutomationResult(workflowID string, taskID string, leaseID string, output interface{}, failure error) (*models.Task, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
+
+	var updatedTaskModel models.Task
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
+		workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid ObjectID format")
+		}
+
+		taskObjectID, err := primitive.ObjectIDFromHex(taskID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid ObjectID format")
+		}
+
+		filter := bson.M{"_id": workflowObjectID}
+		var workflow models.Workflow
+		err = entity.repository.FindOne(c, filter).Decode(&workflow)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("workflow does not exist")
+		}
+
+		index := taskIndex(workflow.Tasks, taskObjectID)
+		if index == -1 {
+			return errors.New("task does not exist")
+		}
+
+		task := &workflow.Tasks[index]
+		if !task.IsAutomated() || task.Status != models.InProgress || task.Automation.LeaseID != leaseID {
+			return errors.New("automated task lease was lost")
+		}
+
+		now := time.Now()
+		set := bson.M{"updated_at": now}
+		if failure == nil {
+			task.Automation.RecordSuccess(now)
+			task.SetStatus(models.Completed, now)
+			if task.Automation.OutputVariable != "" {
+				set["variables."+task.Automation.OutputVariable] = output
+			}
+		} else if !task.Automation.RecordFailure(failure, now) {
+			task.SetStatus(models.Failed, now)
+		}
+		task.UpdatedAt = now
+		set["tasks"] = workflow.Tasks
+
+		update := bson.M{
+			"$set": set,
+			"$inc": bson.M{"version": 1},
+		}
+
+		updateResult, err := entity.repository.UpdateOne(c, withVersion(filter, &workflow.Version), update)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to record automated task result")
+		}
+
+		if updateResult.MatchedCount == 0 {
+			return ErrVersionMismatch
+		}
+
+		updatedTaskModel = *task
+
+		return entity.advanceGateways(c, workflowObjectID)
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return &updatedTaskModel, nil
+}
+
+// RetryAutomatedTask queues a failed automated task again with a fresh set of
+// attempts.
+func (entity *workflowEntity) RetryAutomatedTask(workflowID string, taskID string, version *int64) (*models.Task, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
+
+	var updatedTaskModel models.Task
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
+		workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid ObjectID format")
+		}
+
+		taskObjectID, err := primitive.ObjectIDFromHex(taskID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid ObjectID format")
+		}
+
+		filter := bson.M{"_id": workflowObjectID}
+		var workflow models.Workflow
+		err = entity.repository.FindOne(c, filter).Decode(&workflow)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("workflow does not exist")
+		}
+
+		if version != nil && *version != workflow.Version {
+			return ErrVersionMismatch
+		}
+
+		index := taskIndex(workflow.Tasks, taskObjectID)
+		if index == -1 {
+			return errors.New("task does not exist")
+		}
+
+		task := &workflow.Tasks[index]
+		if !task.IsAutomated() {
+			return errors.New("task is not an automated task")
+		}
+		if task.Status != models.Failed {
+			return errors.New("only failed tasks can be retried")
+		}
+
+		now := time.Now()
+		task.Automation.Queue(now)
+		task.SetStatus(models.InProgress, now)
+		task.UpdatedAt = now
+
+		update := bson.M{
+			"$set": bson.M{
+				"tasks":      workflow.Tasks,
+				"updated_at": now,
+			},
+			"$inc": bson.M{"version": 1},
+		}
+
+		updateResult, err := entity.repository.UpdateOne(c, withVersion(filter, &workflow.Version), update)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to retry automated task")
+		}
+
+		if updateResult.MatchedCount == 0 {
+			return ErrVersionMismatch
+		}
+
+		updatedTaskModel = *task
+
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return &updatedTaskModel, nil
+}
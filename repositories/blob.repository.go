@@ -0,0 +1,203 @@
+package repositories
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"virtual_workflow_management_system_gin/databases"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var BlobStore IBlobStore
+
+// IBlobStore stores file contents under keys chosen by the caller.
+type IBlobStore interface {
+	Put(key string, content io.Reader) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// ErrBlobNotFound is returned when no content is stored under a key.
+var ErrBlobNotFound = errors.New("blob does not exist")
+
+// blobKeyPattern keeps keys usable as file names.
+var blobKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// NewBlobStore returns the blob store selected by ATTACHMENT_STORAGE: "local"
+// keeps files under ATTACHMENT_DIR (default "attachments"), anything else
+// stores them in GridFS next to the rest of the data.
+func NewBlobStore(resource *databases.Resource) IBlobStore {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return nil
+	}
+
+	switch os.Getenv("ATTACHMENT_STORAGE") {
+	case "local":
+		root := os.Getenv("ATTACHMENT_DIR")
+		if root == "" {
+			root = "attachments"
+		}
+		BlobStore = NewLocalBlobStore(root)
+	default:
+		bucket, err := gridfs.NewBucket(resource.MongoDB, options.GridFSBucket().SetName("attachments"))
+		if err != nil {
+			logrus.Error(err)
+			return nil
+		}
+		BlobStore = &gridFSBlobStore{bucket: bucket}
+	}
+	return BlobStore
+}
+
+type localBlobStore struct {
+	root string
+}
+
+// NewLocalBlobStore stores every blob as a file in root.
+func NewLocalBlobStore(root string) IBlobStore {
+	return &localBlobStore{root: root}
+}
+
+func (store *localBlobStore) path(key string) (string, error) {
+	if !blobKeyPattern.MatchString(key) {
+		return "", errors.New("invalid blob key")
+	}
+	return filepath.Join(store.root, key), nil
+}
+
+// Put writes the content to a temporary file first, so a failed upload never
+// leaves a partial blob behind.
+func (store *localBlobStore) Put(key string, content io.Reader) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(store.root, 0o750); err != nil {
+		logrus.Error(err)
+		return errors.New("failed to store blob")
+	}
+
+	file, err := os.CreateTemp(store.root, ".upload-*")
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("failed to store blob")
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		logrus.Error(err)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		logrus.Error(err)
+		return errors.New("failed to store blob")
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		logrus.Error(err)
+		return errors.New("failed to store blob")
+	}
+
+	return nil
+}
+
+func (store *localBlobStore) Open(key string) (io.ReadCloser, error) {
+	path, err := store.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to open blob")
+	}
+
+	return file, nil
+}
+
+func (store *localBlobStore) Delete(key string) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logrus.Error(err)
+		return errors.New("failed to delete blob")
+	}
+
+	return nil
+}
+
+// gridFSBlobStore stores blobs in a GridFS bucket. Keys are the hex encoded
+// ObjectIDs of the files.
+type gridFSBlobStore struct {
+	bucket *gridfs.Bucket
+}
+
+func gridFSFileID(key string) (primitive.ObjectID, error) {
+	fileID, err := primitive.ObjectIDFromHex(key)
+	if err != nil {
+		return primitive.NilObjectID, errors.New("invalid blob key")
+	}
+	return fileID, nil
+}
+
+func (store *gridFSBlobStore) Put(key string, content io.Reader) error {
+	fileID, err := gridFSFileID(key)
+	if err != nil {
+		return err
+	}
+
+	// A failed upload removes the chunks it already wrote.
+	if err := store.bucket.UploadFromStreamWithID(fileID, key, content); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (store *gridFSBlobStore) Open(key string) (io.ReadCloser, error) {
+	fileID, err := gridFSFileID(key)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := store.bucket.OpenDownloadStream(fileID)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil, ErrBlobNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to open blob")
+	}
+
+	return stream, nil
+}
+
+func (store *gridFSBlobStore) Delete(key string) error {
+	fileID, err := gridFSFileID(key)
+	if err != nil {
+		return err
+	}
+
+	if err := store.bucket.Delete(fileID); err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+		logrus.Error(err)
+		return errors.New("failed to delete blob")
+	}
+
+	return nil
+}
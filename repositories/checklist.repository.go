@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"virtual_workflow_management_system_gin/common"
+	"virtual_workflow_management_system_gin/models"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// UpdateTaskChecklist applies change to the task as stored when the
+// transaction reads it and saves the checklist it leaves. Checklists are run
+// state, so no revision is saved. The checklist of a done task is frozen.
+func (entity *workflowEntity) UpdateTaskChecklist(workflowID string, taskID string, change func(task *models.Task) error, version *int64) (*models.Task, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
+
+	var updatedTaskModel models.Task
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
+		workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid ObjectID format")
+		}
+
+		taskObjectID, err := primitive.ObjectIDFromHex(taskID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid ObjectID format")
+		}
+
+		filter := bson.M{"_id": workflowObjectID}
+		var workflow models.Workflow
+		err = entity.repository.FindOne(c, filter).Decode(&workflow)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("workflow does not exist")
+		}
+
+		if version != nil && *version != workflow.Version {
+			return ErrVersionMismatch
+		}
+
+		index := taskIndex(workflow.Tasks, taskObjectID)
+		if index == -1 {
+			return errors.New("task does not exist")
+		}
+
+		task := &workflow.Tasks[index]
+		if task.IsDone() {
+			return errors.New("task is already done")
+		}
+
+		if err := change(task); err != nil {
+			return err
+		}
+		task.SetUpdatedAt()
+
+		update := bson.M{
+			"$set": bson.M{
+				"tasks":      workflow.Tasks,
+				"updated_at": task.UpdatedAt,
+			},
+			"$inc": bson.M{"version": 1},
+		}
+
+		result, err := entity.repository.UpdateOne(c, withVersion(filter, &workflow.Version), update)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to update checklist")
+		}
+
+		if result.MatchedCount == 0 {
+			return ErrVersionMismatch
+		}
+
+		updatedTaskModel = *task
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return &updatedTaskModel, nil
+}
@@ -0,0 +1,255 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var CommentEntity IComment
+
+type commentEntity struct {
+	resource      *databases.Resource
+	repository    *mongo.Collection
+	statusChanges *mongo.Collection
+	parent        context.Context
+}
+
+type IComment interface {
+	WithContext(ctx context.Context) IComment
+	FindCommentsByTaskID(workflowID string, taskID string) ([]models.Comment, error)
+	FindCommentByID(commentID string) (*models.Comment, error)
+	CreateComment(comment models.Comment) (*models.Comment, error)
+	EditComment(commentID string, body string, mentions []string) (*models.Comment, error)
+	DeleteComment(commentID string) (*models.Comment, error)
+	DeleteTaskComments(workflowID string, taskID string) error
+	SaveStatusChange(change models.TaskStatusChange) error
+	FindStatusChangesByTaskID(workflowID string, taskID string) ([]models.TaskStatusChange, error)
+}
+
+func NewCommentEntity(resource *databases.Resource) IComment {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &commentEntity{}
+	}
+	commentRepository := resource.MongoDB.Collection("task_comments")
+	statusChangeRepository := resource.MongoDB.Collection("task_status_changes")
+	CommentEntity = &commentEntity{resource: resource, repository: commentRepository, statusChanges: statusChangeRepository}
+	return CommentEntity
+}
+
+// WithContext returns a copy of the repository whose calls run with ctx, so
+// they take part in the transaction of the caller.
+func (entity *commentEntity) WithContext(ctx context.Context) IComment {
+	bound := *entity
+	bound.parent = ctx
+	return &bound
+}
+
+// taskFilter selects the documents of a task, or of every task of the
+// workflow when taskID is empty.
+func taskFilter(workflowID string, taskID string) (bson.M, error) {
+	workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("invalid ObjectID format")
+	}
+
+	filter := bson.M{"workflow_id": workflowObjectID}
+	if taskID != "" {
+		taskObjectID, err := primitive.ObjectIDFromHex(taskID)
+		if err != nil {
+			logrus.Error(err)
+			return nil, errors.New("invalid ObjectID format")
+		}
+		filter["task_id"] = taskObjectID
+	}
+
+	return filter, nil
+}
+
+func (entity *commentEntity) FindCommentsByTaskID(workflowID string, taskID string) ([]models.Comment, error) {
+	ctx, cancel := initContextFrom(entity.parent)
+	defer cancel()
+
+	filter, err := taskFilter(workflowID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := entity.repository.Find(ctx, filter, opts)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve comments")
+	}
+	defer cursor.Close(ctx)
+
+	comments := []models.Comment{}
+	if err := cursor.All(ctx, &comments); err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve comments")
+	}
+
+	return comments, nil
+}
+
+func (entity *commentEntity) FindCommentByID(commentID string) (*models.Comment, error) {
+	ctx, cancel := initContextFrom(entity.parent)
+	defer cancel()
+
+	commentObjectID, err := primitive.ObjectIDFromHex(commentID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("invalid ObjectID format")
+	}
+
+	var comment models.Comment
+	err = entity.repository.FindOne(ctx, bson.M{"_id": commentObjectID}).Decode(&comment)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("comment does not exist")
+	}
+
+	return &comment, nil
+}
+
+func (entity *commentEntity) CreateComment(comment models.Comment) (*models.Comment, error) {
+	ctx, cancel := initContextFrom(entity.parent)
+	defer cancel()
+
+	comment.ID = primitive.NewObjectID()
+	comment.SetCreatedAt()
+	comment.SetUpdatedAt()
+
+	_, err := entity.repository.InsertOne(ctx, comment)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to create comment")
+	}
+
+	return &comment, nil
+}
+
+func (entity *commentEntity) updateComment(commentID string, set bson.M) (*models.Comment, error) {
+	ctx, cancel := initContextFrom(entity.parent)
+	defer cancel()
+
+	commentObjectID, err := primitive.ObjectIDFromHex(commentID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("invalid ObjectID format")
+	}
+
+	set["updated_at"] = time.Now()
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var comment models.Comment
+	err = entity.repository.FindOneAndUpdate(ctx, bson.M{"_id": commentObjectID, "deleted": false}, bson.M{"$set": set}, opts).Decode(&comment)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("comment does not exist")
+	}
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to update comment")
+	}
+
+	return &comment, nil
+}
+
+func (entity *commentEntity) EditComment(commentID string, body string, mentions []string) (*models.Comment, error) {
+	return entity.updateComment(commentID, bson.M{
+		"body":     body,
+		"mentions": mentions,
+		"edited":   true,
+	})
+}
+
+// DeleteComment clears the text of a comment and marks it deleted, so that
+// its replies stay in the thread.
+func (entity *commentEntity) DeleteComment(commentID string) (*models.Comment, error) {
+	return entity.updateComment(commentID, bson.M{
+		"body":     "",
+		"mentions": []string{},
+		"deleted":  true,
+	})
+}
+
+// DeleteTaskComments removes the comments and the status history of a task,
+// or of every task of the workflow when taskID is empty.
+func (entity *commentEntity) DeleteTaskComments(workflowID string, taskID string) error {
+	ctx, cancel := initContextFrom(entity.parent)
+	defer cancel()
+
+	filter, err := taskFilter(workflowID, taskID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := entity.repository.DeleteMany(ctx, filter); err != nil {
+		logrus.Error(err)
+		return errors.New("failed to delete comments")
+	}
+
+	if _, err := entity.statusChanges.DeleteMany(ctx, filter); err != nil {
+		logrus.Error(err)
+		return errors.New("failed to delete status changes")
+	}
+
+	return nil
+}
+
+// SaveStatusChange stores a status change once, however often it is saved.
+func (entity *commentEntity) SaveStatusChange(change models.TaskStatusChange) error {
+	ctx, cancel := initContextFrom(entity.parent)
+	defer cancel()
+
+	opts := options.Update().SetUpsert(true)
+	update := bson.M{"$setOnInsert": bson.M{
+		"workflow_id":     change.WorkflowID,
+		"task_id":         change.TaskID,
+		"previous_status": change.PreviousStatus,
+		"status":          change.Status,
+		"changed_at":      change.ChangedAt,
+	}}
+	_, err := entity.statusChanges.UpdateOne(ctx, bson.M{"_id": change.ID}, update, opts)
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("failed to save status change")
+	}
+
+	return nil
+}
+
+func (entity *commentEntity) FindStatusChangesByTaskID(workflowID string, taskID string) ([]models.TaskStatusChange, error) {
+	ctx, cancel := initContextFrom(entity.parent)
+	defer cancel()
+
+	filter, err := taskFilter(workflowID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.M{"changed_at": 1})
+	cursor, err := entity.statusChanges.Find(ctx, filter, opts)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve status changes")
+	}
+	defer cursor.Close(ctx)
+
+	changes := []models.TaskStatusChange{}
+	if err := cursor.All(ctx, &changes); err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve status changes")
+	}
+
+	return changes, nil
+}
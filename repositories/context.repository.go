@@ -9,3 +9,13 @@ func initContext() (context.Context, context.CancelFunc) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	return ctx, cancel
 }
+
+// initContextFrom derives the context of a repository call from the context
+// the repository was bound to, so the call takes part in the transaction of
+// the caller. Unbound repositories start from a fresh context.
+func initContextFrom(parent context.Context) (context.Context, context.CancelFunc) {
+	if parent == nil {
+		return initContext()
+	}
+	return context.WithTimeout(parent, 60*time.Second)
+}
@@ -0,0 +1,251 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+	"virtual_workflow_management_system_gin/common"
+	"virtual_workflow_management_system_gin/models"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (entity *workflowEntity) SetVariableDefinitions(workflowID string, definitions []models.FieldDefinition, version *int64) (*models.Workflow, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
+
+	var updatedWorkflow models.Workflow
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
+		workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid ObjectID format")
+		}
+
+		filter := bson.M{"_id": workflowObjectID}
+		var workflow models.Workflow
+		err = entity.repository.FindOne(c, filter).Decode(&workflow)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("workflow does not exist")
+		}
+
+		if version != nil && *version != workflow.Version {
+			return ErrVersionMismatch
+		}
+
+		if err := workflow.CheckVariableDefinitions(definitions); err != nil {
+			return err
+		}
+
+		update := bson.M{
+			"$set": bson.M{
+				"variable_definitions": definitions,
+				"updated_at":           time.Now(),
+			},
+			"$inc": bson.M{"version": 1},
+		}
+
+		result, err := entity.repository.UpdateOne(c, withVersion(filter, &workflow.Version), update)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to update variable definitions")
+		}
+
+		if result.MatchedCount == 0 {
+			return ErrVersionMismatch
+		}
+
+		err = entity.repository.FindOne(c, filter).Decode(&updatedWorkflow)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to retrieve updated workflow")
+		}
+
+		return entity.saveRevision(c, updatedWorkflow)
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return &updatedWorkflow, nil
+}
+
+func (entity *workflowEntity) SetTaskForm(workflowID string, taskID string, form []models.FieldDefinition, version *int64) (*models.Task, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
+
+	var updatedTaskModel models.Task
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
+		workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid ObjectID format")
+		}
+
+		taskObjectID, err := primitive.ObjectIDFromHex(taskID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid ObjectID format")
+		}
+
+		filter := bson.M{"_id": workflowObjectID}
+		var workflow models.Workflow
+		err = entity.repository.FindOne(c, filter).Decode(&workflow)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("workflow does not exist")
+		}
+
+		if version != nil && *version != workflow.Version {
+			return ErrVersionMismatch
+		}
+
+		index := taskIndex(workflow.Tasks, taskObjectID)
+		if index == -1 {
+			return errors.New("task does not exist")
+		}
+
+		if err := models.ValidateForm(form, workflow.VariableDefinitions); err != nil {
+			return err
+		}
+
+		task := &workflow.Tasks[index]
+		task.Form = form
+		task.SetUpdatedAt()
+
+		update := bson.M{
+			"$set": bson.M{
+				"tasks":      workflow.Tasks,
+				"updated_at": task.UpdatedAt,
+			},
+			"$inc": bson.M{"version": 1},
+		}
+
+		result, err := entity.repository.UpdateOne(c, withVersion(filter, &workflow.Version), update)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to update task form")
+		}
+
+		if result.MatchedCount == 0 {
+			return ErrVersionMismatch
+		}
+
+		updatedTaskModel = *task
+
+		return entity.saveRevisionByID(c, workflowObjectID)
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return &updatedTaskModel, nil
+}
+
+// CompleteTask validates the submitted form values, stores them on the task and
+// in the run variables, and completes the task.
+func (entity *workflowEntity) CompleteTask(workflowID string, taskID string, values map[string]interface{}, version *int64) (*models.Task, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
+
+	var completedTask models.Task
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
+		workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid ObjectID format")
+		}
+
+		taskObjectID, err := primitive.ObjectIDFromHex(taskID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid ObjectID format")
+		}
+
+		filter := bson.M{"_id": workflowObjectID}
+		var workflow models.Workflow
+		err = entity.repository.FindOne(c, filter).Decode(&workflow)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("workflow does not exist")
+		}
+
+		if version != nil && *version != workflow.Version {
+			return ErrVersionMismatch
+		}
+
+		index := taskIndex(workflow.Tasks, taskObjectID)
+		if index == -1 {
+			return errors.New("task does not exist")
+		}
+
+		task := &workflow.Tasks[index]
+		if task.HasManagedStatus() {
+			return errors.New("task status is managed by the workflow")
+		}
+		if task.IsDone() {
+			return errors.New("task is already done")
+		}
+		if err := task.CheckChecklistDone(); err != nil {
+			return err
+		}
+
+		normalized, err := task.NormalizeFormValues(values)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		task.FormValues = normalized
+		task.SetStatus(models.Completed, now)
+		task.UpdatedAt = now
+
+		set := bson.M{
+			"tasks":      workflow.Tasks,
+			"updated_at": now,
+		}
+		for name, value := range normalized {
+			set["variables."+name] = value
+		}
+
+		update := bson.M{
+			"$set": set,
+			"$inc": bson.M{"version": 1},
+		}
+
+		result, err := entity.repository.UpdateOne(c, withVersion(filter, &workflow.Version), update)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to complete task")
+		}
+
+		if result.MatchedCount == 0 {
+			return ErrVersionMismatch
+		}
+
+		completedTask = *task
+
+		return entity.advanceGateways(c, workflowObjectID)
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return &completedTask, nil
+}
+
+func taskIndex(tasks []models.Task, taskObjectID primitive.ObjectID) int {
+	for i := range tasks {
+		if tasks[i].ID == taskObjectID {
+			return i
+		}
+	}
+	return -1
+}
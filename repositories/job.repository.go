@@ -0,0 +1,349 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+	"virtual_workflow_management_system_gin/common"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const maxListedJobs = 100
+
+var JobEntity IJob
+
+type jobEntity struct {
+	resource    *databases.Resource
+	repository  *mongo.Collection
+	deadLetters *mongo.Collection
+	claims      *mongo.Collection
+	mongoClient *mongo.Client
+}
+
+type IJob interface {
+	EnqueueJob(job models.Job) (*string, error)
+	ClaimJob(jobTypes map[string]int, lease time.Duration) (*models.Job, error)
+	CompleteJob(jobID primitive.ObjectID, leaseID string) error
+	FailJob(jobID primitive.ObjectID, leaseID string, failure error) error
+	FindJobs(status models.JobStatus, jobType string) ([]models.Job, error)
+	FindJobByID(jobID string) (*models.Job, error)
+	FindDeadLetterJobs(jobType string) ([]models.Job, error)
+	RetryDeadLetterJob(jobID string) (*models.Job, error)
+}
+
+func NewJobEntity(resource *databases.Resource) IJob {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &jobEntity{}
+	}
+	jobRepository := resource.MongoDB.Collection("jobs")
+	deadLetterRepository := resource.MongoDB.Collection("dead_letter_jobs")
+	claimRepository := resource.MongoDB.Collection("job_claims")
+	JobEntity = &jobEntity{resource: resource, repository: jobRepository, deadLetters: deadLetterRepository, claims: claimRepository, mongoClient: resource.MongoDB.Client()}
+	return JobEntity
+}
+
+func (entity *jobEntity) EnqueueJob(job models.Job) (*string, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	job.ID = primitive.NewObjectID()
+	job.Status = models.JobQueued
+	job.SetCreatedAt()
+	job.SetUpdatedAt()
+	if job.ScheduledAt.IsZero() {
+		job.ScheduledAt = job.CreatedAt
+	}
+	if job.Payload == nil {
+		job.Payload = map[string]interface{}{}
+	}
+
+	_, err := entity.repository.InsertOne(ctx, job)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to enqueue job")
+	}
+
+	insertedID := job.ID.Hex()
+	return &insertedID, nil
+}
+
+// ClaimJob leases the oldest due job of one of the given types, which map to
+// how many jobs of the type may hold a lease at the same time across all
+// servers, zero meaning no limit. Running jobs whose lease has expired are
+// claimed again, so jobs of a crashed worker are not lost. Every claim counts
+// as an attempt. It returns nil when no job is due.
+func (entity *jobEntity) ClaimJob(jobTypes map[string]int, lease time.Duration) (*models.Job, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	var claimed *models.Job
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
+		now := time.Now()
+		available := []string{}
+		for jobType, limit := range jobTypes {
+			if limit > 0 {
+				leased, err := entity.repository.CountDocuments(c, bson.M{
+					"type":         jobType,
+					"status":       models.JobRunning,
+					"leased_until": bson.M{"$gt": now},
+				})
+				if err != nil {
+					logrus.Error(err)
+					return errors.New("failed to claim job")
+				}
+				if leased >= int64(limit) {
+					continue
+				}
+			}
+			available = append(available, jobType)
+		}
+		if len(available) == 0 {
+			return nil
+		}
+
+		filter := bson.M{
+			"type": bson.M{"$in": available},
+			"$or": bson.A{
+				bson.M{"status": models.JobQueued, "scheduled_at": bson.M{"$lte": now}},
+				bson.M{"status": models.JobRunning, "leased_until": bson.M{"$lte": now}},
+			},
+		}
+		update := bson.M{
+			"$set": bson.M{
+				"status":       models.JobRunning,
+				"lease_id":     primitive.NewObjectID().Hex(),
+				"leased_until": now.Add(lease),
+				"updated_at":   now,
+			},
+			"$inc": bson.M{"attempts": 1},
+		}
+		opts := options.FindOneAndUpdate().SetSort(bson.M{"scheduled_at": 1}).SetReturnDocument(options.After)
+
+		var job models.Job
+		err := entity.repository.FindOneAndUpdate(c, filter, update, opts).Decode(&job)
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		if err != nil {
+			if isWriteConflict(err) {
+				return err
+			}
+			logrus.Error(err)
+			return errors.New("failed to claim job")
+		}
+
+		// Claims of a limited type write the same document, so of two servers
+		// that counted the same leases only one commits its claim.
+		if jobTypes[job.Type] > 0 {
+			_, err = entity.claims.UpdateOne(c, bson.M{"_id": job.Type}, bson.M{"$set": bson.M{"claimed_at": now}}, options.Update().SetUpsert(true))
+			if err != nil {
+				if isWriteConflict(err) {
+					return err
+				}
+				logrus.Error(err)
+				return errors.New("failed to claim job")
+			}
+		}
+
+		claimed = &job
+		return nil
+	})
+	if isWriteConflict(err) {
+		// Another server claimed the job or one of the same type; try again on
+		// the next poll.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return claimed, nil
+}
+
+func (entity *jobEntity) CompleteJob(jobID primitive.ObjectID, leaseID string) error {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"status":      models.JobSucceeded,
+			"finished_at": now,
+			"updated_at":  now,
+		},
+		"$unset": bson.M{"lease_id": "", "leased_until": "", "last_error": ""},
+	}
+
+	result, err := entity.repository.UpdateOne(ctx, bson.M{"_id": jobID, "lease_id": leaseID}, update)
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("failed to complete job")
+	}
+
+	if result.MatchedCount == 0 {
+		return errors.New("job lease was lost")
+	}
+
+	return nil
+}
+
+// FailJob reschedules a failed job or, once it has no attempts left, moves it
+// to the dead-letter collection.
+func (entity *jobEntity) FailJob(jobID primitive.ObjectID, leaseID string, failure error) error {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	return common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
+		filter := bson.M{"_id": jobID, "lease_id": leaseID}
+		var job models.Job
+		err := entity.repository.FindOne(c, filter).Decode(&job)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("job lease was lost")
+		}
+
+		if job.RecordFailure(failure, time.Now()) {
+			_, err = entity.repository.ReplaceOne(c, filter, job)
+			if err != nil {
+				logrus.Error(err)
+				return errors.New("failed to reschedule job")
+			}
+			return nil
+		}
+
+		_, err = entity.repository.DeleteOne(c, filter)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to dead-letter job")
+		}
+
+		_, err = entity.deadLetters.InsertOne(c, job)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to dead-letter job")
+		}
+
+		return nil
+	})
+}
+
+func (entity *jobEntity) FindJobs(status models.JobStatus, jobType string) ([]models.Job, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	if jobType != "" {
+		filter["type"] = jobType
+	}
+
+	return entity.findJobs(ctx, entity.repository, filter)
+}
+
+// FindJobByID looks the job up in the queue and then among the dead letters.
+func (entity *jobEntity) FindJobByID(jobID string) (*models.Job, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	jobObjectID, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("invalid ObjectID format")
+	}
+
+	var job models.Job
+	err = entity.repository.FindOne(ctx, bson.M{"_id": jobObjectID}).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		err = entity.deadLetters.FindOne(ctx, bson.M{"_id": jobObjectID}).Decode(&job)
+	}
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("job does not exist")
+	}
+
+	return &job, nil
+}
+
+func (entity *jobEntity) FindDeadLetterJobs(jobType string) ([]models.Job, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	filter := bson.M{}
+	if jobType != "" {
+		filter["type"] = jobType
+	}
+
+	return entity.findJobs(ctx, entity.deadLetters, filter)
+}
+
+// RetryDeadLetterJob moves a dead job back to the queue with a fresh set of
+// attempts.
+func (entity *jobEntity) RetryDeadLetterJob(jobID string) (*models.Job, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	var job models.Job
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
+		jobObjectID, err := primitive.ObjectIDFromHex(jobID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid ObjectID format")
+		}
+
+		filter := bson.M{"_id": jobObjectID}
+		err = entity.deadLetters.FindOne(c, filter).Decode(&job)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("only dead jobs can be retried")
+		}
+
+		job.Requeue(time.Now())
+
+		_, err = entity.deadLetters.DeleteOne(c, filter)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to retry job")
+		}
+
+		_, err = entity.repository.InsertOne(c, job)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to retry job")
+		}
+
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (entity *jobEntity) findJobs(ctx context.Context, collection *mongo.Collection, filter bson.M) ([]models.Job, error) {
+	opts := options.Find().SetSort(bson.M{"scheduled_at": -1}).SetLimit(maxListedJobs)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve jobs")
+	}
+	defer cursor.Close(ctx)
+
+	jobs := []models.Job{}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve jobs")
+	}
+
+	return jobs, nil
+}
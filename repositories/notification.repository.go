@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const maxListedNotifications = 100
+
+var NotificationEntity INotification
+
+type notificationEntity struct {
+	resource   *databases.Resource
+	repository *mongo.Collection
+}
+
+type INotification interface {
+	CreateNotifications(notifications []models.Notification) error
+	FindNotificationsByUsername(username string, unreadOnly bool) ([]models.Notification, error)
+	MarkNotificationRead(username string, notificationID string) (*models.Notification, error)
+}
+
+func NewNotificationEntity(resource *databases.Resource) INotification {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &notificationEntity{}
+	}
+	notificationRepository := resource.MongoDB.Collection("notifications")
+	NotificationEntity = &notificationEntity{resource: resource, repository: notificationRepository}
+	return NotificationEntity
+}
+
+func (entity *notificationEntity) CreateNotifications(notifications []models.Notification) error {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	documents := make([]interface{}, 0, len(notifications))
+	for i := range notifications {
+		notifications[i].ID = primitive.NewObjectID()
+		notifications[i].SetCreatedAt()
+		notifications[i].SetUpdatedAt()
+		documents = append(documents, notifications[i])
+	}
+	if len(documents) == 0 {
+		return nil
+	}
+
+	_, err := entity.repository.InsertMany(ctx, documents)
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("failed to create notifications")
+	}
+
+	return nil
+}
+
+// FindNotificationsByUsername returns the most recent notifications of a
+// user, newest first.
+func (entity *notificationEntity) FindNotificationsByUsername(username string, unreadOnly bool) ([]models.Notification, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	filter := bson.M{"username": username}
+	if unreadOnly {
+		filter["read_at"] = bson.M{"$exists": false}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).SetLimit(maxListedNotifications)
+	cursor, err := entity.repository.Find(ctx, filter, opts)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve notifications")
+	}
+	defer cursor.Close(ctx)
+
+	notifications := []models.Notification{}
+	if err := cursor.All(ctx, &notifications); err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve notifications")
+	}
+
+	return notifications, nil
+}
+
+// MarkNotificationRead marks a notification of the user as read. Reading it
+// again keeps the time it was first read.
+func (entity *notificationEntity) MarkNotificationRead(username string, notificationID string) (*models.Notification, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	notificationObjectID, err := primitive.ObjectIDFromHex(notificationID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("invalid ObjectID format")
+	}
+
+	filter := bson.M{"_id": notificationObjectID, "username": username}
+	now := time.Now()
+	_, err = entity.repository.UpdateOne(ctx, bson.M{"_id": notificationObjectID, "username": username, "read_at": bson.M{"$exists": false}}, bson.M{
+		"$set": bson.M{"read_at": now, "updated_at": now},
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to update notification")
+	}
+
+	var notification models.Notification
+	err = entity.repository.FindOne(ctx, filter).Decode(&notification)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("notification does not exist")
+	}
+
+	return &notification, nil
+}
@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var OutboxEntity IOutbox
+
+type outboxEntity struct {
+	resource   *databases.Resource
+	repository *mongo.Collection
+	parent     context.Context
+}
+
+type IOutbox interface {
+	WithContext(ctx context.Context) IOutbox
+	AddEntries(entries []models.OutboxEntry) error
+	ClaimEntry(lease time.Duration) (*models.OutboxEntry, error)
+	DeleteEntry(entryID primitive.ObjectID, leaseID string) error
+}
+
+func NewOutboxEntity(resource *databases.Resource) IOutbox {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &outboxEntity{}
+	}
+	outboxRepository := resource.MongoDB.Collection("event_outbox")
+	OutboxEntity = &outboxEntity{resource: resource, repository: outboxRepository}
+	return OutboxEntity
+}
+
+// WithContext returns a copy of the repository whose calls run with ctx, so
+// entries are written in the transaction of the caller.
+func (entity *outboxEntity) WithContext(ctx context.Context) IOutbox {
+	bound := *entity
+	bound.parent = ctx
+	return &bound
+}
+
+func (entity *outboxEntity) AddEntries(entries []models.OutboxEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	ctx, cancel := initContextFrom(entity.parent)
+	defer cancel()
+
+	documents := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		documents[i] = entry
+	}
+
+	_, err := entity.repository.InsertMany(ctx, documents)
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("failed to write events to the outbox")
+	}
+
+	return nil
+}
+
+// ClaimEntry leases the oldest entry that is not leased by another relay. It
+// returns nil when the outbox is empty.
+func (entity *outboxEntity) ClaimEntry(lease time.Duration) (*models.OutboxEntry, error) {
+	ctx, cancel := initContextFrom(entity.parent)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"leased_until": bson.M{"$exists": false}},
+			bson.M{"leased_until": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"lease_id":     primitive.NewObjectID().Hex(),
+			"leased_until": now.Add(lease),
+		},
+	}
+	opts := options.FindOneAndUpdate().SetSort(bson.M{"occurred_at": 1}).SetReturnDocument(options.After)
+
+	var entry models.OutboxEntry
+	err := entity.repository.FindOneAndUpdate(ctx, filter, update, opts).Decode(&entry)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to claim outbox entry")
+	}
+
+	return &entry, nil
+}
+
+// DeleteEntry removes a relayed entry.
+func (entity *outboxEntity) DeleteEntry(entryID primitive.ObjectID, leaseID string) error {
+	ctx, cancel := initContextFrom(entity.parent)
+	defer cancel()
+
+	result, err := entity.repository.DeleteOne(ctx, bson.M{"_id": entryID, "lease_id": leaseID})
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("failed to delete outbox entry")
+	}
+
+	if result.DeletedCount == 0 {
+		return errors.New("outbox entry lease was lost")
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// reportTimeout bounds a report, which is streamed to the client row by row
+// and may take longer than a regular query.
+const reportTimeout = 10 * time.Minute
+
+var ReportEntity IReport
+
+type reportEntity struct {
+	resource  *databases.Resource
+	workflows *mongo.Collection
+}
+
+type IReport interface {
+	StreamTaskRows(workflowID string, owner string, fn func(models.TaskRow) error) error
+}
+
+func NewReportEntity(resource *databases.Resource) IReport {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &reportEntity{}
+	}
+	workflowRepository := resource.MongoDB.Collection("workflows")
+	ReportEntity = &reportEntity{resource: resource, workflows: workflowRepository}
+	return ReportEntity
+}
+
+// StreamTaskRows calls fn with the tasks of a workflow, or with the tasks of
+// the workflows of owner when workflowID is empty, and of every owner when
+// owner is empty too. Workflows come oldest first and their tasks in order.
+// Rows are read from the cursor one at a time; an error returned by fn stops
+// the stream and is returned as is.
+func (entity *reportEntity) StreamTaskRows(workflowID string, owner string, fn func(models.TaskRow) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+	defer cancel()
+
+	match := ownerFilter(bson.M{}, owner)
+	if workflowID != "" {
+		objectID, err := primitive.ObjectIDFromHex(workflowID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid workflow id")
+		}
+		match["_id"] = objectID
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$unwind", Value: "$tasks"}},
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}, {Key: "tasks.order", Value: 1}}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":           0,
+			"workflow_id":   "$_id",
+			"workflow_name": "$name",
+			"task_id":       "$tasks._id",
+			"task_name":     "$tasks.name",
+			"status":        "$tasks.status",
+			"order":         "$tasks.order",
+			"created_at":    "$tasks.created_at",
+			"updated_at":    "$tasks.updated_at",
+			"assignee":      "$tasks.assignee",
+		}}},
+	}
+
+	// Sorting may not fit the memory limit of a pipeline stage on large
+	// accounts, so it may spill to disk.
+	cursor, err := entity.workflows.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("failed to retrieve tasks")
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var row models.TaskRow
+		if err := cursor.Decode(&row); err != nil {
+			logrus.Error(err)
+			return errors.New("failed to retrieve tasks")
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		logrus.Error(err)
+		return errors.New("failed to retrieve tasks")
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+	"virtual_workflow_management_system_gin/common"
+	"virtual_workflow_management_system_gin/models"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// saveRevision stores the definition of the workflow as the next revision,
+// unless it is identical to the latest one (e.g. only task statuses changed).
+func (entity *workflowEntity) saveRevision(ctx context.Context, workflow models.Workflow) error {
+	revision := models.NewWorkflowRevision(workflow)
+
+	var latest models.WorkflowRevision
+	opts := options.FindOne().SetSort(bson.M{"revision": -1})
+	err := entity.revisions.FindOne(ctx, bson.M{"workflow_id": workflow.ID}, opts).Decode(&latest)
+	switch {
+	case err == mongo.ErrNoDocuments:
+		revision.Revision = 1
+	case err != nil:
+		logrus.Error(err)
+		return errors.New("failed to save workflow revision")
+	case latest.SameDefinition(revision):
+		return nil
+	default:
+		revision.Revision = latest.Revision + 1
+	}
+
+	_, err = entity.revisions.InsertOne(ctx, revision)
+	if err != nil {
+		logrus.Error(err)
+		// Another write numbered its revision the same; the unique index
+		// keeps only one of them.
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrWorkflowConflict
+		}
+		return errors.New("failed to save workflow revision")
+	}
+
+	return nil
+}
+
+// EnsureIndexes creates the unique index that keeps two writes to a workflow
+// from saving the same revision number.
+func (entity *workflowEntity) EnsureIndexes() error {
+	ctx, cancel := entity.initContext()
+	defer cancel()
+
+	_, err := entity.revisions.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "workflow_id", Value: 1}, {Key: "revision", Value: 1}},
+		Options: options.Index().SetName("workflow_revision").SetUnique(true),
+	})
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("failed to create workflow revision index")
+	}
+
+	return nil
+}
+
+func (entity *workflowEntity) saveRevisionByID(ctx context.Context, workflowObjectID primitive.ObjectID) error {
+	var workflow models.Workflow
+	err := entity.repository.FindOne(ctx, bson.M{"_id": workflowObjectID}).Decode(&workflow)
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("failed to retrieve updated workflow")
+	}
+
+	return entity.saveRevision(ctx, workflow)
+}
+
+func (entity *workflowEntity) FindRevisionsByWorkflowID(workflowID string) ([]models.WorkflowRevision, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
+
+	workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("invalid ObjectID format")
+	}
+
+	opts := options.Find().SetSort(bson.M{"revision": 1})
+	cursor, err := entity.revisions.Find(ctx, bson.M{"workflow_id": workflowObjectID}, opts)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve workflow revisions")
+	}
+
+	revisions := []models.WorkflowRevision{}
+	if err = cursor.All(ctx, &revisions); err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve workflow revisions")
+	}
+
+	return revisions, nil
+}
+
+func (entity *workflowEntity) FindRevision(workflowID string, revision int) (*models.WorkflowRevision, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
+
+	workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("invalid ObjectID format")
+	}
+
+	var workflowRevision models.WorkflowRevision
+	err = entity.revisions.FindOne(ctx, bson.M{"workflow_id": workflowObjectID, "revision": revision}).Decode(&workflowRevision)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("revision does not exist")
+	}
+
+	return &workflowRevision, nil
+}
+
+func (entity *workflowEntity) RestoreWorkflowRevision(workflowID string, revision int, version *int64) (*models.Workflow, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
+
+	var restoredWorkflow models.Workflow
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
+		workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid ObjectID format")
+		}
+
+		filter := bson.M{"_id": workflowObjectID}
+		var workflow models.Workflow
+		err = entity.repository.FindOne(c, filter).Decode(&workflow)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("workflow does not exist")
+		}
+
+		if version != nil && *version != workflow.Version {
+			return ErrVersionMismatch
+		}
+
+		var workflowRevision models.WorkflowRevision
+		err = entity.revisions.FindOne(c, bson.M{"workflow_id": workflowObjectID, "revision": revision}).Decode(&workflowRevision)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("revision does not exist")
+		}
+
+		update := bson.M{
+			"$set": bson.M{
+				"name":                 workflowRevision.Name,
+				"tasks":                workflowRevision.RestoreTasks(workflow.Tasks),
+				"variable_definitions": workflowRevision.VariableDefinitions,
+				"updated_at":           time.Now(),
+			},
+			"$inc": bson.M{"version": 1},
+		}
+
+		result, err := entity.repository.UpdateOne(c, withVersion(filter, &workflow.Version), update)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to restore revision")
+		}
+
+		if result.MatchedCount == 0 {
+			return ErrVersionMismatch
+		}
+
+		if err := entity.advanceGateways(c, workflowObjectID); err != nil {
+			return err
+		}
+
+		err = entity.repository.FindOne(c, filter).Decode(&restoredWorkflow)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to retrieve updated workflow")
+		}
+
+		return entity.saveRevision(c, restoredWorkflow)
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return &restoredWorkflow, nil
+}
@@ -0,0 +1,239 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ScheduleEntity ISchedule
+
+type scheduleEntity struct {
+	resource   *databases.Resource
+	repository *mongo.Collection
+}
+
+type ISchedule interface {
+	FindSchedulesByWorkflowID(workflowID string) ([]models.Schedule, error)
+	FindScheduleByID(scheduleID string) (*models.Schedule, error)
+	CreateSchedule(schedule models.Schedule) (*string, error)
+	SetSchedulePaused(scheduleID string, paused bool, nextRunAt *time.Time) (*models.Schedule, error)
+	DeleteSchedule(scheduleID string) error
+	ClaimDueSchedule(lease time.Duration) (*models.Schedule, error)
+	RecordScheduleRuns(scheduleID primitive.ObjectID, leaseID string, runs []models.ScheduleRun, nextRunAt *time.Time) error
+}
+
+func NewScheduleEntity(resource *databases.Resource) ISchedule {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &scheduleEntity{}
+	}
+	scheduleRepository := resource.MongoDB.Collection("schedules")
+	ScheduleEntity = &scheduleEntity{resource: resource, repository: scheduleRepository}
+	return ScheduleEntity
+}
+
+func (entity *scheduleEntity) FindSchedulesByWorkflowID(workflowID string) ([]models.Schedule, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("invalid ObjectID format")
+	}
+
+	cursor, err := entity.repository.Find(ctx, bson.M{"workflow_id": workflowObjectID})
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve schedules")
+	}
+	defer cursor.Close(ctx)
+
+	schedules := []models.Schedule{}
+	if err := cursor.All(ctx, &schedules); err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve schedules")
+	}
+
+	return schedules, nil
+}
+
+func (entity *scheduleEntity) FindScheduleByID(scheduleID string) (*models.Schedule, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	scheduleObjectID, err := primitive.ObjectIDFromHex(scheduleID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("invalid ObjectID format")
+	}
+
+	var schedule models.Schedule
+	err = entity.repository.FindOne(ctx, bson.M{"_id": scheduleObjectID}).Decode(&schedule)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("schedule does not exist")
+	}
+
+	return &schedule, nil
+}
+
+func (entity *scheduleEntity) CreateSchedule(schedule models.Schedule) (*string, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	schedule.ID = primitive.NewObjectID()
+	schedule.SetCreatedAt()
+	schedule.SetUpdatedAt()
+	if schedule.Runs == nil {
+		schedule.Runs = []models.ScheduleRun{}
+	}
+
+	_, err := entity.repository.InsertOne(ctx, schedule)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to create schedule")
+	}
+
+	insertedID := schedule.ID.Hex()
+	return &insertedID, nil
+}
+
+func (entity *scheduleEntity) SetSchedulePaused(scheduleID string, paused bool, nextRunAt *time.Time) (*models.Schedule, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	scheduleObjectID, err := primitive.ObjectIDFromHex(scheduleID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("invalid ObjectID format")
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"paused":     paused,
+			"updated_at": time.Now(),
+		},
+	}
+	if nextRunAt != nil {
+		update["$set"].(bson.M)["next_run_at"] = *nextRunAt
+	} else {
+		update["$unset"] = bson.M{"next_run_at": ""}
+	}
+
+	var schedule models.Schedule
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = entity.repository.FindOneAndUpdate(ctx, bson.M{"_id": scheduleObjectID}, update, opts).Decode(&schedule)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("schedule does not exist")
+	}
+
+	return &schedule, nil
+}
+
+func (entity *scheduleEntity) DeleteSchedule(scheduleID string) error {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	scheduleObjectID, err := primitive.ObjectIDFromHex(scheduleID)
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("invalid ObjectID format")
+	}
+
+	result, err := entity.repository.DeleteOne(ctx, bson.M{"_id": scheduleObjectID})
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("failed to delete schedule")
+	}
+
+	if result.DeletedCount == 0 {
+		return errors.New("schedule does not exist")
+	}
+
+	return nil
+}
+
+// ClaimDueSchedule leases an active schedule whose next run is due. It returns
+// nil when no schedule is due.
+func (entity *scheduleEntity) ClaimDueSchedule(lease time.Duration) (*models.Schedule, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{
+		"paused":      false,
+		"next_run_at": bson.M{"$lte": now},
+		"$or": bson.A{
+			bson.M{"leased_until": nil},
+			bson.M{"leased_until": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"lease_id":     primitive.NewObjectID().Hex(),
+			"leased_until": now.Add(lease),
+		},
+	}
+	opts := options.FindOneAndUpdate().SetSort(bson.M{"next_run_at": 1}).SetReturnDocument(options.After)
+
+	var schedule models.Schedule
+	err := entity.repository.FindOneAndUpdate(ctx, filter, update, opts).Decode(&schedule)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to claim schedule")
+	}
+
+	return &schedule, nil
+}
+
+// RecordScheduleRuns stores the runs created under the given lease, keeping the
+// most recent ones, and releases the schedule until its next run.
+func (entity *scheduleEntity) RecordScheduleRuns(scheduleID primitive.ObjectID, leaseID string, runs []models.ScheduleRun, nextRunAt *time.Time) error {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	now := time.Now()
+	set := bson.M{"updated_at": now}
+	unset := bson.M{"lease_id": "", "leased_until": ""}
+	if nextRunAt != nil {
+		set["next_run_at"] = *nextRunAt
+	} else {
+		unset["next_run_at"] = ""
+	}
+	if len(runs) > 0 {
+		set["last_run_at"] = now
+	}
+
+	update := bson.M{
+		"$set":   set,
+		"$unset": unset,
+		"$push": bson.M{"runs": bson.M{
+			"$each":  runs,
+			"$slice": -models.MaxScheduleRuns,
+		}},
+	}
+
+	result, err := entity.repository.UpdateOne(ctx, bson.M{"_id": scheduleID, "lease_id": leaseID}, update)
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("failed to record schedule runs")
+	}
+
+	if result.MatchedCount == 0 {
+		return errors.New("schedule lease was lost")
+	}
+
+	return nil
+}
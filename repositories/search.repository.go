@@ -0,0 +1,172 @@
+package repositories
+
+import (
+	"errors"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var SearchEntity ISearch
+
+type searchEntity struct {
+	resource  *databases.Resource
+	workflows *mongo.Collection
+	comments  *mongo.Collection
+}
+
+type ISearch interface {
+	EnsureIndexes() error
+	SearchWorkflows(query string, owner string, limit int) ([]models.WorkflowMatch, error)
+	SearchComments(query string, limit int) ([]models.CommentMatch, error)
+	FindWorkflowsByIDs(workflowIDs []primitive.ObjectID, owner string) ([]models.Workflow, error)
+}
+
+func NewSearchEntity(resource *databases.Resource) ISearch {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &searchEntity{}
+	}
+	workflowRepository := resource.MongoDB.Collection("workflows")
+	commentRepository := resource.MongoDB.Collection("task_comments")
+	SearchEntity = &searchEntity{resource: resource, workflows: workflowRepository, comments: commentRepository}
+	return SearchEntity
+}
+
+// EnsureIndexes creates the text indexes searched by the repository. A
+// collection holds one text index, so changing the weights means dropping the
+// old index first.
+func (entity *searchEntity) EnsureIndexes() error {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	_, err := entity.workflows.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "name", Value: "text"},
+			{Key: "labels", Value: "text"},
+			{Key: "tasks.name", Value: "text"},
+			{Key: "tasks.labels", Value: "text"},
+			{Key: "tasks.description", Value: "text"},
+		},
+		Options: options.Index().SetName("workflow_text").SetWeights(bson.D{
+			{Key: "name", Value: models.WorkflowNameWeight},
+			{Key: "labels", Value: models.WorkflowLabelWeight},
+			{Key: "tasks.name", Value: models.TaskNameWeight},
+			{Key: "tasks.labels", Value: models.TaskLabelWeight},
+			{Key: "tasks.description", Value: models.TaskDescriptionWeight},
+		}),
+	})
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("failed to create workflow text index")
+	}
+
+	_, err = entity.comments.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "body", Value: "text"}},
+		Options: options.Index().SetName("comment_text"),
+	})
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("failed to create comment text index")
+	}
+
+	return nil
+}
+
+// ownerFilter limits a query to the workflows of owner. An empty owner leaves
+// the query open to every owner.
+func ownerFilter(filter bson.M, owner string) bson.M {
+	if owner != "" {
+		filter["owner"] = owner
+	}
+	return filter
+}
+
+// SearchWorkflows returns the best matching workflows of owner, or of every
+// owner when owner is empty, best first.
+func (entity *searchEntity) SearchWorkflows(query string, owner string, limit int) ([]models.WorkflowMatch, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	score := bson.M{"$meta": "textScore"}
+	findOptions := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}}).
+		SetLimit(int64(limit))
+
+	cursor, err := entity.workflows.Find(ctx, ownerFilter(bson.M{"$text": bson.M{"$search": query}}, owner), findOptions)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to search workflows")
+	}
+
+	matches := []models.WorkflowMatch{}
+	if err := cursor.All(ctx, &matches); err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to search workflows")
+	}
+
+	return matches, nil
+}
+
+// SearchComments returns the tasks whose comments match best, regardless of
+// who may see them. Deleted comments are left out.
+func (entity *searchEntity) SearchComments(query string, limit int) ([]models.CommentMatch, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"$text": bson.M{"$search": query}, "deleted": false}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"workflow_id": "$workflow_id", "task_id": "$task_id"},
+			"score": bson.M{"$max": bson.M{"$meta": "textScore"}},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "score", Value: -1}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$project", Value: bson.M{
+			"_id":         0,
+			"workflow_id": "$_id.workflow_id",
+			"task_id":     "$_id.task_id",
+			"score":       1,
+			"count":       1,
+		}}},
+	}
+
+	cursor, err := entity.comments.Aggregate(ctx, pipeline)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to search comments")
+	}
+
+	matches := []models.CommentMatch{}
+	if err := cursor.All(ctx, &matches); err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to search comments")
+	}
+
+	return matches, nil
+}
+
+func (entity *searchEntity) FindWorkflowsByIDs(workflowIDs []primitive.ObjectID, owner string) ([]models.Workflow, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	cursor, err := entity.workflows.Find(ctx, ownerFilter(bson.M{"_id": bson.M{"$in": workflowIDs}}, owner))
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve workflows")
+	}
+
+	workflows := []models.Workflow{}
+	if err := cursor.All(ctx, &workflows); err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve workflows")
+	}
+
+	return workflows, nil
+}
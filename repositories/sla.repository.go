@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+	"virtual_workflow_management_system_gin/common"
+	"virtual_workflow_management_system_gin/models"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ClaimEscalatingTask leases the next task with a running SLA clock whose next
+// escalation step is due. It returns nil when no step is due.
+func (entity *workflowEntity) ClaimEscalatingTask(lease time.Duration) (*models.Workflow, *models.Task, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
+
+	now := time.Now()
+	leaseID := primitive.NewObjectID().Hex()
+	filter := bson.M{
+		"tasks": bson.M{"$elemMatch": bson.M{
+			"sla.next_check_at": bson.M{"$lte": now},
+			"sla.stopped_at":    nil,
+			"$or": bson.A{
+				bson.M{"sla.leased_until": nil},
+				bson.M{"sla.leased_until": bson.M{"$lte": now}},
+			},
+		}},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"tasks.$.sla.lease_id":     leaseID,
+			"tasks.$.sla.leased_until": now.Add(lease),
+		},
+	}
+
+	var workflow models.Workflow
+	err := entity.repository.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&workflow)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil, nil
+	}
+	if err != nil {
+		logrus.Error(err)
+		return nil, nil, errors.New("failed to claim escalating task")
+	}
+
+	for i := range workflow.Tasks {
+		if workflow.Tasks[i].SLA != nil && workflow.Tasks[i].SLA.LeaseID == leaseID {
+			return &workflow, &workflow.Tasks[i], nil
+		}
+	}
+
+	return nil, nil, errors.New("failed to claim escalating task")
+}
+
+// EscalateTask runs the due escalation steps of a task leased with
+// ClaimEscalatingTask and returns the escalations it made.
+func (entity *workflowEntity) EscalateTask(workflowID string, taskID string, leaseID string) (*models.Task, []models.Escalation, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
+
+	var updatedTaskModel models.Task
+	var escalations []models.Escalation
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
+		workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid ObjectID format")
+		}
+
+		taskObjectID, err := primitive.ObjectIDFromHex(taskID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid ObjectID format")
+		}
+
+		filter := bson.M{"_id": workflowObjectID}
+		var workflow models.Workflow
+		err = entity.repository.FindOne(c, filter).Decode(&workflow)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("workflow does not exist")
+		}
+
+		index := taskIndex(workflow.Tasks, taskObjectID)
+		if index == -1 {
+			return errors.New("task does not exist")
+		}
+
+		task := &workflow.Tasks[index]
+		if task.SLA == nil || task.SLA.StoppedAt != nil || task.SLA.LeaseID != leaseID {
+			return errors.New("escalating task lease was lost")
+		}
+
+		now := time.Now()
+		escalations = task.Escalate(workflow.Owner, now)
+		task.UpdatedAt = now
+
+		update := bson.M{
+			"$set": bson.M{
+				"tasks":      workflow.Tasks,
+				"updated_at": now,
+			},
+			"$inc": bson.M{"version": 1},
+		}
+
+		updateResult, err := entity.repository.UpdateOne(c, withVersion(filter, &workflow.Version), update)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to escalate task")
+		}
+
+		if updateResult.MatchedCount == 0 {
+			return ErrVersionMismatch
+		}
+
+		updatedTaskModel = *task
+
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, nil, err
+	}
+
+	return &updatedTaskModel, escalations, nil
+}
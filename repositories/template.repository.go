@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"errors"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var TemplateEntity ITemplate
+
+type templateEntity struct {
+	resource   *databases.Resource
+	repository *mongo.Collection
+}
+
+type ITemplate interface {
+	FindTemplatesForUser(user models.JWTUser) ([]models.Template, error)
+	FindTemplateByID(templateID string) (*models.Template, error)
+	CreateTemplate(template models.Template) (*string, error)
+	DeleteTemplate(templateID string) error
+}
+
+func NewTemplateEntity(resource *databases.Resource) ITemplate {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &templateEntity{}
+	}
+	templateRepository := resource.MongoDB.Collection("templates")
+	TemplateEntity = &templateEntity{resource: resource, repository: templateRepository}
+	return TemplateEntity
+}
+
+func (entity *templateEntity) FindTemplatesForUser(user models.JWTUser) ([]models.Template, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	visibility := bson.A{
+		bson.M{"scope": models.GlobalTemplate},
+		bson.M{"scope": models.PersonalTemplate, "owner": user.Username},
+	}
+	if user.Organization != "" {
+		visibility = append(visibility, bson.M{"scope": models.OrganizationTemplate, "organization": user.Organization})
+	}
+
+	cursor, err := entity.repository.Find(ctx, bson.M{"$or": visibility})
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve templates")
+	}
+
+	templates := []models.Template{}
+	err = cursor.All(ctx, &templates)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve templates")
+	}
+
+	return templates, nil
+}
+
+func (entity *templateEntity) FindTemplateByID(templateID string) (*models.Template, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	templateObjectID, err := primitive.ObjectIDFromHex(templateID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("invalid ObjectID format")
+	}
+
+	var template models.Template
+	err = entity.repository.FindOne(ctx, bson.M{"_id": templateObjectID}).Decode(&template)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("template does not exist")
+	}
+
+	return &template, nil
+}
+
+func (entity *templateEntity) CreateTemplate(template models.Template) (*string, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	template.SetCreatedAt()
+	template.SetUpdatedAt()
+
+	insertResult, err := entity.repository.InsertOne(ctx, template)
+	if err != nil {
+		logrus.Errorf("Failed to insert new template: %v", err)
+		return nil, errors.New("failed to create template")
+	}
+
+	insertedID, ok := insertResult.InsertedID.(primitive.ObjectID)
+	if !ok {
+		logrus.Error("Failed to convert InsertedID to ObjectID")
+		return nil, errors.New("failed to convert InsertedID to ObjectID")
+	}
+
+	insertedIDString := insertedID.Hex()
+
+	return &insertedIDString, nil
+}
+
+func (entity *templateEntity) DeleteTemplate(templateID string) error {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	templateObjectID, err := primitive.ObjectIDFromHex(templateID)
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("invalid ObjectID format")
+	}
+
+	result, err := entity.repository.DeleteOne(ctx, bson.M{"_id": templateObjectID})
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("failed to delete template")
+	}
+
+	if result.DeletedCount == 0 {
+		return errors.New("template does not exist")
+	}
+
+	return nil
+}
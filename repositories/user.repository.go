@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"errors"
 	"virtual_workflow_management_system_gin/databases"
 	"virtual_workflow_management_system_gin/models"
@@ -9,6 +10,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var UserEntity IUser
@@ -16,11 +18,15 @@ var UserEntity IUser
 type userEntity struct {
 	resource   *databases.Resource
 	repository *mongo.Collection
+	parent     context.Context
 }
 
 type IUser interface {
+	WithContext(ctx context.Context) IUser
 	CreateOne(user requests.RegisterRequest) (*models.User, error)
 	FindOneByUsername(username string) (*models.User, error)
+	UpdateByUsername(username string, fields map[string]interface{}) (*models.User, error)
+	FindUsernamesByRole(role models.UserRole) ([]string, error)
 }
 
 func NewUserEntity(resource *databases.Resource) IUser {
@@ -32,13 +38,29 @@ func NewUserEntity(resource *databases.Resource) IUser {
 	return UserEntity
 }
 
+// WithContext returns a copy of the repository whose calls run with ctx, e.g.
+// inside a transaction started by the caller.
+func (entity *userEntity) WithContext(ctx context.Context) IUser {
+	bound := *entity
+	bound.parent = ctx
+	return &bound
+}
+
+func (entity *userEntity) initContext() (context.Context, context.CancelFunc) {
+	return initContextFrom(entity.parent)
+}
+
 func (entity *userEntity) CreateOne(user requests.RegisterRequest) (*models.User, error) {
-	ctx, cancel := initContext()
+	ctx, cancel := entity.initContext()
 	defer cancel()
 
+	// Users start out without privileges; only an admin grants a role, an
+	// organization or teams.
 	userModel := models.User{
 		Username: user.Username,
 		Password: user.Password,
+		Role:     models.Employer,
+		Teams:    []string{},
 	}
 
 	existingUser, err := entity.FindOneByUsername(user.Username)
@@ -62,7 +84,7 @@ func (entity *userEntity) CreateOne(user requests.RegisterRequest) (*models.User
 }
 
 func (entity *userEntity) FindOneByUsername(username string) (*models.User, error) {
-	ctx, cancel := initContext()
+	ctx, cancel := entity.initContext()
 	defer cancel()
 
 	filter := bson.M{"username": username}
@@ -75,3 +97,45 @@ func (entity *userEntity) FindOneByUsername(username string) (*models.User, erro
 
 	return &user, nil
 }
+
+func (entity *userEntity) UpdateByUsername(username string, fields map[string]interface{}) (*models.User, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
+
+	var user models.User
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := entity.repository.FindOneAndUpdate(ctx, bson.M{"username": username}, bson.M{"$set": fields}, opts).Decode(&user)
+	if err != nil {
+		logrus.Error(err)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("username does not exist")
+		}
+		return nil, errors.New("failed to update user")
+	}
+
+	return &user, nil
+}
+
+func (entity *userEntity) FindUsernamesByRole(role models.UserRole) ([]string, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
+
+	cursor, err := entity.repository.Find(ctx, bson.M{"role": role}, options.Find().SetProjection(bson.M{"username": 1}))
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve users")
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve users")
+	}
+
+	usernames := make([]string, 0, len(users))
+	for _, user := range users {
+		usernames = append(usernames, user.Username)
+	}
+	return usernames, nil
+}
@@ -0,0 +1,201 @@
+package repositories
+
+import (
+	"errors"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var WebhookEntity IWebhook
+
+type webhookEntity struct {
+	resource   *databases.Resource
+	repository *mongo.Collection
+	deliveries *mongo.Collection
+}
+
+type IWebhook interface {
+	FindWebhooksByWorkflowID(workflowID string) ([]models.Webhook, error)
+	FindWebhooksByOrganization(organization string) ([]models.Webhook, error)
+	FindWebhookByID(webhookID string) (*models.Webhook, error)
+	FindSubscribedWebhooks(event models.WebhookEvent, workflowID primitive.ObjectID, organization string) ([]models.Webhook, error)
+	CreateWebhook(webhook models.Webhook) (*string, error)
+	DeleteWebhook(webhookID string) error
+	CreateDelivery(delivery models.WebhookDelivery) error
+	FindDeliveries(webhookID string) ([]models.WebhookDelivery, error)
+}
+
+func NewWebhookEntity(resource *databases.Resource) IWebhook {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &webhookEntity{}
+	}
+	webhookRepository := resource.MongoDB.Collection("webhooks")
+	deliveryRepository := resource.MongoDB.Collection("webhook_deliveries")
+	WebhookEntity = &webhookEntity{resource: resource, repository: webhookRepository, deliveries: deliveryRepository}
+	return WebhookEntity
+}
+
+func (entity *webhookEntity) findWebhooks(filter bson.M) ([]models.Webhook, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	cursor, err := entity.repository.Find(ctx, filter)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve webhooks")
+	}
+	defer cursor.Close(ctx)
+
+	webhooks := []models.Webhook{}
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve webhooks")
+	}
+
+	return webhooks, nil
+}
+
+func (entity *webhookEntity) FindWebhooksByWorkflowID(workflowID string) ([]models.Webhook, error) {
+	workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("invalid ObjectID format")
+	}
+
+	return entity.findWebhooks(bson.M{"workflow_id": workflowObjectID})
+}
+
+func (entity *webhookEntity) FindWebhooksByOrganization(organization string) ([]models.Webhook, error) {
+	return entity.findWebhooks(bson.M{"organization": organization})
+}
+
+// FindSubscribedWebhooks returns the active webhooks subscribed to an event of
+// the workflow, either directly or through the organization of its owner.
+func (entity *webhookEntity) FindSubscribedWebhooks(event models.WebhookEvent, workflowID primitive.ObjectID, organization string) ([]models.Webhook, error) {
+	scopes := bson.A{bson.M{"workflow_id": workflowID}}
+	if organization != "" {
+		scopes = append(scopes, bson.M{"organization": organization})
+	}
+
+	return entity.findWebhooks(bson.M{
+		"active": true,
+		"events": event,
+		"$or":    scopes,
+	})
+}
+
+func (entity *webhookEntity) FindWebhookByID(webhookID string) (*models.Webhook, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	webhookObjectID, err := primitive.ObjectIDFromHex(webhookID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("invalid ObjectID format")
+	}
+
+	var webhook models.Webhook
+	err = entity.repository.FindOne(ctx, bson.M{"_id": webhookObjectID}).Decode(&webhook)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("webhook does not exist")
+	}
+
+	return &webhook, nil
+}
+
+func (entity *webhookEntity) CreateWebhook(webhook models.Webhook) (*string, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	webhook.ID = primitive.NewObjectID()
+	webhook.SetCreatedAt()
+	webhook.SetUpdatedAt()
+
+	_, err := entity.repository.InsertOne(ctx, webhook)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to create webhook")
+	}
+
+	insertedID := webhook.ID.Hex()
+	return &insertedID, nil
+}
+
+// DeleteWebhook removes a webhook together with its delivery log. Deliveries
+// that are still queued are dropped when they run.
+func (entity *webhookEntity) DeleteWebhook(webhookID string) error {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	webhookObjectID, err := primitive.ObjectIDFromHex(webhookID)
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("invalid ObjectID format")
+	}
+
+	result, err := entity.repository.DeleteOne(ctx, bson.M{"_id": webhookObjectID})
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("failed to delete webhook")
+	}
+
+	if result.DeletedCount == 0 {
+		return errors.New("webhook does not exist")
+	}
+
+	if _, err := entity.deliveries.DeleteMany(ctx, bson.M{"webhook_id": webhookObjectID}); err != nil {
+		logrus.Error(err)
+	}
+
+	return nil
+}
+
+func (entity *webhookEntity) CreateDelivery(delivery models.WebhookDelivery) error {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	delivery.ID = primitive.NewObjectID()
+
+	_, err := entity.deliveries.InsertOne(ctx, delivery)
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("failed to log webhook delivery")
+	}
+
+	return nil
+}
+
+// FindDeliveries returns the most recent deliveries of a webhook, newest first.
+func (entity *webhookEntity) FindDeliveries(webhookID string) ([]models.WebhookDelivery, error) {
+	ctx, cancel := initContext()
+	defer cancel()
+
+	webhookObjectID, err := primitive.ObjectIDFromHex(webhookID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("invalid ObjectID format")
+	}
+
+	opts := options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(models.MaxListedDeliveries)
+	cursor, err := entity.deliveries.Find(ctx, bson.M{"webhook_id": webhookObjectID}, opts)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve webhook deliveries")
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := []models.WebhookDelivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to retrieve webhook deliveries")
+	}
+
+	return deliveries, nil
+}
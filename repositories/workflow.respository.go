@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"errors"
+	"time"
 	"virtual_workflow_management_system_gin/common"
 	"virtual_workflow_management_system_gin/databases"
 	"virtual_workflow_management_system_gin/models"
@@ -15,25 +16,62 @@ import (
 
 var WorkflowEntity IWorkflow
 
+var ErrVersionMismatch = errors.New("workflow version mismatch")
+
+// ErrWorkflowConflict reports a write that kept losing races with concurrent
+// writes to the workflow although the caller asked for no particular version.
+var ErrWorkflowConflict = errors.New("workflow is being changed by another request, try again")
+
+var (
+	workflowPatchFields = map[string]bool{"name": true, "labels": true}
+	taskPatchFields     = map[string]bool{"name": true, "description": true, "status": true, "labels": true}
+)
+
 type workflowEntity struct {
 	resource    *databases.Resource
 	repository  *mongo.Collection
+	revisions   *mongo.Collection
+	schedules   *mongo.Collection
 	mongoClient *mongo.Client
+	parent      context.Context
 }
 
 type IWorkflow interface {
+	WithContext(ctx context.Context) IWorkflow
 	FindWorkflowsByUsername(username string) ([]models.Workflow, error)
 	FindWorkflowByID(workflowID string) (*models.Workflow, error)
 	CreateWorkflow(workflow models.Workflow) (*string, error)
-	UpdateWorkflow(workflowID string, workflow models.Workflow) (*models.Workflow, error)
-	DeleteWorkflow(workflowID string) error
-	TransferWorkflowByID(workflowID string, workflow models.Workflow) (*models.Workflow, error)
+	UpdateWorkflow(workflowID string, workflow models.Workflow, version *int64) (*models.Workflow, error)
+	DeleteWorkflow(workflowID string, version *int64) error
+	TransferWorkflowByID(workflowID string, workflow models.Workflow, version *int64) (*models.Workflow, error)
 	FindTasksByWorkflowID(workflowID string) ([]models.Task, error)
 	FindTaskByID(workflowID string, taskID string) (*models.Task, error)
-	FindMaxTaskOrderByWorkflowID(workflowID string) (*int, error)
 	CreateTaskByWorkflowID(workflowID string, task models.Task) (*string, error)
-	UpdateTaskByID(workflowID string, taskID string, task models.Task) (*models.Task, error)
-	DeleteTaskByID(workflowID string, taskID string) error
+	UpdateTaskByID(workflowID string, taskID string, task models.Task, version *int64) (*models.Task, error)
+	DeleteTaskByID(workflowID string, taskID string, version *int64) error
+	ReorderTasksByWorkflowID(workflowID string, taskIDs []string, version *int64) ([]models.Task, error)
+	PatchWorkflow(workflowID string, fields map[string]interface{}, version *int64) (*models.Workflow, error)
+	PatchTaskByID(workflowID string, taskID string, fields map[string]interface{}, version *int64) (*models.Task, error)
+	FindRevisionsByWorkflowID(workflowID string) ([]models.WorkflowRevision, error)
+	FindRevision(workflowID string, revision int) (*models.WorkflowRevision, error)
+	RestoreWorkflowRevision(workflowID string, revision int, version *int64) (*models.Workflow, error)
+	SetWorkflowVariables(workflowID string, variables map[string]interface{}, version *int64) (*models.Workflow, error)
+	StartWorkflow(workflowID string, version *int64) (*models.Workflow, error)
+	VoteOnApproval(workflowID string, taskID string, user models.JWTUser, decision models.ApprovalDecision, comment string) (*models.Task, error)
+	SetVariableDefinitions(workflowID string, definitions []models.FieldDefinition, version *int64) (*models.Workflow, error)
+	SetTaskForm(workflowID string, taskID string, form []models.FieldDefinition, version *int64) (*models.Task, error)
+	CompleteTask(workflowID string, taskID string, values map[string]interface{}, version *int64) (*models.Task, error)
+	ClaimAutomatedTask(lease time.Duration) (*models.Workflow, *models.Task, error)
+	RecordAutomationResult(workflowID string, taskID string, leaseID string, output interface{}, failure error) (*models.Task, error)
+	RetryAutomatedTask(workflowID string, taskID string, version *int64) (*models.Task, error)
+	ClaimEscalatingTask(lease time.Duration) (*models.Workflow, *models.Task, error)
+	EscalateTask(workflowID string, taskID string, leaseID string) (*models.Task, []models.Escalation, error)
+	UpdateTaskChecklist(workflowID string, taskID string, change func(task *models.Task) error, version *int64) (*models.Task, error)
+	EnsureIndexes() error
+	LeadTime(filter models.ReportFilter) (*models.LeadTimeReport, error)
+	CycleTimes(filter models.ReportFilter, limit int) ([]models.CycleTime, error)
+	WeeklyThroughput(filter models.ReportFilter) ([]models.WeeklyThroughput, error)
+	SlowestSteps(workflowID string, filter models.ReportFilter, limit int) ([]models.StepDuration, error)
 }
 
 func NewWorkflowEntity(resource *databases.Resource) IWorkflow {
@@ -41,12 +79,33 @@ func NewWorkflowEntity(resource *databases.Resource) IWorkflow {
 		return &workflowEntity{}
 	}
 	workflowRepository := resource.MongoDB.Collection("workflows")
-	WorkflowEntity = &workflowEntity{resource: resource, repository: workflowRepository, mongoClient: resource.MongoDB.Client()}
+	revisionRepository := resource.MongoDB.Collection("workflow_revisions")
+	scheduleRepository := resource.MongoDB.Collection("schedules")
+	WorkflowEntity = &workflowEntity{resource: resource, repository: workflowRepository, revisions: revisionRepository, schedules: scheduleRepository, mongoClient: resource.MongoDB.Client()}
 	return WorkflowEntity
 }
 
+// isWriteConflict reports whether err is a write conflict with a concurrent
+// transaction, after which the whole transaction has to be retried.
+func isWriteConflict(err error) bool {
+	var serverError mongo.ServerError
+	return errors.As(err, &serverError) && serverError.HasErrorLabel("TransientTransactionError")
+}
+
+// WithContext returns a copy of the repository whose calls run with ctx, e.g.
+// inside a transaction started by the caller.
+func (entity *workflowEntity) WithContext(ctx context.Context) IWorkflow {
+	bound := *entity
+	bound.parent = ctx
+	return &bound
+}
+
+func (entity *workflowEntity) initContext() (context.Context, context.CancelFunc) {
+	return initContextFrom(entity.parent)
+}
+
 func (entity *workflowEntity) FindWorkflowsByUsername(username string) ([]models.Workflow, error) {
-	ctx, cancel := initContext()
+	ctx, cancel := entity.initContext()
 	defer cancel()
 
 	cursor, err := entity.repository.Find(ctx, bson.M{
@@ -68,7 +127,7 @@ func (entity *workflowEntity) FindWorkflowsByUsername(username string) ([]models
 }
 
 func (entity *workflowEntity) FindWorkflowByID(workflowID string) (*models.Workflow, error) {
-	ctx, cancel := initContext()
+	ctx, cancel := entity.initContext()
 	defer cancel()
 
 	workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
@@ -89,35 +148,49 @@ func (entity *workflowEntity) FindWorkflowByID(workflowID string) (*models.Workf
 }
 
 func (entity *workflowEntity) CreateWorkflow(workflow models.Workflow) (*string, error) {
-	ctx, cancel := initContext()
+	ctx, cancel := entity.initContext()
 	defer cancel()
 
 	workflow.SetCreatedAt()
 	workflow.SetUpdatedAt()
-
-	insertResult, err := entity.repository.InsertOne(ctx, workflow)
-	if err != nil {
-		logrus.Errorf("Failed to insert new workflow: %v", err)
-		return nil, errors.New("failed to create workflow")
+	if workflow.Variables == nil {
+		workflow.Variables = map[string]interface{}{}
 	}
+	workflow.AdvanceGateways()
 
-	insertedID, ok := insertResult.InsertedID.(primitive.ObjectID)
-	if !ok {
-		logrus.Error("Failed to convert InsertedID to ObjectID")
-		return nil, errors.New("failed to convert InsertedID to ObjectID")
-	}
+	var insertedIDString string
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
+		insertResult, err := entity.repository.InsertOne(c, workflow)
+		if err != nil {
+			logrus.Errorf("Failed to insert new workflow: %v", err)
+			return errors.New("failed to create workflow")
+		}
+
+		insertedID, ok := insertResult.InsertedID.(primitive.ObjectID)
+		if !ok {
+			logrus.Error("Failed to convert InsertedID to ObjectID")
+			return errors.New("failed to convert InsertedID to ObjectID")
+		}
+
+		workflow.ID = insertedID
+		insertedIDString = insertedID.Hex()
 
-	insertedIDString := insertedID.Hex()
+		return entity.saveRevision(c, workflow)
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
 
 	return &insertedIDString, nil
 }
 
-func (entity *workflowEntity) UpdateWorkflow(workflowID string, workflow models.Workflow) (*models.Workflow, error) {
-	var updatedWorkflow models.Workflow
-	err := common.WithTransaction(context.TODO(), entity.mongoClient, func(c context.Context, session mongo.Session) error {
-		ctx, cancel := initContext()
-		defer cancel()
+func (entity *workflowEntity) UpdateWorkflow(workflowID string, workflow models.Workflow, version *int64) (*models.Workflow, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
 
+	var updatedWorkflow models.Workflow
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
 		workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
 		if err != nil {
 			logrus.Error(err)
@@ -130,26 +203,35 @@ func (entity *workflowEntity) UpdateWorkflow(workflowID string, workflow models.
 		update := bson.M{
 			"$set": bson.M{
 				"name":       workflow.Name,
+				"labels":     workflow.Labels,
 				"updated_at": workflow.UpdatedAt,
 			},
+			"$inc": bson.M{"version": 1},
 		}
 
-		result, err := entity.repository.UpdateOne(ctx, filter, update)
+		result, err := entity.repository.UpdateOne(c, withVersion(filter, version), update)
 		if err != nil {
 			logrus.Error(err)
 			return errors.New("failed to update workflow")
 		}
 
-		if result.ModifiedCount == 0 {
+		if result.MatchedCount == 0 {
+			if version != nil {
+				return ErrVersionMismatch
+			}
 			return errors.New("no workflow was updated")
 		}
 
-		err = entity.repository.FindOne(ctx, filter).Decode(&updatedWorkflow)
+		err = entity.repository.FindOne(c, filter).Decode(&updatedWorkflow)
 		if err != nil {
 			logrus.Error(err)
 			return errors.New("failed to retrieve updated workflow")
 		}
 
+		if err := entity.saveRevision(c, updatedWorkflow); err != nil {
+			return err
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -160,11 +242,13 @@ func (entity *workflowEntity) UpdateWorkflow(workflowID string, workflow models.
 	return &updatedWorkflow, nil
 }
 
-func (entity *workflowEntity) DeleteWorkflow(workflowID string) error {
-	err := common.WithTransaction(context.TODO(), entity.mongoClient, func(c context.Context, session mongo.Session) error {
-		ctx, cancel := initContext()
-		defer cancel()
+// DeleteWorkflow removes the workflow together with its revisions and the
+// schedules that copy it.
+func (entity *workflowEntity) DeleteWorkflow(workflowID string, version *int64) error {
+	ctx, cancel := entity.initContext()
+	defer cancel()
 
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
 		workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
 		if err != nil {
 			logrus.Error(err)
@@ -173,12 +257,28 @@ func (entity *workflowEntity) DeleteWorkflow(workflowID string) error {
 
 		filter := bson.M{"_id": workflowObjectID}
 
-		_, err = entity.repository.DeleteOne(ctx, filter)
+		result, err := entity.repository.DeleteOne(c, withVersion(filter, version))
 		if err != nil {
 			logrus.Error(err)
 			return errors.New("failed to delete workflow")
 		}
 
+		if result.DeletedCount == 0 && version != nil {
+			return ErrVersionMismatch
+		}
+
+		_, err = entity.revisions.DeleteMany(c, bson.M{"workflow_id": workflowObjectID})
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to delete workflow revisions")
+		}
+
+		_, err = entity.schedules.DeleteMany(c, bson.M{"workflow_id": workflowObjectID})
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to delete workflow schedules")
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -189,12 +289,12 @@ func (entity *workflowEntity) DeleteWorkflow(workflowID string) error {
 	return nil
 }
 
-func (entity *workflowEntity) TransferWorkflowByID(workflowID string, workflow models.Workflow) (*models.Workflow, error) {
-	var updatedWorkflow models.Workflow
-	err := common.WithTransaction(context.TODO(), entity.mongoClient, func(c context.Context, session mongo.Session) error {
-		ctx, cancel := initContext()
-		defer cancel()
+func (entity *workflowEntity) TransferWorkflowByID(workflowID string, workflow models.Workflow, version *int64) (*models.Workflow, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
 
+	var updatedWorkflow models.Workflow
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
 		workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
 		if err != nil {
 			logrus.Error(err)
@@ -206,21 +306,26 @@ func (entity *workflowEntity) TransferWorkflowByID(workflowID string, workflow m
 		filter := bson.M{"_id": workflowObjectID}
 		update := bson.M{
 			"$set": bson.M{
-				"owner": workflow.Owner,
+				"owner":      workflow.Owner,
+				"updated_at": workflow.UpdatedAt,
 			},
+			"$inc": bson.M{"version": 1},
 		}
 
-		result, err := entity.repository.UpdateOne(ctx, filter, update)
+		result, err := entity.repository.UpdateOne(c, withVersion(filter, version), update)
 		if err != nil {
 			logrus.Error(err)
 			return errors.New("failed to update workflow")
 		}
 
-		if result.ModifiedCount == 0 {
+		if result.MatchedCount == 0 {
+			if version != nil {
+				return ErrVersionMismatch
+			}
 			return errors.New("no workflow was updated")
 		}
 
-		err = entity.repository.FindOne(ctx, filter).Decode(&updatedWorkflow)
+		err = entity.repository.FindOne(c, filter).Decode(&updatedWorkflow)
 		if err != nil {
 			logrus.Error(err)
 			return errors.New("failed to retrieve updated workflow")
@@ -237,7 +342,7 @@ func (entity *workflowEntity) TransferWorkflowByID(workflowID string, workflow m
 }
 
 func (entity *workflowEntity) FindTasksByWorkflowID(workflowID string) ([]models.Task, error) {
-	ctx, cancel := initContext()
+	ctx, cancel := entity.initContext()
 	defer cancel()
 
 	workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
@@ -277,7 +382,7 @@ func (entity *workflowEntity) FindTasksByWorkflowID(workflowID string) ([]models
 }
 
 func (entity *workflowEntity) FindTaskByID(workflowID string, taskID string) (*models.Task, error) {
-	_, cancel := initContext()
+	_, cancel := entity.initContext()
 	defer cancel()
 
 	taskObjectID, err := primitive.ObjectIDFromHex(taskID)
@@ -302,93 +407,416 @@ func (entity *workflowEntity) FindTaskByID(workflowID string, taskID string) (*m
 	return nil, errors.New("task does not exist")
 }
 
-func (entity *workflowEntity) FindMaxTaskOrderByWorkflowID(workflowID string) (*int, error) {
-	ctx, cancel := initContext()
+func (entity *workflowEntity) CreateTaskByWorkflowID(workflowID string, task models.Task) (*string, error) {
+	ctx, cancel := entity.initContext()
 	defer cancel()
 
-	workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
+	var taskIDString string
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
+		workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid ObjectID format")
+		}
+
+		filter := bson.M{"_id": workflowObjectID}
+		var workflow models.Workflow
+		err = entity.repository.FindOne(c, filter).Decode(&workflow)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("workflow not found")
+		}
+
+		existingTaskIDs := map[primitive.ObjectID]bool{}
+		for _, existingTask := range workflow.Tasks {
+			existingTaskIDs[existingTask.ID] = true
+		}
+		for _, dependencyID := range task.DependsOn {
+			if !existingTaskIDs[dependencyID] {
+				return errors.New("dependency task does not exist")
+			}
+		}
+		if task.Gateway != nil {
+			for _, flow := range task.Gateway.Flows {
+				if !existingTaskIDs[flow.Target] {
+					return errors.New("gateway flow target does not exist")
+				}
+			}
+		}
+		if err := models.ValidateForm(task.Form, workflow.VariableDefinitions); err != nil {
+			return err
+		}
+
+		task.ID = primitive.NewObjectID()
+		task.Order = len(workflow.Tasks) + 1
+		task.SetCreatedAt()
+		task.SetUpdatedAt()
+
+		update := bson.M{
+			"$push": bson.M{
+				"tasks": task,
+			},
+			"$inc": bson.M{"version": 1},
+		}
+
+		updateResult, err := entity.repository.UpdateOne(c, withVersion(filter, &workflow.Version), update)
+		if err != nil {
+			logrus.Error(err)
+			if isWriteConflict(err) {
+				return ErrVersionMismatch
+			}
+			return errors.New("failed to create task")
+		}
+
+		if updateResult.MatchedCount == 0 {
+			return ErrVersionMismatch
+		}
+
+		taskIDString = task.ID.Hex()
+
+		if err := entity.advanceGateways(c, workflowObjectID); err != nil {
+			return err
+		}
+
+		if err := entity.saveRevisionByID(c, workflowObjectID); err != nil {
+			return err
+		}
+
+		return nil
+	})
 	if err != nil {
 		logrus.Error(err)
-		return nil, errors.New("invalid ObjectID format")
+		return nil, err
 	}
 
-	aggregatePipeline := mongo.Pipeline{
-		bson.D{{Key: "$match", Value: bson.M{"_id": workflowObjectID}}},
-		bson.D{{Key: "$unwind", Value: bson.M{"path": "$tasks", "preserveNullAndEmptyArrays": false}}},
-		bson.D{{Key: "$group", Value: bson.M{"_id": "$_id", "maxOrder": bson.M{"$max": "$tasks.order"}}}},
-	}
+	return &taskIDString, nil
+}
 
-	cursor, err := entity.repository.Aggregate(ctx, aggregatePipeline)
+func (entity *workflowEntity) UpdateTaskByID(workflowID string, taskID string, task models.Task, version *int64) (*models.Task, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
+
+	var updatedTaskModel models.Task
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
+		workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid ObjectID format")
+		}
+
+		taskObjectID, err := primitive.ObjectIDFromHex(taskID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid ObjectID format")
+		}
+
+		filter := bson.M{"_id": workflowObjectID}
+		var workflow models.Workflow
+		err = entity.repository.FindOne(c, filter).Decode(&workflow)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("workflow does not exist")
+		}
+
+		if version != nil && *version != workflow.Version {
+			return ErrVersionMismatch
+		}
+
+		tasks := models.NormalizeTaskOrders(workflow.Tasks)
+		index := -1
+		for i := range tasks {
+			if tasks[i].ID == taskObjectID {
+				index = i
+				break
+			}
+		}
+		if index == -1 {
+			return errors.New("task does not exist")
+		}
+
+		updatedTaskModel = tasks[index]
+		if updatedTaskModel.HasManagedStatus() && updatedTaskModel.Status != task.Status {
+			return errors.New("task status is managed by the workflow")
+		}
+		if task.Status == models.Completed && updatedTaskModel.Status != models.Completed {
+			if err := updatedTaskModel.CheckFormSubmitted(); err != nil {
+				return err
+			}
+			if err := updatedTaskModel.CheckChecklistDone(); err != nil {
+				return err
+			}
+		}
+		updatedTaskModel.Name = task.Name
+		updatedTaskModel.Description = task.Description
+		updatedTaskModel.Labels = task.Labels
+		if updatedTaskModel.Assignee != task.Assignee {
+			updatedTaskModel.Assignee = task.Assignee
+			updatedTaskModel.AssigneeRole = ""
+		}
+		updatedTaskModel.SetUpdatedAt()
+		updatedTaskModel.SetStatus(task.Status, updatedTaskModel.UpdatedAt)
+
+		// Moving a task shifts its neighbours instead of leaving duplicate orders behind.
+		newIndex := task.Order - 1
+		if newIndex < 0 {
+			newIndex = 0
+		}
+		if newIndex > len(tasks)-1 {
+			newIndex = len(tasks) - 1
+		}
+		tasks = append(tasks[:index], tasks[index+1:]...)
+		tasks = append(tasks[:newIndex], append([]models.Task{updatedTaskModel}, tasks[newIndex:]...)...)
+		tasks = models.NormalizeTaskOrders(tasks)
+		updatedTaskModel.Order = newIndex + 1
+
+		update := bson.M{
+			"$set": bson.M{
+				"tasks":      tasks,
+				"updated_at": updatedTaskModel.UpdatedAt,
+			},
+			"$inc": bson.M{"version": 1},
+		}
+
+		updateResult, err := entity.repository.UpdateOne(c, withVersion(filter, &workflow.Version), update)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to update task")
+		}
+
+		if updateResult.MatchedCount == 0 {
+			return ErrVersionMismatch
+		}
+
+		if err := entity.advanceGateways(c, workflowObjectID); err != nil {
+			return err
+		}
+
+		if err := entity.saveRevisionByID(c, workflowObjectID); err != nil {
+			return err
+		}
+
+		return nil
+	})
 	if err != nil {
-		logrus.Error("Failed to aggregate: ", err)
+		logrus.Error(err)
 		return nil, err
 	}
 
-	var results []bson.M
-	if err = cursor.All(ctx, &results); err != nil {
-		logrus.Error("Failed to decode cursor: ", err)
-		return nil, err
-	}
+	return &updatedTaskModel, nil
+}
 
-	if len(results) == 0 {
-		return nil, nil
-	}
+func (entity *workflowEntity) DeleteTaskByID(workflowID string, taskID string, version *int64) error {
+	ctx, cancel := entity.initContext()
+	defer cancel()
+
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
+		workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid ObjectID format")
+		}
+
+		taskObjectID, err := primitive.ObjectIDFromHex(taskID)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("invalid ObjectID format")
+		}
+
+		filter := bson.M{"_id": workflowObjectID}
+		var workflow models.Workflow
+		err = entity.repository.FindOne(c, filter).Decode(&workflow)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("workflow does not exist")
+		}
+
+		if version != nil && *version != workflow.Version {
+			return ErrVersionMismatch
+		}
+
+		tasks := []models.Task{}
+		for _, task := range workflow.Tasks {
+			if task.ID != taskObjectID {
+				tasks = append(tasks, task)
+			}
+		}
+		if len(tasks) == len(workflow.Tasks) {
+			return errors.New("no task was deleted")
+		}
+
+		tasks = models.RemoveTaskDependency(tasks, taskObjectID)
+
+		update := bson.M{
+			"$set": bson.M{
+				"tasks":      models.NormalizeTaskOrders(tasks),
+				"updated_at": time.Now(),
+			},
+			"$inc": bson.M{"version": 1},
+		}
+
+		updateResult, err := entity.repository.UpdateOne(c, withVersion(filter, &workflow.Version), update)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to delete task")
+		}
+
+		if updateResult.MatchedCount == 0 {
+			return ErrVersionMismatch
+		}
+
+		if err := entity.advanceGateways(c, workflowObjectID); err != nil {
+			return err
+		}
+
+		if err := entity.saveRevisionByID(c, workflowObjectID); err != nil {
+			return err
+		}
 
-	maxOrder := results[0]["maxOrder"]
-	maxOrderInt := int(maxOrder.(int32))
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
 
-	return &maxOrderInt, nil
+	return nil
 }
 
-func (entity *workflowEntity) CreateTaskByWorkflowID(workflowID string, task models.Task) (*string, error) {
-	var taskIDString string
-	err := common.WithTransaction(context.TODO(), entity.mongoClient, func(c context.Context, session mongo.Session) error {
-		ctx, cancel := initContext()
-		defer cancel()
+func (entity *workflowEntity) ReorderTasksByWorkflowID(workflowID string, taskIDs []string, version *int64) ([]models.Task, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
 
+	var reorderedTasks []models.Task
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
 		workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
 		if err != nil {
 			logrus.Error(err)
 			return errors.New("invalid ObjectID format")
 		}
 
-		task.ID = primitive.NewObjectID()
-		task.SetCreatedAt()
-		task.SetUpdatedAt()
-
 		filter := bson.M{"_id": workflowObjectID}
+		var workflow models.Workflow
+		err = entity.repository.FindOne(c, filter).Decode(&workflow)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("workflow does not exist")
+		}
+
+		if version != nil && *version != workflow.Version {
+			return ErrVersionMismatch
+		}
+
+		if len(taskIDs) != len(workflow.Tasks) {
+			return errors.New("task list must contain every task of the workflow exactly once")
+		}
+
+		tasksByID := make(map[primitive.ObjectID]models.Task, len(workflow.Tasks))
+		for _, task := range workflow.Tasks {
+			tasksByID[task.ID] = task
+		}
+
+		now := time.Now()
+		reorderedTasks = make([]models.Task, 0, len(taskIDs))
+		for i, taskID := range taskIDs {
+			taskObjectID, err := primitive.ObjectIDFromHex(taskID)
+			if err != nil {
+				logrus.Error(err)
+				return errors.New("invalid ObjectID format")
+			}
+
+			task, ok := tasksByID[taskObjectID]
+			if !ok {
+				return errors.New("task list must contain every task of the workflow exactly once")
+			}
+			delete(tasksByID, taskObjectID)
+
+			if task.Order != i+1 {
+				task.Order = i + 1
+				task.UpdatedAt = now
+			}
+			reorderedTasks = append(reorderedTasks, task)
+		}
+
 		update := bson.M{
-			"$push": bson.M{
-				"tasks": task,
+			"$set": bson.M{
+				"tasks":      reorderedTasks,
+				"updated_at": now,
 			},
+			"$inc": bson.M{"version": 1},
 		}
 
-		updateResult, err := entity.repository.UpdateOne(ctx, filter, update)
+		updateResult, err := entity.repository.UpdateOne(c, withVersion(filter, &workflow.Version), update)
 		if err != nil {
 			logrus.Error(err)
-			return errors.New("failed to create task")
+			return errors.New("failed to reorder tasks")
 		}
 
 		if updateResult.MatchedCount == 0 {
-			logrus.Error("workflow not found")
-			return errors.New("workflow not found")
+			return ErrVersionMismatch
 		}
 
-		var updatedWorkflow models.Workflow
-		err = entity.repository.FindOne(ctx, filter).Decode(&updatedWorkflow)
+		if err := entity.saveRevisionByID(c, workflowObjectID); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return reorderedTasks, nil
+}
+
+func (entity *workflowEntity) PatchWorkflow(workflowID string, fields map[string]interface{}, version *int64) (*models.Workflow, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
+
+	var updatedWorkflow models.Workflow
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
+		workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
 		if err != nil {
 			logrus.Error(err)
-			return errors.New("failed to retrieve updated workflow")
+			return errors.New("invalid ObjectID format")
+		}
+
+		set := bson.M{"updated_at": time.Now()}
+		for field, value := range fields {
+			if !workflowPatchFields[field] {
+				return errors.New("field cannot be patched: " + field)
+			}
+			set[field] = value
 		}
 
-		updatedTasks := updatedWorkflow.Tasks
-		updatedTasksLength := len(updatedTasks)
+		filter := bson.M{"_id": workflowObjectID}
+		update := bson.M{
+			"$set": set,
+			"$inc": bson.M{"version": 1},
+		}
+
+		result, err := entity.repository.UpdateOne(c, withVersion(filter, version), update)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to update workflow")
+		}
+
+		if result.MatchedCount == 0 {
+			if version != nil {
+				return ErrVersionMismatch
+			}
+			return errors.New("no workflow was updated")
+		}
 
-		if updatedTasksLength == 0 {
-			return errors.New("no task was created")
+		err = entity.repository.FindOne(c, filter).Decode(&updatedWorkflow)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to retrieve updated workflow")
 		}
 
-		taskIDString = updatedTasks[updatedTasksLength-1].ID.Hex()
+		if err := entity.saveRevision(c, updatedWorkflow); err != nil {
+			return err
+		}
 
 		return nil
 	})
@@ -397,15 +825,15 @@ func (entity *workflowEntity) CreateTaskByWorkflowID(workflowID string, task mod
 		return nil, err
 	}
 
-	return &taskIDString, nil
+	return &updatedWorkflow, nil
 }
 
-func (entity *workflowEntity) UpdateTaskByID(workflowID string, taskID string, task models.Task) (*models.Task, error) {
-	var updatedTaskModel models.Task
-	err := common.WithTransaction(context.TODO(), entity.mongoClient, func(c context.Context, session mongo.Session) error {
-		ctx, cancel := initContext()
-		defer cancel()
+func (entity *workflowEntity) PatchTaskByID(workflowID string, taskID string, fields map[string]interface{}, version *int64) (*models.Task, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
 
+	var updatedTaskModel models.Task
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
 		workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
 		if err != nil {
 			logrus.Error(err)
@@ -418,38 +846,81 @@ func (entity *workflowEntity) UpdateTaskByID(workflowID string, taskID string, t
 			return errors.New("invalid ObjectID format")
 		}
 
-		task.SetUpdatedAt()
+		now := time.Now()
+		set := bson.M{
+			"tasks.$.updated_at": now,
+			"updated_at":         now,
+		}
+		for field, value := range fields {
+			if !taskPatchFields[field] {
+				return errors.New("field cannot be patched: " + field)
+			}
+			set["tasks.$."+field] = value
+		}
 
 		filter := bson.M{"_id": workflowObjectID, "tasks._id": taskObjectID}
 		update := bson.M{
-			"$set": bson.M{
-				"tasks.$.name":        task.Name,
-				"tasks.$.description": task.Description,
-				"tasks.$.status":      task.Status,
-				"tasks.$.order":       task.Order,
-				"tasks.$.updated_at":  task.UpdatedAt,
-			},
+			"$set": set,
+			"$inc": bson.M{"version": 1},
+		}
+
+		if status, ok := fields["status"].(models.TaskStatus); ok {
+			var workflow models.Workflow
+			if err := entity.repository.FindOne(c, filter).Decode(&workflow); err != nil {
+				logrus.Error(err)
+				return errors.New("no task was updated")
+			}
+			index := taskIndex(workflow.Tasks, taskObjectID)
+			if index == -1 {
+				return errors.New("task does not exist")
+			}
+
+			current := workflow.Tasks[index]
+			if current.Status != status {
+				if current.HasManagedStatus() {
+					return errors.New("task status is managed by the workflow")
+				}
+				if status == models.Completed {
+					if err := current.CheckFormSubmitted(); err != nil {
+						return err
+					}
+					if err := current.CheckChecklistDone(); err != nil {
+						return err
+					}
+				}
+				update["$push"] = bson.M{"tasks.$.status_history": models.StatusTransition{From: current.Status, To: status, At: now}}
+			}
 		}
 
-		updateResult, err := entity.repository.UpdateOne(ctx, filter, update)
+		result, err := entity.repository.UpdateOne(c, withVersion(filter, version), update)
 		if err != nil {
 			logrus.Error(err)
 			return errors.New("failed to update task")
 		}
 
-		if updateResult.MatchedCount == 0 {
+		if result.MatchedCount == 0 {
+			if version != nil {
+				return ErrVersionMismatch
+			}
 			return errors.New("no task was updated")
 		}
 
+		if err := entity.advanceGateways(c, workflowObjectID); err != nil {
+			return err
+		}
+
 		var updatedWorkflow models.Workflow
-		err = entity.repository.FindOne(ctx, filter).Decode(&updatedWorkflow)
+		err = entity.repository.FindOne(c, filter).Decode(&updatedWorkflow)
 		if err != nil {
 			logrus.Error(err)
 			return errors.New("failed to retrieve updated workflow")
 		}
 
-		updatedTasks := updatedWorkflow.Tasks
-		for _, updatedTask := range updatedTasks {
+		if err := entity.saveRevision(c, updatedWorkflow); err != nil {
+			return err
+		}
+
+		for _, updatedTask := range updatedWorkflow.Tasks {
 			if updatedTask.ID == taskObjectID {
 				updatedTaskModel = updatedTask
 				return nil
@@ -466,18 +937,84 @@ func (entity *workflowEntity) UpdateTaskByID(workflowID string, taskID string, t
 	return &updatedTaskModel, nil
 }
 
-func (entity *workflowEntity) DeleteTaskByID(workflowID string, taskID string) error {
-	err := common.WithTransaction(context.TODO(), entity.mongoClient, func(c context.Context, session mongo.Session) error {
-		ctx, cancel := initContext()
-		defer cancel()
+func (entity *workflowEntity) SetWorkflowVariables(workflowID string, variables map[string]interface{}, version *int64) (*models.Workflow, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
 
+	var updatedWorkflow models.Workflow
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
 		workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
 		if err != nil {
 			logrus.Error(err)
 			return errors.New("invalid ObjectID format")
 		}
 
-		taskObjectID, err := primitive.ObjectIDFromHex(taskID)
+		filter := bson.M{"_id": workflowObjectID}
+		var workflow models.Workflow
+		err = entity.repository.FindOne(c, filter).Decode(&workflow)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("workflow does not exist")
+		}
+
+		if version != nil && *version != workflow.Version {
+			return ErrVersionMismatch
+		}
+
+		normalized, err := workflow.NormalizeVariables(variables)
+		if err != nil {
+			return err
+		}
+
+		set := bson.M{"updated_at": time.Now()}
+		for name, value := range normalized {
+			set["variables."+name] = value
+		}
+
+		update := bson.M{
+			"$set": set,
+			"$inc": bson.M{"version": 1},
+		}
+
+		result, err := entity.repository.UpdateOne(c, withVersion(filter, &workflow.Version), update)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to update workflow variables")
+		}
+
+		if result.MatchedCount == 0 {
+			return ErrVersionMismatch
+		}
+
+		if err := entity.advanceGateways(c, workflowObjectID); err != nil {
+			return err
+		}
+
+		err = entity.repository.FindOne(c, filter).Decode(&updatedWorkflow)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to retrieve updated workflow")
+		}
+
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return &updatedWorkflow, nil
+}
+
+// StartWorkflow starts the run of the workflow, letting the gateways nothing
+// leads to decide on its variables.
+func (entity *workflowEntity) StartWorkflow(workflowID string, version *int64) (*models.Workflow, error) {
+	ctx, cancel := entity.initContext()
+	defer cancel()
+
+	var startedWorkflow models.Workflow
+	err := common.WithTransaction(ctx, entity.mongoClient, func(c context.Context, session mongo.Session) error {
+		workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
 		if err != nil {
 			logrus.Error(err)
 			return errors.New("invalid ObjectID format")
@@ -485,29 +1022,90 @@ func (entity *workflowEntity) DeleteTaskByID(workflowID string, taskID string) e
 
 		filter := bson.M{"_id": workflowObjectID}
 		update := bson.M{
-			"$pull": bson.M{
-				"tasks": bson.M{
-					"_id": taskObjectID,
-				},
-			},
+			"$set": bson.M{"started_at": time.Now(), "updated_at": time.Now()},
+			"$inc": bson.M{"version": 1},
 		}
 
-		updateResult, err := entity.repository.UpdateOne(ctx, filter, update)
+		startFilter := withVersion(bson.M{"_id": workflowObjectID, "started_at": bson.M{"$exists": false}}, version)
+		result, err := entity.repository.UpdateOne(c, startFilter, update)
 		if err != nil {
 			logrus.Error(err)
-			return errors.New("failed to delete task")
+			return errors.New("failed to start workflow")
 		}
 
-		if updateResult.MatchedCount == 0 {
-			return errors.New("no task was deleted")
+		if result.MatchedCount == 0 {
+			var workflow models.Workflow
+			if err := entity.repository.FindOne(c, filter).Decode(&workflow); err != nil {
+				logrus.Error(err)
+				return errors.New("workflow does not exist")
+			}
+			if workflow.StartedAt != nil {
+				return errors.New("workflow has already started")
+			}
+			return ErrVersionMismatch
+		}
+
+		if err := entity.advanceGateways(c, workflowObjectID); err != nil {
+			return err
+		}
+
+		err = entity.repository.FindOne(c, filter).Decode(&startedWorkflow)
+		if err != nil {
+			logrus.Error(err)
+			return errors.New("failed to retrieve updated workflow")
 		}
 
 		return nil
 	})
 	if err != nil {
 		logrus.Error(err)
-		return err
+		return nil, err
+	}
+
+	return &startedWorkflow, nil
+}
+
+// advanceGateways evaluates the gateways that became ready through a mutation,
+// within the transaction of that mutation.
+func (entity *workflowEntity) advanceGateways(ctx context.Context, workflowObjectID primitive.ObjectID) error {
+	filter := bson.M{"_id": workflowObjectID}
+	var workflow models.Workflow
+	err := entity.repository.FindOne(ctx, filter).Decode(&workflow)
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("failed to retrieve updated workflow")
+	}
+
+	if !workflow.AdvanceGateways() {
+		return nil
+	}
+
+	_, err = entity.repository.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"tasks": workflow.Tasks}})
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("failed to advance workflow gateways")
 	}
 
 	return nil
 }
+
+// withVersion narrows an update filter to the expected workflow version so the
+// check and the write happen atomically. Documents created before versioning
+// have no version field and are treated as version 0.
+func withVersion(filter bson.M, version *int64) bson.M {
+	if version == nil {
+		return filter
+	}
+
+	versionedFilter := bson.M{}
+	for key, value := range filter {
+		versionedFilter[key] = value
+	}
+	if *version == 0 {
+		versionedFilter["version"] = bson.M{"$in": bson.A{0, nil}}
+	} else {
+		versionedFilter["version"] = *version
+	}
+
+	return versionedFilter
+}
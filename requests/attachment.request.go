@@ -0,0 +1,10 @@
+package requests
+
+import "mime/multipart"
+
+type UploadAttachmentRequest struct {
+	File *multipart.FileHeader `form:"file" binding:"required"`
+	// Checksum is the hex encoded SHA-256 of the file, if the client wants it
+	// verified.
+	Checksum string `form:"checksum" binding:"omitempty,len=64,hexadecimal"`
+}
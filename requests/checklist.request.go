@@ -0,0 +1,11 @@
+package requests
+
+type ChecklistItemRequest struct {
+	Text     string `json:"text" binding:"required,max=500"`
+	Required bool   `json:"required"`
+	Assignee string `json:"assignee" binding:"max=100"`
+}
+
+type ReorderChecklistRequest struct {
+	ItemIDs []string `json:"item_ids" binding:"required"`
+}
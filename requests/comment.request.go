@@ -0,0 +1,10 @@
+package requests
+
+type CreateCommentRequest struct {
+	Body     string `json:"body" binding:"required,max=10000"`
+	ParentID string `json:"parent_id" binding:"omitempty,len=24,hexadecimal"`
+}
+
+type EditCommentRequest struct {
+	Body string `json:"body" binding:"required,max=10000"`
+}
@@ -0,0 +1,8 @@
+package requests
+
+import "virtual_workflow_management_system_gin/models"
+
+type JobQueryRequest struct {
+	Status models.JobStatus `form:"status" binding:"omitempty,oneof=Queued Running Succeeded"`
+	Type   string           `form:"type" binding:"max=100"`
+}
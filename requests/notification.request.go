@@ -0,0 +1,5 @@
+package requests
+
+type NotificationQueryRequest struct {
+	Unread bool `form:"unread"`
+}
@@ -0,0 +1,10 @@
+package requests
+
+import "time"
+
+type ReportRequest struct {
+	From  *time.Time `form:"from"`
+	To    *time.Time `form:"to"`
+	Team  string     `form:"team" binding:"max=100"`
+	Limit int        `form:"limit" binding:"omitempty,min=1,max=100"`
+}
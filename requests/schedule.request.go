@@ -0,0 +1,18 @@
+package requests
+
+import (
+	"time"
+	"virtual_workflow_management_system_gin/models"
+)
+
+type CreateScheduleRequest struct {
+	Expression string               `json:"expression" binding:"required,max=100"`
+	Timezone   string               `json:"timezone" binding:"max=64"`
+	StartAt    *time.Time           `json:"start_at"`
+	EndAt      *time.Time           `json:"end_at"`
+	CatchUp    models.CatchUpPolicy `json:"catch_up" binding:"omitempty,oneof=Skip Once All"`
+}
+
+type UpcomingRunsRequest struct {
+	Count int `form:"count" binding:"omitempty,min=1,max=50"`
+}
@@ -0,0 +1,10 @@
+package requests
+
+import "virtual_workflow_management_system_gin/models"
+
+type SearchRequest struct {
+	Query    string              `form:"q" binding:"required,min=2,max=200"`
+	Labels   []string            `form:"label" binding:"max=20,dive,max=50"`
+	Statuses []models.TaskStatus `form:"status" binding:"max=6,dive,oneof=Pending 'In Progress' Completed Skipped Rejected Failed"`
+	Limit    int                 `form:"limit" binding:"omitempty,min=1,max=100"`
+}
@@ -0,0 +1,53 @@
+package requests
+
+import "virtual_workflow_management_system_gin/models"
+
+type TemplateParameterRequest struct {
+	Name        string `json:"name" binding:"required,max=50"`
+	Description string `json:"description"`
+	Default     string `json:"default"`
+	Required    bool   `json:"required"`
+}
+
+type TemplateGatewayFlowRequest struct {
+	Target    string `json:"target" binding:"required"`
+	Condition string `json:"condition"`
+}
+
+type TemplateGatewayRequest struct {
+	Kind  models.GatewayKind           `json:"kind" binding:"required,oneof=Exclusive Parallel Inclusive"`
+	Flows []TemplateGatewayFlowRequest `json:"flows" binding:"required,min=1,dive"`
+}
+
+type TemplateTaskRequest struct {
+	Key         string                   `json:"key" binding:"required,max=50"`
+	Name        string                   `json:"name" binding:"required,min=3,max=100"`
+	Description string                   `json:"description"`
+	DependsOn   []string                 `json:"depends_on"`
+	Type        models.TaskType          `json:"type" binding:"omitempty,oneof=User Gateway Approval Automated"`
+	Gateway     *TemplateGatewayRequest  `json:"gateway" binding:"required_if=Type Gateway"`
+	Approval    *ApprovalRequest         `json:"approval" binding:"required_if=Type Approval"`
+	Automation  *AutomationRequest       `json:"automation" binding:"required_if=Type Automated"`
+	Form        []FieldDefinitionRequest `json:"form" binding:"dive"`
+	SLA         *SLARequest              `json:"sla"`
+}
+
+type CreateTemplateRequest struct {
+	Name        string                     `json:"name" binding:"required,min=3,max=100"`
+	Description string                     `json:"description"`
+	Scope       models.TemplateScope       `json:"scope" binding:"required,oneof=Global Organization Personal"`
+	Parameters  []TemplateParameterRequest `json:"parameters" binding:"dive"`
+	Tasks       []TemplateTaskRequest      `json:"tasks" binding:"dive"`
+	Variables   []FieldDefinitionRequest   `json:"variable_definitions" binding:"dive"`
+}
+
+type InstantiateTemplateRequest struct {
+	Name       string            `json:"name" binding:"required,min=3,max=100"`
+	Parameters map[string]string `json:"parameters"`
+}
+
+type SaveWorkflowAsTemplateRequest struct {
+	Name        string               `json:"name" binding:"required,min=3,max=100"`
+	Description string               `json:"description"`
+	Scope       models.TemplateScope `json:"scope" binding:"required,oneof=Global Organization Personal"`
+}
@@ -8,9 +8,16 @@ type LoginRequest struct {
 }
 
 type RegisterRequest struct {
-	Username string          `json:"username" binding:"required,min=3,max=100"`
-	Password string          `json:"password" binding:"required,min=6"`
-	Role     models.UserRole `json:"role" binding:"required"`
+	Username string `json:"username" binding:"required,min=3,max=100"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// UpdateUserRequest changes what an admin grants a user. Fields left out keep
+// their value.
+type UpdateUserRequest struct {
+	Role         *models.UserRole `json:"role" binding:"omitempty,oneof=Admin Employer"`
+	Organization *string          `json:"organization" binding:"omitempty,max=100"`
+	Teams        *[]string        `json:"teams" binding:"omitempty,max=20,dive,min=1,max=100"`
 }
 
 type RefreshTokenRequest struct {
@@ -0,0 +1,9 @@
+package requests
+
+import "virtual_workflow_management_system_gin/models"
+
+type CreateWebhookRequest struct {
+	URL    string                `json:"url" binding:"required,url,max=2048"`
+	Events []models.WebhookEvent `json:"events" binding:"required,min=1,dive,oneof=workflow.created workflow.transferred task.created task.status_changed"`
+	Secret string                `json:"secret" binding:"omitempty,min=16,max=128"`
+}
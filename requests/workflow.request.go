@@ -3,16 +3,107 @@ package requests
 import "virtual_workflow_management_system_gin/models"
 
 type CreateWorkflowRequest struct {
-	Name string `json:"name" binding:"required,min=3,max=100"`
+	Name   string   `json:"name" binding:"required,min=3,max=100"`
+	Labels []string `json:"labels" binding:"max=20"`
 }
 
 type CreateTaskRequest struct {
-	Name        string `json:"name" binding:"required,min=3,max=100"`
-	Description string `json:"description"`
+	Name        string                   `json:"name" binding:"required,min=3,max=100"`
+	Description string                   `json:"description"`
+	DependsOn   []string                 `json:"depends_on"`
+	Type        models.TaskType          `json:"type" binding:"omitempty,oneof=User Gateway Approval Automated"`
+	Gateway     *GatewayRequest          `json:"gateway" binding:"required_if=Type Gateway"`
+	Approval    *ApprovalRequest         `json:"approval" binding:"required_if=Type Approval"`
+	Automation  *AutomationRequest       `json:"automation" binding:"required_if=Type Automated"`
+	Form        []FieldDefinitionRequest `json:"form" binding:"dive"`
+	Assignee    string                   `json:"assignee" binding:"max=100"`
+	SLA         *SLARequest              `json:"sla"`
+	Checklist   []ChecklistItemRequest   `json:"checklist" binding:"max=100,dive"`
+	Labels      []string                 `json:"labels" binding:"max=20"`
+}
+
+type GatewayFlowRequest struct {
+	Target    string `json:"target" binding:"required"`
+	Condition string `json:"condition"`
+}
+
+type GatewayRequest struct {
+	Kind  models.GatewayKind   `json:"kind" binding:"required,oneof=Exclusive Parallel Inclusive"`
+	Flows []GatewayFlowRequest `json:"flows" binding:"required,min=1,dive"`
+}
+
+type ApproverRequest struct {
+	Kind  models.ApproverKind `json:"kind" binding:"required,oneof=User Role Team"`
+	Value string              `json:"value" binding:"required,max=100"`
+}
+
+type ApprovalRequest struct {
+	Approvers []ApproverRequest `json:"approvers" binding:"required,min=1,dive"`
+	Quorum    models.QuorumRule `json:"quorum" binding:"required,oneof=Any All Count"`
+	Required  int               `json:"required" binding:"required_if=Quorum Count,min=0"`
+	Variable  string            `json:"variable"`
+}
+
+type HTTPCallRequest struct {
+	Method  string            `json:"method" binding:"required,oneof=GET POST PUT PATCH DELETE"`
+	URL     string            `json:"url" binding:"required,url"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body" binding:"max=10000"`
+}
+
+type AutomationRequest struct {
+	Executor       models.ExecutorKind `json:"executor" binding:"required,oneof=HTTP Handler"`
+	HTTP           *HTTPCallRequest    `json:"http" binding:"required_if=Executor HTTP"`
+	Handler        string              `json:"handler" binding:"required_if=Executor Handler,max=100"`
+	MaxAttempts    int                 `json:"max_attempts" binding:"min=0,max=10"`
+	BackoffSeconds int                 `json:"backoff_seconds" binding:"min=0,max=3600"`
+	TimeoutSeconds int                 `json:"timeout_seconds" binding:"min=0,max=300"`
+	OutputVariable string              `json:"output_variable"`
+}
+
+type EscalationStepRequest struct {
+	Action       models.EscalationAction `json:"action" binding:"required,oneof=NotifyAssignee NotifyOwner ReassignToRole"`
+	AfterSeconds int64                   `json:"after_seconds" binding:"min=0,max=2592000"`
+	Role         models.UserRole         `json:"role" binding:"required_if=Action ReassignToRole,omitempty,oneof=Admin Employer"`
+}
+
+type SLARequest struct {
+	DurationSeconds int64                   `json:"duration_seconds" binding:"required,min=60,max=31536000"`
+	AtRiskPercent   int                     `json:"at_risk_percent" binding:"min=0,max=99"`
+	Escalation      []EscalationStepRequest `json:"escalation" binding:"max=10,dive"`
+}
+
+type VoteRequest struct {
+	Comment string `json:"comment" binding:"max=1000"`
+}
+
+type FieldDefinitionRequest struct {
+	Name     string           `json:"name" binding:"required,max=50"`
+	Label    string           `json:"label" binding:"max=100"`
+	Type     models.FieldType `json:"type" binding:"required,oneof=String Number Date Enum User"`
+	Required bool             `json:"required"`
+	Options  []string         `json:"options" binding:"dive,required"`
+}
+
+type SetVariableDefinitionsRequest struct {
+	Variables []FieldDefinitionRequest `json:"variables" binding:"dive"`
+}
+
+type SetTaskFormRequest struct {
+	Fields []FieldDefinitionRequest `json:"fields" binding:"dive"`
+}
+
+type CompleteTaskRequest struct {
+	Values map[string]interface{} `json:"values"`
+}
+
+type SetVariablesRequest struct {
+	Variables map[string]interface{} `json:"variables" binding:"required"`
 }
 
 type EditWorkflowRequest struct {
-	Name string `json:"name" binding:"min=3,max=100"`
+	Name   string   `json:"name" binding:"min=3,max=100"`
+	Labels []string `json:"labels" binding:"max=20"`
 }
 
 type EditTaskRequest struct {
@@ -20,4 +111,47 @@ type EditTaskRequest struct {
 	Description string            `json:"description"`
 	Status      models.TaskStatus `json:"status" binding:"required"`
 	Order       int               `json:"order" binding:"required"`
+	Assignee    string            `json:"assignee" binding:"max=100"`
+	Labels      []string          `json:"labels" binding:"max=20"`
+}
+
+type MoveTaskRequest struct {
+	TaskID   string `json:"task_id" binding:"required"`
+	BeforeID string `json:"before_id" binding:"required_without=AfterID,excluded_with=AfterID"`
+	AfterID  string `json:"after_id" binding:"required_without=BeforeID,excluded_with=BeforeID"`
+}
+
+type ReorderTasksRequest struct {
+	TaskIDs []string         `json:"task_ids" binding:"required_without=Move"`
+	Move    *MoveTaskRequest `json:"move" binding:"required_without=TaskIDs"`
+}
+
+type PatchRequest struct {
+	ContentType string
+	Document    []byte
+}
+
+type TaskOperationRequest struct {
+	Op     models.BatchOperation `json:"op" binding:"required,oneof=create update delete"`
+	TaskID string                `json:"task_id" binding:"required_unless=Op create"`
+	Create *CreateTaskRequest    `json:"create" binding:"required_if=Op create"`
+	Update *EditTaskRequest      `json:"update" binding:"required_if=Op update"`
+}
+
+type BatchTasksRequest struct {
+	Mode       models.BatchMode       `json:"mode" binding:"omitempty,oneof=atomic best_effort"`
+	Operations []TaskOperationRequest `json:"operations" binding:"required,min=1,max=100,dive"`
+}
+
+type ExportWorkflowRequest struct {
+	Format string `form:"format" binding:"omitempty,oneof=yaml json bpmn"`
+}
+
+type WorkflowGraphRequest struct {
+	Format string `form:"format" binding:"omitempty,oneof=mermaid dot"`
+}
+
+type ImportWorkflowRequest struct {
+	ContentType string
+	Document    []byte
 }
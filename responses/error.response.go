@@ -13,3 +13,11 @@ func Error(ctx *gin.Context, msg string) {
 func ErrorWithToken(ctx *gin.Context, msg string) {
 	common.ResultJson(ctx, common.TOKEN_EXPIRED, msg, map[string]interface{}{})
 }
+
+func ErrorWithStatus(ctx *gin.Context, status int, msg string) {
+	common.ResultJsonWithStatus(ctx, status, common.ERROR, msg, map[string]interface{}{})
+}
+
+func ErrorWithData(ctx *gin.Context, msg string, data interface{}) {
+	common.ResultJson(ctx, common.ERROR, msg, data)
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"virtual_workflow_management_system_gin/controllers"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitAttachmentRouter(routerGroup *gin.RouterGroup, resource *databases.Resource) {
+	attachmentController := controllers.NewAttachmentController(resource)
+
+	authorizedGroup := routerGroup.Group("/workflows")
+	authorizedGroup.Use(middlewares.JWTAuthMiddleware(resource.Redis))
+	authorizedGroup.GET("/:id/tasks/:taskID/attachments", attachmentController.GetAttachments)
+	authorizedGroup.POST("/:id/tasks/:taskID/attachments", attachmentController.UploadAttachment)
+	authorizedGroup.GET("/:id/tasks/:taskID/attachments/:attachmentID", attachmentController.DownloadAttachment)
+	authorizedGroup.DELETE("/:id/tasks/:taskID/attachments/:attachmentID", attachmentController.DeleteAttachment)
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"virtual_workflow_management_system_gin/controllers"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitCommentRouter(routerGroup *gin.RouterGroup, resource *databases.Resource) {
+	commentController := controllers.NewCommentController(resource)
+
+	authorizedGroup := routerGroup.Group("/workflows")
+	authorizedGroup.Use(middlewares.JWTAuthMiddleware(resource.Redis))
+	authorizedGroup.GET("/:id/tasks/:taskID/comments", commentController.GetComments)
+	authorizedGroup.POST("/:id/tasks/:taskID/comments", commentController.CreateComment)
+	authorizedGroup.GET("/:id/tasks/:taskID/comments/activity", commentController.GetActivity)
+	authorizedGroup.PUT("/:id/tasks/:taskID/comments/:commentID", commentController.EditComment)
+	authorizedGroup.DELETE("/:id/tasks/:taskID/comments/:commentID", commentController.DeleteComment)
+}
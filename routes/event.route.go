@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"virtual_workflow_management_system_gin/controllers"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitEventRouter(routerGroup *gin.RouterGroup, resource *databases.Resource) {
+	eventController := controllers.NewEventController(resource)
+
+	authorizedGroup := routerGroup.Group("/workflows")
+	authorizedGroup.Use(middlewares.JWTAuthMiddleware(resource.Redis))
+	authorizedGroup.POST("/:id/events/ticket", eventController.CreateStreamTicket)
+
+	streamGroup := routerGroup.Group("/workflows")
+	streamGroup.Use(middlewares.StreamAuthMiddleware(resource.Redis))
+	streamGroup.GET("/:id/events", eventController.StreamEvents)
+	streamGroup.GET("/:id/events/ws", eventController.StreamEventsWebSocket)
+}
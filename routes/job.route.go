@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"virtual_workflow_management_system_gin/controllers"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitJobRouter(routerGroup *gin.RouterGroup, resource *databases.Resource) {
+	jobController := controllers.NewJobController(resource)
+
+	authorizedGroup := routerGroup.Group("/admin")
+	authorizedGroup.Use(middlewares.JWTAuthMiddleware(resource.Redis))
+	authorizedGroup.GET("/jobs", jobController.GetJobs)
+	authorizedGroup.GET("/jobs/:id", jobController.GetJob)
+	authorizedGroup.POST("/jobs/:id/retry", jobController.RetryJob)
+	authorizedGroup.GET("/dead-letter-jobs", jobController.GetDeadLetterJobs)
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"virtual_workflow_management_system_gin/controllers"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitNotificationRouter(routerGroup *gin.RouterGroup, resource *databases.Resource) {
+	notificationController := controllers.NewNotificationController(resource)
+
+	authorizedGroup := routerGroup.Group("/notifications")
+	authorizedGroup.Use(middlewares.JWTAuthMiddleware(resource.Redis))
+	authorizedGroup.GET("", notificationController.GetNotifications)
+	authorizedGroup.POST("/:id/read", notificationController.ReadNotification)
+}
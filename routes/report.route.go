@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"virtual_workflow_management_system_gin/controllers"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitReportRouter(routerGroup *gin.RouterGroup, resource *databases.Resource) {
+	reportController := controllers.NewReportController(resource)
+
+	workflowGroup := routerGroup.Group("/workflows")
+	workflowGroup.Use(middlewares.JWTAuthMiddleware(resource.Redis))
+	workflowGroup.GET("/:id/tasks/export.csv", reportController.ExportWorkflowTasks)
+	workflowGroup.GET("/:id/tasks/export.xlsx", reportController.ExportWorkflowTasks)
+
+	authorizedGroup := routerGroup.Group("/reports")
+	authorizedGroup.Use(middlewares.JWTAuthMiddleware(resource.Redis))
+	authorizedGroup.GET("/tasks.csv", reportController.ExportTasks)
+	authorizedGroup.GET("/tasks.xlsx", reportController.ExportTasks)
+	authorizedGroup.GET("/lead-time", reportController.GetLeadTime)
+	authorizedGroup.GET("/cycle-time", reportController.GetCycleTimes)
+	authorizedGroup.GET("/throughput", reportController.GetThroughput)
+	authorizedGroup.GET("/workflows/:id/steps", reportController.GetSlowestSteps)
+}
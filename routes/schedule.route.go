@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"virtual_workflow_management_system_gin/controllers"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitScheduleRouter(routerGroup *gin.RouterGroup, resource *databases.Resource) {
+	scheduleController := controllers.NewScheduleController(resource)
+
+	authorizedGroup := routerGroup.Group("/schedules")
+	authorizedGroup.Use(middlewares.JWTAuthMiddleware(resource.Redis))
+	authorizedGroup.GET("/:id", scheduleController.GetSchedule)
+	authorizedGroup.DELETE("/:id", scheduleController.DeleteSchedule)
+	authorizedGroup.POST("/:id/pause", scheduleController.PauseSchedule)
+	authorizedGroup.POST("/:id/resume", scheduleController.ResumeSchedule)
+	authorizedGroup.GET("/:id/upcoming", scheduleController.GetUpcomingRuns)
+
+	workflowGroup := routerGroup.Group("/workflows")
+	workflowGroup.Use(middlewares.JWTAuthMiddleware(resource.Redis))
+	workflowGroup.GET("/:id/schedules", scheduleController.GetSchedules)
+	workflowGroup.POST("/:id/schedules", scheduleController.CreateSchedule)
+}
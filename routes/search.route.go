@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"virtual_workflow_management_system_gin/controllers"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitSearchRouter(routerGroup *gin.RouterGroup, resource *databases.Resource) {
+	searchController := controllers.NewSearchController(resource)
+
+	authorizedGroup := routerGroup.Group("/search")
+	authorizedGroup.Use(middlewares.JWTAuthMiddleware(resource.Redis))
+	authorizedGroup.GET("", searchController.Search)
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"virtual_workflow_management_system_gin/controllers"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitTemplateRouter(routerGroup *gin.RouterGroup, resource *databases.Resource) {
+	templateController := controllers.NewTemplateController(resource)
+
+	authorizedGroup := routerGroup.Group("/templates")
+	authorizedGroup.Use(middlewares.JWTAuthMiddleware(resource.Redis))
+	authorizedGroup.GET("", templateController.GetTemplates)
+	authorizedGroup.GET("/:id", templateController.GetTemplate)
+	authorizedGroup.POST("", templateController.CreateTemplate)
+	authorizedGroup.DELETE("/:id", templateController.DeleteTemplate)
+	authorizedGroup.POST("/:id/instantiate", templateController.InstantiateTemplate)
+
+	workflowGroup := routerGroup.Group("/workflows")
+	workflowGroup.Use(middlewares.JWTAuthMiddleware(resource.Redis))
+	workflowGroup.POST("/:id/template", templateController.SaveWorkflowAsTemplate)
+}
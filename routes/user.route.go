@@ -19,4 +19,8 @@ func InitUserRouter(routerGroup *gin.RouterGroup, resource *databases.Resource)
 	authorizedGroup := routerGroup.Group("")
 	authorizedGroup.Use(middlewares.JWTAuthMiddleware(resource.Redis))
 	authorizedGroup.POST("logout", userController.Logout)
+
+	adminGroup := routerGroup.Group("/admin")
+	adminGroup.Use(middlewares.JWTAuthMiddleware(resource.Redis))
+	adminGroup.PATCH("/users/:username", userController.UpdateUser)
 }
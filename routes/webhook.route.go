@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"virtual_workflow_management_system_gin/controllers"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitWebhookRouter(routerGroup *gin.RouterGroup, resource *databases.Resource) {
+	webhookController := controllers.NewWebhookController(resource)
+
+	authorizedGroup := routerGroup.Group("/webhooks")
+	authorizedGroup.Use(middlewares.JWTAuthMiddleware(resource.Redis))
+	authorizedGroup.GET("", webhookController.GetOrganizationWebhooks)
+	authorizedGroup.POST("", webhookController.CreateOrganizationWebhook)
+	authorizedGroup.GET("/:id", webhookController.GetWebhook)
+	authorizedGroup.DELETE("/:id", webhookController.DeleteWebhook)
+	authorizedGroup.GET("/:id/deliveries", webhookController.GetDeliveries)
+	authorizedGroup.POST("/:id/test", webhookController.SendTestEvent)
+
+	workflowGroup := routerGroup.Group("/workflows")
+	workflowGroup.Use(middlewares.JWTAuthMiddleware(resource.Redis))
+	workflowGroup.GET("/:id/webhooks", webhookController.GetWorkflowWebhooks)
+	workflowGroup.POST("/:id/webhooks", webhookController.CreateWorkflowWebhook)
+}
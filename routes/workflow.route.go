@@ -11,16 +11,42 @@ import (
 func InitWorkflowRouter(routerGroup *gin.RouterGroup, resource *databases.Resource) {
 	workflowController := controllers.NewWorkflowController(resource)
 
+	routerGroup.GET("/schemas/workflow-definition.json", workflowController.GetWorkflowDefinitionSchema)
+
 	authorizedGroup := routerGroup.Group("/workflows")
 	authorizedGroup.Use(middlewares.JWTAuthMiddleware(resource.Redis))
 	authorizedGroup.GET("", workflowController.GetWorkflows)
 	authorizedGroup.GET("/:id", workflowController.GetWorkflow)
 	authorizedGroup.POST("", workflowController.CreateWorkflow)
+	authorizedGroup.POST("/import", workflowController.ImportWorkflow)
 	authorizedGroup.PUT("/:id", workflowController.EditWorkflow)
+	authorizedGroup.PATCH("/:id", workflowController.PatchWorkflow)
 	authorizedGroup.DELETE("/:id", workflowController.DeleteWorkflow)
 	authorizedGroup.PUT("/:id/transfer/:username", workflowController.TransferWorkflow)
+	authorizedGroup.PUT("/:id/variables", workflowController.SetVariables)
+	authorizedGroup.POST("/:id/start", workflowController.StartWorkflow)
+	authorizedGroup.PUT("/:id/variables/definitions", workflowController.SetVariableDefinitions)
+	authorizedGroup.GET("/:id/validate", workflowController.ValidateWorkflow)
+	authorizedGroup.GET("/:id/export", workflowController.ExportWorkflow)
+	authorizedGroup.GET("/:id/graph", workflowController.GetWorkflowGraph)
+	authorizedGroup.GET("/:id/revisions", workflowController.GetRevisions)
+	authorizedGroup.GET("/:id/revisions/diff", workflowController.DiffRevisions)
+	authorizedGroup.GET("/:id/revisions/:revision", workflowController.GetRevision)
+	authorizedGroup.POST("/:id/revisions/:revision/restore", workflowController.RestoreRevision)
 	authorizedGroup.GET("/:id/tasks", workflowController.GetTasks)
 	authorizedGroup.GET("/:id/tasks/:taskID", workflowController.GetTask)
 	authorizedGroup.POST("/:id/tasks", workflowController.CreateTask)
+	authorizedGroup.POST("/:id/tasks:method", workflowController.BatchTasks)
+	authorizedGroup.PUT("/:id/tasks/order", workflowController.ReorderTasks)
 	authorizedGroup.PUT("/:id/tasks/:taskID", workflowController.EditTask)
+	authorizedGroup.PATCH("/:id/tasks/:taskID", workflowController.PatchTask)
+	authorizedGroup.POST("/:id/tasks/:taskID/approve", workflowController.ApproveTask)
+	authorizedGroup.POST("/:id/tasks/:taskID/reject", workflowController.RejectTask)
+	authorizedGroup.PUT("/:id/tasks/:taskID/form", workflowController.SetTaskForm)
+	authorizedGroup.POST("/:id/tasks/:taskID/complete", workflowController.CompleteTask)
+	authorizedGroup.POST("/:id/tasks/:taskID/retry", workflowController.RetryTask)
+	authorizedGroup.POST("/:id/tasks/:taskID/checklist", workflowController.AddChecklistItem)
+	authorizedGroup.PUT("/:id/tasks/:taskID/checklist/order", workflowController.ReorderChecklist)
+	authorizedGroup.POST("/:id/tasks/:taskID/checklist/:itemID/toggle", workflowController.ToggleChecklistItem)
+	authorizedGroup.DELETE("/:id/tasks/:taskID/checklist/:itemID", workflowController.RemoveChecklistItem)
 }
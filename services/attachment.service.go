@@ -0,0 +1,303 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/repositories"
+	"virtual_workflow_management_system_gin/requests"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const maxAttachmentFilename = 255
+
+var (
+	ErrAttachmentTooLarge     = errors.New("attachment is too large")
+	ErrUnsupportedContentType = errors.New("unsupported attachment content type")
+)
+
+func init() {
+	Events.SubscribeAsync("attachments", removeAttachments, JobOptions{MaxAttempts: 5, BackoffSeconds: 30},
+		models.TaskDeleted{}.EventName(),
+		models.WorkflowDeleted{}.EventName(),
+	)
+}
+
+var AttachmentService IAttachmentService
+
+type attachmentService struct {
+	attachmentEntity repositories.IAttachment
+	workflowEntity   repositories.IWorkflow
+	blobStore        repositories.IBlobStore
+	events           *eventPublisher
+}
+
+type IAttachmentService interface {
+	GetAttachments(workflowID string, taskID string) ([]models.Attachment, error)
+	GetAttachment(workflowID string, taskID string, attachmentID string) (*models.Attachment, error)
+	UploadAttachment(user models.JWTUser, workflowID string, taskID string, req requests.UploadAttachmentRequest) (*models.Attachment, error)
+	OpenAttachment(attachment *models.Attachment) (io.ReadCloser, error)
+	DeleteAttachment(workflowID string, taskID string, attachmentID string) error
+}
+
+func NewAttachmentService(resource *databases.Resource) *attachmentService {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &attachmentService{}
+	}
+	return &attachmentService{
+		attachmentEntity: repositories.NewAttachmentEntity(resource),
+		workflowEntity:   repositories.NewWorkflowEntity(resource),
+		blobStore:        repositories.NewBlobStore(resource),
+		events:           newEventPublisher(resource),
+	}
+}
+
+func (service *attachmentService) GetAttachments(workflowID string, taskID string) ([]models.Attachment, error) {
+	if _, err := service.workflowEntity.FindTaskByID(workflowID, taskID); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	attachments, err := service.attachmentEntity.FindAttachmentsByTaskID(workflowID, taskID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// GetAttachment returns an attachment of the task. Attachments of other tasks
+// do not exist here.
+func (service *attachmentService) GetAttachment(workflowID string, taskID string, attachmentID string) (*models.Attachment, error) {
+	attachment, err := service.attachmentEntity.FindAttachmentByID(attachmentID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	if attachment.WorkflowID.Hex() != workflowID || attachment.TaskID.Hex() != taskID {
+		return nil, errors.New("attachment does not exist")
+	}
+
+	return attachment, nil
+}
+
+// UploadAttachment streams the file to the blob store while computing its
+// checksum, then records the attachment. A checksum sent along must match.
+func (service *attachmentService) UploadAttachment(user models.JWTUser, workflowID string, taskID string, req requests.UploadAttachmentRequest) (*models.Attachment, error) {
+	if service.blobStore == nil {
+		return nil, errors.New("attachments are not available")
+	}
+
+	task, err := service.workflowEntity.FindTaskByID(workflowID, taskID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	if req.File.Size > models.MaxAttachmentSize {
+		return nil, ErrAttachmentTooLarge
+	}
+
+	file, err := req.File.Open()
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to read attachment")
+	}
+	defer file.Close()
+
+	content := bufio.NewReaderSize(file, 512)
+	contentType, err := attachmentContentType(req.File.Header.Get("Content-Type"), content)
+	if err != nil {
+		return nil, err
+	}
+
+	attachmentModel := models.Attachment{
+		TaskID:      task.ID,
+		Filename:    attachmentFilename(req.File.Filename),
+		ContentType: contentType,
+		UploadedBy:  user.Username,
+	}
+	attachmentModel.ID = primitive.NewObjectID()
+	attachmentModel.WorkflowID, _ = primitive.ObjectIDFromHex(workflowID)
+	attachmentModel.StorageKey = attachmentModel.ID.Hex()
+
+	hash := sha256.New()
+	counter := &countingWriter{}
+	limited := io.LimitReader(content, models.MaxAttachmentSize+1)
+	if err := service.blobStore.Put(attachmentModel.StorageKey, io.TeeReader(limited, io.MultiWriter(hash, counter))); err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to store attachment")
+	}
+	attachmentModel.Size = counter.count
+	attachmentModel.Checksum = hex.EncodeToString(hash.Sum(nil))
+
+	if attachmentModel.Size > models.MaxAttachmentSize {
+		service.removeBlob(attachmentModel.StorageKey)
+		return nil, ErrAttachmentTooLarge
+	}
+	if req.Checksum != "" && !strings.EqualFold(req.Checksum, attachmentModel.Checksum) {
+		service.removeBlob(attachmentModel.StorageKey)
+		return nil, errors.New("attachment checksum does not match")
+	}
+
+	var attachment *models.Attachment
+	err = service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		attachment, err = service.attachmentEntity.WithContext(ctx).CreateAttachment(attachmentModel)
+		if err != nil {
+			return err
+		}
+		events.record(models.AttachmentAdded{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Attachment: *attachment})
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		service.removeBlob(attachmentModel.StorageKey)
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+func (service *attachmentService) OpenAttachment(attachment *models.Attachment) (io.ReadCloser, error) {
+	if service.blobStore == nil {
+		return nil, errors.New("attachments are not available")
+	}
+
+	content, err := service.blobStore.Open(attachment.StorageKey)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to open attachment")
+	}
+
+	return content, nil
+}
+
+// DeleteAttachment removes an attachment. Its content is removed once the
+// attachment is gone, so a failure never leaves an attachment without content.
+func (service *attachmentService) DeleteAttachment(workflowID string, taskID string, attachmentID string) error {
+	attachment, err := service.GetAttachment(workflowID, taskID, attachmentID)
+	if err != nil {
+		return err
+	}
+
+	err = service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		if err := service.attachmentEntity.WithContext(ctx).DeleteAttachment(attachmentID); err != nil {
+			return err
+		}
+		events.record(models.AttachmentDeleted{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Attachment: *attachment})
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	service.removeBlob(attachment.StorageKey)
+	return nil
+}
+
+func (service *attachmentService) removeBlob(key string) {
+	if err := service.blobStore.Delete(key); err != nil {
+		logrus.Error(err)
+	}
+}
+
+// attachmentContentType returns the declared content type of a file, or the
+// sniffed one when the client did not declare a specific type.
+func attachmentContentType(declared string, content *bufio.Reader) (string, error) {
+	contentType, _, err := mime.ParseMediaType(declared)
+	if err != nil || contentType == "application/octet-stream" {
+		head, _ := content.Peek(512)
+		contentType, _, _ = mime.ParseMediaType(http.DetectContentType(head))
+	}
+
+	if !models.AttachmentContentTypes[contentType] {
+		return "", ErrUnsupportedContentType
+	}
+
+	return contentType, nil
+}
+
+// attachmentFilename keeps the base name of an uploaded file without control
+// characters.
+func attachmentFilename(filename string) string {
+	filename = filepath.Base(strings.ReplaceAll(filename, "\\", "/"))
+	filename = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, filename)
+
+	if filename == "" || filename == "." || filename == ".." || filename == "/" {
+		return "attachment"
+	}
+	if len(filename) > maxAttachmentFilename {
+		extension := filepath.Ext(filename)
+		if len(extension) > maxAttachmentFilename/2 {
+			extension = ""
+		}
+		filename = strings.ToValidUTF8(filename[:maxAttachmentFilename-len(extension)], "") + extension
+	}
+	return filename
+}
+
+type countingWriter struct {
+	count int64
+}
+
+func (writer *countingWriter) Write(p []byte) (int, error) {
+	writer.count += int64(len(p))
+	return len(p), nil
+}
+
+// removeAttachments removes the attachments of deleted tasks and workflows.
+func removeAttachments(ctx context.Context, event models.DomainEvent) error {
+	if repositories.AttachmentEntity == nil || repositories.BlobStore == nil {
+		return errors.New("attachments are not available")
+	}
+	attachmentEntity := repositories.AttachmentEntity.WithContext(ctx)
+
+	var attachments []models.Attachment
+	var err error
+	switch payload := event.Payload.(type) {
+	case models.TaskDeleted:
+		attachments, err = attachmentEntity.FindAttachmentsByTaskID(payload.WorkflowID, payload.TaskID)
+	case models.WorkflowDeleted:
+		attachments, err = attachmentEntity.FindAttachmentsByTaskID(payload.WorkflowID, "")
+	default:
+		return nil
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	for _, attachment := range attachments {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := repositories.BlobStore.Delete(attachment.StorageKey); err != nil {
+			return err
+		}
+		if err := attachmentEntity.DeleteAttachment(attachment.ID.Hex()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
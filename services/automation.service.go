@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"virtual_workflow_management_system_gin/common"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/repositories"
+	"virtual_workflow_management_system_gin/requests"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultMaxAttempts    = 3
+	defaultBackoffSeconds = 10
+	defaultTimeoutSeconds = 30
+
+	// automationLease outlasts the longest allowed timeout, so a task is only
+	// claimed again when its worker is gone.
+	automationLease      = 10 * time.Minute
+	automationPollPeriod = 2 * time.Second
+	maxResponseSize      = 1 << 20
+)
+
+// TaskHandler runs an automated task in process. It receives the run variables
+// and returns the output stored in the output variable of the task. Handlers
+// must return when ctx is done.
+type TaskHandler func(ctx context.Context, variables map[string]interface{}) (interface{}, error)
+
+var (
+	taskHandlers      = map[string]TaskHandler{}
+	taskHandlersMutex sync.RWMutex
+)
+
+func init() {
+	// timestamp records when the run reached the task, in UTC.
+	RegisterTaskHandler("timestamp", func(ctx context.Context, variables map[string]interface{}) (interface{}, error) {
+		return time.Now().UTC().Format(time.RFC3339), nil
+	})
+}
+
+// RegisterTaskHandler makes a handler available to automated tasks under the
+// given name. Handlers are registered at startup, before the worker starts.
+func RegisterTaskHandler(name string, handler TaskHandler) {
+	taskHandlersMutex.Lock()
+	defer taskHandlersMutex.Unlock()
+	taskHandlers[name] = handler
+}
+
+func findTaskHandler(name string) (TaskHandler, bool) {
+	taskHandlersMutex.RLock()
+	defer taskHandlersMutex.RUnlock()
+	handler, ok := taskHandlers[name]
+	return handler, ok
+}
+
+// AutomationWorker runs automated tasks with a fixed pool of goroutines.
+type AutomationWorker struct {
+	workflowEntity repositories.IWorkflow
+	events         *eventPublisher
+	httpClient     *http.Client
+	workers        int
+	cancel         context.CancelFunc
+	wg             sync.WaitGroup
+}
+
+func NewAutomationWorker(resource *databases.Resource, workers int) *AutomationWorker {
+	if workers < 1 {
+		workers = 1
+	}
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &AutomationWorker{workers: workers}
+	}
+	return &AutomationWorker{
+		workflowEntity: repositories.NewWorkflowEntity(resource),
+		events:         newEventPublisher(resource),
+		httpClient:     common.NewOutboundClient(0),
+		workers:        workers,
+	}
+}
+
+func (worker *AutomationWorker) Start() {
+	if worker.workflowEntity == nil || worker.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	worker.cancel = cancel
+	for i := 0; i < worker.workers; i++ {
+		worker.wg.Add(1)
+		go func() {
+			defer worker.wg.Done()
+			worker.run(ctx)
+		}()
+	}
+}
+
+// Stop waits for running tasks to finish. Tasks interrupted by the shutdown
+// are retried once their lease expires.
+func (worker *AutomationWorker) Stop() {
+	if worker.cancel == nil {
+		return
+	}
+	worker.cancel()
+	worker.wg.Wait()
+	worker.cancel = nil
+}
+
+func (worker *AutomationWorker) run(ctx context.Context) {
+	for {
+		if !worker.runNext(ctx) {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(automationPollPeriod):
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// runNext claims and runs one due task. It reports whether a task was run.
+func (worker *AutomationWorker) runNext(ctx context.Context) bool {
+	workflow, task, err := worker.workflowEntity.ClaimAutomatedTask(automationLease)
+	if err != nil || task == nil {
+		return false
+	}
+
+	output, failure := worker.execute(ctx, workflow.Variables, task.Automation)
+	if ctx.Err() != nil {
+		// Leave the lease to expire so another worker runs the task again.
+		return true
+	}
+	if failure != nil {
+		logrus.Warn("automated task " + task.ID.Hex() + " failed: " + failure.Error())
+	}
+
+	workflowID := workflow.ID.Hex()
+	err = worker.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		recordedTask, err := worker.workflowEntity.WithContext(ctx).RecordAutomationResult(workflowID, task.ID.Hex(), task.Automation.LeaseID, output, failure)
+		if err != nil {
+			return err
+		}
+
+		events.record(models.TaskAttempted{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Task: *recordedTask})
+		events.recordStatusChange(workflowID, recordedTask, task.Status)
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	return true
+}
+
+func (worker *AutomationWorker) execute(ctx context.Context, variables map[string]interface{}, automation *models.Automation) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, automation.Timeout())
+	defer cancel()
+
+	switch automation.Executor {
+	case models.HTTPExecutor:
+		return worker.callHTTP(ctx, variables, automation.HTTP)
+	case models.HandlerExecutor:
+		handler, ok := findTaskHandler(automation.Handler)
+		if !ok {
+			return nil, errors.New("unknown task handler: " + automation.Handler)
+		}
+		return runTaskHandler(ctx, handler, variables)
+	}
+
+	return nil, errors.New("unknown executor: " + string(automation.Executor))
+}
+
+func (worker *AutomationWorker) callHTTP(ctx context.Context, variables map[string]interface{}, call *models.HTTPCall) (interface{}, error) {
+	// The allowed hosts may have changed since the task was created.
+	if err := common.CheckOutboundURL(call.URL); err != nil {
+		return nil, err
+	}
+
+	body, err := call.RenderBody(variables)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, call.Method, call.URL, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range call.Headers {
+		request.Header.Set(name, value)
+	}
+	if body != "" && request.Header.Get("Content-Type") == "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := worker.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(response.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, fmt.Errorf("endpoint responded with status %d", response.StatusCode)
+	}
+
+	var output interface{}
+	if json.Unmarshal(content, &output) == nil {
+		return output, nil
+	}
+
+	return string(content), nil
+}
+
+// runTaskHandler runs a handler until it returns, so the task holds its worker
+// for as long as it runs. A handler that panics or overruns ctx fails the
+// attempt.
+func runTaskHandler(ctx context.Context, handler TaskHandler, variables map[string]interface{}) (output interface{}, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			output, err = nil, fmt.Errorf("handler panicked: %v", recovered)
+		}
+	}()
+
+	output, err = handler(ctx, variables)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, errors.New("handler timed out")
+	}
+	return output, err
+}
+
+func newAutomation(req requests.AutomationRequest) (*models.Automation, error) {
+	automation := &models.Automation{
+		Executor:       req.Executor,
+		Handler:        req.Handler,
+		MaxAttempts:    req.MaxAttempts,
+		BackoffSeconds: req.BackoffSeconds,
+		TimeoutSeconds: req.TimeoutSeconds,
+		OutputVariable: req.OutputVariable,
+	}
+	if automation.MaxAttempts == 0 {
+		automation.MaxAttempts = defaultMaxAttempts
+	}
+	if automation.BackoffSeconds == 0 {
+		automation.BackoffSeconds = defaultBackoffSeconds
+	}
+	if automation.TimeoutSeconds == 0 {
+		automation.TimeoutSeconds = defaultTimeoutSeconds
+	}
+	if req.HTTP != nil {
+		automation.HTTP = &models.HTTPCall{
+			Method:  req.HTTP.Method,
+			URL:     req.HTTP.URL,
+			Headers: req.HTTP.Headers,
+			Body:    req.HTTP.Body,
+		}
+	}
+
+	if err := automation.Validate(); err != nil {
+		return nil, err
+	}
+	if automation.Executor == models.HTTPExecutor {
+		if err := common.CheckOutboundURL(automation.HTTP.URL); err != nil {
+			return nil, err
+		}
+	}
+	if automation.Executor == models.HandlerExecutor {
+		if _, ok := findTaskHandler(automation.Handler); !ok {
+			return nil, errors.New("unknown task handler: " + automation.Handler)
+		}
+	}
+
+	return automation, nil
+}
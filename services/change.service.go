@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	changeChannel = "workflow_changes"
+	// changeBuffer is how many events a slow subscriber may fall behind
+	// before further events are dropped for it.
+	changeBuffer = 64
+)
+
+// Changes is the change feed of this server. Services publish to it and the
+// event streams subscribe to it.
+var Changes = NewChangeFeed()
+
+func init() {
+	Events.Subscribe("changes", publishChange)
+}
+
+type IChangeService interface {
+	Publish(event models.ChangeEvent)
+	Subscribe(workflowID string) (<-chan models.ChangeEvent, func())
+}
+
+// ChangeFeed fans change events out to the subscribers of a workflow. Once
+// started with Redis, events go through a Redis channel so that subscribers on
+// every server receive them. Without Redis, or when publishing fails, events
+// only reach the subscribers of this server.
+type ChangeFeed struct {
+	mutex       sync.RWMutex
+	subscribers map[string]map[chan models.ChangeEvent]struct{}
+	redis       *redis.Client
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+func NewChangeFeed() *ChangeFeed {
+	return &ChangeFeed{
+		subscribers: map[string]map[chan models.ChangeEvent]struct{}{},
+	}
+}
+
+// Start subscribes to the Redis channel. The feed stays in-process if Redis is
+// not available.
+func (feed *ChangeFeed) Start(resource *databases.Resource) {
+	if resource == nil || resource.Redis == nil || feed.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pubsub := resource.Redis.Subscribe(ctx, changeChannel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		logrus.Warning("change feed falls back to in-process delivery: ", err)
+		pubsub.Close()
+		cancel()
+		return
+	}
+
+	feed.mutex.Lock()
+	feed.redis = resource.Redis
+	feed.mutex.Unlock()
+
+	feed.cancel = cancel
+	feed.wg.Add(1)
+	go func() {
+		defer feed.wg.Done()
+		defer pubsub.Close()
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				var event models.ChangeEvent
+				if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+					logrus.Error(err)
+					continue
+				}
+				feed.deliver(event)
+			}
+		}
+	}()
+}
+
+// Stop leaves the Redis channel and ends every subscription, so that open
+// streams finish before the server shuts down.
+func (feed *ChangeFeed) Stop() {
+	feed.mutex.Lock()
+	feed.redis = nil
+	for workflowID, subscribers := range feed.subscribers {
+		for events := range subscribers {
+			close(events)
+		}
+		delete(feed.subscribers, workflowID)
+	}
+	feed.mutex.Unlock()
+
+	if feed.cancel == nil {
+		return
+	}
+	feed.cancel()
+	feed.wg.Wait()
+	feed.cancel = nil
+}
+
+// Publish sends an event to the subscribers of its workflow on every server.
+func (feed *ChangeFeed) Publish(event models.ChangeEvent) {
+	if event.ID == "" {
+		event.ID = primitive.NewObjectID().Hex()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+
+	feed.mutex.RLock()
+	redisClient := feed.redis
+	feed.mutex.RUnlock()
+
+	if redisClient != nil {
+		message, err := json.Marshal(event)
+		if err == nil {
+			err = redisClient.Publish(context.Background(), changeChannel, message).Err()
+		}
+		if err == nil {
+			return
+		}
+		logrus.Error(err)
+	}
+
+	feed.deliver(event)
+}
+
+// Subscribe returns the events of a workflow and a function that ends the
+// subscription and closes the channel.
+func (feed *ChangeFeed) Subscribe(workflowID string) (<-chan models.ChangeEvent, func()) {
+	events := make(chan models.ChangeEvent, changeBuffer)
+
+	feed.mutex.Lock()
+	if feed.subscribers[workflowID] == nil {
+		feed.subscribers[workflowID] = map[chan models.ChangeEvent]struct{}{}
+	}
+	feed.subscribers[workflowID][events] = struct{}{}
+	feed.mutex.Unlock()
+
+	unsubscribe := func() {
+		feed.mutex.Lock()
+		defer feed.mutex.Unlock()
+		if _, ok := feed.subscribers[workflowID][events]; !ok {
+			return
+		}
+		delete(feed.subscribers[workflowID], events)
+		if len(feed.subscribers[workflowID]) == 0 {
+			delete(feed.subscribers, workflowID)
+		}
+		close(events)
+	}
+
+	return events, unsubscribe
+}
+
+// deliver hands an event to the subscribers on this server without waiting
+// for slow ones.
+func (feed *ChangeFeed) deliver(event models.ChangeEvent) {
+	feed.mutex.RLock()
+	defer feed.mutex.RUnlock()
+
+	for events := range feed.subscribers[event.WorkflowID] {
+		select {
+		case events <- event:
+		default:
+			logrus.Warning("dropping change event " + event.ID + " for a slow subscriber")
+		}
+	}
+}
+
+// publishChange forwards the events of workflows and their tasks to the change
+// feed.
+func publishChange(ctx context.Context, event models.DomainEvent) error {
+	payload, ok := event.Payload.(models.WorkflowEvent)
+	if !ok {
+		return nil
+	}
+
+	Changes.Publish(models.ChangeEvent{
+		ID:         event.ID,
+		Type:       event.Name,
+		WorkflowID: payload.ScopeWorkflowID(),
+		Data:       payload,
+		CreatedAt:  event.OccurredAt,
+	})
+
+	return nil
+}
@@ -0,0 +1,308 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/repositories"
+	"virtual_workflow_management_system_gin/requests"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func init() {
+	Events.SubscribeAsync("task_activity", recordTaskActivity, JobOptions{MaxAttempts: 5, BackoffSeconds: 10},
+		models.TaskStatusChanged{}.EventName(),
+		models.TaskDeleted{}.EventName(),
+		models.WorkflowDeleted{}.EventName(),
+	)
+	Events.SubscribeAsync("mentions", notifyMentions, JobOptions{MaxAttempts: 5, BackoffSeconds: 10},
+		models.CommentAdded{}.EventName(),
+		models.CommentEdited{}.EventName(),
+	)
+}
+
+var CommentService ICommentService
+
+type commentService struct {
+	commentEntity  repositories.IComment
+	workflowEntity repositories.IWorkflow
+	userEntity     repositories.IUser
+	events         *eventPublisher
+}
+
+type ICommentService interface {
+	GetComments(workflowID string, taskID string) ([]models.Comment, error)
+	GetComment(workflowID string, taskID string, commentID string) (*models.Comment, error)
+	GetActivity(workflowID string, taskID string) ([]models.Activity, error)
+	CreateComment(user models.JWTUser, workflowID string, taskID string, req requests.CreateCommentRequest) (*models.Comment, error)
+	EditComment(workflowID string, taskID string, commentID string, req requests.EditCommentRequest) (*models.Comment, error)
+	DeleteComment(workflowID string, taskID string, commentID string) error
+}
+
+func NewCommentService(resource *databases.Resource) *commentService {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &commentService{}
+	}
+	return &commentService{
+		commentEntity:  repositories.NewCommentEntity(resource),
+		workflowEntity: repositories.NewWorkflowEntity(resource),
+		userEntity:     repositories.NewUserEntity(resource),
+		events:         newEventPublisher(resource),
+	}
+}
+
+// GetComments returns the comments of a task as threads, oldest first.
+func (service *commentService) GetComments(workflowID string, taskID string) ([]models.Comment, error) {
+	if _, err := service.workflowEntity.FindTaskByID(workflowID, taskID); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	comments, err := service.commentEntity.FindCommentsByTaskID(workflowID, taskID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return models.BuildCommentThreads(comments), nil
+}
+
+// GetComment returns a comment of the task. Comments of other tasks and
+// deleted comments do not exist here.
+func (service *commentService) GetComment(workflowID string, taskID string, commentID string) (*models.Comment, error) {
+	comment, err := service.commentEntity.FindCommentByID(commentID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	if comment.WorkflowID.Hex() != workflowID || comment.TaskID.Hex() != taskID || comment.Deleted {
+		return nil, errors.New("comment does not exist")
+	}
+
+	return comment, nil
+}
+
+// GetActivity returns the comments and status changes of a task in the order
+// they happened.
+func (service *commentService) GetActivity(workflowID string, taskID string) ([]models.Activity, error) {
+	if _, err := service.workflowEntity.FindTaskByID(workflowID, taskID); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	comments, err := service.commentEntity.FindCommentsByTaskID(workflowID, taskID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	changes, err := service.commentEntity.FindStatusChangesByTaskID(workflowID, taskID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return models.BuildActivityFeed(comments, changes), nil
+}
+
+func (service *commentService) CreateComment(user models.JWTUser, workflowID string, taskID string, req requests.CreateCommentRequest) (*models.Comment, error) {
+	task, err := service.workflowEntity.FindTaskByID(workflowID, taskID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	mentions, err := service.checkMentions(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	commentModel := models.Comment{
+		TaskID:   task.ID,
+		Author:   user.Username,
+		Body:     req.Body,
+		Mentions: mentions,
+	}
+	commentModel.WorkflowID, _ = primitive.ObjectIDFromHex(workflowID)
+
+	if req.ParentID != "" {
+		parent, err := service.GetComment(workflowID, taskID, req.ParentID)
+		if err != nil {
+			return nil, errors.New("parent comment does not exist")
+		}
+		commentModel.ParentID = &parent.ID
+	}
+
+	var comment *models.Comment
+	err = service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		comment, err = service.commentEntity.WithContext(ctx).CreateComment(commentModel)
+		if err != nil {
+			return err
+		}
+		events.record(models.CommentAdded{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Comment: *comment})
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+func (service *commentService) EditComment(workflowID string, taskID string, commentID string, req requests.EditCommentRequest) (*models.Comment, error) {
+	previous, err := service.GetComment(workflowID, taskID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	mentions, err := service.checkMentions(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	mentioned := map[string]bool{}
+	for _, username := range previous.Mentions {
+		mentioned[username] = true
+	}
+	newMentions := []string{}
+	for _, username := range mentions {
+		if !mentioned[username] {
+			newMentions = append(newMentions, username)
+		}
+	}
+
+	var comment *models.Comment
+	err = service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		comment, err = service.commentEntity.WithContext(ctx).EditComment(commentID, req.Body, mentions)
+		if err != nil {
+			return err
+		}
+		events.record(models.CommentEdited{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Comment: *comment, NewMentions: newMentions})
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+func (service *commentService) DeleteComment(workflowID string, taskID string, commentID string) error {
+	if _, err := service.GetComment(workflowID, taskID, commentID); err != nil {
+		return err
+	}
+
+	err := service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		comment, err := service.commentEntity.WithContext(ctx).DeleteComment(commentID)
+		if err != nil {
+			return err
+		}
+		events.record(models.CommentDeleted{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Comment: *comment})
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// checkMentions returns the users mentioned in a comment, making sure that
+// they exist.
+func (service *commentService) checkMentions(body string) ([]string, error) {
+	mentions := models.ParseMentions(body)
+	if len(mentions) > models.MaxMentions {
+		return nil, errors.New("too many mentions")
+	}
+
+	for _, username := range mentions {
+		if _, err := service.userEntity.FindOneByUsername(username); err != nil {
+			logrus.Error(err)
+			return nil, errors.New("mentioned user does not exist: " + username)
+		}
+	}
+
+	return mentions, nil
+}
+
+// recordTaskActivity keeps the status history shown in the activity feed of
+// tasks, and drops comments and history together with their task.
+func recordTaskActivity(ctx context.Context, event models.DomainEvent) error {
+	if repositories.CommentEntity == nil {
+		return errors.New("comments are not available")
+	}
+	commentEntity := repositories.CommentEntity.WithContext(ctx)
+
+	switch payload := event.Payload.(type) {
+	case models.TaskStatusChanged:
+		workflowObjectID, err := primitive.ObjectIDFromHex(payload.WorkflowID)
+		if err != nil {
+			logrus.Error(err)
+			return nil
+		}
+		return commentEntity.SaveStatusChange(models.TaskStatusChange{
+			ID:             event.ID,
+			WorkflowID:     workflowObjectID,
+			TaskID:         payload.Task.ID,
+			PreviousStatus: payload.PreviousStatus,
+			Status:         payload.Task.Status,
+			ChangedAt:      event.OccurredAt,
+		})
+	case models.TaskDeleted:
+		return commentEntity.DeleteTaskComments(payload.WorkflowID, payload.TaskID)
+	case models.WorkflowDeleted:
+		return commentEntity.DeleteTaskComments(payload.WorkflowID, "")
+	}
+
+	return nil
+}
+
+// notifyMentions notifies the users a comment mentions for the first time.
+// Authors are not notified of their own mentions.
+func notifyMentions(ctx context.Context, event models.DomainEvent) error {
+	var comment models.Comment
+	var mentions []string
+	switch payload := event.Payload.(type) {
+	case models.CommentAdded:
+		comment, mentions = payload.Comment, payload.Comment.Mentions
+	case models.CommentEdited:
+		comment, mentions = payload.Comment, payload.NewMentions
+	default:
+		return nil
+	}
+
+	if repositories.JobEntity == nil {
+		return errors.New("notifications are not available")
+	}
+	jobService := &jobService{jobEntity: repositories.JobEntity}
+
+	for _, username := range mentions {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if username == comment.Author {
+			continue
+		}
+		_, err := jobService.EnqueueJob(NotificationJob, map[string]interface{}{
+			"username":    username,
+			"subject":     comment.Author + " mentioned you in a comment",
+			"workflow_id": comment.WorkflowID.Hex(),
+			"task_id":     comment.TaskID.Hex(),
+			"comment_id":  comment.ID.Hex(),
+		}, time.Time{})
+		if err != nil {
+			logrus.Error(err)
+			return err
+		}
+	}
+
+	return nil
+}
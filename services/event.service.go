@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+	"virtual_workflow_management_system_gin/common"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/repositories"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	eventTransactionTimeout = 60 * time.Second
+	outboxLease             = time.Minute
+	outboxPollPeriod        = time.Second
+	// eventJobPrefix prefixes the job type of every asynchronous subscriber.
+	eventJobPrefix = "event."
+)
+
+// EventHandler handles a domain event.
+type EventHandler func(ctx context.Context, event models.DomainEvent) error
+
+type eventSubscription struct {
+	subscriber string
+	handler    EventHandler
+	names      map[models.EventName]bool
+}
+
+func (subscription eventSubscription) wants(name models.EventName) bool {
+	return len(subscription.names) == 0 || subscription.names[name]
+}
+
+// EventBus hands the events published by the service layer to subscribers.
+// Synchronous subscribers run right after the transaction of the mutation
+// commits; their errors are logged and never fail the mutation. Asynchronous
+// subscribers are fed from the outbox through the job queue, so they receive
+// every committed event at least once, retried with backoff.
+type EventBus struct {
+	mutex sync.RWMutex
+	sync  []eventSubscription
+	async []eventSubscription
+	// relay wakes the outbox relay when events were committed.
+	relay chan struct{}
+}
+
+// Events is the event bus of this server. Subscribers register at startup.
+var Events = NewEventBus()
+
+func NewEventBus() *EventBus {
+	return &EventBus{relay: make(chan struct{}, 1)}
+}
+
+func newEventNames(names []models.EventName) map[models.EventName]bool {
+	set := map[models.EventName]bool{}
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// Subscribe runs handler for the named events, or for every event when no
+// name is given, right after they are committed.
+func (bus *EventBus) Subscribe(subscriber string, handler EventHandler, names ...models.EventName) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	bus.sync = append(bus.sync, eventSubscription{subscriber: subscriber, handler: handler, names: newEventNames(names)})
+}
+
+// SubscribeAsync runs handler for the named events, or for every event when
+// no name is given, as jobs of the type "event.<subscriber>".
+func (bus *EventBus) SubscribeAsync(subscriber string, handler EventHandler, options JobOptions, names ...models.EventName) {
+	bus.mutex.Lock()
+	bus.async = append(bus.async, eventSubscription{subscriber: subscriber, handler: handler, names: newEventNames(names)})
+	bus.mutex.Unlock()
+
+	RegisterJobHandler(eventJobPrefix+subscriber, func(ctx context.Context, payload map[string]interface{}) error {
+		event, err := decodeEventJob(payload)
+		if err != nil {
+			// A payload that cannot be decoded will not decode on a retry either.
+			logrus.Error(err)
+			return nil
+		}
+		return handler(ctx, *event)
+	}, options)
+}
+
+// dispatch runs the synchronous subscribers of committed events.
+func (bus *EventBus) dispatch(events []models.DomainEvent) {
+	bus.mutex.RLock()
+	subscriptions := bus.sync
+	bus.mutex.RUnlock()
+
+	for _, event := range events {
+		for _, subscription := range subscriptions {
+			if !subscription.wants(event.Name) {
+				continue
+			}
+			if err := subscription.handler(context.Background(), event); err != nil {
+				logrus.Error("event subscriber " + subscription.subscriber + " failed on " + string(event.Name) + ": " + err.Error())
+			}
+		}
+	}
+}
+
+// asyncSubscribers returns the asynchronous subscribers of an event.
+func (bus *EventBus) asyncSubscribers(name models.EventName) []string {
+	bus.mutex.RLock()
+	defer bus.mutex.RUnlock()
+
+	subscribers := []string{}
+	for _, subscription := range bus.async {
+		if subscription.wants(name) {
+			subscribers = append(subscribers, subscription.subscriber)
+		}
+	}
+	return subscribers
+}
+
+func (bus *EventBus) wakeRelay() {
+	select {
+	case bus.relay <- struct{}{}:
+	default:
+	}
+}
+
+// eventRecorder collects the events of a transaction.
+type eventRecorder struct {
+	events []models.DomainEvent
+}
+
+func (recorder *eventRecorder) record(payloads ...models.Event) {
+	for _, payload := range payloads {
+		recorder.events = append(recorder.events, models.DomainEvent{
+			ID:         primitive.NewObjectID().Hex(),
+			Name:       payload.EventName(),
+			OccurredAt: time.Now().UTC(),
+			Payload:    payload,
+		})
+	}
+}
+
+// recordStatusChange records TaskStatusChanged if the task left the previous
+// status.
+func (recorder *eventRecorder) recordStatusChange(workflowID string, task *models.Task, previous models.TaskStatus) {
+	if task.Status == previous {
+		return
+	}
+	recorder.record(models.TaskStatusChanged{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Task: *task, PreviousStatus: previous})
+}
+
+// eventPublisher runs the mutations of a service in transactions that write
+// their events to the outbox.
+type eventPublisher struct {
+	bus          *EventBus
+	mongoClient  *mongo.Client
+	outboxEntity repositories.IOutbox
+}
+
+func newEventPublisher(resource *databases.Resource) *eventPublisher {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return nil
+	}
+	return &eventPublisher{
+		bus:          Events,
+		mongoClient:  resource.MongoDB.Client(),
+		outboxEntity: repositories.NewOutboxEntity(resource),
+	}
+}
+
+// transaction runs fn in a transaction. Repositories bound to the context
+// passed to fn take part in it. The events fn records are written to the
+// outbox before the transaction commits and dispatched once it did; if fn
+// fails, nothing is written and no event is published.
+func (publisher *eventPublisher) transaction(fn func(ctx context.Context, events *eventRecorder) error) error {
+	if publisher == nil {
+		return fn(context.Background(), &eventRecorder{})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), eventTransactionTimeout)
+	defer cancel()
+
+	var recorder eventRecorder
+	err := common.WithTransaction(ctx, publisher.mongoClient, func(c context.Context, session mongo.Session) error {
+		recorder = eventRecorder{}
+		if err := fn(c, &recorder); err != nil {
+			return err
+		}
+
+		entries := make([]models.OutboxEntry, 0, len(recorder.events))
+		for _, event := range recorder.events {
+			payload, err := json.Marshal(event.Payload)
+			if err != nil {
+				logrus.Error(err)
+				return errors.New("failed to encode event " + string(event.Name))
+			}
+			entryID, _ := primitive.ObjectIDFromHex(event.ID)
+			entries = append(entries, models.OutboxEntry{
+				ID:         entryID,
+				Event:      event.Name,
+				Payload:    string(payload),
+				OccurredAt: event.OccurredAt,
+			})
+		}
+
+		return publisher.outboxEntity.WithContext(c).AddEntries(entries)
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(recorder.events) > 0 {
+		publisher.bus.dispatch(recorder.events)
+		publisher.bus.wakeRelay()
+	}
+
+	return nil
+}
+
+func decodeEventJob(payload map[string]interface{}) (*models.DomainEvent, error) {
+	eventID, _ := payload["event_id"].(string)
+	name, _ := payload["event"].(string)
+	body, _ := payload["payload"].(string)
+	occurredAt, _ := payload["occurred_at"].(string)
+
+	event, err := models.DecodeEvent(models.EventName(name), []byte(body))
+	if err != nil {
+		return nil, err
+	}
+	occurred, _ := time.Parse(time.RFC3339Nano, occurredAt)
+
+	return &models.DomainEvent{ID: eventID, Name: models.EventName(name), OccurredAt: occurred, Payload: event}, nil
+}
+
+// OutboxRelay moves events from the outbox to the job queue, one job per
+// asynchronous subscriber. An entry is leased while it is relayed and deleted
+// afterwards; if a server stops half way the entry is relayed again, so a
+// subscriber may see an event twice but never miss it.
+type OutboxRelay struct {
+	bus          *EventBus
+	outboxEntity repositories.IOutbox
+	jobService   IJobService
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+}
+
+func NewOutboxRelay(resource *databases.Resource) *OutboxRelay {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &OutboxRelay{bus: Events}
+	}
+	return &OutboxRelay{
+		bus:          Events,
+		outboxEntity: repositories.NewOutboxEntity(resource),
+		jobService:   NewJobService(resource),
+	}
+}
+
+func (relay *OutboxRelay) Start() {
+	if relay.outboxEntity == nil || relay.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	relay.cancel = cancel
+	relay.wg.Add(1)
+	go func() {
+		defer relay.wg.Done()
+		for {
+			for ctx.Err() == nil && relay.runNext() {
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-relay.bus.relay:
+			case <-time.After(outboxPollPeriod):
+			}
+		}
+	}()
+}
+
+func (relay *OutboxRelay) Stop() {
+	if relay.cancel == nil {
+		return
+	}
+	relay.cancel()
+	relay.wg.Wait()
+	relay.cancel = nil
+}
+
+// runNext relays one outbox entry. It reports whether there was one.
+func (relay *OutboxRelay) runNext() bool {
+	entry, err := relay.outboxEntity.ClaimEntry(outboxLease)
+	if err != nil || entry == nil {
+		return false
+	}
+
+	for _, subscriber := range relay.bus.asyncSubscribers(entry.Event) {
+		_, err := relay.jobService.EnqueueJob(eventJobPrefix+subscriber, map[string]interface{}{
+			"event_id":    entry.ID.Hex(),
+			"event":       string(entry.Event),
+			"payload":     entry.Payload,
+			"occurred_at": entry.OccurredAt.Format(time.RFC3339Nano),
+		}, time.Time{})
+		if err != nil {
+			// The lease expires and the entry is relayed again.
+			logrus.Error(err)
+			return true
+		}
+	}
+
+	if err := relay.outboxEntity.DeleteEntry(entry.ID, entry.LeaseID); err != nil {
+		logrus.Error(err)
+	}
+
+	return true
+}
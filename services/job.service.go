@@ -0,0 +1,306 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/repositories"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultJobAttempts       = 5
+	defaultJobBackoffSeconds = 30
+	defaultJobTimeoutSeconds = 60
+
+	// jobLease outlasts the longest allowed job timeout.
+	jobLease      = 10 * time.Minute
+	maxJobTimeout = 5 * time.Minute
+	jobPollPeriod = time.Second
+)
+
+// JobHandler runs a job with its payload. Returning an error fails the attempt.
+// Handlers must return when ctx is done.
+type JobHandler func(ctx context.Context, payload map[string]interface{}) error
+
+// JobOptions configures a job type. Concurrency limits how many jobs of the
+// type run at the same time across all servers; zero means no limit beyond the
+// size of the worker pools.
+type JobOptions struct {
+	Concurrency    int
+	MaxAttempts    int
+	BackoffSeconds int
+	Timeout        time.Duration
+}
+
+type jobType struct {
+	handler JobHandler
+	options JobOptions
+}
+
+var (
+	jobTypes      = map[string]jobType{}
+	jobTypesMutex sync.RWMutex
+)
+
+// RegisterJobHandler makes a job type available to the job queue. Job types
+// are registered at startup, before the queue starts.
+func RegisterJobHandler(name string, handler JobHandler, options JobOptions) {
+	if options.MaxAttempts < 1 {
+		options.MaxAttempts = defaultJobAttempts
+	}
+	if options.BackoffSeconds < 1 {
+		options.BackoffSeconds = defaultJobBackoffSeconds
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = defaultJobTimeoutSeconds * time.Second
+	}
+	if options.Timeout > maxJobTimeout {
+		options.Timeout = maxJobTimeout
+	}
+
+	jobTypesMutex.Lock()
+	defer jobTypesMutex.Unlock()
+	jobTypes[name] = jobType{handler: handler, options: options}
+}
+
+func findJobType(name string) (jobType, bool) {
+	jobTypesMutex.RLock()
+	defer jobTypesMutex.RUnlock()
+	registered, ok := jobTypes[name]
+	return registered, ok
+}
+
+var JobService IJobService
+
+type jobService struct {
+	jobEntity repositories.IJob
+}
+
+type IJobService interface {
+	EnqueueJob(jobType string, payload map[string]interface{}, scheduledAt time.Time) (*string, error)
+	GetJobs(status models.JobStatus, jobType string) ([]models.Job, error)
+	GetJobByID(jobID string) (*models.Job, error)
+	GetDeadLetterJobs(jobType string) ([]models.Job, error)
+	RetryJob(jobID string) (*models.Job, error)
+}
+
+func NewJobService(resource *databases.Resource) *jobService {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &jobService{}
+	}
+	return &jobService{
+		jobEntity: repositories.NewJobEntity(resource),
+	}
+}
+
+// EnqueueJob stores a job of a registered type to run at scheduledAt, or right
+// away when scheduledAt is zero.
+func (service *jobService) EnqueueJob(jobType string, payload map[string]interface{}, scheduledAt time.Time) (*string, error) {
+	registered, ok := findJobType(jobType)
+	if !ok {
+		return nil, errors.New("unknown job type: " + jobType)
+	}
+
+	job := models.Job{
+		Type:           jobType,
+		Payload:        payload,
+		MaxAttempts:    registered.options.MaxAttempts,
+		BackoffSeconds: registered.options.BackoffSeconds,
+		ScheduledAt:    scheduledAt,
+	}
+
+	insertedID, err := service.jobEntity.EnqueueJob(job)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return insertedID, nil
+}
+
+func (service *jobService) GetJobs(status models.JobStatus, jobType string) ([]models.Job, error) {
+	jobs, err := service.jobEntity.FindJobs(status, jobType)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (service *jobService) GetJobByID(jobID string) (*models.Job, error) {
+	job, err := service.jobEntity.FindJobByID(jobID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (service *jobService) GetDeadLetterJobs(jobType string) ([]models.Job, error) {
+	jobs, err := service.jobEntity.FindDeadLetterJobs(jobType)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (service *jobService) RetryJob(jobID string) (*models.Job, error) {
+	job, err := service.jobEntity.RetryDeadLetterJob(jobID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// JobQueue runs queued jobs with a fixed pool of goroutines, respecting the
+// concurrency limit of every job type.
+type JobQueue struct {
+	jobEntity repositories.IJob
+	workers   int
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+func NewJobQueue(resource *databases.Resource, workers int) *JobQueue {
+	if workers < 1 {
+		workers = 1
+	}
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &JobQueue{workers: workers}
+	}
+	return &JobQueue{
+		jobEntity: repositories.NewJobEntity(resource),
+		workers:   workers,
+	}
+}
+
+func (queue *JobQueue) Start() {
+	if queue.jobEntity == nil || queue.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	queue.cancel = cancel
+	for i := 0; i < queue.workers; i++ {
+		queue.wg.Add(1)
+		go func() {
+			defer queue.wg.Done()
+			queue.run(ctx)
+		}()
+	}
+}
+
+// Stop waits for running jobs to finish. Jobs interrupted by the shutdown are
+// claimed again once their lease expires.
+func (queue *JobQueue) Stop() {
+	if queue.cancel == nil {
+		return
+	}
+	queue.cancel()
+	queue.wg.Wait()
+	queue.cancel = nil
+}
+
+func (queue *JobQueue) run(ctx context.Context) {
+	for {
+		if !queue.runNext(ctx) {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(jobPollPeriod):
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// claim leases a job of a registered type. The limits of the job types are
+// enforced by the claim, so they hold across servers.
+func (queue *JobQueue) claim() (*models.Job, *jobType) {
+	limits := map[string]int{}
+	jobTypesMutex.RLock()
+	for name, registered := range jobTypes {
+		limits[name] = registered.options.Concurrency
+	}
+	jobTypesMutex.RUnlock()
+
+	job, err := queue.jobEntity.ClaimJob(limits, jobLease)
+	if err != nil || job == nil {
+		return nil, nil
+	}
+
+	registered, ok := findJobType(job.Type)
+	if !ok {
+		return nil, nil
+	}
+
+	return job, &registered
+}
+
+// runNext claims and runs one due job. It reports whether a job was run.
+func (queue *JobQueue) runNext(ctx context.Context) bool {
+	job, registered := queue.claim()
+	if job == nil {
+		return false
+	}
+
+	if job.Attempts > job.MaxAttempts {
+		// The lease of the last attempt expired before the job finished.
+		if err := queue.jobEntity.FailJob(job.ID, job.LeaseID, errors.New("job lease expired")); err != nil {
+			logrus.Error(err)
+		}
+		return true
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, registered.options.Timeout)
+	defer cancel()
+	failure := runJobHandler(runCtx, registered.handler, job.Payload)
+	if ctx.Err() != nil {
+		// Leave the lease to expire so the job runs again after the restart.
+		return true
+	}
+
+	var err error
+	if failure != nil {
+		logrus.Warn("job " + job.ID.Hex() + " of type " + job.Type + " failed: " + failure.Error())
+		err = queue.jobEntity.FailJob(job.ID, job.LeaseID, failure)
+	} else {
+		err = queue.jobEntity.CompleteJob(job.ID, job.LeaseID)
+	}
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	return true
+}
+
+// runJobHandler runs a handler until it returns, so the job holds its lease
+// and counts against the limit of its type for as long as it runs. A handler
+// that panics or overruns ctx fails the attempt.
+func runJobHandler(ctx context.Context, handler JobHandler, payload map[string]interface{}) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("handler panicked: %v", recovered)
+		}
+	}()
+
+	err = handler(ctx, payload)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return errors.New("handler timed out")
+	}
+	return err
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/repositories"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NotificationJob delivers a notification to a user or to every user with a
+// role. The payload holds the recipient as "username" or "role", a "subject"
+// and the workflow, task and comment it is about.
+const NotificationJob = "notification"
+
+func init() {
+	RegisterJobHandler(NotificationJob, deliverNotification, JobOptions{MaxAttempts: 5})
+}
+
+// deliverNotification adds the notification to the feed of every recipient.
+func deliverNotification(ctx context.Context, payload map[string]interface{}) error {
+	if repositories.NotificationEntity == nil || repositories.UserEntity == nil {
+		return errors.New("notifications are not available")
+	}
+
+	recipients := []string{}
+	if username, _ := payload["username"].(string); username != "" {
+		recipients = append(recipients, username)
+	} else if role, _ := payload["role"].(string); role != "" {
+		usernames, err := repositories.UserEntity.WithContext(ctx).FindUsernamesByRole(models.UserRole(role))
+		if err != nil {
+			return err
+		}
+		recipients = usernames
+	}
+
+	subject, _ := payload["subject"].(string)
+	notifications := make([]models.Notification, 0, len(recipients))
+	for _, username := range recipients {
+		notifications = append(notifications, models.Notification{
+			Username:   username,
+			Subject:    subject,
+			WorkflowID: payloadObjectID(payload, "workflow_id"),
+			TaskID:     payloadObjectID(payload, "task_id"),
+			CommentID:  payloadObjectID(payload, "comment_id"),
+		})
+	}
+
+	return repositories.NotificationEntity.CreateNotifications(notifications)
+}
+
+// payloadObjectID reads an optional ObjectID from a job payload.
+func payloadObjectID(payload map[string]interface{}, key string) *primitive.ObjectID {
+	hex, _ := payload[key].(string)
+	objectID, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return nil
+	}
+	return &objectID
+}
+
+var NotificationService INotificationService
+
+type notificationService struct {
+	notificationEntity repositories.INotification
+}
+
+type INotificationService interface {
+	GetNotifications(user models.JWTUser, unreadOnly bool) ([]models.Notification, error)
+	ReadNotification(user models.JWTUser, notificationID string) (*models.Notification, error)
+}
+
+func NewNotificationService(resource *databases.Resource) *notificationService {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &notificationService{}
+	}
+	return &notificationService{
+		notificationEntity: repositories.NewNotificationEntity(resource),
+	}
+}
+
+func (service *notificationService) GetNotifications(user models.JWTUser, unreadOnly bool) ([]models.Notification, error) {
+	notifications, err := service.notificationEntity.FindNotificationsByUsername(user.Username, unreadOnly)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+func (service *notificationService) ReadNotification(user models.JWTUser, notificationID string) (*models.Notification, error) {
+	notification, err := service.notificationEntity.MarkNotificationRead(user.Username, notificationID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return notification, nil
+}
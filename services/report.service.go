@@ -0,0 +1,188 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"virtual_workflow_management_system_gin/common"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/repositories"
+	"virtual_workflow_management_system_gin/requests"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultReportRange = 90 * 24 * time.Hour
+	maxReportRange     = 366 * 24 * time.Hour
+	defaultReportLimit = 10
+)
+
+type reportService struct {
+	reportEntity   repositories.IReport
+	workflowEntity repositories.IWorkflow
+}
+
+type IReportService interface {
+	ExportWorkflowTasks(workflowID string, writer common.TableWriter) error
+	ExportTasks(user models.JWTUser, writer common.TableWriter) error
+	GetLeadTime(user models.JWTUser, req requests.ReportRequest) (*models.LeadTimeReport, error)
+	GetCycleTimes(user models.JWTUser, req requests.ReportRequest) ([]models.CycleTime, error)
+	GetThroughput(user models.JWTUser, req requests.ReportRequest) ([]models.WeeklyThroughput, error)
+	GetSlowestSteps(workflowID string, req requests.ReportRequest) ([]models.StepDuration, error)
+}
+
+func NewReportService(resource *databases.Resource) *reportService {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &reportService{}
+	}
+	return &reportService{
+		reportEntity:   repositories.NewReportEntity(resource),
+		workflowEntity: repositories.NewWorkflowEntity(resource),
+	}
+}
+
+// ExportWorkflowTasks writes the tasks of a workflow to writer, header first.
+// The writer is left open.
+func (service *reportService) ExportWorkflowTasks(workflowID string, writer common.TableWriter) error {
+	return service.exportTasks(workflowID, "", writer)
+}
+
+// ExportTasks writes the tasks of every workflow the user may see to writer,
+// header first. The writer is left open.
+func (service *reportService) ExportTasks(user models.JWTUser, writer common.TableWriter) error {
+	return service.exportTasks("", reportOwner(user), writer)
+}
+
+// reportOwner is the owner whose workflows the reports of the user cover, or
+// empty for every owner.
+func reportOwner(user models.JWTUser) string {
+	if models.CanSeeAllWorkflows(user) {
+		return ""
+	}
+	return user.Username
+}
+
+// exportTasks writes the header once the query has succeeded, so a failing
+// query leaves the writer untouched and the caller free to report the error.
+func (service *reportService) exportTasks(workflowID string, owner string, writer common.TableWriter) error {
+	headerWritten := false
+	writeHeader := func() error {
+		if headerWritten {
+			return nil
+		}
+		headerWritten = true
+		return writer.Write(models.TaskRowHeader)
+	}
+
+	err := service.reportEntity.StreamTaskRows(workflowID, owner, func(row models.TaskRow) error {
+		if err := writeHeader(); err != nil {
+			return err
+		}
+		return writer.Write(row.Record())
+	})
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return writeHeader()
+}
+
+// reportFilter reads the range and team of a report. The range ends now and
+// spans 90 days unless told otherwise.
+func reportFilter(owner string, req requests.ReportRequest) (models.ReportFilter, error) {
+	to := time.Now()
+	if req.To != nil {
+		to = *req.To
+	}
+	from := to.Add(-defaultReportRange)
+	if req.From != nil {
+		from = *req.From
+	}
+
+	if !from.Before(to) {
+		return models.ReportFilter{}, errors.New("from must be before to")
+	}
+	if to.Sub(from) > maxReportRange {
+		return models.ReportFilter{}, errors.New("report range is longer than a year")
+	}
+
+	return models.ReportFilter{From: from, To: to, Owner: owner, Team: strings.TrimSpace(req.Team)}, nil
+}
+
+func reportLimit(req requests.ReportRequest) int {
+	if req.Limit == 0 {
+		return defaultReportLimit
+	}
+	return req.Limit
+}
+
+// GetLeadTime summarizes how long the workflows the user may see took from
+// their creation until they were done, over the workflows done in the range.
+func (service *reportService) GetLeadTime(user models.JWTUser, req requests.ReportRequest) (*models.LeadTimeReport, error) {
+	filter, err := reportFilter(reportOwner(user), req)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := service.workflowEntity.LeadTime(filter)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// GetCycleTimes summarizes the cycle times of the tasks the user may see,
+// per task name, slowest first.
+func (service *reportService) GetCycleTimes(user models.JWTUser, req requests.ReportRequest) ([]models.CycleTime, error) {
+	filter, err := reportFilter(reportOwner(user), req)
+	if err != nil {
+		return nil, err
+	}
+
+	cycleTimes, err := service.workflowEntity.CycleTimes(filter, reportLimit(req))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return cycleTimes, nil
+}
+
+// GetThroughput counts the tasks the user may see completed per week of the
+// range, including the weeks without completions.
+func (service *reportService) GetThroughput(user models.JWTUser, req requests.ReportRequest) ([]models.WeeklyThroughput, error) {
+	filter, err := reportFilter(reportOwner(user), req)
+	if err != nil {
+		return nil, err
+	}
+
+	weeks, err := service.workflowEntity.WeeklyThroughput(filter)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return models.FillThroughputWeeks(weeks, filter.From, filter.To), nil
+}
+
+// GetSlowestSteps lists the steps of a workflow whose tasks took longest to be
+// done across the runs of the workflow created in the range.
+func (service *reportService) GetSlowestSteps(workflowID string, req requests.ReportRequest) ([]models.StepDuration, error) {
+	filter, err := reportFilter("", req)
+	if err != nil {
+		return nil, err
+	}
+
+	steps, err := service.workflowEntity.SlowestSteps(workflowID, filter, reportLimit(req))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return steps, nil
+}
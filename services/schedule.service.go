@@ -0,0 +1,290 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/repositories"
+	"virtual_workflow_management_system_gin/requests"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	scheduleLease      = 5 * time.Minute
+	schedulePollPeriod = 15 * time.Second
+)
+
+var ScheduleService IScheduleService
+
+type scheduleService struct {
+	scheduleEntity repositories.ISchedule
+	workflowEntity repositories.IWorkflow
+}
+
+type IScheduleService interface {
+	GetSchedules(workflowID string) ([]models.Schedule, error)
+	GetScheduleByID(scheduleID string) (*models.Schedule, error)
+	CreateSchedule(workflowID string, username string, req requests.CreateScheduleRequest) (*string, error)
+	PauseSchedule(scheduleID string) (*models.Schedule, error)
+	ResumeSchedule(scheduleID string) (*models.Schedule, error)
+	DeleteSchedule(scheduleID string) error
+	GetUpcomingRuns(scheduleID string, count int) ([]time.Time, error)
+}
+
+func NewScheduleService(resource *databases.Resource) *scheduleService {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &scheduleService{}
+	}
+	return &scheduleService{
+		scheduleEntity: repositories.NewScheduleEntity(resource),
+		workflowEntity: repositories.NewWorkflowEntity(resource),
+	}
+}
+
+func (service *scheduleService) GetSchedules(workflowID string) ([]models.Schedule, error) {
+	schedules, err := service.scheduleEntity.FindSchedulesByWorkflowID(workflowID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+func (service *scheduleService) GetScheduleByID(scheduleID string) (*models.Schedule, error) {
+	schedule, err := service.scheduleEntity.FindScheduleByID(scheduleID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+func (service *scheduleService) CreateSchedule(workflowID string, username string, req requests.CreateScheduleRequest) (*string, error) {
+	workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("invalid ObjectID format")
+	}
+
+	scheduleModel := models.Schedule{
+		WorkflowID: workflowObjectID,
+		Owner:      username,
+		Expression: req.Expression,
+		Timezone:   req.Timezone,
+		StartAt:    req.StartAt,
+		EndAt:      req.EndAt,
+		CatchUp:    req.CatchUp,
+		Runs:       []models.ScheduleRun{},
+	}
+	if scheduleModel.Timezone == "" {
+		scheduleModel.Timezone = "UTC"
+	}
+	if scheduleModel.CatchUp == "" {
+		scheduleModel.CatchUp = models.CatchUpSkip
+	}
+
+	if err := scheduleModel.Validate(); err != nil {
+		return nil, err
+	}
+
+	scheduleModel.Reschedule(time.Now())
+	if scheduleModel.NextRunAt == nil {
+		return nil, errors.New("schedule has no upcoming runs")
+	}
+
+	insertedID, err := service.scheduleEntity.CreateSchedule(scheduleModel)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return insertedID, nil
+}
+
+func (service *scheduleService) PauseSchedule(scheduleID string) (*models.Schedule, error) {
+	schedule, err := service.scheduleEntity.FindScheduleByID(scheduleID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	updatedSchedule, err := service.scheduleEntity.SetSchedulePaused(scheduleID, true, schedule.NextRunAt)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return updatedSchedule, nil
+}
+
+// ResumeSchedule continues with the next occurrence from now on; occurrences
+// that fell into the pause are not caught up.
+func (service *scheduleService) ResumeSchedule(scheduleID string) (*models.Schedule, error) {
+	schedule, err := service.scheduleEntity.FindScheduleByID(scheduleID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	schedule.Reschedule(time.Now())
+
+	updatedSchedule, err := service.scheduleEntity.SetSchedulePaused(scheduleID, false, schedule.NextRunAt)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return updatedSchedule, nil
+}
+
+func (service *scheduleService) DeleteSchedule(scheduleID string) error {
+	err := service.scheduleEntity.DeleteSchedule(scheduleID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (service *scheduleService) GetUpcomingRuns(scheduleID string, count int) ([]time.Time, error) {
+	schedule, err := service.scheduleEntity.FindScheduleByID(scheduleID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	if count == 0 {
+		count = models.DefaultUpcomingRuns
+	}
+
+	return schedule.Upcoming(time.Now(), count), nil
+}
+
+// Scheduler creates the workflow copies of due schedules. A schedule is leased
+// while its copies are created, so several servers can run a scheduler. If a
+// server stops half way the lease expires and the run is repeated, so a copy
+// may be created twice but never skipped.
+type Scheduler struct {
+	scheduleEntity repositories.ISchedule
+	workflowEntity repositories.IWorkflow
+	events         *eventPublisher
+	cancel         context.CancelFunc
+	wg             sync.WaitGroup
+}
+
+func NewScheduler(resource *databases.Resource) *Scheduler {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &Scheduler{}
+	}
+	return &Scheduler{
+		scheduleEntity: repositories.NewScheduleEntity(resource),
+		workflowEntity: repositories.NewWorkflowEntity(resource),
+		events:         newEventPublisher(resource),
+	}
+}
+
+func (scheduler *Scheduler) Start() {
+	if scheduler.scheduleEntity == nil || scheduler.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	scheduler.cancel = cancel
+	scheduler.wg.Add(1)
+	go func() {
+		defer scheduler.wg.Done()
+		for {
+			for ctx.Err() == nil && scheduler.runNext() {
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(schedulePollPeriod):
+			}
+		}
+	}()
+}
+
+func (scheduler *Scheduler) Stop() {
+	if scheduler.cancel == nil {
+		return
+	}
+	scheduler.cancel()
+	scheduler.wg.Wait()
+	scheduler.cancel = nil
+}
+
+// runNext runs one due schedule. It reports whether a schedule was due.
+func (scheduler *Scheduler) runNext() bool {
+	schedule, err := scheduler.scheduleEntity.ClaimDueSchedule(scheduleLease)
+	if err != nil || schedule == nil {
+		return false
+	}
+
+	runs := []models.ScheduleRun{}
+	occurrences := schedule.DueRuns(time.Now())
+	if len(occurrences) > 0 {
+		workflow, err := scheduler.workflowEntity.FindWorkflowByID(schedule.WorkflowID.Hex())
+		if err != nil {
+			logrus.Error(err)
+			occurrences = nil
+		}
+		for _, occurrence := range occurrences {
+			run, err := scheduler.createRun(*workflow, schedule, occurrence)
+			if err != nil {
+				logrus.Error(err)
+				continue
+			}
+			runs = append(runs, *run)
+		}
+	}
+
+	err = scheduler.scheduleEntity.RecordScheduleRuns(schedule.ID, schedule.LeaseID, runs, schedule.NextRunAt)
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	return true
+}
+
+// createRun copies the workflow with fresh pending tasks, named after the
+// occurrence in the time zone of the schedule.
+func (scheduler *Scheduler) createRun(workflow models.Workflow, schedule *models.Schedule, occurrence time.Time) (*models.ScheduleRun, error) {
+	template := models.NewTemplateFromWorkflow(workflow)
+	name := workflow.Name + " " + occurrence.Format("2006-01-02 15:04")
+	run, err := template.Instantiate(name, schedule.Owner, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The schedule starts the runs it creates.
+	startedAt := time.Now()
+	run.StartedAt = &startedAt
+
+	err = scheduler.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		insertedID, err := scheduler.workflowEntity.WithContext(ctx).CreateWorkflow(*run)
+		if err != nil {
+			return err
+		}
+
+		run.ID, err = primitive.ObjectIDFromHex(*insertedID)
+		if err != nil {
+			return err
+		}
+
+		events.record(models.WorkflowCreated{WorkflowScope: models.WorkflowScope{WorkflowID: *insertedID}, Workflow: *run})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ScheduleRun{WorkflowID: run.ID, ScheduledAt: occurrence, CreatedAt: time.Now()}, nil
+}
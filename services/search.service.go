@@ -0,0 +1,119 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/repositories"
+	"virtual_workflow_management_system_gin/requests"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultSearchLimit = 20
+	// maxSearchCandidates bounds the workflows and commented tasks the text
+	// indexes return before hits are ranked and filtered.
+	maxSearchCandidates = 200
+)
+
+type searchService struct {
+	searchEntity repositories.ISearch
+}
+
+type ISearchService interface {
+	Search(user models.JWTUser, req requests.SearchRequest) (*models.SearchResult, error)
+}
+
+func NewSearchService(resource *databases.Resource) *searchService {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &searchService{}
+	}
+	return &searchService{
+		searchEntity: repositories.NewSearchEntity(resource),
+	}
+}
+
+// EnsureIndexes creates the text indexes search depends on.
+func (service *searchService) EnsureIndexes() error {
+	if service.searchEntity == nil {
+		return errors.New("search is not available")
+	}
+	return service.searchEntity.EnsureIndexes()
+}
+
+// Search ranks the workflows and tasks the user may see against the query.
+// Facets count the hits before the label and status filters apply, so they
+// show how a filter would narrow the result.
+func (service *searchService) Search(user models.JWTUser, req requests.SearchRequest) (*models.SearchResult, error) {
+	owner := user.Username
+	if models.CanSeeAllWorkflows(user) {
+		owner = ""
+	}
+
+	workflows, err := service.searchEntity.SearchWorkflows(req.Query, owner, maxSearchCandidates)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	comments, err := service.searchEntity.SearchComments(req.Query, maxSearchCandidates)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	// Comments may match in workflows whose own text does not.
+	found := map[primitive.ObjectID]bool{}
+	for _, match := range workflows {
+		found[match.ID] = true
+	}
+	missing := []primitive.ObjectID{}
+	for _, comment := range comments {
+		if !found[comment.WorkflowID] {
+			found[comment.WorkflowID] = true
+			missing = append(missing, comment.WorkflowID)
+		}
+	}
+	if len(missing) > 0 {
+		commented, err := service.searchEntity.FindWorkflowsByIDs(missing, owner)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		for _, workflow := range commented {
+			workflows = append(workflows, models.WorkflowMatch{Workflow: workflow})
+		}
+	}
+
+	visible := []models.WorkflowMatch{}
+	for _, match := range workflows {
+		if match.CheckWorkflowAccess(user, "delete") {
+			visible = append(visible, match)
+		}
+	}
+
+	workflowHits, taskHits := models.BuildSearchHits(models.SearchTerms(req.Query), visible, comments)
+	facets := models.CountSearchFacets(workflowHits, taskHits)
+
+	labels := make([]string, 0, len(req.Labels))
+	for _, label := range req.Labels {
+		labels = append(labels, strings.ToLower(strings.TrimSpace(label)))
+	}
+	workflowHits, taskHits = models.FilterSearchHits(workflowHits, taskHits, labels, req.Statuses)
+
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+	if len(workflowHits) > limit {
+		workflowHits = workflowHits[:limit]
+	}
+	if len(taskHits) > limit {
+		taskHits = taskHits[:limit]
+	}
+
+	return &models.SearchResult{Workflows: workflowHits, Tasks: taskHits, Facets: facets}, nil
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/repositories"
+	"virtual_workflow_management_system_gin/requests"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	escalationLease = time.Minute
+	slaPollPeriod   = 30 * time.Second
+)
+
+// SLAMonitor runs the escalation steps of tasks that breached their SLA.
+// Notifications are queued as jobs, so the job queue has to run as well.
+type SLAMonitor struct {
+	workflowEntity repositories.IWorkflow
+	events         *eventPublisher
+	jobService     IJobService
+	cancel         context.CancelFunc
+	wg             sync.WaitGroup
+}
+
+func NewSLAMonitor(resource *databases.Resource) *SLAMonitor {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &SLAMonitor{}
+	}
+	return &SLAMonitor{
+		workflowEntity: repositories.NewWorkflowEntity(resource),
+		events:         newEventPublisher(resource),
+		jobService:     NewJobService(resource),
+	}
+}
+
+func (monitor *SLAMonitor) Start() {
+	if monitor.workflowEntity == nil || monitor.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	monitor.cancel = cancel
+	monitor.wg.Add(1)
+	go func() {
+		defer monitor.wg.Done()
+		for {
+			for ctx.Err() == nil && monitor.runNext() {
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(slaPollPeriod):
+			}
+		}
+	}()
+}
+
+func (monitor *SLAMonitor) Stop() {
+	if monitor.cancel == nil {
+		return
+	}
+	monitor.cancel()
+	monitor.wg.Wait()
+	monitor.cancel = nil
+}
+
+// runNext escalates one task. It reports whether a task was due.
+func (monitor *SLAMonitor) runNext() bool {
+	workflow, task, err := monitor.workflowEntity.ClaimEscalatingTask(escalationLease)
+	if err != nil || task == nil {
+		return false
+	}
+
+	var escalatedTask *models.Task
+	var escalations []models.Escalation
+	workflowID := workflow.ID.Hex()
+	err = monitor.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		var err error
+		escalatedTask, escalations, err = monitor.workflowEntity.WithContext(ctx).EscalateTask(workflowID, task.ID.Hex(), task.SLA.LeaseID)
+		if err != nil {
+			return err
+		}
+
+		if len(escalations) > 0 {
+			events.record(models.TaskEscalated{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Task: *escalatedTask, Escalations: escalations})
+		}
+		return nil
+	})
+	if err != nil {
+		// The lease expires and the task is escalated on a later run.
+		logrus.Error(err)
+		return true
+	}
+
+	for _, escalation := range escalations {
+		monitor.notify(workflow, escalatedTask, escalation)
+	}
+
+	return true
+}
+
+func (monitor *SLAMonitor) notify(workflow *models.Workflow, task *models.Task, escalation models.Escalation) {
+	subject := "Task \"" + task.Name + "\" in workflow \"" + workflow.Name + "\" is overdue"
+	if escalation.Action == models.ReassignToRole {
+		subject = "Overdue task \"" + task.Name + "\" in workflow \"" + workflow.Name + "\" was reassigned to your role"
+	}
+
+	payload := map[string]interface{}{
+		"subject":     subject,
+		"workflow_id": workflow.ID.Hex(),
+		"task_id":     task.ID.Hex(),
+	}
+	switch {
+	case escalation.Username != "":
+		payload["username"] = escalation.Username
+	case escalation.Role != "":
+		payload["role"] = string(escalation.Role)
+	default:
+		// Nobody is assigned to the task, so there is nobody to notify.
+		return
+	}
+
+	if _, err := monitor.jobService.EnqueueJob(NotificationJob, payload, time.Time{}); err != nil {
+		logrus.Error(err)
+	}
+}
+
+func newSLA(req requests.SLARequest) (*models.SLA, error) {
+	sla := &models.SLA{
+		DurationSeconds: req.DurationSeconds,
+		AtRiskPercent:   req.AtRiskPercent,
+		Steps:           []models.EscalationStep{},
+		Escalations:     []models.Escalation{},
+	}
+	if sla.AtRiskPercent == 0 {
+		sla.AtRiskPercent = models.DefaultAtRiskPercent
+	}
+	for _, step := range req.Escalation {
+		sla.Steps = append(sla.Steps, models.EscalationStep{
+			Action:       step.Action,
+			AfterSeconds: step.AfterSeconds,
+			Role:         step.Role,
+		})
+	}
+
+	if err := sla.Validate(); err != nil {
+		return nil, err
+	}
+
+	return sla, nil
+}
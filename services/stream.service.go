@@ -0,0 +1,43 @@
+package services
+
+import (
+	"errors"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/middlewares"
+	"virtual_workflow_management_system_gin/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+var StreamService IStreamService
+
+type streamService struct {
+	redis middlewares.RedisClientInterface
+}
+
+type IStreamService interface {
+	IssueStreamTicket(user models.JWTUser, workflowID string) (string, error)
+}
+
+func NewStreamService(resource *databases.Resource) *streamService {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &streamService{}
+	}
+	return &streamService{
+		redis: resource.Redis,
+	}
+}
+
+func (service *streamService) IssueStreamTicket(user models.JWTUser, workflowID string) (string, error) {
+	if service.redis == nil {
+		return "", errors.New("event streams are not available")
+	}
+
+	ticket, err := middlewares.IssueStreamTicket(user, workflowID, service.redis)
+	if err != nil {
+		logrus.Error(err)
+		return "", err
+	}
+
+	return ticket, nil
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/repositories"
+	"virtual_workflow_management_system_gin/requests"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var TemplateService ITemplateService
+
+type templateService struct {
+	templateEntity repositories.ITemplate
+	workflowEntity repositories.IWorkflow
+	events         *eventPublisher
+}
+
+type ITemplateService interface {
+	GetTemplates(user models.JWTUser) ([]models.Template, error)
+	GetTemplateByID(templateID string) (*models.Template, error)
+	CreateTemplate(user models.JWTUser, req requests.CreateTemplateRequest) (*string, error)
+	DeleteTemplateByID(templateID string) error
+	InstantiateTemplate(templateID string, username string, req requests.InstantiateTemplateRequest) (*string, error)
+	SaveWorkflowAsTemplate(workflowID string, user models.JWTUser, req requests.SaveWorkflowAsTemplateRequest) (*string, error)
+}
+
+func NewTemplateService(resource *databases.Resource) ITemplateService {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &templateService{}
+	}
+	TemplateService = &templateService{
+		templateEntity: repositories.NewTemplateEntity(resource),
+		workflowEntity: repositories.NewWorkflowEntity(resource),
+		events:         newEventPublisher(resource),
+	}
+	return TemplateService
+}
+
+func (service *templateService) GetTemplates(user models.JWTUser) ([]models.Template, error) {
+	templates, err := service.templateEntity.FindTemplatesForUser(user)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+func (service *templateService) GetTemplateByID(templateID string) (*models.Template, error) {
+	template, err := service.templateEntity.FindTemplateByID(templateID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return template, nil
+}
+
+func (service *templateService) CreateTemplate(user models.JWTUser, req requests.CreateTemplateRequest) (*string, error) {
+	organization, err := templateOrganization(user, req.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	parameters := make([]models.TemplateParameter, 0, len(req.Parameters))
+	for _, parameter := range req.Parameters {
+		parameters = append(parameters, models.TemplateParameter{
+			Name:        parameter.Name,
+			Description: parameter.Description,
+			Default:     parameter.Default,
+			Required:    parameter.Required,
+		})
+	}
+
+	tasks := make([]models.TemplateTask, 0, len(req.Tasks))
+	for i, task := range req.Tasks {
+		dependsOn := task.DependsOn
+		if dependsOn == nil {
+			dependsOn = []string{}
+		}
+		templateTask := models.TemplateTask{
+			Key:         task.Key,
+			Name:        task.Name,
+			Description: task.Description,
+			Order:       i + 1,
+			DependsOn:   dependsOn,
+			Type:        models.UserTask,
+		}
+		if task.Type == models.GatewayTask {
+			templateTask.Type = models.GatewayTask
+			templateTask.Gateway = &models.TemplateGateway{Kind: task.Gateway.Kind, Flows: []models.TemplateGatewayFlow{}}
+			for _, flow := range task.Gateway.Flows {
+				templateTask.Gateway.Flows = append(templateTask.Gateway.Flows, models.TemplateGatewayFlow{Target: flow.Target, Condition: flow.Condition})
+			}
+		}
+		if task.Type == models.ApprovalTask {
+			approval, err := newApproval(*task.Approval)
+			if err != nil {
+				return nil, err
+			}
+			templateTask.Type = models.ApprovalTask
+			templateTask.Approval = approval
+		}
+		if task.Type == models.AutomatedTask {
+			automation, err := newAutomation(*task.Automation)
+			if err != nil {
+				return nil, err
+			}
+			templateTask.Type = models.AutomatedTask
+			templateTask.Automation = automation
+		}
+		templateTask.Form = newFieldDefinitions(task.Form)
+		if task.SLA != nil {
+			sla, err := newSLA(*task.SLA)
+			if err != nil {
+				return nil, err
+			}
+			templateTask.SLA = sla
+		}
+		tasks = append(tasks, templateTask)
+	}
+
+	templateModel := models.Template{
+		Name:                req.Name,
+		Description:         req.Description,
+		Scope:               req.Scope,
+		Organization:        organization,
+		Owner:               user.Username,
+		Parameters:          parameters,
+		Tasks:               tasks,
+		VariableDefinitions: newFieldDefinitions(req.Variables),
+	}
+
+	if err := templateModel.ValidateTasks(); err != nil {
+		return nil, err
+	}
+
+	insertedID, err := service.templateEntity.CreateTemplate(templateModel)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return insertedID, nil
+}
+
+func (service *templateService) DeleteTemplateByID(templateID string) error {
+	if err := service.templateEntity.DeleteTemplate(templateID); err != nil {
+		logrus.Error(err)
+		return err
+	}
+	return nil
+}
+
+func (service *templateService) InstantiateTemplate(templateID string, username string, req requests.InstantiateTemplateRequest) (*string, error) {
+	template, err := service.templateEntity.FindTemplateByID(templateID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	workflow, err := template.Instantiate(req.Name, username, req.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tasks are embedded in the workflow document, so a single insert creates
+	// the workflow together with all of its tasks and dependencies atomically.
+	var insertedID *string
+	err = service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		var err error
+		insertedID, err = service.workflowEntity.WithContext(ctx).CreateWorkflow(*workflow)
+		if err != nil {
+			return err
+		}
+
+		workflow.ID, _ = primitive.ObjectIDFromHex(*insertedID)
+		events.record(models.WorkflowCreated{WorkflowScope: models.WorkflowScope{WorkflowID: *insertedID}, Workflow: *workflow})
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return insertedID, nil
+}
+
+func (service *templateService) SaveWorkflowAsTemplate(workflowID string, user models.JWTUser, req requests.SaveWorkflowAsTemplateRequest) (*string, error) {
+	organization, err := templateOrganization(user, req.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	workflow, err := service.workflowEntity.FindWorkflowByID(workflowID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	templateModel := models.NewTemplateFromWorkflow(*workflow)
+	templateModel.Name = req.Name
+	templateModel.Description = req.Description
+	templateModel.Scope = req.Scope
+	templateModel.Organization = organization
+	templateModel.Owner = user.Username
+
+	insertedID, err := service.templateEntity.CreateTemplate(templateModel)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return insertedID, nil
+}
+
+func templateOrganization(user models.JWTUser, scope models.TemplateScope) (string, error) {
+	switch scope {
+	case models.GlobalTemplate:
+		if user.Role != models.Admin {
+			return "", errors.New("only admins can create global templates")
+		}
+	case models.OrganizationTemplate:
+		if user.Organization == "" {
+			return "", errors.New("user does not belong to an organization")
+		}
+		return user.Organization, nil
+	}
+
+	return "", nil
+}
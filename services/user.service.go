@@ -1,7 +1,10 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"slices"
+	"strings"
 	"virtual_workflow_management_system_gin/common"
 	"virtual_workflow_management_system_gin/databases"
 	"virtual_workflow_management_system_gin/middlewares"
@@ -20,6 +23,7 @@ var UserService IUserService
 type userService struct {
 	userEntity repositories.IUser
 	redis      *redis.Client
+	events     *eventPublisher
 }
 
 type IUserService interface {
@@ -28,6 +32,7 @@ type IUserService interface {
 	RefreshToken(c *gin.Context, req requests.RefreshTokenRequest)
 	Logout(username string) error
 	GetUsersByUsername(username string) (*models.User, error)
+	UpdateUser(username string, req requests.UpdateUserRequest) (*models.User, error)
 }
 
 func NewUserService(resource *databases.Resource) IUserService {
@@ -37,6 +42,7 @@ func NewUserService(resource *databases.Resource) IUserService {
 	UserService = &userService{
 		userEntity: repositories.NewUserEntity(resource),
 		redis:      resource.Redis,
+		events:     newEventPublisher(resource),
 	}
 	return UserService
 }
@@ -45,10 +51,16 @@ func (service *userService) Register(c *gin.Context, req requests.RegisterReques
 	reqWithHashedPassword := requests.RegisterRequest{
 		Username: req.Username,
 		Password: common.HashPassword(req.Password),
-		Role:     req.Role,
 	}
 
-	_, err := service.userEntity.CreateOne(reqWithHashedPassword)
+	err := service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		user, err := service.userEntity.WithContext(ctx).CreateOne(reqWithHashedPassword)
+		if err != nil {
+			return err
+		}
+		events.record(models.UserRegistered{Username: user.Username, Role: user.Role, Organization: user.Organization, Teams: user.Teams})
+		return nil
+	})
 	if err != nil {
 		logrus.Error(err)
 		responses.Error(c, err.Error())
@@ -82,6 +94,7 @@ func (service *userService) Login(c *gin.Context, req requests.LoginRequest) {
 		responses.Error(c, "failed to generate token")
 		return
 	}
+	service.publish(models.UserLoggedIn{Username: user.Username})
 
 	responses.OkWithData(c, gin.H{
 		"access_token":  jwt["access_token"],
@@ -96,6 +109,9 @@ func (service *userService) RefreshToken(c *gin.Context, req requests.RefreshTok
 		responses.Error(c, "failed to refresh token")
 		return
 	}
+	if claims, err := middlewares.ParseJWTToken(jwt["access_token"]); err == nil {
+		service.publish(models.UserTokenRefreshed{Username: claims.Username})
+	}
 
 	responses.OkWithData(c, gin.H{
 		"access_token":  jwt["access_token"],
@@ -110,10 +126,23 @@ func (service *userService) Logout(username string) error {
 		logrus.Error(err)
 		return errors.New("failed to logout")
 	}
+	service.publish(models.UserLoggedOut{Username: username})
 
 	return nil
 }
 
+// publish publishes an event that comes with no database change. The session
+// already changed in Redis, so a failure is only logged.
+func (service *userService) publish(event models.Event) {
+	err := service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		events.record(event)
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+	}
+}
+
 func (service *userService) GetUsersByUsername(username string) (*models.User, error) {
 	user, err := service.userEntity.FindOneByUsername(username)
 	if err != nil {
@@ -123,3 +152,49 @@ func (service *userService) GetUsersByUsername(username string) (*models.User, e
 
 	return user, nil
 }
+
+// UpdateUser changes the role, organization and teams of a user. They travel
+// in the tokens of the user, so the sessions of the user end and the change
+// applies from their next login.
+func (service *userService) UpdateUser(username string, req requests.UpdateUserRequest) (*models.User, error) {
+	fields := map[string]interface{}{}
+	if req.Role != nil {
+		fields["role"] = *req.Role
+	}
+	if req.Organization != nil {
+		fields["organization"] = strings.TrimSpace(*req.Organization)
+	}
+	if req.Teams != nil {
+		teams := []string{}
+		for _, team := range *req.Teams {
+			if team = strings.TrimSpace(team); team != "" && !slices.Contains(teams, team) {
+				teams = append(teams, team)
+			}
+		}
+		fields["teams"] = teams
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("nothing to update")
+	}
+
+	var updated *models.User
+	err := service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		user, err := service.userEntity.WithContext(ctx).UpdateByUsername(username, fields)
+		if err != nil {
+			return err
+		}
+		events.record(models.UserUpdated{Username: user.Username, Role: user.Role, Organization: user.Organization, Teams: user.Teams})
+		updated = user
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	if err := middlewares.DeleteJWTToken(username, service.redis); err != nil {
+		return nil, errors.New("failed to end the sessions of the user")
+	}
+
+	return updated, nil
+}
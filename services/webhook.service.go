@@ -0,0 +1,416 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+	"virtual_workflow_management_system_gin/common"
+	"virtual_workflow_management_system_gin/databases"
+	"virtual_workflow_management_system_gin/models"
+	"virtual_workflow_management_system_gin/repositories"
+	"virtual_workflow_management_system_gin/requests"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookDeliveryJob posts an event to a webhook. Failed deliveries are retried
+// by the job queue with exponential backoff.
+const WebhookDeliveryJob = "webhook.delivery"
+
+const (
+	webhookTimeout         = 10 * time.Second
+	maxLoggedResponseSize  = 1024
+	webhookSecretBytes     = 32
+	webhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// webhookClient only reaches public addresses, since endpoints and the
+// responses logged with deliveries come from users.
+var webhookClient = common.NewOutboundClient(webhookTimeout)
+
+func init() {
+	RegisterJobHandler(WebhookDeliveryJob, deliverWebhook, JobOptions{MaxAttempts: 8, BackoffSeconds: 30, Timeout: 2 * webhookTimeout})
+	Events.SubscribeAsync("webhooks", dispatchWebhooks, JobOptions{MaxAttempts: 5, BackoffSeconds: 10},
+		models.WorkflowCreated{}.EventName(),
+		models.WorkflowTransferred{}.EventName(),
+		models.TaskCreated{}.EventName(),
+		models.TaskStatusChanged{}.EventName(),
+	)
+}
+
+var WebhookService IWebhookService
+
+type webhookService struct {
+	webhookEntity repositories.IWebhook
+}
+
+type IWebhookService interface {
+	GetWorkflowWebhooks(workflowID string) ([]models.Webhook, error)
+	GetOrganizationWebhooks(organization string) ([]models.Webhook, error)
+	GetWebhookByID(webhookID string) (*models.Webhook, error)
+	CreateWebhook(user models.JWTUser, workflowID string, req requests.CreateWebhookRequest) (*models.Webhook, error)
+	DeleteWebhook(webhookID string) error
+	GetDeliveries(webhookID string) ([]models.WebhookDelivery, error)
+	SendTestEvent(webhookID string) (*models.WebhookDelivery, error)
+}
+
+func NewWebhookService(resource *databases.Resource) *webhookService {
+	if resource == nil || resource.MongoDB == nil || resource.Redis == nil {
+		return &webhookService{}
+	}
+	return &webhookService{
+		webhookEntity: repositories.NewWebhookEntity(resource),
+	}
+}
+
+func (service *webhookService) GetWorkflowWebhooks(workflowID string) ([]models.Webhook, error) {
+	webhooks, err := service.webhookEntity.FindWebhooksByWorkflowID(workflowID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (service *webhookService) GetOrganizationWebhooks(organization string) ([]models.Webhook, error) {
+	webhooks, err := service.webhookEntity.FindWebhooksByOrganization(organization)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (service *webhookService) GetWebhookByID(webhookID string) (*models.Webhook, error) {
+	webhook, err := service.webhookEntity.FindWebhookByID(webhookID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// CreateWebhook subscribes to the events of a workflow or, without a workflow
+// ID, to the events of every workflow owned by the organization of the user.
+// A secret is generated unless the request brings one.
+func (service *webhookService) CreateWebhook(user models.JWTUser, workflowID string, req requests.CreateWebhookRequest) (*models.Webhook, error) {
+	if err := common.CheckOutboundURL(req.URL); err != nil {
+		return nil, err
+	}
+
+	webhookModel := models.Webhook{
+		Owner:  user.Username,
+		URL:    req.URL,
+		Secret: req.Secret,
+		Events: req.Events,
+		Active: true,
+	}
+
+	if workflowID != "" {
+		workflowObjectID, err := primitive.ObjectIDFromHex(workflowID)
+		if err != nil {
+			logrus.Error(err)
+			return nil, errors.New("invalid ObjectID format")
+		}
+		webhookModel.WorkflowID = &workflowObjectID
+	} else {
+		if user.Organization == "" {
+			return nil, errors.New("user does not belong to an organization")
+		}
+		webhookModel.Organization = user.Organization
+	}
+
+	if webhookModel.Secret == "" {
+		secret := make([]byte, webhookSecretBytes)
+		if _, err := rand.Read(secret); err != nil {
+			logrus.Error(err)
+			return nil, errors.New("failed to generate webhook secret")
+		}
+		webhookModel.Secret = hex.EncodeToString(secret)
+	}
+
+	insertedID, err := service.webhookEntity.CreateWebhook(webhookModel)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	webhookModel.ID, _ = primitive.ObjectIDFromHex(*insertedID)
+
+	return &webhookModel, nil
+}
+
+func (service *webhookService) DeleteWebhook(webhookID string) error {
+	err := service.webhookEntity.DeleteWebhook(webhookID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (service *webhookService) GetDeliveries(webhookID string) ([]models.WebhookDelivery, error) {
+	deliveries, err := service.webhookEntity.FindDeliveries(webhookID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// SendTestEvent delivers a webhook.test event right away, without retries, and
+// returns the logged delivery.
+func (service *webhookService) SendTestEvent(webhookID string) (*models.WebhookDelivery, error) {
+	webhook, err := service.webhookEntity.FindWebhookByID(webhookID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	payload := newWebhookPayload(primitive.NewObjectID().Hex(), time.Now().UTC(), models.WebhookTestEvent, nil, map[string]interface{}{
+		"message": "This is a test event.",
+	})
+	body, err := json.Marshal(payload)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("failed to encode webhook payload")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+
+	delivery := sendWebhook(ctx, webhook, payload.ID, payload.Event, body)
+	if err := service.webhookEntity.CreateDelivery(delivery); err != nil {
+		logrus.Error(err)
+	}
+
+	return &delivery, nil
+}
+
+// dispatchWebhooks subscribes webhooks to domain events. The payload of a
+// delivery carries the ID of the event, so an endpoint can tell a delivery
+// that was repeated after a retry.
+func dispatchWebhooks(ctx context.Context, event models.DomainEvent) error {
+	dispatcher := newWebhookDispatcher()
+	if dispatcher == nil {
+		return errors.New("webhooks are not available")
+	}
+
+	switch payload := event.Payload.(type) {
+	case models.WorkflowCreated:
+		return dispatcher.dispatch(ctx, event, models.WorkflowCreatedEvent, &payload.Workflow, map[string]interface{}{})
+	case models.WorkflowTransferred:
+		return dispatcher.dispatch(ctx, event, models.WorkflowTransferredEvent, &payload.Workflow, map[string]interface{}{
+			"owner": payload.Workflow.Owner,
+		})
+	case models.TaskCreated:
+		return dispatcher.dispatchByWorkflowID(ctx, event, models.TaskCreatedEvent, payload.WorkflowID, map[string]interface{}{
+			"task": webhookTask(&payload.Task),
+		})
+	case models.TaskStatusChanged:
+		return dispatcher.dispatchByWorkflowID(ctx, event, models.TaskStatusChangedEvent, payload.WorkflowID, map[string]interface{}{
+			"task":            webhookTask(&payload.Task),
+			"previous_status": payload.PreviousStatus,
+		})
+	}
+
+	return nil
+}
+
+// webhookDispatcher queues a delivery job for every webhook subscribed to an
+// event.
+type webhookDispatcher struct {
+	webhookEntity  repositories.IWebhook
+	workflowEntity repositories.IWorkflow
+	userEntity     repositories.IUser
+	jobService     IJobService
+}
+
+// newWebhookDispatcher builds a dispatcher from the repositories set up at
+// startup. It returns nil if they are not.
+func newWebhookDispatcher() *webhookDispatcher {
+	if repositories.WebhookEntity == nil || repositories.WorkflowEntity == nil || repositories.UserEntity == nil || repositories.JobEntity == nil {
+		return nil
+	}
+	return &webhookDispatcher{
+		webhookEntity:  repositories.WebhookEntity,
+		workflowEntity: repositories.WorkflowEntity,
+		userEntity:     repositories.UserEntity,
+		jobService:     &jobService{jobEntity: repositories.JobEntity},
+	}
+}
+
+func (dispatcher *webhookDispatcher) dispatch(ctx context.Context, source models.DomainEvent, event models.WebhookEvent, workflow *models.Workflow, data map[string]interface{}) error {
+	organization := ""
+	if owner, err := dispatcher.userEntity.FindOneByUsername(workflow.Owner); err == nil {
+		organization = owner.Organization
+	}
+
+	webhooks, err := dispatcher.webhookEntity.FindSubscribedWebhooks(event, workflow.ID, organization)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	payload := newWebhookPayload(source.ID, source.OccurredAt, event, workflow, data)
+	body, err := json.Marshal(payload)
+	if err != nil {
+		logrus.Error(err)
+		return nil
+	}
+
+	for _, webhook := range webhooks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		_, err := dispatcher.jobService.EnqueueJob(WebhookDeliveryJob, map[string]interface{}{
+			"webhook_id": webhook.ID.Hex(),
+			"event_id":   payload.ID,
+			"event":      string(event),
+			"body":       string(body),
+		}, time.Time{})
+		if err != nil {
+			logrus.Error(err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dispatchByWorkflowID dispatches an event of a workflow that is not at hand.
+func (dispatcher *webhookDispatcher) dispatchByWorkflowID(ctx context.Context, source models.DomainEvent, event models.WebhookEvent, workflowID string, data map[string]interface{}) error {
+	workflow, err := dispatcher.workflowEntity.FindWorkflowByID(workflowID)
+	if err != nil {
+		// The workflow was deleted in the meantime.
+		logrus.Warning("dropping " + string(event) + " of missing workflow " + workflowID)
+		return nil
+	}
+
+	return dispatcher.dispatch(ctx, source, event, workflow, data)
+}
+
+func newWebhookPayload(id string, createdAt time.Time, event models.WebhookEvent, workflow *models.Workflow, data map[string]interface{}) models.WebhookPayload {
+	payload := models.WebhookPayload{
+		ID:        id,
+		Event:     event,
+		CreatedAt: createdAt,
+		Data:      data,
+	}
+	if workflow != nil {
+		payload.Workflow = map[string]interface{}{
+			"id":    workflow.ID.Hex(),
+			"name":  workflow.Name,
+			"owner": workflow.Owner,
+		}
+	}
+	return payload
+}
+
+// webhookTask is the part of a task sent in payloads.
+func webhookTask(task *models.Task) map[string]interface{} {
+	return map[string]interface{}{
+		"id":       task.ID.Hex(),
+		"name":     task.Name,
+		"type":     task.Type,
+		"status":   task.Status,
+		"assignee": task.Assignee,
+	}
+}
+
+// deliverWebhook runs a delivery job. Deliveries for webhooks that were
+// deleted or deactivated in the meantime are dropped.
+func deliverWebhook(ctx context.Context, payload map[string]interface{}) error {
+	if repositories.WebhookEntity == nil {
+		return errors.New("webhooks are not available")
+	}
+
+	webhookID, _ := payload["webhook_id"].(string)
+	eventID, _ := payload["event_id"].(string)
+	event, _ := payload["event"].(string)
+	body, _ := payload["body"].(string)
+
+	webhook, err := repositories.WebhookEntity.FindWebhookByID(webhookID)
+	if err != nil {
+		logrus.Warning("dropping delivery for missing webhook " + webhookID)
+		return nil
+	}
+	if !webhook.Active {
+		return nil
+	}
+
+	delivery := sendWebhook(ctx, webhook, eventID, models.WebhookEvent(event), []byte(body))
+	if err := repositories.WebhookEntity.CreateDelivery(delivery); err != nil {
+		logrus.Error(err)
+	}
+
+	if !delivery.Succeeded {
+		return errors.New(delivery.Error)
+	}
+
+	return nil
+}
+
+// sendWebhook posts a signed payload and describes the outcome as a delivery.
+// Any 2xx response counts as delivered.
+func sendWebhook(ctx context.Context, webhook *models.Webhook, eventID string, event models.WebhookEvent, body []byte) (delivery models.WebhookDelivery) {
+	delivery = models.WebhookDelivery{
+		WebhookID: webhook.ID,
+		EventID:   eventID,
+		Event:     event,
+		Payload:   string(body),
+		CreatedAt: time.Now(),
+	}
+	defer func() {
+		delivery.DurationMS = time.Since(delivery.CreatedAt).Milliseconds()
+	}()
+
+	if err := common.CheckOutboundURL(webhook.URL); err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "Virtual-Workflow-Webhooks")
+	request.Header.Set("X-Webhook-Event", string(event))
+	request.Header.Set("X-Webhook-Delivery", eventID)
+	request.Header.Set(webhookSignatureHeader, webhook.Sign(body))
+
+	response, err := webhookClient.Do(request)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	defer response.Body.Close()
+
+	content, _ := io.ReadAll(io.LimitReader(response.Body, maxLoggedResponseSize))
+	delivery.StatusCode = response.StatusCode
+	delivery.Response = string(content)
+	delivery.Succeeded = response.StatusCode >= 200 && response.StatusCode < 300
+	if !delivery.Succeeded {
+		delivery.Error = fmt.Sprintf("endpoint responded with status %d", response.StatusCode)
+	}
+
+	return delivery
+}
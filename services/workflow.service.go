@@ -1,36 +1,76 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
 	"virtual_workflow_management_system_gin/common"
 	"virtual_workflow_management_system_gin/databases"
 	"virtual_workflow_management_system_gin/models"
 	"virtual_workflow_management_system_gin/repositories"
 	"virtual_workflow_management_system_gin/requests"
 
+	"github.com/gin-gonic/gin/binding"
 	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 var WorkflowService IWorkflowService
 
+// createTaskAttempts bounds the retries of a task creation racing other
+// writes to its workflow.
+const createTaskAttempts = 3
+
+// errBatchRolledBack aborts the transaction of a failed atomic batch.
+var errBatchRolledBack = errors.New("batch was rolled back")
+
 type workflowService struct {
 	workflowEntity repositories.IWorkflow
+	userEntity     repositories.IUser
 	mongoClient    *mongo.Client
+	events         *eventPublisher
 }
 
 type IWorkflowService interface {
+	EnsureIndexes() error
 	GetWorkflows(username string) ([]models.Workflow, error)
 	GetWorkflowByID(workflowID string) (*models.Workflow, error)
 	CreateWorkflow(username string, req requests.CreateWorkflowRequest) (*string, error)
-	EditWorkflowByID(workflowID string, req requests.EditWorkflowRequest) (*models.Workflow, error)
-	DeleteWorkflowByID(workflowID string) error
-	TransferWorkflowByID(workflowID string, username string) (*models.Workflow, error)
+	EditWorkflowByID(workflowID string, req requests.EditWorkflowRequest, version *int64) (*models.Workflow, error)
+	DeleteWorkflowByID(workflowID string, version *int64) error
+	TransferWorkflowByID(workflowID string, username string, version *int64) (*models.Workflow, error)
 	GetTasksByWorkflowID(workflowID string) ([]models.Task, error)
 	GetTaskByID(workflowID string, taskID string) (*models.Task, error)
 	CreateTaskByWorkflowID(workflowID string, req requests.CreateTaskRequest) (*string, error)
-	EditTaskByID(workflowID string, taskID string, req requests.EditTaskRequest) (*models.Task, error)
-	DeleteTaskByID(workflowID string, taskID string) error
+	EditTaskByID(workflowID string, taskID string, req requests.EditTaskRequest, version *int64) (*models.Task, error)
+	DeleteTaskByID(workflowID string, taskID string, version *int64) error
+	ReorderTasksByWorkflowID(workflowID string, req requests.ReorderTasksRequest, version *int64) ([]models.Task, error)
+	BatchTasks(workflowID string, req requests.BatchTasksRequest, version *int64) (*models.BatchResult, error)
+	PatchWorkflowByID(workflowID string, req requests.PatchRequest, version *int64) (*models.Workflow, error)
+	PatchTaskByID(workflowID string, taskID string, req requests.PatchRequest, version *int64) (*models.Task, error)
+	GetRevisionsByWorkflowID(workflowID string) ([]models.WorkflowRevision, error)
+	GetRevision(workflowID string, revision int) (*models.WorkflowRevision, error)
+	DiffRevisions(workflowID string, from int, to int) (*models.WorkflowDiff, error)
+	RestoreRevision(workflowID string, revision int, version *int64) (*models.Workflow, error)
+	SetVariables(workflowID string, req requests.SetVariablesRequest, version *int64) (*models.Workflow, error)
+	StartWorkflow(workflowID string, version *int64) (*models.Workflow, error)
+	ValidateWorkflowDefinition(workflowID string) ([]models.DefinitionProblem, error)
+	ExportWorkflow(workflowID string) (*models.WorkflowDefinition, error)
+	ImportWorkflow(username string, req requests.ImportWorkflowRequest) (*string, error)
+	ImportBPMN(username string, req requests.ImportWorkflowRequest) (*string, []string, error)
+	VoteOnTask(workflowID string, taskID string, user models.JWTUser, decision models.ApprovalDecision, req requests.VoteRequest) (*models.Task, error)
+	SetVariableDefinitions(workflowID string, req requests.SetVariableDefinitionsRequest, version *int64) (*models.Workflow, error)
+	SetTaskForm(workflowID string, taskID string, req requests.SetTaskFormRequest, version *int64) (*models.Task, error)
+	CompleteTask(workflowID string, taskID string, req requests.CompleteTaskRequest, version *int64) (*models.Task, error)
+	RetryTask(workflowID string, taskID string, version *int64) (*models.Task, error)
+	AddChecklistItem(workflowID string, taskID string, req requests.ChecklistItemRequest, version *int64) (*models.Task, error)
+	ToggleChecklistItem(workflowID string, taskID string, itemID string, user models.JWTUser, version *int64) (*models.Task, error)
+	ReorderChecklist(workflowID string, taskID string, req requests.ReorderChecklistRequest, version *int64) (*models.Task, error)
+	RemoveChecklistItem(workflowID string, taskID string, itemID string, version *int64) (*models.Task, error)
 }
 
 func NewWorkflowService(resource *databases.Resource) *workflowService {
@@ -39,8 +79,18 @@ func NewWorkflowService(resource *databases.Resource) *workflowService {
 	}
 	return &workflowService{
 		workflowEntity: repositories.NewWorkflowEntity(resource),
+		userEntity:     repositories.NewUserEntity(resource),
 		mongoClient:    resource.MongoDB.Client(),
+		events:         newEventPublisher(resource),
+	}
+}
+
+// EnsureIndexes creates the indexes the workflow repository depends on.
+func (service *workflowService) EnsureIndexes() error {
+	if service.workflowEntity == nil {
+		return errors.New("workflows are not available")
 	}
+	return service.workflowEntity.EnsureIndexes()
 }
 
 func (service *workflowService) GetWorkflows(username string) ([]models.Workflow, error) {
@@ -64,13 +114,30 @@ func (service *workflowService) GetWorkflowByID(workflowID string) (*models.Work
 }
 
 func (service *workflowService) CreateWorkflow(username string, req requests.CreateWorkflowRequest) (*string, error) {
+	labels, err := models.NormalizeLabels(req.Labels)
+	if err != nil {
+		return nil, err
+	}
+
 	workflowModel := models.Workflow{
-		Name:  req.Name,
-		Tasks: []models.Task{},
-		Owner: username,
+		Name:   req.Name,
+		Tasks:  []models.Task{},
+		Owner:  username,
+		Labels: labels,
 	}
 
-	insertedID, err := service.workflowEntity.CreateWorkflow(workflowModel)
+	var insertedID *string
+	err = service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		var err error
+		insertedID, err = service.workflowEntity.WithContext(ctx).CreateWorkflow(workflowModel)
+		if err != nil {
+			return err
+		}
+
+		workflowModel.ID, _ = primitive.ObjectIDFromHex(*insertedID)
+		events.record(models.WorkflowCreated{WorkflowScope: models.WorkflowScope{WorkflowID: *insertedID}, Workflow: workflowModel})
+		return nil
+	})
 	if err != nil {
 		logrus.Error(err)
 		return nil, err
@@ -79,12 +146,28 @@ func (service *workflowService) CreateWorkflow(username string, req requests.Cre
 	return insertedID, nil
 }
 
-func (service *workflowService) EditWorkflowByID(workflowID string, req requests.EditWorkflowRequest) (*models.Workflow, error) {
+func (service *workflowService) EditWorkflowByID(workflowID string, req requests.EditWorkflowRequest, version *int64) (*models.Workflow, error) {
+	labels, err := models.NormalizeLabels(req.Labels)
+	if err != nil {
+		return nil, err
+	}
+
 	workflowModel := models.Workflow{
-		Name: req.Name,
+		Name:   req.Name,
+		Labels: labels,
 	}
 
-	workflow, err := service.workflowEntity.UpdateWorkflow(workflowID, workflowModel)
+	var workflow *models.Workflow
+	err = service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		var err error
+		workflow, err = service.workflowEntity.WithContext(ctx).UpdateWorkflow(workflowID, workflowModel, version)
+		if err != nil {
+			return err
+		}
+
+		events.record(models.WorkflowUpdated{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Workflow: *workflow})
+		return nil
+	})
 	if err != nil {
 		logrus.Error(err)
 		return nil, err
@@ -93,20 +176,39 @@ func (service *workflowService) EditWorkflowByID(workflowID string, req requests
 	return workflow, nil
 }
 
-func (service *workflowService) DeleteWorkflowByID(workflowID string) error {
-	if err := service.workflowEntity.DeleteWorkflow(workflowID); err != nil {
+func (service *workflowService) DeleteWorkflowByID(workflowID string, version *int64) error {
+	err := service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		if err := service.workflowEntity.WithContext(ctx).DeleteWorkflow(workflowID, version); err != nil {
+			return err
+		}
+
+		events.record(models.WorkflowDeleted{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}})
+		return nil
+	})
+	if err != nil {
 		logrus.Error(err)
 		return err
 	}
+
 	return nil
 }
 
-func (service *workflowService) TransferWorkflowByID(workflowID string, username string) (*models.Workflow, error) {
+func (service *workflowService) TransferWorkflowByID(workflowID string, username string, version *int64) (*models.Workflow, error) {
 	workflowModel := models.Workflow{
 		Owner: username,
 	}
 
-	workflow, err := service.workflowEntity.TransferWorkflowByID(workflowID, workflowModel)
+	var workflow *models.Workflow
+	err := service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		var err error
+		workflow, err = service.workflowEntity.WithContext(ctx).TransferWorkflowByID(workflowID, workflowModel, version)
+		if err != nil {
+			return err
+		}
+
+		events.record(models.WorkflowTransferred{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Workflow: *workflow})
+		return nil
+	})
 	if err != nil {
 		logrus.Error(err)
 		return nil, err
@@ -122,6 +224,9 @@ func (service *workflowService) GetTasksByWorkflowID(workflowID string) ([]model
 		return nil, err
 	}
 
+	models.EvaluateSLAs(tasks, time.Now())
+	models.EvaluateChecklists(tasks)
+
 	return tasks, nil
 }
 
@@ -132,65 +237,1070 @@ func (service *workflowService) GetTaskByID(workflowID string, taskID string) (*
 		return nil, err
 	}
 
+	if task.SLA != nil {
+		task.SLA.Evaluate(time.Now())
+	}
+	task.EvaluateChecklist()
+
 	return task, nil
 }
 
 func (service *workflowService) CreateTaskByWorkflowID(workflowID string, req requests.CreateTaskRequest) (*string, error) {
+	taskModel, err := service.newTask(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The request names no version, so losing a race with another write is
+	// retried on the fresh workflow instead of failing a precondition.
 	var insertedID *string
-	err := common.WithTransaction(context.TODO(), service.mongoClient, func(c context.Context, session mongo.Session) error {
-		maxOrder, err := service.workflowEntity.FindMaxTaskOrderByWorkflowID(workflowID)
+	for attempt := 1; ; attempt++ {
+		err = service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+			task, err := service.createTask(ctx, events, workflowID, *taskModel)
+			if err != nil {
+				return err
+			}
+
+			taskID := task.ID.Hex()
+			insertedID = &taskID
+			return nil
+		})
+		if !errors.Is(err, repositories.ErrVersionMismatch) {
+			break
+		}
+		if attempt == createTaskAttempts {
+			err = repositories.ErrWorkflowConflict
+			break
+		}
+	}
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return insertedID, nil
+}
+
+// newTask builds the task a create request describes.
+func (service *workflowService) newTask(req requests.CreateTaskRequest) (*models.Task, error) {
+	dependsOn, err := parseObjectIDs(req.DependsOn)
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := models.NormalizeLabels(req.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	taskModel := models.Task{
+		Name:        req.Name,
+		Description: req.Description,
+		Status:      models.Pending,
+		DependsOn:   dependsOn,
+		Type:        models.UserTask,
+		Labels:      labels,
+	}
+
+	if req.Type == models.GatewayTask {
+		gateway, err := newGateway(*req.Gateway)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		taskModel.Type = models.GatewayTask
+		taskModel.Gateway = gateway
+	}
+
+	if req.Type == models.ApprovalTask {
+		approval, err := newApproval(*req.Approval)
+		if err != nil {
+			return nil, err
+		}
+		taskModel.Type = models.ApprovalTask
+		taskModel.Approval = approval
+	}
+
+	if req.Type == models.AutomatedTask {
+		automation, err := newAutomation(*req.Automation)
+		if err != nil {
+			return nil, err
 		}
+		taskModel.Type = models.AutomatedTask
+		taskModel.Automation = automation
+	}
 
-		order := 1
-		if maxOrder != nil {
-			order = *maxOrder + 1
+	taskModel.Form = newFieldDefinitions(req.Form)
+
+	if req.Assignee != "" {
+		if err := service.checkUserReferences([]string{req.Assignee}); err != nil {
+			return nil, err
 		}
+		taskModel.Assignee = req.Assignee
+	}
 
-		taskModel := models.Task{
-			Name:        req.Name,
-			Description: req.Description,
-			Status:      models.TaskStatus("Pending"),
-			Order:       order,
+	if req.SLA != nil {
+		sla, err := newSLA(*req.SLA)
+		if err != nil {
+			return nil, err
 		}
+		taskModel.SLA = sla
+	}
 
-		insertedID, err = service.workflowEntity.CreateTaskByWorkflowID(workflowID, taskModel)
+	for _, itemReq := range req.Checklist {
+		item, err := service.newChecklistItem(itemReq)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		if err := taskModel.AddChecklistItem(*item); err != nil {
+			return nil, err
 		}
+	}
 
-		return nil
+	return &taskModel, nil
+}
+
+// createTask adds the task to the workflow in the transaction of ctx.
+func (service *workflowService) createTask(ctx context.Context, events *eventRecorder, workflowID string, taskModel models.Task) (*models.Task, error) {
+	workflowEntity := service.workflowEntity.WithContext(ctx)
+
+	insertedID, err := workflowEntity.CreateTaskByWorkflowID(workflowID, taskModel)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the task back for the status and SLA it got from the workflow.
+	task, err := workflowEntity.FindTaskByID(workflowID, *insertedID)
+	if err != nil {
+		return nil, err
+	}
+
+	events.record(models.TaskCreated{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Task: *task})
+	return task, nil
+}
+
+func (service *workflowService) EditTaskByID(workflowID string, taskID string, req requests.EditTaskRequest, version *int64) (*models.Task, error) {
+	taskModel, err := service.newTaskEdit(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var task *models.Task
+	err = service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		var err error
+		task, err = service.updateTask(ctx, events, workflowID, taskID, *taskModel, version)
+		return err
 	})
 	if err != nil {
 		logrus.Error(err)
 		return nil, err
 	}
 
-	return insertedID, nil
+	return task, nil
 }
 
-func (service *workflowService) EditTaskByID(workflowID string, taskID string, req requests.EditTaskRequest) (*models.Task, error) {
-	taskModel := models.Task{
+// newTaskEdit builds the task fields an edit request replaces.
+func (service *workflowService) newTaskEdit(req requests.EditTaskRequest) (*models.Task, error) {
+	labels, err := models.NormalizeLabels(req.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Assignee != "" {
+		if err := service.checkUserReferences([]string{req.Assignee}); err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.Task{
 		Name:        req.Name,
 		Description: req.Description,
 		Status:      req.Status,
 		Order:       req.Order,
+		Assignee:    req.Assignee,
+		Labels:      labels,
+	}, nil
+}
+
+// updateTask replaces the fields of the task in the transaction of ctx.
+func (service *workflowService) updateTask(ctx context.Context, events *eventRecorder, workflowID string, taskID string, taskModel models.Task, version *int64) (*models.Task, error) {
+	workflowEntity := service.workflowEntity.WithContext(ctx)
+
+	previous, err := workflowEntity.FindTaskByID(workflowID, taskID)
+	if err != nil {
+		return nil, err
 	}
 
-	task, err := service.workflowEntity.UpdateTaskByID(workflowID, taskID, taskModel)
+	task, err := workflowEntity.UpdateTaskByID(workflowID, taskID, taskModel, version)
 	if err != nil {
-		logrus.Error(err)
 		return nil, err
 	}
 
+	events.record(models.TaskUpdated{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Task: *task})
+	events.recordStatusChange(workflowID, task, previous.Status)
 	return task, nil
 }
 
-func (service *workflowService) DeleteTaskByID(workflowID string, taskID string) error {
-	if err := service.workflowEntity.DeleteTaskByID(workflowID, taskID); err != nil {
+func (service *workflowService) DeleteTaskByID(workflowID string, taskID string, version *int64) error {
+	err := service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		return service.deleteTask(ctx, events, workflowID, taskID, version)
+	})
+	if err != nil {
 		logrus.Error(err)
 		return err
 	}
+
+	return nil
+}
+
+// deleteTask removes the task in the transaction of ctx.
+func (service *workflowService) deleteTask(ctx context.Context, events *eventRecorder, workflowID string, taskID string, version *int64) error {
+	if err := service.workflowEntity.WithContext(ctx).DeleteTaskByID(workflowID, taskID, version); err != nil {
+		return err
+	}
+
+	events.record(models.TaskDeleted{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, TaskID: taskID})
 	return nil
 }
+
+// BatchTasks applies the operations in order, checking the workflow version
+// once before the first of them. An atomic batch runs in one transaction that
+// stops at the first failing operation and commits nothing. A best-effort batch
+// runs every operation in a transaction of its own, since MongoDB aborts a
+// transaction after a failed write, and commits the operations that succeed.
+func (service *workflowService) BatchTasks(workflowID string, req requests.BatchTasksRequest, version *int64) (*models.BatchResult, error) {
+	mode := req.Mode
+	if mode == "" {
+		mode = models.AtomicBatch
+	}
+	result := &models.BatchResult{Mode: mode, Results: make([]models.BatchOperationResult, len(req.Operations))}
+
+	// Build the tasks up front, so the transaction does not wait on the user
+	// lookups of their validation.
+	taskModels := make([]*models.Task, len(req.Operations))
+	for i, operation := range req.Operations {
+		result.Results[i] = models.BatchOperationResult{Index: i, Op: operation.Op, TaskID: operation.TaskID}
+
+		var err error
+		switch operation.Op {
+		case models.CreateOperation:
+			taskModels[i], err = service.newTask(*operation.Create)
+		case models.UpdateOperation:
+			taskModels[i], err = service.newTaskEdit(*operation.Update)
+		}
+		if err != nil {
+			result.Fail(i, err)
+			if mode == models.AtomicBatch {
+				return result, nil
+			}
+		}
+	}
+
+	if mode != models.AtomicBatch {
+		return service.batchTasksBestEffort(workflowID, req, taskModels, result, version)
+	}
+
+	err := service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		workflow, err := service.workflowEntity.WithContext(ctx).FindWorkflowByID(workflowID)
+		if err != nil {
+			return err
+		}
+		if version != nil && *version != workflow.Version {
+			return repositories.ErrVersionMismatch
+		}
+
+		for i, operation := range req.Operations {
+			task, err := service.applyTaskOperation(ctx, events, workflowID, operation, taskModels[i])
+			if err != nil {
+				result.Fail(i, err)
+				return errBatchRolledBack
+			}
+
+			result.Results[i].Status = models.OperationApplied
+			if task != nil {
+				result.Results[i].TaskID = task.ID.Hex()
+				result.Results[i].Task = task
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errBatchRolledBack) {
+		return result, nil
+	}
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	result.Committed = true
+	return result, nil
+}
+
+// batchTasksBestEffort commits every operation of a best-effort batch on its
+// own, so a failing one leaves the others in place.
+func (service *workflowService) batchTasksBestEffort(workflowID string, req requests.BatchTasksRequest, taskModels []*models.Task, result *models.BatchResult, version *int64) (*models.BatchResult, error) {
+	workflow, err := service.workflowEntity.FindWorkflowByID(workflowID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	if version != nil && *version != workflow.Version {
+		return nil, repositories.ErrVersionMismatch
+	}
+
+	for i, operation := range req.Operations {
+		if result.Results[i].Status == models.OperationFailed {
+			continue
+		}
+
+		var task *models.Task
+		err := service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+			var err error
+			task, err = service.applyTaskOperation(ctx, events, workflowID, operation, taskModels[i])
+			return err
+		})
+		if err != nil {
+			result.Fail(i, err)
+			continue
+		}
+
+		result.Results[i].Status = models.OperationApplied
+		if task != nil {
+			result.Results[i].TaskID = task.ID.Hex()
+			result.Results[i].Task = task
+		}
+	}
+
+	result.Committed = true
+	return result, nil
+}
+
+func (service *workflowService) applyTaskOperation(ctx context.Context, events *eventRecorder, workflowID string, operation requests.TaskOperationRequest, taskModel *models.Task) (*models.Task, error) {
+	switch operation.Op {
+	case models.CreateOperation:
+		return service.createTask(ctx, events, workflowID, *taskModel)
+	case models.UpdateOperation:
+		return service.updateTask(ctx, events, workflowID, operation.TaskID, *taskModel, nil)
+	case models.DeleteOperation:
+		return nil, service.deleteTask(ctx, events, workflowID, operation.TaskID, nil)
+	default:
+		return nil, errors.New("unknown operation: " + string(operation.Op))
+	}
+}
+
+func (service *workflowService) ReorderTasksByWorkflowID(workflowID string, req requests.ReorderTasksRequest, version *int64) ([]models.Task, error) {
+	var tasks []models.Task
+	err := service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		workflowEntity := service.workflowEntity.WithContext(ctx)
+
+		taskIDs := req.TaskIDs
+		if req.Move != nil {
+			current, err := workflowEntity.FindTasksByWorkflowID(workflowID)
+			if err != nil {
+				return err
+			}
+
+			taskIDs, err = moveTaskID(current, *req.Move)
+			if err != nil {
+				return err
+			}
+		}
+
+		var err error
+		tasks, err = workflowEntity.ReorderTasksByWorkflowID(workflowID, taskIDs, version)
+		if err != nil {
+			return err
+		}
+
+		events.record(models.TasksReordered{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Tasks: tasks})
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+func moveTaskID(tasks []models.Task, move requests.MoveTaskRequest) ([]string, error) {
+	anchorID := move.BeforeID
+	if anchorID == "" {
+		anchorID = move.AfterID
+	}
+	if anchorID == move.TaskID {
+		return nil, errors.New("task cannot be moved relative to itself")
+	}
+
+	found := false
+	taskIDs := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		if task.ID.Hex() == move.TaskID {
+			found = true
+			continue
+		}
+		taskIDs = append(taskIDs, task.ID.Hex())
+	}
+	if !found {
+		return nil, errors.New("task does not exist")
+	}
+
+	for i, taskID := range taskIDs {
+		if taskID != anchorID {
+			continue
+		}
+		if move.BeforeID == "" {
+			i++
+		}
+		return append(taskIDs[:i], append([]string{move.TaskID}, taskIDs[i:]...)...), nil
+	}
+
+	return nil, errors.New("anchor task does not exist")
+}
+
+func (service *workflowService) PatchWorkflowByID(workflowID string, req requests.PatchRequest, version *int64) (*models.Workflow, error) {
+	workflow, err := service.workflowEntity.FindWorkflowByID(workflowID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	if version != nil && *version != workflow.Version {
+		return nil, repositories.ErrVersionMismatch
+	}
+
+	current := requests.EditWorkflowRequest{Name: workflow.Name, Labels: workflow.Labels}
+	var patched requests.EditWorkflowRequest
+	if err := applyPatch(req, current, &patched); err != nil {
+		return nil, err
+	}
+
+	fields := map[string]interface{}{}
+	if patched.Name != current.Name {
+		fields["name"] = patched.Name
+	}
+	labels, err := models.NormalizeLabels(patched.Labels)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Equal(labels, current.Labels) {
+		fields["labels"] = labels
+	}
+	if len(fields) == 0 {
+		return workflow, nil
+	}
+
+	var updatedWorkflow *models.Workflow
+	err = service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		var err error
+		updatedWorkflow, err = service.workflowEntity.WithContext(ctx).PatchWorkflow(workflowID, fields, version)
+		if err != nil {
+			return err
+		}
+
+		events.record(models.WorkflowUpdated{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Workflow: *updatedWorkflow})
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return updatedWorkflow, nil
+}
+
+func (service *workflowService) PatchTaskByID(workflowID string, taskID string, req requests.PatchRequest, version *int64) (*models.Task, error) {
+	task, err := service.workflowEntity.FindTaskByID(workflowID, taskID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	current := requests.EditTaskRequest{
+		Name:        task.Name,
+		Description: task.Description,
+		Status:      task.Status,
+		Order:       task.Order,
+		Assignee:    task.Assignee,
+		Labels:      task.Labels,
+	}
+	var patched requests.EditTaskRequest
+	if err := applyPatch(req, current, &patched); err != nil {
+		return nil, err
+	}
+
+	// Changing the order moves neighbouring tasks too, so it goes through the full update.
+	if patched.Order != current.Order {
+		return service.EditTaskByID(workflowID, taskID, patched, version)
+	}
+
+	fields := map[string]interface{}{}
+	if patched.Name != current.Name {
+		fields["name"] = patched.Name
+	}
+	if patched.Description != current.Description {
+		fields["description"] = patched.Description
+	}
+	labels, err := models.NormalizeLabels(patched.Labels)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Equal(labels, current.Labels) {
+		fields["labels"] = labels
+	}
+	if patched.Status != current.Status {
+		fields["status"] = patched.Status
+	}
+	if patched.Assignee != current.Assignee {
+		if patched.Assignee != "" {
+			if err := service.checkUserReferences([]string{patched.Assignee}); err != nil {
+				return nil, err
+			}
+		}
+		// Naming a person takes the task back from a role it was escalated to.
+		fields["assignee"] = patched.Assignee
+		fields["assignee_role"] = ""
+	}
+	if len(fields) == 0 {
+		return task, nil
+	}
+
+	var updatedTask *models.Task
+	err = service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		workflowEntity := service.workflowEntity.WithContext(ctx)
+
+		previous, err := workflowEntity.FindTaskByID(workflowID, taskID)
+		if err != nil {
+			return err
+		}
+
+		updatedTask, err = workflowEntity.PatchTaskByID(workflowID, taskID, fields, version)
+		if err != nil {
+			return err
+		}
+
+		events.record(models.TaskUpdated{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Task: *updatedTask})
+		events.recordStatusChange(workflowID, updatedTask, previous.Status)
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return updatedTask, nil
+}
+
+func (service *workflowService) GetRevisionsByWorkflowID(workflowID string) ([]models.WorkflowRevision, error) {
+	revisions, err := service.workflowEntity.FindRevisionsByWorkflowID(workflowID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (service *workflowService) GetRevision(workflowID string, revision int) (*models.WorkflowRevision, error) {
+	workflowRevision, err := service.workflowEntity.FindRevision(workflowID, revision)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return workflowRevision, nil
+}
+
+func (service *workflowService) DiffRevisions(workflowID string, from int, to int) (*models.WorkflowDiff, error) {
+	fromRevision, err := service.workflowEntity.FindRevision(workflowID, from)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	toRevision, err := service.workflowEntity.FindRevision(workflowID, to)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	diff := models.DiffRevisions(*fromRevision, *toRevision)
+
+	return &diff, nil
+}
+
+func (service *workflowService) RestoreRevision(workflowID string, revision int, version *int64) (*models.Workflow, error) {
+	var workflow *models.Workflow
+	err := service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		var err error
+		workflow, err = service.workflowEntity.WithContext(ctx).RestoreWorkflowRevision(workflowID, revision, version)
+		if err != nil {
+			return err
+		}
+
+		events.record(models.WorkflowRestored{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Workflow: *workflow, Revision: revision})
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return workflow, nil
+}
+
+func (service *workflowService) SetVariables(workflowID string, req requests.SetVariablesRequest, version *int64) (*models.Workflow, error) {
+	for name := range req.Variables {
+		if !models.VariableNamePattern.MatchString(name) {
+			return nil, errors.New("invalid variable name: " + name)
+		}
+	}
+
+	current, err := service.workflowEntity.FindWorkflowByID(workflowID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	if err := service.checkUserReferences(models.UserReferences(current.VariableDefinitions, req.Variables)); err != nil {
+		return nil, err
+	}
+
+	var workflow *models.Workflow
+	err = service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		var err error
+		workflow, err = service.workflowEntity.WithContext(ctx).SetWorkflowVariables(workflowID, req.Variables, version)
+		if err != nil {
+			return err
+		}
+
+		events.record(models.WorkflowVariablesSet{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Workflow: *workflow})
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return workflow, nil
+}
+
+// StartWorkflow starts the run of the workflow. Gateways nothing leads to only
+// decide once it has started.
+func (service *workflowService) StartWorkflow(workflowID string, version *int64) (*models.Workflow, error) {
+	var workflow *models.Workflow
+	err := service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		var err error
+		workflow, err = service.workflowEntity.WithContext(ctx).StartWorkflow(workflowID, version)
+		if err != nil {
+			return err
+		}
+
+		events.record(models.WorkflowStarted{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Workflow: *workflow})
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return workflow, nil
+}
+
+func (service *workflowService) ValidateWorkflowDefinition(workflowID string) ([]models.DefinitionProblem, error) {
+	workflow, err := service.workflowEntity.FindWorkflowByID(workflowID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return workflow.ValidateDefinition(), nil
+}
+
+func (service *workflowService) ExportWorkflow(workflowID string) (*models.WorkflowDefinition, error) {
+	workflow, err := service.workflowEntity.FindWorkflowByID(workflowID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	definition := models.NewWorkflowDefinition(*workflow, time.Now())
+	return &definition, nil
+}
+
+func (service *workflowService) ImportWorkflow(username string, req requests.ImportWorkflowRequest) (*string, error) {
+	definition, err := models.ParseWorkflowDefinition(req.ContentType, req.Document)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.importDefinition(username, definition)
+}
+
+// ImportBPMN creates a workflow from the process of a BPMN document. The
+// warnings list what the workflow could not take over.
+func (service *workflowService) ImportBPMN(username string, req requests.ImportWorkflowRequest) (*string, []string, error) {
+	definition, warnings, err := models.ParseBPMN(req.Document)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	insertedID, err := service.importDefinition(username, definition)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return insertedID, warnings, nil
+}
+
+// importDefinition recreates a workflow definition for the user. The workflow
+// is created with all of its tasks in a single insert, so nothing of an invalid
+// definition is left behind.
+func (service *workflowService) importDefinition(username string, definition *models.WorkflowDefinition) (*string, error) {
+	workflowModel, err := definition.Instantiate(username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.checkUserReferences(definition.Usernames()); err != nil {
+		return nil, err
+	}
+
+	var insertedID *string
+	err = service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		var err error
+		insertedID, err = service.workflowEntity.WithContext(ctx).CreateWorkflow(*workflowModel)
+		if err != nil {
+			return err
+		}
+
+		workflowModel.ID, _ = primitive.ObjectIDFromHex(*insertedID)
+		events.record(models.WorkflowCreated{WorkflowScope: models.WorkflowScope{WorkflowID: *insertedID}, Workflow: *workflowModel})
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return insertedID, nil
+}
+
+// VoteOnTask matches the voter against the approvers with the role and teams
+// stored for them, not the ones their token claims.
+func (service *workflowService) VoteOnTask(workflowID string, taskID string, user models.JWTUser, decision models.ApprovalDecision, req requests.VoteRequest) (*models.Task, error) {
+	voter, err := service.userEntity.FindOneByUsername(user.Username)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("user does not exist")
+	}
+
+	var task *models.Task
+	err = service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		workflowEntity := service.workflowEntity.WithContext(ctx)
+
+		previous, err := workflowEntity.FindTaskByID(workflowID, taskID)
+		if err != nil {
+			return err
+		}
+
+		task, err = workflowEntity.VoteOnApproval(workflowID, taskID, voter.Identity(), decision, req.Comment)
+		if err != nil {
+			return err
+		}
+
+		events.record(models.TaskVoted{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Task: *task, Username: user.Username, Decision: decision})
+		events.recordStatusChange(workflowID, task, previous.Status)
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (service *workflowService) SetVariableDefinitions(workflowID string, req requests.SetVariableDefinitionsRequest, version *int64) (*models.Workflow, error) {
+	definitions := newFieldDefinitions(req.Variables)
+	if err := models.ValidateVariableDefinitions(definitions); err != nil {
+		return nil, err
+	}
+
+	var workflow *models.Workflow
+	err := service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		var err error
+		workflow, err = service.workflowEntity.WithContext(ctx).SetVariableDefinitions(workflowID, definitions, version)
+		if err != nil {
+			return err
+		}
+
+		events.record(models.WorkflowVariableDefinitionsSet{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Workflow: *workflow})
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return workflow, nil
+}
+
+func (service *workflowService) SetTaskForm(workflowID string, taskID string, req requests.SetTaskFormRequest, version *int64) (*models.Task, error) {
+	var task *models.Task
+	err := service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		var err error
+		task, err = service.workflowEntity.WithContext(ctx).SetTaskForm(workflowID, taskID, newFieldDefinitions(req.Fields), version)
+		if err != nil {
+			return err
+		}
+
+		events.record(models.TaskFormSet{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Task: *task})
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (service *workflowService) CompleteTask(workflowID string, taskID string, req requests.CompleteTaskRequest, version *int64) (*models.Task, error) {
+	task, err := service.workflowEntity.FindTaskByID(workflowID, taskID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	if err := service.checkUserReferences(models.UserReferences(task.Form, req.Values)); err != nil {
+		return nil, err
+	}
+
+	var completedTask *models.Task
+	err = service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		var err error
+		completedTask, err = service.workflowEntity.WithContext(ctx).CompleteTask(workflowID, taskID, req.Values, version)
+		if err != nil {
+			return err
+		}
+
+		events.record(models.TaskCompleted{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Task: *completedTask})
+		events.recordStatusChange(workflowID, completedTask, task.Status)
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return completedTask, nil
+}
+
+func (service *workflowService) RetryTask(workflowID string, taskID string, version *int64) (*models.Task, error) {
+	var task *models.Task
+	err := service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		var err error
+		task, err = service.workflowEntity.WithContext(ctx).RetryAutomatedTask(workflowID, taskID, version)
+		if err != nil {
+			return err
+		}
+
+		events.record(models.TaskRetried{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Task: *task})
+		events.recordStatusChange(workflowID, task, models.Failed)
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (service *workflowService) AddChecklistItem(workflowID string, taskID string, req requests.ChecklistItemRequest, version *int64) (*models.Task, error) {
+	item, err := service.newChecklistItem(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.updateChecklist(workflowID, taskID, func(task *models.Task) error {
+		return task.AddChecklistItem(*item)
+	}, version)
+}
+
+func (service *workflowService) ToggleChecklistItem(workflowID string, taskID string, itemID string, user models.JWTUser, version *int64) (*models.Task, error) {
+	itemObjectID, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("invalid ObjectID format")
+	}
+
+	return service.updateChecklist(workflowID, taskID, func(task *models.Task) error {
+		return task.ToggleChecklistItem(itemObjectID, user.Username, time.Now())
+	}, version)
+}
+
+func (service *workflowService) ReorderChecklist(workflowID string, taskID string, req requests.ReorderChecklistRequest, version *int64) (*models.Task, error) {
+	itemIDs, err := parseObjectIDs(req.ItemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.updateChecklist(workflowID, taskID, func(task *models.Task) error {
+		return task.ReorderChecklist(itemIDs)
+	}, version)
+}
+
+func (service *workflowService) RemoveChecklistItem(workflowID string, taskID string, itemID string, version *int64) (*models.Task, error) {
+	itemObjectID, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, errors.New("invalid ObjectID format")
+	}
+
+	return service.updateChecklist(workflowID, taskID, func(task *models.Task) error {
+		return task.RemoveChecklistItem(itemObjectID)
+	}, version)
+}
+
+func (service *workflowService) updateChecklist(workflowID string, taskID string, change func(task *models.Task) error, version *int64) (*models.Task, error) {
+	var task *models.Task
+	err := service.events.transaction(func(ctx context.Context, events *eventRecorder) error {
+		var err error
+		task, err = service.workflowEntity.WithContext(ctx).UpdateTaskChecklist(workflowID, taskID, change, version)
+		if err != nil {
+			return err
+		}
+
+		events.record(models.TaskChecklistChanged{WorkflowScope: models.WorkflowScope{WorkflowID: workflowID}, Task: *task})
+		return nil
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	task.EvaluateChecklist()
+	return task, nil
+}
+
+// checkUserReferences makes sure that values of user fields name existing users.
+func (service *workflowService) checkUserReferences(usernames []string) error {
+	for _, username := range usernames {
+		if _, err := service.userEntity.FindOneByUsername(username); err != nil {
+			logrus.Error(err)
+			return errors.New("referenced user does not exist: " + username)
+		}
+	}
+	return nil
+}
+
+func (service *workflowService) newChecklistItem(req requests.ChecklistItemRequest) (*models.ChecklistItem, error) {
+	if req.Assignee != "" {
+		if err := service.checkUserReferences([]string{req.Assignee}); err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.ChecklistItem{
+		Text:     req.Text,
+		Required: req.Required,
+		Assignee: req.Assignee,
+	}, nil
+}
+
+func newFieldDefinitions(reqs []requests.FieldDefinitionRequest) []models.FieldDefinition {
+	fields := make([]models.FieldDefinition, 0, len(reqs))
+	for _, req := range reqs {
+		fields = append(fields, models.FieldDefinition{
+			Name:     req.Name,
+			Label:    req.Label,
+			Type:     req.Type,
+			Required: req.Required,
+			Options:  req.Options,
+		})
+	}
+	return fields
+}
+
+func newApproval(req requests.ApprovalRequest) (*models.Approval, error) {
+	approval := &models.Approval{
+		Approvers: make([]models.Approver, 0, len(req.Approvers)),
+		Quorum:    req.Quorum,
+		Required:  req.Required,
+		Variable:  req.Variable,
+		Votes:     []models.ApprovalVote{},
+	}
+	for _, approver := range req.Approvers {
+		approval.Approvers = append(approval.Approvers, models.Approver{Kind: approver.Kind, Value: approver.Value})
+	}
+
+	if err := approval.Validate(); err != nil {
+		return nil, err
+	}
+
+	return approval, nil
+}
+
+func newGateway(req requests.GatewayRequest) (*models.Gateway, error) {
+	gateway := &models.Gateway{Kind: req.Kind, Flows: []models.GatewayFlow{}, Taken: []primitive.ObjectID{}}
+	for _, flow := range req.Flows {
+		target, err := primitive.ObjectIDFromHex(flow.Target)
+		if err != nil {
+			logrus.Error(err)
+			return nil, errors.New("invalid ObjectID format")
+		}
+		gateway.Flows = append(gateway.Flows, models.GatewayFlow{Target: target, Condition: flow.Condition})
+	}
+
+	if err := gateway.Validate(primitive.NilObjectID); err != nil {
+		return nil, err
+	}
+
+	return gateway, nil
+}
+
+// applyPatch applies a merge patch or JSON patch to the editable representation
+// of a resource and validates the result with the same rules as a full update.
+func applyPatch(req requests.PatchRequest, current interface{}, patched interface{}) error {
+	original, err := json.Marshal(current)
+	if err != nil {
+		logrus.Error(err)
+		return errors.New("failed to prepare patch")
+	}
+
+	var document []byte
+	if req.ContentType == common.JSONPatchContentType {
+		document, err = common.ApplyJSONPatch(original, req.Document)
+	} else {
+		document, err = common.ApplyMergePatch(original, req.Document)
+	}
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(patched); err != nil {
+		return errors.New("Invalid input")
+	}
+
+	if err := binding.Validator.ValidateStruct(patched); err != nil {
+		return errors.New("Invalid input")
+	}
+
+	return nil
+}
+
+func parseObjectIDs(ids []string) ([]primitive.ObjectID, error) {
+	objectIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			logrus.Error(err)
+			return nil, errors.New("invalid ObjectID format")
+		}
+		objectIDs = append(objectIDs, objectID)
+	}
+	return objectIDs, nil
+}